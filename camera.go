@@ -2,6 +2,7 @@ package main
 
 import (
 	"fluorescence/geometry"
+	"fmt"
 	"math"
 	"math/rand"
 )
@@ -16,11 +17,14 @@ type Camera struct {
 	AspectRatio    float64         `json:"aspect_ratio"`
 	Aperture       float64         `json:"aperture"`
 	FocusDistance  float64         `json:"focus_distance"`
+	ShutterOpen    float64         `json:"shutter_open"`  // time the shutter opens, moving objects are placed from time 0 to 1
+	ShutterClose   float64         `json:"shutter_close"` // time the shutter closes, the same as it opens for no motion blur
 
-	lensRadius float64
-	theta      float64
-	halfWidth  float64
-	halfHeight float64
+	lensRadius  float64
+	theta       float64
+	halfWidth   float64
+	halfHeight  float64
+	pixelSpread float64
 
 	w geometry.Vector
 	u geometry.Vector
@@ -34,6 +38,9 @@ type Camera struct {
 // Setup is called after allocating the Camera struct and filling the exported fields
 // It fills the unexported fields, such as derived vectors and measures
 func (c *Camera) Setup(p *Parameters) error {
+	if c.ShutterClose < c.ShutterOpen {
+		return fmt.Errorf("camera shutter closes (%f) before it opens (%f)", c.ShutterClose, c.ShutterOpen)
+	}
 	c.UpVector = c.UpVector.Unit()
 	c.AspectRatio = float64(p.ImageWidth) / float64(p.ImageHeight)
 
@@ -41,6 +48,7 @@ func (c *Camera) Setup(p *Parameters) error {
 	c.theta = c.VerticalFOV * math.Pi / 180.0
 	c.halfHeight = math.Tan(c.theta / 2.0)
 	c.halfWidth = c.AspectRatio * c.halfHeight
+	c.pixelSpread = 2.0 * c.halfHeight / float64(p.ImageHeight)
 
 	c.w = c.TargetLocation.To(c.EyeLocation).Unit()
 	c.u = c.UpVector.Cross(c.w)
@@ -57,10 +65,22 @@ func (c *Camera) Setup(p *Parameters) error {
 	return nil
 }
 
+// GetRayCone returns the cone covering a single pixel, around any Ray from GetRay
+func (c *Camera) GetRayCone() geometry.RayCone {
+	return geometry.RayCone{
+		Width:  0.0,
+		Spread: c.pixelSpread,
+	}
+}
+
 // GetRay returns a Ray from the eye location to a point on the view place u% across and v% up
 func (c *Camera) GetRay(u float64, v float64, rng *rand.Rand) geometry.Ray {
 	randomOnLens := geometry.RandomOnUnitDisk(rng).MultScalar(c.lensRadius)
 	offset := c.u.MultScalar(randomOnLens.X).Add(c.v.MultScalar(randomOnLens.Y))
+	time := c.ShutterOpen
+	if c.ShutterClose > c.ShutterOpen {
+		time += rng.Float64() * (c.ShutterClose - c.ShutterOpen)
+	}
 	return geometry.Ray{
 		Origin: c.EyeLocation.AddVector(offset),
 		Direction: c.lowerLeftCorner.AddVector(
@@ -68,5 +88,6 @@ func (c *Camera) GetRay(u float64, v float64, rng *rand.Rand) geometry.Ray {
 			c.verical.MultScalar(v)).From(
 			c.EyeLocation).Sub(
 			offset).Unit(),
+		Time: time,
 	}
 }
@@ -0,0 +1,53 @@
+package geometry
+
+import "math"
+
+// ONB is an orthonormal basis, used to move directions between world space
+// and a local frame built around a single vector (usually a surface normal)
+type ONB struct {
+	U Vector
+	V Vector
+	W Vector
+}
+
+// NewONB creates an orthonormal basis with its W axis pointing along w
+func NewONB(w Vector) ONB {
+	unitW := w.Unit()
+	var a Vector
+	if math.Abs(unitW.X) > 0.9 {
+		a = VectorUp
+	} else {
+		a = VectorRight
+	}
+	v := unitW.Cross(a).Unit()
+	u := v.Cross(unitW)
+	return ONB{
+		U: u,
+		V: v,
+		W: unitW,
+	}
+}
+
+// FromLocal converts a Vector expressed in this basis into world space
+func (o ONB) FromLocal(a Vector) Vector {
+	return o.U.MultScalar(a.X).Add(o.V.MultScalar(a.Y)).Add(o.W.MultScalar(a.Z))
+}
+
+// AngleAround returns the angle of a Vector around the W axis, measured from the U axis
+// towards the V axis, as a fraction of a full turn in [0, 1)
+func (o ONB) AngleAround(a Vector) float64 {
+	angle := math.Atan2(a.Dot(o.V), a.Dot(o.U)) / (2.0 * math.Pi)
+	if angle < 0.0 {
+		angle += 1.0
+	}
+	return angle
+}
+
+// ToLocal converts a world space Vector into this basis
+func (o ONB) ToLocal(a Vector) Vector {
+	return Vector{
+		X: a.Dot(o.U),
+		Y: a.Dot(o.V),
+		Z: a.Dot(o.W),
+	}
+}
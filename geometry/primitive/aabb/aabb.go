@@ -27,6 +27,44 @@ func SurroundingBox(aabb1, aabb2 *AABB) *AABB {
 	}
 }
 
+// sweepSteps is the number of intervals a motion is sampled over when bounding it
+const sweepSteps = 64
+
+// Swept returns the box around a box carried by a motion over times t0 to t1
+// the motion's corners are sampled at evenly spaced times, and the result is padded by half the farthest any corner moves between samples,
+// which covers the arcs of rotations of up to half a turn between samples
+func Swept(box *AABB, t0, t1 float64, motion func(p geometry.Point, time float64) geometry.Point) *AABB {
+	minPoint := geometry.PointMax
+	maxPoint := geometry.PointMax.Negate()
+	padding := 0.0
+	for i := 0.0; i < 2; i++ {
+		for j := 0.0; j < 2; j++ {
+			for k := 0.0; k < 2; k++ {
+				corner := geometry.Point{
+					X: i*box.B.X + (1-i)*box.A.X,
+					Y: j*box.B.Y + (1-j)*box.A.Y,
+					Z: k*box.B.Z + (1-k)*box.A.Z,
+				}
+				var previous geometry.Point
+				for step := 0; step <= sweepSteps; step++ {
+					moved := motion(corner, t0+(t1-t0)*float64(step)/sweepSteps)
+					if step > 0 {
+						padding = math.Max(padding, previous.To(moved).Magnitude()/2.0)
+					}
+					previous = moved
+					maxPoint = geometry.MaxComponents(maxPoint, moved)
+					minPoint = geometry.MinComponents(minPoint, moved)
+				}
+			}
+		}
+	}
+	pad := geometry.Vector{X: padding, Y: padding, Z: padding}
+	return &AABB{
+		A: minPoint.SubVector(pad),
+		B: maxPoint.AddVector(pad),
+	}
+}
+
 // func (aabb *AABB) Intersection(ray geometry.Ray, t0, t1 float64) bool {
 // 	return aabb.IntersectionNew(ray, t0, t1)
 // 	// return aabb.IntersectionClassic(ray, t0, t1)
@@ -2,6 +2,7 @@ package aabb
 
 import (
 	"fluorescence/geometry"
+	"math"
 	"testing"
 )
 
@@ -273,3 +274,29 @@ func BenchmarkAABBIntersectionZAxisMiss(b *testing.B) {
 	}
 	aabbHit = h
 }
+
+func TestSweptQuarterTurn(t *testing.T) {
+	box := &AABB{
+		A: geometry.Point{X: 1.0, Y: -0.5, Z: 0.0},
+		B: geometry.Point{X: 2.0, Y: 0.5, Z: 1.0},
+	}
+	// a quarter turn about the Z axis, which swings the box's far corners out past both ends of the motion
+	turn := func(p geometry.Point, time float64) geometry.Point {
+		sinTheta, cosTheta := math.Sincos(time * math.Pi / 2.0)
+		return geometry.Point{
+			X: cosTheta*p.X - sinTheta*p.Y,
+			Y: sinTheta*p.X + cosTheta*p.Y,
+			Z: p.Z,
+		}
+	}
+	swept := Swept(box, 0.0, 1.0, turn)
+	for step := 0; step <= 1000; step++ {
+		time := float64(step) / 1000.0
+		for _, corner := range []geometry.Point{box.A, box.B, {X: 2.0, Y: -0.5}, {X: 2.0, Y: 0.5, Z: 1.0}} {
+			p := turn(corner, time)
+			if p.X < swept.A.X || p.Y < swept.A.Y || p.Z < swept.A.Z || p.X > swept.B.X || p.Y > swept.B.Y || p.Z > swept.B.Z {
+				t.Fatalf("Expected %v at time %f to be inside %v to %v\n", p, time, swept.A, swept.B)
+			}
+		}
+	}
+}
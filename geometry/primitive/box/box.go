@@ -147,6 +147,11 @@ func (b *Box) IsClosed() bool {
 	return true
 }
 
+// SurfaceArea returns the surface area of this object
+func (b *Box) SurfaceArea() (float64, bool) {
+	return b.list.SurfaceArea()
+}
+
 // Copy returns a shallow copy of this object
 func (b *Box) Copy() primitive.Primitive {
 	newB := *b
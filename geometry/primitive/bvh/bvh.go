@@ -20,19 +20,24 @@ type BVH struct {
 }
 
 // New sets up and returns a new BVH
-func New(pl *primitivelist.PrimitiveList) (*BVH, error) {
+// boxes are built around where each object is from time t0 to t1, so moving objects are bounded over the camera's shutter interval
+func New(pl *primitivelist.PrimitiveList, t0, t1 float64) (*BVH, error) {
 	newBVH := &BVH{}
 
 	// can we do the sort?
-	_, ok := pl.BoundingBox(0, 0)
+	_, ok := pl.BoundingBox(t0, t1)
 	if !ok {
 		return nil, fmt.Errorf("no bounding box for input Primitive List")
 	}
+	boxes := make([]*aabb.AABB, len(pl.List))
+	for i, p := range pl.List {
+		boxes[i], _ = p.BoundingBox(t0, t1)
+	}
 
 	// pick the best axis
 	var axisNum int
-	firstBox, _ := pl.List[0].BoundingBox(0, 0)
-	lastBox, _ := pl.List[len(pl.List)-1].BoundingBox(0, 0)
+	firstBox := boxes[0]
+	lastBox := boxes[len(boxes)-1]
 
 	xDif := math.Abs(firstBox.A.X - lastBox.A.X)
 	yDif := math.Abs(firstBox.A.Y - lastBox.A.Y)
@@ -47,24 +52,22 @@ func New(pl *primitivelist.PrimitiveList) (*BVH, error) {
 
 	// do the sort
 	// axisNum := rand.Intn(3)
-	if axisNum == 0 {
-		sort.Sort(primitivelist.ByXPos(*pl))
-	} else if axisNum == 1 {
-		sort.Sort(primitivelist.ByYPos(*pl))
-	} else {
-		sort.Sort(primitivelist.ByZPos(*pl))
-	}
+	sort.Sort(byAxis{
+		list:    pl.List,
+		boxes:   boxes,
+		axisNum: axisNum,
+	})
 
 	// fill children
 	if len(pl.List) == 1 {
 		newBVH.left = pl.List[0]
 		newBVH.isSingle = true
 	} else {
-		left, err := New(pl.FirstHalfCopy())
+		left, err := New(pl.FirstHalfCopy(), t0, t1)
 		if err != nil {
 			return nil, err
 		}
-		right, err := New(pl.LastHalfCopy())
+		right, err := New(pl.LastHalfCopy(), t0, t1)
 		if err != nil {
 			return nil, err
 		}
@@ -72,14 +75,14 @@ func New(pl *primitivelist.PrimitiveList) (*BVH, error) {
 		newBVH.right = right
 	}
 	// est. box
-	leftBox, leftOk := newBVH.left.BoundingBox(0, 0)
+	leftBox, leftOk := newBVH.left.BoundingBox(t0, t1)
 	if newBVH.isSingle {
 		if !leftOk {
 			return nil, fmt.Errorf("no bounding box for some leaf of BVH")
 		}
 		newBVH.box = leftBox
 	} else {
-		rightBox, rightOk := newBVH.right.BoundingBox(0, 0)
+		rightBox, rightOk := newBVH.right.BoundingBox(t0, t1)
 		if !leftOk || !rightOk {
 			return nil, fmt.Errorf("no bounding box for some leaf of BVH")
 		}
@@ -88,6 +91,34 @@ func New(pl *primitivelist.PrimitiveList) (*BVH, error) {
 	return newBVH, nil
 }
 
+// byAxis sorts objects by the low corner of their boxes along an axis
+// the boxes are found once before sorting, as those of moving objects are costly to find
+type byAxis struct {
+	list    []primitive.Primitive
+	boxes   []*aabb.AABB
+	axisNum int
+}
+
+func (a byAxis) Len() int {
+	return len(a.list)
+}
+
+func (a byAxis) Swap(i, j int) {
+	a.list[i], a.list[j] = a.list[j], a.list[i]
+	a.boxes[i], a.boxes[j] = a.boxes[j], a.boxes[i]
+}
+
+func (a byAxis) Less(i, j int) bool {
+	switch a.axisNum {
+	case 0:
+		return a.boxes[i].A.X < a.boxes[j].A.X
+	case 1:
+		return a.boxes[i].A.Y < a.boxes[j].A.Y
+	default:
+		return a.boxes[i].A.Z < a.boxes[j].A.Z
+	}
+}
+
 // Intersection computer the intersection of this object and a given ray if it exists
 func (b *BVH) Intersection(ray geometry.Ray, tMin, tMax float64) (*material.RayHit, bool) {
 	hitBox := b.box.Intersection(ray, tMin, tMax)
@@ -17,7 +17,7 @@ func UnitBVHNTriangles(n int, xOffset, yOffset, zOffset float64) *BVH {
 	for i := 0; i < n; i++ {
 		pl.List = append(pl.List, triangle.Unit(xOffset+float64(i), yOffset, zOffset))
 	}
-	bvh, _ := New(pl)
+	bvh, _ := New(pl, 0, 0)
 	return bvh
 }
 
@@ -26,7 +26,7 @@ func UnitBVHNRectangles(n int, xOffset, yOffset, zOffset float64) *BVH {
 	for i := 0; i < n; i++ {
 		pl.List = append(pl.List, rectangle.Unit(xOffset+float64(i), yOffset, zOffset))
 	}
-	bvh, _ := New(pl)
+	bvh, _ := New(pl, 0, 0)
 	return bvh
 }
 
@@ -35,7 +35,7 @@ func UnitBVHNSpheres(n int, xOffset, yOffset, zOffset float64) *BVH {
 	for i := 0; i < n; i++ {
 		pl.List = append(pl.List, sphere.Unit(xOffset+float64(i), yOffset, zOffset))
 	}
-	bvh, _ := New(pl)
+	bvh, _ := New(pl, 0, 0)
 	return bvh
 }
 
@@ -0,0 +1,188 @@
+package csg
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/geometry/primitive"
+	"fluorescence/geometry/primitive/aabb"
+	"fluorescence/shading/material"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// advance is how far past a surface the search for the next surface along a ray starts
+const advance = 1e-7
+
+// operation is the way a CSG combines its two objects
+type operation int
+
+const (
+	unionOperation operation = iota
+	intersectionOperation
+	differenceOperation
+)
+
+// contains returns whether a point is inside the combined solid, given whether it is inside each object
+func (o operation) contains(insideLeft, insideRight bool) bool {
+	switch o {
+	case unionOperation:
+		return insideLeft || insideRight
+	case intersectionOperation:
+		return insideLeft && insideRight
+	default:
+		return insideLeft && !insideRight
+	}
+}
+
+// Operand is one of the two objects a CSG combines, as read from JSON
+type Operand struct {
+	TypeName string      `json:"type"`
+	Data     interface{} `json:"data"`
+}
+
+// CSG is a solid made by combining the space inside two closed objects
+// a ray's entries into and exits from each object are walked in order, and only surfaces where the ray
+// crosses into or out of the combined solid are hit, so each object's normals must face out of it
+type CSG struct {
+	Operation    string  `json:"operation"` // union, intersection, or difference, which cuts the right object out of the left
+	LeftOperand  Operand `json:"left"`
+	RightOperand Operand `json:"right"`
+	Left         primitive.Primitive
+	Right        primitive.Primitive
+	operation    operation
+}
+
+// Setup sets up a CSG's internal fields
+func (c *CSG) Setup() (*CSG, error) {
+	switch strings.ToLower(c.Operation) {
+	case "union":
+		c.operation = unionOperation
+	case "intersection":
+		c.operation = intersectionOperation
+	case "difference":
+		c.operation = differenceOperation
+	default:
+		return nil, fmt.Errorf("csg operation (%s) not a valid operation", c.Operation)
+	}
+	if c.Left == nil || c.Right == nil {
+		return nil, fmt.Errorf("csg %s is missing an operand", c.Operation)
+	}
+	if !c.Left.IsClosed() {
+		return nil, fmt.Errorf("csg operand (%s) is not closed", c.LeftOperand.TypeName)
+	}
+	if !c.Right.IsClosed() {
+		return nil, fmt.Errorf("csg operand (%s) is not closed", c.RightOperand.TypeName)
+	}
+	return c, nil
+}
+
+// Intersection computer the intersection of this object and a given ray if it exists
+func (c *CSG) Intersection(ray geometry.Ray, tMin, tMax float64) (*material.RayHit, bool) {
+	// surfaces past tMax still tell whether the ray starts inside each object
+	leftHit, leftWasHit := c.Left.Intersection(ray, tMin, math.MaxFloat64)
+	rightHit, rightWasHit := c.Right.Intersection(ray, tMin, math.MaxFloat64)
+	insideLeft := leftWasHit && !isEntry(ray, leftHit)
+	insideRight := rightWasHit && !isEntry(ray, rightHit)
+	inside := c.operation.contains(insideLeft, insideRight)
+
+	for leftWasHit || rightWasHit {
+		isLeft := leftWasHit && (!rightWasHit || leftHit.Time <= rightHit.Time)
+		rayHit := rightHit
+		if isLeft {
+			rayHit = leftHit
+		}
+		if rayHit.Time > tMax {
+			return nil, false
+		}
+		if isLeft {
+			insideLeft = isEntry(ray, leftHit)
+		} else {
+			insideRight = isEntry(ray, rightHit)
+		}
+		if c.operation.contains(insideLeft, insideRight) != inside {
+			// the right object's surface bounds a difference from outside of it, so faces the other way
+			if !isLeft && c.operation == differenceOperation {
+				flip(rayHit)
+			}
+			return rayHit, true
+		}
+		// the ray only crossed a surface inside or outside the solid, so look for the next one along
+		if isLeft {
+			leftHit, leftWasHit = c.Left.Intersection(ray, leftHit.Time+advance, math.MaxFloat64)
+		} else {
+			rightHit, rightWasHit = c.Right.Intersection(ray, rightHit.Time+advance, math.MaxFloat64)
+		}
+	}
+	return nil, false
+}
+
+// isEntry returns whether a ray enters an object at a hit, rather than leaving it
+func isEntry(ray geometry.Ray, rayHit *material.RayHit) bool {
+	return ray.Direction.Dot(rayHit.Geometric()) < 0.0
+}
+
+// flip turns a hit to face the other side of its surface
+// the bitangent is turned with the normals so the tangent frame keeps its handedness
+func flip(rayHit *material.RayHit) {
+	rayHit.NormalAtHit = rayHit.NormalAtHit.Negate()
+	rayHit.GeometricNormal = rayHit.GeometricNormal.Negate()
+	rayHit.Bitangent = rayHit.Bitangent.Negate()
+	rayHit.ObjectBitangent = rayHit.ObjectBitangent.Negate()
+}
+
+// BoundingBox returns an AABB for this object
+func (c *CSG) BoundingBox(t0, t1 float64) (*aabb.AABB, bool) {
+	leftBox, leftOk := c.Left.BoundingBox(t0, t1)
+	rightBox, rightOk := c.Right.BoundingBox(t0, t1)
+	switch c.operation {
+	case unionOperation:
+		if !leftOk || !rightOk {
+			return nil, false
+		}
+		return aabb.SurroundingBox(leftBox, rightBox), true
+	case intersectionOperation:
+		// the solid is inside both objects, so inside both boxes
+		if !leftOk {
+			return rightBox, rightOk
+		}
+		if !rightOk {
+			return leftBox, leftOk
+		}
+		minPoint := geometry.MaxComponents(leftBox.A, rightBox.A)
+		return &aabb.AABB{
+			A: minPoint,
+			B: geometry.MaxComponents(minPoint, geometry.MinComponents(leftBox.B, rightBox.B)),
+		}, true
+	default:
+		return leftBox, leftOk
+	}
+}
+
+// SetMaterial sets the material of both objects
+func (c *CSG) SetMaterial(m material.Material) {
+	c.Left.SetMaterial(m)
+	c.Right.SetMaterial(m)
+}
+
+// IsInfinite returns whether this object is infinite
+func (c *CSG) IsInfinite() bool {
+	switch c.operation {
+	case unionOperation:
+		return c.Left.IsInfinite() || c.Right.IsInfinite()
+	case intersectionOperation:
+		return c.Left.IsInfinite() && c.Right.IsInfinite()
+	default:
+		return c.Left.IsInfinite()
+	}
+}
+
+// IsClosed returns whether this object is closed
+func (c *CSG) IsClosed() bool {
+	return true
+}
+
+// Copy returns a shallow copy of this object
+func (c *CSG) Copy() primitive.Primitive {
+	newC := *c
+	return &newC
+}
@@ -0,0 +1,206 @@
+package csg
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/geometry/primitive/box"
+	"fluorescence/geometry/primitive/cylinder"
+	"fluorescence/geometry/primitive/rectangle"
+	"fluorescence/geometry/primitive/sphere"
+	"math"
+	"testing"
+)
+
+var csgHit bool
+
+// overlappingSpheres returns a CSG of two spheres of radius 1.5 centered on (-1, 0, 0) and (1, 0, 0)
+func overlappingSpheres(t testing.TB, operation string) *CSG {
+	left, err := (&sphere.Sphere{Center: geometry.Point{X: -1.0}, Radius: 1.5}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up sphere: %s\n", err)
+	}
+	right, err := (&sphere.Sphere{Center: geometry.Point{X: 1.0}, Radius: 1.5}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up sphere: %s\n", err)
+	}
+	c, err := (&CSG{
+		Operation: operation,
+		Left:      left,
+		Right:     right,
+	}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up csg: %s\n", err)
+	}
+	return c
+}
+
+// drilledBox returns a CSG of a box from (-1, -1, -1) to (1, 1, 1) with a hole of radius 0.5 along the X axis
+func drilledBox(t testing.TB) *CSG {
+	b, err := (&box.Box{
+		A: geometry.Point{X: -1.0, Y: -1.0, Z: -1.0},
+		B: geometry.Point{X: 1.0, Y: 1.0, Z: 1.0},
+	}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up box: %s\n", err)
+	}
+	c, err := (&cylinder.Cylinder{
+		A:      geometry.Point{X: -2.0},
+		B:      geometry.Point{X: 2.0},
+		Radius: 0.5,
+	}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up cylinder: %s\n", err)
+	}
+	d, err := (&CSG{
+		Operation: "difference",
+		Left:      b,
+		Right:     c,
+	}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up csg: %s\n", err)
+	}
+	return d
+}
+
+func TestCSGUnion(t *testing.T) {
+	c := overlappingSpheres(t, "union")
+	ray := geometry.Ray{
+		Origin:    geometry.Point{X: -5.0},
+		Direction: geometry.Vector{X: 1.0},
+	}
+	rh, h := c.Intersection(ray, 1e-7, math.MaxFloat64)
+	if !h {
+		t.Fatalf("Expected true (hit) but got %t\n", h)
+	}
+	if math.Abs(rh.Time-2.5) > 1e-9 {
+		t.Errorf("Expected hit at time 2.5 but got %f\n", rh.Time)
+	}
+	// from inside both spheres, the surface of the left sphere inside the right one is passed by
+	ray.Origin = geometry.PointZero
+	rh, h = c.Intersection(ray, 1e-7, math.MaxFloat64)
+	if !h {
+		t.Fatalf("Expected true (hit) but got %t\n", h)
+	}
+	if math.Abs(rh.Time-2.5) > 1e-9 {
+		t.Errorf("Expected hit at time 2.5 but got %f\n", rh.Time)
+	}
+}
+
+func TestCSGIntersection(t *testing.T) {
+	// the operation is read without regard to case
+	c := overlappingSpheres(t, "Intersection")
+	ray := geometry.Ray{
+		Origin:    geometry.Point{X: -5.0},
+		Direction: geometry.Vector{X: 1.0},
+	}
+	rh, h := c.Intersection(ray, 1e-7, math.MaxFloat64)
+	if !h {
+		t.Fatalf("Expected true (hit) but got %t\n", h)
+	}
+	if math.Abs(rh.Time-4.5) > 1e-9 {
+		t.Errorf("Expected hit at time 4.5 but got %f\n", rh.Time)
+	}
+	expectedNormal := geometry.Vector{X: -1.0}
+	if rh.NormalAtHit.Sub(expectedNormal).Magnitude() > 1e-9 {
+		t.Errorf("Expected normal %v but got %v\n", expectedNormal, rh.NormalAtHit)
+	}
+	// a ray through only the left sphere misses the lens
+	ray = geometry.Ray{
+		Origin:    geometry.Point{X: -1.0, Y: 0.0, Z: 5.0},
+		Direction: geometry.Vector{Z: -1.0},
+	}
+	if _, h := c.Intersection(ray, 1e-7, math.MaxFloat64); h {
+		t.Errorf("Expected false (miss) but got %t\n", h)
+	}
+	box, ok := c.BoundingBox(0, 0)
+	if !ok {
+		t.Fatalf("Expected a bounding box\n")
+	}
+	if math.Abs(box.A.X+0.5) > 1e-6 || math.Abs(box.B.X-0.5) > 1e-6 {
+		t.Errorf("Expected box from X -0.5 to 0.5 but got %f to %f\n", box.A.X, box.B.X)
+	}
+}
+
+func TestCSGDifference(t *testing.T) {
+	d := drilledBox(t)
+	// straight down the hole
+	ray := geometry.Ray{
+		Origin:    geometry.Point{X: 5.0},
+		Direction: geometry.Vector{X: -1.0},
+	}
+	if _, h := d.Intersection(ray, 1e-7, math.MaxFloat64); h {
+		t.Errorf("Expected false (miss) but got %t\n", h)
+	}
+	// across the hole, from inside the box wall above it
+	ray = geometry.Ray{
+		Origin:    geometry.Point{X: 0.0, Y: 0.0, Z: 5.0},
+		Direction: geometry.Vector{Z: -1.0},
+	}
+	rh, h := d.Intersection(ray, 4.2, math.MaxFloat64)
+	if !h {
+		t.Fatalf("Expected true (hit) but got %t\n", h)
+	}
+	if math.Abs(rh.Time-4.5) > 1e-9 {
+		t.Errorf("Expected hit at time 4.5 but got %f\n", rh.Time)
+	}
+	// the wall of the hole faces into the hole
+	expectedNormal := geometry.Vector{Z: -1.0}
+	if rh.NormalAtHit.Sub(expectedNormal).Magnitude() > 1e-9 || rh.Geometric().Sub(expectedNormal).Magnitude() > 1e-9 {
+		t.Errorf("Expected normal %v but got %v\n", expectedNormal, rh.NormalAtHit)
+	}
+}
+
+func TestCSGDifferenceFromInsideHole(t *testing.T) {
+	d := drilledBox(t)
+	ray := geometry.Ray{
+		Origin:    geometry.PointZero,
+		Direction: geometry.Vector{Z: 1.0},
+	}
+	// the box's own faces are past the end of a short ray, and must not be mistaken for leaving the solid
+	if _, h := d.Intersection(ray, 1e-7, 0.4); h {
+		t.Errorf("Expected false (miss) but got %t\n", h)
+	}
+	rh, h := d.Intersection(ray, 1e-7, math.MaxFloat64)
+	if !h {
+		t.Fatalf("Expected true (hit) but got %t\n", h)
+	}
+	if math.Abs(rh.Time-0.5) > 1e-9 {
+		t.Errorf("Expected hit at time 0.5 but got %f\n", rh.Time)
+	}
+	if rh.NormalAtHit.Dot(ray.Direction) >= 0.0 {
+		t.Errorf("Expected normal %v to face the ray\n", rh.NormalAtHit)
+	}
+}
+
+func TestCSGSetupErrors(t *testing.T) {
+	s, err := (&sphere.Sphere{Radius: 1.0}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up sphere: %s\n", err)
+	}
+	r, err := (&rectangle.Rectangle{
+		A: geometry.Point{X: -1.0, Y: -1.0, Z: 0.0},
+		B: geometry.Point{X: 1.0, Y: 1.0, Z: 0.0},
+	}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up rectangle: %s\n", err)
+	}
+	if _, err := (&CSG{Operation: "difference", Left: s, Right: r}).Setup(); err == nil {
+		t.Errorf("Expected an error for an operand which is not closed\n")
+	}
+	if _, err := (&CSG{Operation: "xor", Left: s, Right: s}).Setup(); err == nil {
+		t.Errorf("Expected an error for an unknown operation\n")
+	}
+}
+
+func BenchmarkCSGDifferenceHit(b *testing.B) {
+	d := drilledBox(b)
+	ray := geometry.Ray{
+		Origin:    geometry.Point{X: 0.0, Y: 0.0, Z: 5.0},
+		Direction: geometry.Vector{Z: -1.0},
+	}
+	var h bool
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, h = d.Intersection(ray, 1e-7, math.MaxFloat64)
+	}
+	csgHit = h
+}
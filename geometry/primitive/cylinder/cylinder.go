@@ -92,6 +92,11 @@ func (c *Cylinder) IsClosed() bool {
 	return true
 }
 
+// SurfaceArea returns the surface area of this object
+func (c *Cylinder) SurfaceArea() (float64, bool) {
+	return c.list.SurfaceArea()
+}
+
 // Copy returns a shallow copy of this object
 func (c *Cylinder) Copy() primitive.Primitive {
 	newC := *c
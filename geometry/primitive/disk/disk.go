@@ -16,6 +16,7 @@ type Disk struct {
 	Radius        float64         `json:"radius"`
 	IsCulled      bool            `json:"is_culled"`
 	radiusSquared float64
+	basis         geometry.ONB // tangent frame of the disk's surface
 	mat           material.Material
 }
 
@@ -31,6 +32,7 @@ func (d *Disk) Setup() (*Disk, error) {
 		return nil, fmt.Errorf("disk radius is 0 or negative")
 	}
 	d.radiusSquared = d.Radius * d.Radius
+	d.basis = geometry.NewONB(d.Normal)
 	return d, nil
 }
 
@@ -60,11 +62,19 @@ func (d *Disk) Intersection(ray geometry.Ray, tMin, tMax float64) (*material.Ray
 	// 	return nil, false
 	// }
 
+	// the texture is stretched over the square bounding the disk
 	return &material.RayHit{
-		Ray:         ray,
-		NormalAtHit: d.Normal,
-		Time:        t,
-		Material:    d.mat,
+		Ray:             ray,
+		Point:           hitPoint,
+		ObjectPoint:     hitPoint,
+		NormalAtHit:     d.Normal,
+		GeometricNormal: d.Normal,
+		Time:            t,
+		U:               0.5 + diskVector.Dot(d.basis.U)/(2.0*d.Radius),
+		V:               0.5 + diskVector.Dot(d.basis.V)/(2.0*d.Radius),
+		Tangent:         d.basis.U.MultScalar(2.0 * d.Radius),
+		Bitangent:       d.basis.V.MultScalar(2.0 * d.Radius),
+		Material:        d.mat,
 	}, true
 }
 
@@ -102,6 +112,11 @@ func (d *Disk) IsClosed() bool {
 	return false
 }
 
+// SurfaceArea returns the surface area of this object
+func (d *Disk) SurfaceArea() (float64, bool) {
+	return math.Pi * d.radiusSquared, true
+}
+
 // Copy return a shallow copy of this object
 func (d *Disk) Copy() primitive.Primitive {
 	newD := *d
@@ -105,6 +105,11 @@ func (hc *HollowCylinder) IsClosed() bool {
 	return true
 }
 
+// SurfaceArea returns the surface area of this object
+func (hc *HollowCylinder) SurfaceArea() (float64, bool) {
+	return hc.list.SurfaceArea()
+}
+
 // Copy returns a shallow copy of this object
 func (hc *HollowCylinder) Copy() primitive.Primitive {
 	newHC := *hc
@@ -18,6 +18,7 @@ type HollowDisk struct {
 	IsCulled           bool            `json:"is_culled"`
 	innerRadiusSquared float64
 	outerRadiusSquared float64
+	basis              geometry.ONB // tangent frame of the hollow disk's surface
 	mat                material.Material
 }
 
@@ -49,6 +50,7 @@ func (hd *HollowDisk) Setup() (*HollowDisk, error) {
 	hd.Normal = hd.Normal.Unit()
 	hd.innerRadiusSquared = hd.InnerRadius * hd.InnerRadius
 	hd.outerRadiusSquared = hd.OuterRadius * hd.OuterRadius
+	hd.basis = geometry.NewONB(hd.Normal)
 	return hd, nil
 }
 
@@ -81,11 +83,19 @@ func (hd *HollowDisk) Intersection(ray geometry.Ray, tMin, tMax float64) (*mater
 	// 	return nil, false
 	// }
 
+	// the texture is stretched over the square bounding the hollow disk
 	return &material.RayHit{
-		Ray:         ray,
-		NormalAtHit: hd.Normal,
-		Time:        t,
-		Material:    hd.mat,
+		Ray:             ray,
+		Point:           hitPoint,
+		ObjectPoint:     hitPoint,
+		NormalAtHit:     hd.Normal,
+		GeometricNormal: hd.Normal,
+		Time:            t,
+		U:               0.5 + diskVector.Dot(hd.basis.U)/(2.0*hd.OuterRadius),
+		V:               0.5 + diskVector.Dot(hd.basis.V)/(2.0*hd.OuterRadius),
+		Tangent:         hd.basis.U.MultScalar(2.0 * hd.OuterRadius),
+		Bitangent:       hd.basis.V.MultScalar(2.0 * hd.OuterRadius),
+		Material:        hd.mat,
 	}, true
 }
 
@@ -123,6 +133,11 @@ func (hd *HollowDisk) IsClosed() bool {
 	return false
 }
 
+// SurfaceArea returns the surface area of this object
+func (hd *HollowDisk) SurfaceArea() (float64, bool) {
+	return math.Pi * (hd.outerRadiusSquared - hd.innerRadiusSquared), true
+}
+
 // Copy returns a shallow copy of thie object
 func (hd *HollowDisk) Copy() primitive.Primitive {
 	newHD := *hd
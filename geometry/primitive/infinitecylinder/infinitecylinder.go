@@ -14,6 +14,7 @@ type InfiniteCylinder struct {
 	Ray                geometry.Ray `json:"ray"`
 	Radius             float64      `json:"radius"`
 	HasInvertedNormals bool         `json:"has_inverted_normals"`
+	basis              geometry.ONB // frame around the axis, whose U axis is where texture coordinate U starts
 	mat                material.Material
 }
 
@@ -37,11 +38,12 @@ func (ic *InfiniteCylinder) Setup() (*InfiniteCylinder, error) {
 	if ic.Radius <= 0.0 {
 		return nil, fmt.Errorf("infinite cylinder radius is 0 or negative")
 	}
-	ic.Ray.Direction.Unit()
+	ic.Ray.Direction = ic.Ray.Direction.Unit()
 	return &InfiniteCylinder{
 		Ray:                ic.Ray,
 		Radius:             ic.Radius,
 		HasInvertedNormals: ic.HasInvertedNormals,
+		basis:              geometry.NewONB(ic.Ray.Direction),
 	}, nil
 }
 
@@ -64,21 +66,39 @@ func (ic *InfiniteCylinder) Intersection(ray geometry.Ray, tMin, tMax float64) (
 		t1 := (-b - root) / a
 		// return if within range
 		if t1 >= tMin && t1 <= tMax {
+			p1 := ray.PointAt(t1)
+			normal := ic.normalAt(p1)
 			return &material.RayHit{
-				Ray:         ray,
-				NormalAtHit: ic.normalAt(ray.PointAt(t1)),
-				Time:        t1,
-				Material:    ic.mat,
+				Ray:             ray,
+				Point:           p1,
+				ObjectPoint:     p1,
+				NormalAtHit:     normal,
+				GeometricNormal: normal,
+				Time:            t1,
+				U:               ic.textureUAt(p1),
+				V:               ic.Ray.ClosestTime(p1),
+				Tangent:         ic.tangentAt(p1),
+				Bitangent:       ic.Ray.Direction,
+				Material:        ic.mat,
 			}, true
 		}
 		// evaluate and return second solution if in range
 		t2 := (-b + root) / a
 		if t2 >= tMin && t2 <= tMax {
+			p2 := ray.PointAt(t2)
+			normal := ic.normalAt(p2)
 			return &material.RayHit{
-				Ray:         ray,
-				NormalAtHit: ic.normalAt(ray.PointAt(t2)),
-				Time:        t2,
-				Material:    ic.mat,
+				Ray:             ray,
+				Point:           p2,
+				ObjectPoint:     p2,
+				NormalAtHit:     normal,
+				GeometricNormal: normal,
+				Time:            t2,
+				U:               ic.textureUAt(p2),
+				V:               ic.Ray.ClosestTime(p2),
+				Tangent:         ic.tangentAt(p2),
+				Bitangent:       ic.Ray.Direction,
+				Material:        ic.mat,
 			}, true
 		}
 	}
@@ -112,6 +132,19 @@ func (ic *InfiniteCylinder) Copy() primitive.Primitive {
 	return &newIC
 }
 
+// textureUAt returns the texture coordinate U, the fraction of the way around the axis, at a point
+// the point is assumed to be on the surface of the object
+// texture coordinate V is the distance along the axis from the ray's origin, so textures repeat every unit
+func (ic *InfiniteCylinder) textureUAt(p geometry.Point) float64 {
+	return ic.basis.AngleAround(ic.Ray.ClosestPoint(p).To(p))
+}
+
+// tangentAt returns the rate of change of a point on the surface of this object with texture coordinate U
+// the point is assumed to be on the surface of the object
+func (ic *InfiniteCylinder) tangentAt(p geometry.Point) geometry.Vector {
+	return ic.Ray.Direction.Cross(ic.Ray.ClosestPoint(p).To(p)).Unit().MultScalar(2.0 * math.Pi * ic.Radius)
+}
+
 // normalAt returns the normal of this object at the specified point
 // the point is assumed to be on the surface of the object
 func (ic *InfiniteCylinder) normalAt(p geometry.Point) geometry.Vector {
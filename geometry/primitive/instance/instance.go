@@ -0,0 +1,112 @@
+package instance
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/geometry/primitive"
+	"fluorescence/geometry/primitive/aabb"
+	"fluorescence/geometry/primitive/transform/affine"
+	"fluorescence/shading/material"
+	"fmt"
+)
+
+// Instance places another object's geometry in the scene with its own transform and material
+// the geometry, and any bounding volume hierarchy inside it, is shared rather than copied,
+// so the same object can be placed many times for little more than the size of an Instance
+type Instance struct {
+	ObjectName        string          `json:"object_name"`        // name of the object whose geometry is placed
+	Translation       geometry.Vector `json:"translation"`        // offset of the object's origin
+	Rotation          geometry.Vector `json:"rotation"`           // angles in degrees about the X, Y, then Z axes
+	Scale             geometry.Vector `json:"scale"`              // scale along each axis, applied before rotating, or 1 on every axis if zero
+	OverrideMaterials bool            `json:"override_materials"` // should the instance's material replace the materials the geometry carries itself?
+	Primitive         primitive.Primitive
+	matrix            *affine.Matrix
+	mat               material.Material
+}
+
+// Setup sets up an Instance's internal fields
+func (i *Instance) Setup() (*Instance, error) {
+	if i.Primitive == nil {
+		return nil, fmt.Errorf("instance of (%s) has no geometry", i.ObjectName)
+	}
+	if i.Scale == geometry.VectorZero {
+		i.Scale = geometry.Vector{X: 1.0, Y: 1.0, Z: 1.0}
+	}
+	m, err := affine.Compose([]affine.Step{
+		{TypeName: "scale", Vector: i.Scale},
+		{TypeName: "rotate", Axis: geometry.Vector{X: 1.0}, Angle: i.Rotation.X},
+		{TypeName: "rotate", Axis: geometry.Vector{Y: 1.0}, Angle: i.Rotation.Y},
+		{TypeName: "rotate", Axis: geometry.Vector{Z: 1.0}, Angle: i.Rotation.Z},
+		{TypeName: "translate", Vector: i.Translation},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("instance of (%s): %v", i.ObjectName, err)
+	}
+	i.matrix, err = affine.New(m)
+	if err != nil {
+		return nil, fmt.Errorf("instance of (%s): %v", i.ObjectName, err)
+	}
+	return i, nil
+}
+
+// Intersection computer the intersection of this object and a given ray if it exists
+// the ray's direction is carried into the object's space without being normalized, so hit times are the same in both spaces
+func (i *Instance) Intersection(ray geometry.Ray, tMin, tMax float64) (*material.RayHit, bool) {
+	rayHit, wasHit := i.Primitive.Intersection(i.matrix.ToObjectRay(ray), tMin, tMax)
+	if !wasHit {
+		return nil, false
+	}
+	i.matrix.ToWorldHit(rayHit, ray)
+	if i.mat != nil && (rayHit.Material == nil || i.OverrideMaterials) {
+		rayHit.Material = i.mat
+	}
+	return rayHit, true
+}
+
+// BoundingBox returns an AABB for this object
+func (i *Instance) BoundingBox(t0, t1 float64) (*aabb.AABB, bool) {
+	box, ok := i.Primitive.BoundingBox(t0, t1)
+	if !ok {
+		return nil, false
+	}
+	return i.matrix.BoundingBox(box), true
+}
+
+// SetMaterial sets the material of this instance
+// the shared geometry is left untouched, as other instances of it may use other materials
+func (i *Instance) SetMaterial(m material.Material) {
+	i.mat = m
+}
+
+// IsInfinite returns whether this object is infinite
+func (i *Instance) IsInfinite() bool {
+	return i.Primitive.IsInfinite()
+}
+
+// IsClosed returns whether this object is closed
+func (i *Instance) IsClosed() bool {
+	return i.Primitive.IsClosed()
+}
+
+// SurfaceArea returns the surface area of this object
+// the area is only known when the instance is scaled equally along every axis
+func (i *Instance) SurfaceArea() (float64, bool) {
+	surface, ok := i.Primitive.(primitive.Surface)
+	if !ok {
+		return 0.0, false
+	}
+	scale, ok := i.matrix.AreaScale()
+	if !ok {
+		return 0.0, false
+	}
+	area, ok := surface.SurfaceArea()
+	if !ok {
+		return 0.0, false
+	}
+	return area * scale, true
+}
+
+// Copy returns a shallow copy of this object, which shares its geometry with the original
+func (i *Instance) Copy() primitive.Primitive {
+	newI := *i
+	return &newI
+}
@@ -0,0 +1,316 @@
+package instance
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/geometry/primitive/sphere"
+	"fluorescence/geometry/primitive/transform/affine"
+	"fluorescence/geometry/primitive/transform/rotate"
+	"fluorescence/geometry/primitive/transform/translate"
+	"fluorescence/geometry/primitive/triangle"
+	"fluorescence/shading/material"
+	"math"
+	"testing"
+)
+
+var instanceHit bool
+
+func newScaledSphereInstance(t testing.TB) (*sphere.Sphere, *Instance) {
+	s, err := (&sphere.Sphere{Radius: 1.0}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up sphere: %s\n", err)
+	}
+	i, err := (&Instance{
+		ObjectName:  "sphere",
+		Translation: geometry.Vector{X: 5.0, Y: 0.0, Z: 0.0},
+		Rotation:    geometry.Vector{X: 0.0, Y: 90.0, Z: 0.0},
+		Scale:       geometry.Vector{X: 2.0, Y: 2.0, Z: 2.0},
+		Primitive:   s,
+	}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up instance: %s\n", err)
+	}
+	return s, i
+}
+
+func TestInstanceIntersection(t *testing.T) {
+	_, i := newScaledSphereInstance(t)
+	r := geometry.Ray{
+		Origin:    geometry.Point{X: 5.0, Y: 0.0, Z: 10.0},
+		Direction: geometry.Vector{X: 0.0, Y: 0.0, Z: -1.0},
+	}
+	rh, h := i.Intersection(r, 1e-7, math.MaxFloat64)
+	if !h {
+		t.Fatalf("Expected true (hit) but got %t\n", h)
+	}
+	if math.Abs(rh.Time-8.0) > 1e-9 {
+		t.Errorf("Expected hit at time 8 but got %f\n", rh.Time)
+	}
+	expectedNormal := geometry.Vector{X: 0.0, Y: 0.0, Z: 1.0}
+	if rh.NormalAtHit.Sub(expectedNormal).Magnitude() > 1e-9 {
+		t.Errorf("Expected normal %v but got %v\n", expectedNormal, rh.NormalAtHit)
+	}
+	if rh.Ray != r {
+		t.Errorf("Expected the hit to carry the world ray %v but got %v\n", r, rh.Ray)
+	}
+}
+
+func TestInstanceIntersectionNonUniformScale(t *testing.T) {
+	s, err := (&sphere.Sphere{Radius: 1.0}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up sphere: %s\n", err)
+	}
+	i, err := (&Instance{
+		Scale:     geometry.Vector{X: 4.0, Y: 1.0, Z: 1.0},
+		Primitive: s,
+	}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up instance: %s\n", err)
+	}
+	// a ray hitting the stretched sphere at 45 degrees to its long axis, where the object's normal would be wrong
+	r := geometry.Ray{
+		Origin:    geometry.Point{X: 2.0, Y: 10.0, Z: 0.0},
+		Direction: geometry.Vector{X: 0.0, Y: -1.0, Z: 0.0},
+	}
+	rh, h := i.Intersection(r, 1e-7, math.MaxFloat64)
+	if !h {
+		t.Fatalf("Expected true (hit) but got %t\n", h)
+	}
+	// the ellipsoid x^2/16 + y^2 + z^2 = 1 has a normal along its gradient (x/16, y, z)
+	p := r.PointAt(rh.Time)
+	expectedNormal := geometry.Vector{X: p.X / 16.0, Y: p.Y, Z: p.Z}.Unit()
+	if rh.NormalAtHit.Sub(expectedNormal).Magnitude() > 1e-9 {
+		t.Errorf("Expected normal %v but got %v\n", expectedNormal, rh.NormalAtHit)
+	}
+	if _, ok := i.SurfaceArea(); ok {
+		t.Errorf("Expected no surface area for a non-uniformly scaled instance\n")
+	}
+}
+
+func TestInstanceMaterial(t *testing.T) {
+	s, i := newScaledSphereInstance(t)
+	own := &material.Lambertian{}
+	override := &material.Lambertian{}
+	r := geometry.Ray{
+		Origin:    geometry.Point{X: 5.0, Y: 0.0, Z: 10.0},
+		Direction: geometry.Vector{X: 0.0, Y: 0.0, Z: -1.0},
+	}
+
+	i.SetMaterial(override)
+	rh, _ := i.Intersection(r, 1e-7, math.MaxFloat64)
+	if rh.Material != override {
+		t.Errorf("Expected the instance's material on geometry without one\n")
+	}
+
+	s.SetMaterial(own)
+	rh, _ = i.Intersection(r, 1e-7, math.MaxFloat64)
+	if rh.Material != own {
+		t.Errorf("Expected the geometry's own material to be kept\n")
+	}
+
+	i.OverrideMaterials = true
+	rh, _ = i.Intersection(r, 1e-7, math.MaxFloat64)
+	if rh.Material != override {
+		t.Errorf("Expected the instance's material to override the geometry's own\n")
+	}
+}
+
+func TestInstanceCopySharesGeometry(t *testing.T) {
+	s, i := newScaledSphereInstance(t)
+	first := &material.Lambertian{}
+	second := &material.Lambertian{}
+	copied := i.Copy()
+	i.SetMaterial(first)
+	copied.SetMaterial(second)
+	if copied.(*Instance).Primitive != s {
+		t.Errorf("Expected the copy to share its geometry\n")
+	}
+	r := geometry.Ray{
+		Origin:    geometry.Point{X: 5.0, Y: 0.0, Z: 10.0},
+		Direction: geometry.Vector{X: 0.0, Y: 0.0, Z: -1.0},
+	}
+	rh, _ := i.Intersection(r, 1e-7, math.MaxFloat64)
+	if rh.Material != first {
+		t.Errorf("Expected setting the copy's material to leave the original's alone\n")
+	}
+	rh, _ = s.Intersection(r, 1e-7, math.MaxFloat64)
+	if rh != nil && rh.Material != nil {
+		t.Errorf("Expected the shared geometry to be left without a material\n")
+	}
+}
+
+func TestInstanceBoundingBox(t *testing.T) {
+	_, i := newScaledSphereInstance(t)
+	box, ok := i.BoundingBox(0, 0)
+	if !ok {
+		t.Fatalf("Expected a bounding box\n")
+	}
+	expectedA := geometry.Point{X: 3.0, Y: -2.0, Z: -2.0}
+	expectedB := geometry.Point{X: 7.0, Y: 2.0, Z: 2.0}
+	if box.A.To(expectedA).Magnitude() > 1e-6 || box.B.To(expectedB).Magnitude() > 1e-6 {
+		t.Errorf("Expected box from %v to %v but got %v to %v\n", expectedA, expectedB, box.A, box.B)
+	}
+	area, ok := i.SurfaceArea()
+	if !ok || math.Abs(area-16.0*math.Pi) > 1e-9 {
+		t.Errorf("Expected surface area %f but got %f (%t)\n", 16.0*math.Pi, area, ok)
+	}
+}
+
+func TestInstanceNestedTransformsSphere(t *testing.T) {
+	s, err := (&sphere.Sphere{Radius: 1.0}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up sphere: %s\n", err)
+	}
+	ry, err := (&rotate.RotationY{AngleDegrees: 90.0, Primitive: s}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up rotation: %s\n", err)
+	}
+	tr, err := (&translate.Translation{Displacement: geometry.Vector{X: 0.0, Y: 0.0, Z: -3.0}, Primitive: ry}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up translation: %s\n", err)
+	}
+	af, err := (&affine.Transform{
+		Steps: []affine.Step{
+			{TypeName: "scale", Vector: geometry.Vector{X: 2.0, Y: 2.0, Z: 2.0}},
+			{TypeName: "rotate", Axis: geometry.Vector{X: 1.0}, Angle: 30.0},
+		},
+		Primitive: tr,
+	}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up transform: %s\n", err)
+	}
+	i, err := (&Instance{
+		Translation: geometry.Vector{X: 1.0, Y: 2.0, Z: -4.0},
+		Rotation:    geometry.Vector{X: 0.0, Y: 45.0, Z: 10.0},
+		Primitive:   af,
+	}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up instance: %s\n", err)
+	}
+	q, err := (&rotate.Quaternion{AxisAngles: [3]float64{20.0, 0.0, -35.0}, Order: "xyz", Primitive: i}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up quaternion: %s\n", err)
+	}
+
+	// every step is rigid or scales evenly, so the world shape is a sphere of radius 2 centered in its box
+	box, _ := q.BoundingBox(0, 0)
+	center := box.A.AddVector(box.A.To(box.B).DivScalar(2.0))
+	origin := geometry.Point{X: 0.0, Y: 0.0, Z: 50.0}
+	r := geometry.Ray{
+		Origin:    origin,
+		Direction: origin.To(center).Unit().MultScalar(3.0),
+	}
+	rh, h := q.Intersection(r, 1e-7, math.MaxFloat64)
+	if !h {
+		t.Fatalf("Expected true (hit) but got %t\n", h)
+	}
+	if rh.Ray != r {
+		t.Errorf("Expected the hit to carry the world ray %v but got %v\n", r, rh.Ray)
+	}
+	expectedPoint := origin.AddVector(origin.To(center).Unit().MultScalar(origin.To(center).Magnitude() - 2.0))
+	if rh.Point.To(expectedPoint).Magnitude() > 1e-6 {
+		t.Errorf("Expected world point %v but got %v\n", expectedPoint, rh.Point)
+	}
+	if rh.Point.To(r.PointAt(rh.Time)).Magnitude() > 1e-6 {
+		t.Errorf("Expected world point %v to be along the ray at time %f\n", rh.Point, rh.Time)
+	}
+	if math.Abs(geometry.PointZero.To(rh.ObjectPoint).Magnitude()-1.0) > 1e-9 {
+		t.Errorf("Expected object point %v on the unit sphere\n", rh.ObjectPoint)
+	}
+	expectedNormal := center.To(rh.Point).Unit()
+	if rh.NormalAtHit.Sub(expectedNormal).Magnitude() > 1e-6 {
+		t.Errorf("Expected shading normal %v but got %v\n", expectedNormal, rh.NormalAtHit)
+	}
+	if rh.GeometricNormal.Sub(expectedNormal).Magnitude() > 1e-6 {
+		t.Errorf("Expected geometric normal %v but got %v\n", expectedNormal, rh.GeometricNormal)
+	}
+}
+
+func TestInstanceNestedTransformsSmoothTriangle(t *testing.T) {
+	tri, err := (&triangle.Triangle{
+		A:       geometry.Point{X: -1.0, Y: -1.0, Z: 0.0},
+		B:       geometry.Point{X: 1.0, Y: -1.0, Z: 0.0},
+		C:       geometry.Point{X: 0.0, Y: 1.0, Z: 0.0},
+		UVB:     [2]float64{1.0, 0.0},
+		UVC:     [2]float64{0.0, 1.0},
+		NormalA: geometry.Vector{X: -1.0, Y: 0.0, Z: 1.0}.Unit(),
+		NormalB: geometry.Vector{X: 1.0, Y: 0.0, Z: 1.0}.Unit(),
+		NormalC: geometry.Vector{X: 0.0, Y: 1.0, Z: 1.0}.Unit(),
+	}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up triangle: %s\n", err)
+	}
+	rx, err := (&rotate.RotationX{AngleDegrees: -20.0, Primitive: tri}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up rotation: %s\n", err)
+	}
+	af, err := (&affine.Transform{
+		Steps: []affine.Step{
+			{TypeName: "scale", Vector: geometry.Vector{X: 3.0, Y: 1.0, Z: 2.0}},
+			{TypeName: "matrix", Elements: [16]float64{
+				1, 0.5, 0, 0,
+				0, 1, 0, 0,
+				0, 0, 1, 0,
+				0, 0, 0, 1,
+			}},
+		},
+		Primitive: rx,
+	}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up transform: %s\n", err)
+	}
+	i, err := (&Instance{
+		Translation: geometry.Vector{X: 0.0, Y: 0.0, Z: -5.0},
+		Rotation:    geometry.Vector{X: 0.0, Y: 15.0, Z: 0.0},
+		Primitive:   af,
+	}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up instance: %s\n", err)
+	}
+	tr, err := (&translate.Translation{Displacement: geometry.Vector{X: 0.5, Y: 0.0, Z: 0.0}, Primitive: i}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up translation: %s\n", err)
+	}
+
+	r := geometry.Ray{
+		Origin:    geometry.Point{X: 0.6, Y: -0.2, Z: 10.0},
+		Direction: geometry.Vector{X: 0.0, Y: 0.0, Z: -1.0},
+	}
+	rh, h := tr.Intersection(r, 1e-7, math.MaxFloat64)
+	if !h {
+		t.Fatalf("Expected true (hit) but got %t\n", h)
+	}
+	if rh.Point.To(r.PointAt(rh.Time)).Magnitude() > 1e-9 {
+		t.Errorf("Expected world point %v to be along the ray at time %f\n", rh.Point, rh.Time)
+	}
+	if math.Abs(rh.ObjectPoint.Z) > 1e-12 {
+		t.Errorf("Expected object point %v on the triangle's plane\n", rh.ObjectPoint)
+	}
+	// the geometric normal stays perpendicular to the surface, which the tangents run across
+	geometric := rh.GeometricNormal
+	if math.Abs(geometric.Magnitude()-1.0) > 1e-9 {
+		t.Errorf("Expected a unit geometric normal but got %v\n", geometric)
+	}
+	if math.Abs(geometric.Dot(rh.Tangent.Unit())) > 1e-9 || math.Abs(geometric.Dot(rh.Bitangent.Unit())) > 1e-9 {
+		t.Errorf("Expected geometric normal %v perpendicular to tangents %v and %v\n", geometric, rh.Tangent, rh.Bitangent)
+	}
+	if rh.NormalAtHit.Sub(geometric).Magnitude() < 1e-3 {
+		t.Errorf("Expected the smoothed shading normal to differ from the geometric normal %v\n", geometric)
+	}
+	if rh.NormalAtHit.Dot(geometric) <= 0.0 {
+		t.Errorf("Expected shading normal %v on the same side as geometric normal %v\n", rh.NormalAtHit, geometric)
+	}
+}
+
+func BenchmarkInstanceIntersectionHit(b *testing.B) {
+	_, i := newScaledSphereInstance(b)
+	r := geometry.Ray{
+		Origin:    geometry.Point{X: 5.0, Y: 0.0, Z: 10.0},
+		Direction: geometry.Vector{X: 0.0, Y: 0.0, Z: -1.0},
+	}
+	var h bool
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		_, h = i.Intersection(r, 1e-7, math.MaxFloat64)
+	}
+	instanceHit = h
+}
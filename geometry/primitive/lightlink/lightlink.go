@@ -0,0 +1,62 @@
+package lightlink
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/geometry/primitive"
+	"fluorescence/geometry/primitive/aabb"
+	"fluorescence/shading/material"
+)
+
+// Linked is a primitive whose surface is only illuminated by some of the scene's lights,
+// or whose surface is itself a named source of light
+type Linked struct {
+	Primitive primitive.Primitive
+	Link      *material.LightLink   // lights illuminating the surface, or nil if all of them do
+	Source    *material.LightSource // name the surface's own light goes by, or nil if it has none
+}
+
+// Intersection computer the intersection of this object and a given ray if it exists
+func (l *Linked) Intersection(ray geometry.Ray, tMin, tMax float64) (*material.RayHit, bool) {
+	rh, ok := l.Primitive.Intersection(ray, tMin, tMax)
+	if ok {
+		rh.LightLink = l.Link
+		rh.Source = l.Source
+	}
+	return rh, ok
+}
+
+// BoundingBox returns an AABB for this object
+func (l *Linked) BoundingBox(t0, t1 float64) (*aabb.AABB, bool) {
+	return l.Primitive.BoundingBox(t0, t1)
+}
+
+// SetMaterial sets the material of this object
+func (l *Linked) SetMaterial(m material.Material) {
+	l.Primitive.SetMaterial(m)
+}
+
+// IsInfinite returns whether this object is infinite
+func (l *Linked) IsInfinite() bool {
+	return l.Primitive.IsInfinite()
+}
+
+// IsClosed returns whether this object is closed
+func (l *Linked) IsClosed() bool {
+	return l.Primitive.IsClosed()
+}
+
+// SurfaceArea returns the surface area of this object
+func (l *Linked) SurfaceArea() (float64, bool) {
+	surface, ok := l.Primitive.(primitive.Surface)
+	if !ok {
+		return 0.0, false
+	}
+	return surface.SurfaceArea()
+}
+
+// Copy returns a copy of this object, with its own copy of the underlying Primitive
+func (l *Linked) Copy() primitive.Primitive {
+	newL := *l
+	newL.Primitive = l.Primitive.Copy()
+	return &newL
+}
@@ -0,0 +1,56 @@
+package lightlink
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/geometry/primitive/sphere"
+	"fluorescence/shading/material"
+	"math"
+	"testing"
+)
+
+func TestLinkedHitCarriesLinkAndSource(t *testing.T) {
+	s, err := (&sphere.Sphere{Radius: 1.0}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up sphere: %s\n", err)
+	}
+	l := &Linked{
+		Primitive: s,
+		Link:      &material.LightLink{Exclude: []string{"fill"}},
+		Source:    &material.LightSource{Name: "glowing_ball", Group: 2},
+	}
+	ray := geometry.Ray{
+		Origin:    geometry.Point{Z: 5.0},
+		Direction: geometry.Vector{Z: -1.0},
+	}
+	rh, h := l.Copy().Intersection(ray, 1e-7, math.MaxFloat64)
+	if !h {
+		t.Fatalf("Expected true (hit) but got %t\n", h)
+	}
+	if rh.LightLink != l.Link || rh.Source != l.Source {
+		t.Errorf("Expected the hit to carry the link and source\n")
+	}
+	if rh.LightLink.Illuminates("fill") || !rh.LightLink.Illuminates("key") {
+		t.Errorf("Expected the hit to be lit by key but not fill\n")
+	}
+}
+
+func TestLightLinkIlluminates(t *testing.T) {
+	for _, c := range []struct {
+		link     *material.LightLink
+		name     string
+		expected bool
+	}{
+		{nil, "key", true},
+		{&material.LightLink{}, "key", true},
+		{&material.LightLink{Include: []string{"key"}}, "key", true},
+		{&material.LightLink{Include: []string{"key"}}, "fill", false},
+		{&material.LightLink{Exclude: []string{"key"}}, "key", false},
+		{&material.LightLink{Exclude: []string{"key"}}, "environment", true},
+		// exclusion wins over inclusion
+		{&material.LightLink{Include: []string{"key"}, Exclude: []string{"key"}}, "key", false},
+	} {
+		if illuminates := c.link.Illuminates(c.name); illuminates != c.expected {
+			t.Errorf("Expected %t for light %s with link %v but got %t\n", c.expected, c.name, c.link, illuminates)
+		}
+	}
+}
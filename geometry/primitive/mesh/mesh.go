@@ -0,0 +1,399 @@
+package mesh
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/geometry/primitive"
+	"fluorescence/geometry/primitive/aabb"
+	"fluorescence/geometry/primitive/triangle"
+	"fluorescence/shading"
+	"fluorescence/shading/material"
+	"fluorescence/shading/texture"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Mesh is a collection of triangles loaded from a model file, kept in their own bounding volume hierarchy
+// triangles share their corners through indexed vertex arrays, rather than each being a separate Primitive,
+// so models with millions of faces stay small in memory
+// triangles given a material by the file keep it, and the rest take the material set on the Mesh
+type Mesh struct {
+	FileName        string   `json:"file_name"`         // path of a Wavefront .obj, .ply, or .stl file
+	Groups          []string `json:"groups"`            // names of the .obj groups or objects to load from the file, or all of them if empty
+	UseMaterials    bool     `json:"use_materials"`     // whether materials from an .obj file's .mtl libraries are used where faces name one
+	UseVertexColors bool     `json:"use_vertex_colors"` // whether the file's vertex colors tint the reflectance of the triangles' materials
+	UseFloat32      bool     `json:"use_float32"`       // whether vertices are stored in single precision, halving their memory at some cost to precision
+	CreaseAngle     float64  `json:"crease_angle"`      // for files without vertex normals, including .stl files, the largest angle in degrees between faces which are smoothed into each other
+	IsCulled        bool     `json:"is_culled"`         // whether the triangles are single-sided
+	vertices        vertexArrays
+	faces           []face     // ordered so the faces of each leaf of the tree are next to each other
+	nodes           []treeNode // bounding volume hierarchy over the faces, with its root first
+	materials       []material.Material
+	area            float64
+	isClosed        bool
+	degenerateFaces int
+	mat             material.Material
+}
+
+// face is a triangle of a Mesh, stored by value as the indices of its corners in the Mesh's vertex arrays
+type face struct {
+	vertices [3]uint32
+	material int32 // index into the Mesh's materials, or -1 to use the material set on the Mesh
+}
+
+// Data holds the vertices and faces of a Mesh built in code rather than read from a file
+type Data struct {
+	Positions     []geometry.Point
+	UVs           [][2]float64        // texture coordinates at each position, or empty if there are none
+	Normals       []geometry.Vector   // normal at each position, or empty; faces are wound to agree with them
+	Faces         [][3]int            // corners of each triangle, as indices into Positions
+	Materials     []material.Material // materials used by the faces
+	FaceMaterials []int               // index into Materials for each face, or -1 to use the Mesh's; empty if no face has its own
+}
+
+// meshVertex holds the indices of a face corner's position, texture coordinates and normal
+// indices are into the file's lists, with -1 meaning the corner has none
+type meshVertex struct {
+	position int
+	uv       int
+	normal   int
+}
+
+// meshFace is a triangle read from a model file, with polygons already split into triangles
+type meshFace struct {
+	vertices     [3]meshVertex
+	materialName string
+	material     int // index into the Mesh's materials, or -1, once the face's material name is resolved
+}
+
+// meshData holds the geometry read from a model file
+type meshData struct {
+	positions []geometry.Point
+	uvs       [][2]float64
+	normals   []geometry.Vector
+	colors    []shading.Color // a color for each position, or empty if the file has none
+	faces     []meshFace
+	libraries []string // file names of the .mtl material libraries an .obj file uses
+}
+
+// Setup loads the Mesh's file and builds its triangles
+// the file's format is found from its extension, and faces which collapse to a line or point are skipped
+// images and vertex colors used by the file's materials have textureGamma removed
+func (m *Mesh) Setup(textureGamma float64) (*Mesh, error) {
+	file, err := os.Open(m.FileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var data *meshData
+	switch strings.ToLower(filepath.Ext(m.FileName)) {
+	case ".obj":
+		data, err = readOBJ(file, m.Groups)
+	case ".ply":
+		data, err = readPLY(file)
+	case ".stl":
+		data, err = readSTL(file)
+	default:
+		return nil, fmt.Errorf("mesh file (%s) is not a .obj, .ply, or .stl file", m.FileName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading mesh (%s): %v", m.FileName, err)
+	}
+
+	materialIndices := map[string]int{}
+	m.materials = nil
+	if m.UseMaterials {
+		cache := texture.NewCache()
+		for _, library := range data.libraries {
+			mtlMaterials, err := readMTL(filepath.Join(filepath.Dir(m.FileName), library))
+			if err != nil {
+				return nil, fmt.Errorf("reading mesh (%s) materials: %v", m.FileName, err)
+			}
+			for _, mm := range mtlMaterials {
+				newMaterial, err := mm.toMaterial(textureGamma, cache)
+				if err != nil {
+					return nil, err
+				}
+				materialIndices[mm.name] = len(m.materials)
+				m.materials = append(m.materials, newMaterial)
+			}
+		}
+	}
+	for i := range data.faces {
+		data.faces[i].material = -1
+		if index, ok := materialIndices[data.faces[i].materialName]; ok {
+			data.faces[i].material = index
+		}
+	}
+	if m.UseVertexColors && len(data.colors) != len(data.positions) {
+		return nil, fmt.Errorf("mesh (%s) does not have a color for every vertex", m.FileName)
+	}
+	if !m.UseVertexColors {
+		data.colors = nil
+	}
+	// files without their own normals can have them made from the faces, with zero leaving every face flat
+	if m.CreaseAngle < 0.0 || m.CreaseAngle > 180.0 {
+		return nil, fmt.Errorf("mesh (%s) crease angle (%f) is not between 0 and 180 degrees", m.FileName, m.CreaseAngle)
+	}
+	if len(data.normals) == 0 && m.CreaseAngle > 0.0 {
+		data.generateNormals(m.CreaseAngle)
+	}
+
+	m.build(data, textureGamma)
+	if len(m.faces) == 0 {
+		return nil, fmt.Errorf("mesh (%s) has no faces to load", m.FileName)
+	}
+	return m, nil
+}
+
+// New returns a Mesh of the faces given, stored in single precision if useFloat32 is set
+// corners at the same position are treated as shared, and faces which collapse to a line or point are skipped
+func New(d *Data, useFloat32 bool) (*Mesh, error) {
+	if len(d.FaceMaterials) > 0 && len(d.FaceMaterials) != len(d.Faces) {
+		return nil, fmt.Errorf("mesh has %d faces but %d face materials", len(d.Faces), len(d.FaceMaterials))
+	}
+	// positions are joined so faces are matched along their edges even if their other attributes differ
+	data := &meshData{
+		uvs:     d.UVs,
+		normals: d.Normals,
+	}
+	welded := map[geometry.Point]int{}
+	positionIndices := make([]int, len(d.Positions))
+	for i, p := range d.Positions {
+		index, ok := welded[p]
+		if !ok {
+			index = len(data.positions)
+			welded[p] = index
+			data.positions = append(data.positions, p)
+		}
+		positionIndices[i] = index
+	}
+	for i, corners := range d.Faces {
+		mf := meshFace{material: -1}
+		if len(d.FaceMaterials) > 0 {
+			mf.material = d.FaceMaterials[i]
+			if mf.material >= len(d.Materials) {
+				return nil, fmt.Errorf("mesh face %d uses material %d which is not defined", i, mf.material)
+			}
+		}
+		for j, corner := range corners {
+			if corner < 0 || corner >= len(d.Positions) {
+				return nil, fmt.Errorf("mesh face %d refers to a vertex which is not defined", i)
+			}
+			mf.vertices[j] = meshVertex{position: positionIndices[corner], uv: -1, normal: -1}
+			if len(d.UVs) == len(d.Positions) {
+				mf.vertices[j].uv = corner
+			}
+			if len(d.Normals) == len(d.Positions) {
+				mf.vertices[j].normal = corner
+			}
+		}
+		data.faces = append(data.faces, mf)
+	}
+
+	m := &Mesh{
+		UseFloat32: useFloat32,
+		materials:  d.Materials,
+	}
+	m.build(data, 1.0)
+	if len(m.faces) == 0 {
+		return nil, fmt.Errorf("mesh has no faces")
+	}
+	return m, nil
+}
+
+// build fills the Mesh's vertex arrays, faces, and tree from the data
+// corners sharing a position, texture coordinates and normal become a single vertex,
+// and vertex colors, if the data has them, have gamma removed
+func (m *Mesh) build(data *meshData, gamma float64) {
+	useColors := len(data.colors) > 0
+	useUVs := len(data.uvs) > 0
+	useNormals := len(data.normals) > 0
+
+	positions := []geometry.Point{}
+	uvs := [][2]float64{}
+	normals := []geometry.Vector{}
+	colors := []shading.Color{}
+	welded := map[meshVertex]uint32{}
+	position := func(index int) geometry.Point {
+		p := data.positions[index]
+		// faces are checked against the positions as they will be stored
+		if m.UseFloat32 {
+			return geometry.Point{X: float64(float32(p.X)), Y: float64(float32(p.Y)), Z: float64(float32(p.Z))}
+		}
+		return p
+	}
+	vertex := func(corner meshVertex) uint32 {
+		if index, ok := welded[corner]; ok {
+			return index
+		}
+		index := uint32(len(positions))
+		welded[corner] = index
+		positions = append(positions, position(corner.position))
+		if useUVs {
+			uv := [2]float64{}
+			if corner.uv >= 0 {
+				uv = data.uvs[corner.uv]
+			}
+			uvs = append(uvs, uv)
+		}
+		if useNormals {
+			normal := geometry.VectorZero
+			if corner.normal >= 0 {
+				normal = data.normals[corner.normal]
+			}
+			normals = append(normals, normal)
+		}
+		if useColors {
+			colors = append(colors, data.colors[corner.position].Pow(gamma))
+		}
+		return index
+	}
+
+	// a closed mesh has every edge shared by exactly two of its triangles
+	edges := map[[2]int]int{}
+	m.faces = make([]face, 0, len(data.faces))
+	m.area = 0.0
+	m.degenerateFaces = 0
+	for _, mf := range data.faces {
+		a, b, c := position(mf.vertices[0].position), position(mf.vertices[1].position), position(mf.vertices[2].position)
+		if triangle.IsDegenerate(a, b, c) {
+			// faces which collapse to a line or point can't be hit, so are left out
+			m.degenerateFaces++
+			continue
+		}
+		corners := mf.vertices
+		// texture coordinates are only used if every corner has them
+		if corners[0].uv < 0 || corners[1].uv < 0 || corners[2].uv < 0 {
+			corners[0].uv, corners[1].uv, corners[2].uv = -1, -1, -1
+		}
+		// faces with vertex normals are wound so their surface normal points the same way
+		averageNormal := geometry.VectorZero
+		for _, corner := range corners {
+			if corner.normal >= 0 {
+				averageNormal = averageNormal.Add(data.normals[corner.normal])
+			}
+		}
+		surfaceNormal := a.To(b).Cross(a.To(c))
+		if surfaceNormal.Dot(averageNormal) < 0 {
+			corners[1], corners[2] = corners[2], corners[1]
+		}
+		m.faces = append(m.faces, face{
+			vertices: [3]uint32{vertex(corners[0]), vertex(corners[1]), vertex(corners[2])},
+			material: int32(mf.material),
+		})
+		m.area += surfaceNormal.Magnitude() / 2.0
+		for i := 0; i < 3; i++ {
+			p, q := corners[i].position, corners[(i+1)%3].position
+			if p > q {
+				p, q = q, p
+			}
+			edges[[2]int{p, q}]++
+		}
+	}
+	m.isClosed = len(m.faces) > 0
+	for _, count := range edges {
+		if count != 2 {
+			m.isClosed = false
+			break
+		}
+	}
+	if !useColors {
+		colors = nil
+	}
+	m.vertices = newVertexArrays(positions, uvs, normals, colors, m.UseFloat32)
+	if len(m.faces) > 0 {
+		m.nodes = buildTree(m.faces, &m.vertices)
+	}
+}
+
+// DegenerateFaces returns the number of faces which were skipped for collapsing to a line or point
+func (m *Mesh) DegenerateFaces() int {
+	return m.degenerateFaces
+}
+
+// triangle returns a prepared Triangle for a face, which is only made for faces which are hit
+// it is returned by value, so that it need not be allocated
+func (m *Mesh) triangle(f *face) (triangle.Triangle, error) {
+	t := triangle.Triangle{
+		A:        m.vertices.position(f.vertices[0]),
+		B:        m.vertices.position(f.vertices[1]),
+		C:        m.vertices.position(f.vertices[2]),
+		IsCulled: m.IsCulled,
+	}
+	if m.vertices.hasUVs() {
+		t.UVA = m.vertices.uv(f.vertices[0])
+		t.UVB = m.vertices.uv(f.vertices[1])
+		t.UVC = m.vertices.uv(f.vertices[2])
+		t.HasUVs = true
+	}
+	// corners without a normal have a zero one, which leaves their face flat
+	if m.vertices.hasNormals() {
+		t.NormalA = m.vertices.normal(f.vertices[0])
+		t.NormalB = m.vertices.normal(f.vertices[1])
+		t.NormalC = m.vertices.normal(f.vertices[2])
+	}
+	if m.vertices.hasColors() {
+		t.ColorA = m.vertices.color(f.vertices[0])
+		t.ColorB = m.vertices.color(f.vertices[1])
+		t.ColorC = m.vertices.color(f.vertices[2])
+		t.HasColors = true
+	}
+	_, err := t.Setup()
+	return t, err
+}
+
+// faceMaterial returns the material of a face
+func (m *Mesh) faceMaterial(f *face) material.Material {
+	if f.material >= 0 {
+		return m.materials[f.material]
+	}
+	return m.mat
+}
+
+// Intersection computer the intersection of this object and a given ray if it exists
+func (m *Mesh) Intersection(ray geometry.Ray, tMin, tMax float64) (*material.RayHit, bool) {
+	index, ok := m.closestFace(ray, tMin, tMax)
+	if !ok {
+		return nil, false
+	}
+	f := &m.faces[index]
+	t, err := m.triangle(f)
+	if err != nil {
+		return nil, false
+	}
+	t.SetMaterial(m.faceMaterial(f))
+	return t.Intersection(ray, tMin, tMax)
+}
+
+// BoundingBox returns an AABB for this object
+func (m *Mesh) BoundingBox(t0, t1 float64) (*aabb.AABB, bool) {
+	return m.boundingBox(), true
+}
+
+// SetMaterial sets the material of the triangles without a material of their own
+func (m *Mesh) SetMaterial(mat material.Material) {
+	m.mat = mat
+}
+
+// IsInfinite returns whether this object is infinite
+func (m *Mesh) IsInfinite() bool {
+	return false
+}
+
+// IsClosed returns whether this object is closed
+func (m *Mesh) IsClosed() bool {
+	return m.isClosed
+}
+
+// SurfaceArea returns the surface area of this object
+func (m *Mesh) SurfaceArea() (float64, bool) {
+	return m.area, true
+}
+
+// Copy returns a shallow copy of this object, which shares its vertices and faces with the original
+func (m *Mesh) Copy() primitive.Primitive {
+	newM := *m
+	return &newM
+}
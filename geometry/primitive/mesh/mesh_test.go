@@ -0,0 +1,542 @@
+package mesh
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"fluorescence/shading/material"
+	"fluorescence/shading/texture"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var meshHit bool
+
+// cubeOBJ is a unit cube from (0, 0, 0) to (1, 1, 1) made of quads, with its right side in a group of its own
+const cubeOBJ = `# unit cube
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+v 0 0 1
+v 1 0 1
+v 1 1 1
+v 0 1 1
+vt 0 0
+vt 1 0
+vt 1 1
+vt 0 1
+g sides
+f 1/1 4/4 3/3 2/2
+f 5/1 6/2 7/3 8/4
+f 1 2 6 5
+f 4 8 7 3
+f 1 5 8 4
+g right
+f -7 -3 -2 -6
+`
+
+func writeTestFile(t testing.TB, name, contents string) string {
+	fileName := filepath.Join(t.TempDir(), name)
+	err := ioutil.WriteFile(fileName, []byte(contents), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fileName
+}
+
+func cubeRay(x, y float64) geometry.Ray {
+	return geometry.Ray{
+		Origin: geometry.Point{
+			X: x,
+			Y: y,
+			Z: 5.0,
+		},
+		Direction: geometry.Vector{
+			X: 0.0,
+			Y: 0.0,
+			Z: -1.0,
+		},
+	}
+}
+
+func TestReadOBJTriangulatesPolygons(t *testing.T) {
+	data, err := readOBJ(strings.NewReader(cubeOBJ), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.faces) != 12 {
+		t.Errorf("Expected 12 triangles but got %d\n", len(data.faces))
+	}
+	// the last quad uses negative indices, counting back from vertex 8
+	first := data.faces[len(data.faces)-2]
+	if first.vertices[0].position != 1 || first.vertices[1].position != 5 || first.vertices[2].position != 6 {
+		t.Errorf("Expected negative indices to resolve to positions 1, 5 and 6 but got %d, %d and %d\n",
+			first.vertices[0].position, first.vertices[1].position, first.vertices[2].position)
+	}
+	if data.faces[0].vertices[1].uv != 3 || data.faces[0].vertices[1].normal != -1 {
+		t.Errorf("Expected uv 3 and no normal but got uv %d and normal %d\n",
+			data.faces[0].vertices[1].uv, data.faces[0].vertices[1].normal)
+	}
+}
+
+func TestReadOBJGroups(t *testing.T) {
+	data, err := readOBJ(strings.NewReader(cubeOBJ), []string{"right"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.faces) != 2 {
+		t.Errorf("Expected 2 triangles in group right but got %d\n", len(data.faces))
+	}
+}
+
+func TestReadOBJUndefinedVertex(t *testing.T) {
+	_, err := readOBJ(strings.NewReader("v 0 0 0\nv 1 0 0\nf 1 2 3\n"), nil)
+	if err == nil {
+		t.Errorf("Expected error for face using undefined vertex but got none\n")
+	}
+}
+
+func TestMeshIsClosed(t *testing.T) {
+	m, err := (&Mesh{FileName: writeTestFile(t, "cube.obj", cubeOBJ)}).Setup(2.2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.IsClosed() {
+		t.Errorf("Expected cube to be closed\n")
+	}
+	area, _ := m.SurfaceArea()
+	if math.Abs(area-6.0) > 1e-9 {
+		t.Errorf("Expected surface area 6 but got %f\n", area)
+	}
+
+	open, err := (&Mesh{FileName: writeTestFile(t, "cube.obj", cubeOBJ), Groups: []string{"sides"}}).Setup(2.2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if open.IsClosed() {
+		t.Errorf("Expected cube without its right side to be open\n")
+	}
+}
+
+func TestMeshIntersectionHit(t *testing.T) {
+	m, err := (&Mesh{FileName: writeTestFile(t, "cube.obj", cubeOBJ)}).Setup(2.2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rh, h := m.Intersection(cubeRay(0.3, 0.6), 1e-7, math.MaxFloat64)
+	if !h {
+		t.Fatalf("Expected true (hit) but got %t\n", h)
+	}
+	if math.Abs(rh.Time-4.0) > 1e-9 {
+		t.Errorf("Expected the nearest face at time 4 but got %f\n", rh.Time)
+	}
+}
+
+func TestMeshIntersectionMiss(t *testing.T) {
+	m, err := (&Mesh{FileName: writeTestFile(t, "cube.obj", cubeOBJ)}).Setup(2.2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, h := m.Intersection(cubeRay(1.3, 0.6), 1e-7, math.MaxFloat64)
+	if h {
+		t.Errorf("Expected false (miss) but got %t\n", h)
+	}
+}
+
+func TestMeshMaterials(t *testing.T) {
+	dir := t.TempDir()
+	ioutil.WriteFile(filepath.Join(dir, "glass.mtl"), []byte("newmtl glass\nKd 1 1 1\nNi 1.45\nd 0.1\nnewmtl red\nKd 0.8 0.1 0.1\n"), 0644)
+	objFileName := filepath.Join(dir, "cube.obj")
+	ioutil.WriteFile(objFileName, []byte("mtllib glass.mtl\nusemtl glass\n"+cubeOBJ), 0644)
+	m, err := (&Mesh{FileName: objFileName, UseMaterials: true}).Setup(2.2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rh, h := m.Intersection(cubeRay(0.3, 0.6), 1e-7, math.MaxFloat64)
+	if !h {
+		t.Fatalf("Expected true (hit) but got %t\n", h)
+	}
+	if rh.Material == nil || !rh.Material.IsSpecular() {
+		t.Errorf("Expected the transparent .mtl material to map to a Dielectric\n")
+	}
+}
+
+func BenchmarkMeshIntersectionHit(b *testing.B) {
+	var sb strings.Builder
+	// a grid of 100 by 100 quads in the plane z = 0
+	for y := 0; y <= 100; y++ {
+		for x := 0; x <= 100; x++ {
+			sb.WriteString("v " + strconv.Itoa(x) + " " + strconv.Itoa(y) + " 0\n")
+		}
+	}
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 100; x++ {
+			i := y*101 + x + 1
+			sb.WriteString("f " + strconv.Itoa(i) + " " + strconv.Itoa(i+1) + " " + strconv.Itoa(i+102) + " " + strconv.Itoa(i+101) + "\n")
+		}
+	}
+	m, err := (&Mesh{FileName: writeTestFile(b, "grid.obj", sb.String())}).Setup(2.2)
+	if err != nil {
+		b.Fatal(err)
+	}
+	r := cubeRay(50.3, 50.6)
+	var h bool
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, h = m.Intersection(r, 1e-7, math.MaxFloat64)
+	}
+	meshHit = h
+}
+
+// tetrahedronPLY is a closed tetrahedron with colored vertices, and a fifth face which collapses to a line
+const tetrahedronPLY = `ply
+format ascii 1.0
+comment a tetrahedron
+element vertex 5
+property float x
+property float y
+property float z
+property uchar red
+property uchar green
+property uchar blue
+element face 5
+property list uchar int vertex_indices
+end_header
+0 0 0 255 0 0
+1 0 0 0 255 0
+0 1 0 0 0 255
+0 0 1 255 255 255
+2 0 0 0 0 0
+3 0 2 1
+3 0 1 3
+3 0 3 2
+3 1 2 3
+3 0 1 4
+`
+
+func TestReadPLYASCII(t *testing.T) {
+	data, err := readPLY(strings.NewReader(tetrahedronPLY))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.positions) != 5 || len(data.faces) != 5 {
+		t.Errorf("Expected 5 vertices and 5 faces but got %d and %d\n", len(data.positions), len(data.faces))
+	}
+	if data.colors[1].Green != 1.0 || data.colors[1].Red != 0.0 {
+		t.Errorf("Expected vertex 1 to be green but got %v\n", data.colors[1])
+	}
+}
+
+func TestReadPLYBinary(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		format := "binary_little_endian"
+		if order == binary.BigEndian {
+			format = "binary_big_endian"
+		}
+		var buf bytes.Buffer
+		buf.WriteString("ply\nformat " + format + " 1.0\nelement vertex 4\nproperty double x\nproperty double y\nproperty double z\n" +
+			"element face 1\nproperty list uchar uint vertex_indices\nend_header\n")
+		for _, p := range [][3]float64{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 1, 0}} {
+			binary.Write(&buf, order, p)
+		}
+		buf.WriteByte(4)
+		binary.Write(&buf, order, []uint32{0, 1, 2, 3})
+		data, err := readPLY(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data.faces) != 2 {
+			t.Errorf("Expected quad in %s to be split into 2 triangles but got %d\n", format, len(data.faces))
+		}
+		if data.positions[2] != (geometry.Point{X: 1, Y: 1, Z: 0}) {
+			t.Errorf("Expected vertex 2 in %s at (1, 1, 0) but got %v\n", format, data.positions[2])
+		}
+	}
+}
+
+func TestReadPLYListLength(t *testing.T) {
+	for _, c := range []struct {
+		name      string
+		countType string
+		count     []byte
+	}{
+		// a length far past the end of the file must not be allocated up front
+		{"too long", "uint", []byte{0xff, 0xff, 0xff, 0x7f}},
+		{"negative", "char", []byte{0xff}},
+	} {
+		var buf bytes.Buffer
+		buf.WriteString("ply\nformat binary_little_endian 1.0\nelement vertex 3\nproperty float x\nproperty float y\nproperty float z\n" +
+			"element face 1\nproperty list " + c.countType + " int vertex_indices\nend_header\n")
+		binary.Write(&buf, binary.LittleEndian, []float32{0, 0, 0, 1, 0, 0, 0, 1, 0})
+		buf.Write(c.count)
+		binary.Write(&buf, binary.LittleEndian, []int32{0, 1, 2})
+		if _, err := readPLY(&buf); err == nil {
+			t.Errorf("Expected an error for a list length which is %s\n", c.name)
+		}
+	}
+}
+
+func TestMeshSkipsDegenerateFaces(t *testing.T) {
+	m, err := (&Mesh{FileName: writeTestFile(t, "tetrahedron.ply", tetrahedronPLY), UseVertexColors: true}).Setup(1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.DegenerateFaces() != 1 {
+		t.Errorf("Expected 1 degenerate face but got %d\n", m.DegenerateFaces())
+	}
+	if !m.IsClosed() {
+		t.Errorf("Expected tetrahedron to be closed once its degenerate face is skipped\n")
+	}
+	m.SetMaterial(material.Lambertian{
+		ReflectanceTexture: &texture.Color{Color: shading.Color{Red: 0.5, Green: 0.5, Blue: 0.5}},
+		EmittanceTexture:   &texture.Color{},
+	})
+	// straight down onto the face in the plane z = 0, whose corners are red, green and blue
+	r := geometry.Ray{
+		Origin:    geometry.Point{X: 0.25, Y: 0.25, Z: -1.0},
+		Direction: geometry.Vector{X: 0.0, Y: 0.0, Z: 1.0},
+	}
+	rh, h := m.Intersection(r, 1e-7, math.MaxFloat64)
+	if !h {
+		t.Fatalf("Expected true (hit) but got %t\n", h)
+	}
+	if !rh.HasColor || math.Abs(rh.Color.Red-0.5) > 1e-9 || math.Abs(rh.Color.Green-0.25) > 1e-9 || math.Abs(rh.Color.Blue-0.25) > 1e-9 {
+		t.Errorf("Expected vertex colors blended to (0.5, 0.25, 0.25) but got %v\n", rh.Color)
+	}
+	// the blended color tints the reflectance of the mesh's own material
+	c := rh.Material.Reflectance(*rh)
+	if math.Abs(c.Red-0.25) > 1e-9 || math.Abs(c.Green-0.125) > 1e-9 || math.Abs(c.Blue-0.125) > 1e-9 {
+		t.Errorf("Expected reflectance (0.25, 0.125, 0.125) but got %v\n", c)
+	}
+}
+
+// coloredTrianglePLY is a triangle with both vertex colors and texture coordinates
+const coloredTrianglePLY = `ply
+format ascii 1.0
+element vertex 3
+property float x
+property float y
+property float z
+property float u
+property float v
+property uchar red
+property uchar green
+property uchar blue
+element face 1
+property list uchar int vertex_indices
+end_header
+0 0 0 0 0 255 0 0
+1 0 0 1 0 0 255 0
+0 1 0 0 1 0 0 255
+3 0 1 2
+`
+
+func TestMeshColorsKeepUVs(t *testing.T) {
+	m, err := (&Mesh{FileName: writeTestFile(t, "triangle.ply", coloredTrianglePLY), UseVertexColors: true}).Setup(1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := geometry.Ray{
+		Origin:    geometry.Point{X: 0.25, Y: 0.5, Z: -1.0},
+		Direction: geometry.Vector{X: 0.0, Y: 0.0, Z: 1.0},
+	}
+	rh, h := m.Intersection(r, 1e-7, math.MaxFloat64)
+	if !h {
+		t.Fatalf("Expected true (hit) but got %t\n", h)
+	}
+	if !rh.HasColor {
+		t.Errorf("Expected the hit to have a vertex color\n")
+	}
+	if math.Abs(rh.U-0.25) > 1e-9 || math.Abs(rh.V-0.5) > 1e-9 {
+		t.Errorf("Expected texture coordinates (0.25, 0.5) but got (%f, %f)\n", rh.U, rh.V)
+	}
+}
+
+func TestMeshIntersectionAllocations(t *testing.T) {
+	m, err := (&Mesh{FileName: writeTestFile(t, "tetrahedron.ply", tetrahedronPLY), UseVertexColors: true}).Setup(1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := geometry.Ray{
+		Origin:    geometry.Point{X: 0.25, Y: 0.25, Z: -1.0},
+		Direction: geometry.Vector{X: 0.0, Y: 0.0, Z: 1.0},
+	}
+	// the triangle and its colors are made on the stack, leaving only the RayHit itself
+	allocations := testing.AllocsPerRun(100, func() {
+		_, meshHit = m.Intersection(r, 1e-7, math.MaxFloat64)
+	})
+	if allocations > 1.0 {
+		t.Errorf("Expected at most 1 allocation per hit but got %f\n", allocations)
+	}
+}
+
+func TestReadSTL(t *testing.T) {
+	ascii := "solid tetrahedron\n" +
+		"facet normal 0 0 -1\nouter loop\nvertex 0 0 0\nvertex 0 1 0\nvertex 1 0 0\nendloop\nendfacet\n" +
+		"facet normal 0 -1 0\nouter loop\nvertex 0 0 0\nvertex 1 0 0\nvertex 0 0 1\nendloop\nendfacet\n" +
+		"facet normal -1 0 0\nouter loop\nvertex 0 0 0\nvertex 0 0 1\nvertex 0 1 0\nendloop\nendfacet\n" +
+		"facet normal 1 1 1\nouter loop\nvertex 1 0 0\nvertex 0 1 0\nvertex 0 0 1\nendloop\nendfacet\n" +
+		"endsolid tetrahedron\n"
+	data, err := readSTL(strings.NewReader(ascii))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.positions) != 4 || len(data.faces) != 4 {
+		t.Errorf("Expected 4 welded vertices and 4 faces but got %d and %d\n", len(data.positions), len(data.faces))
+	}
+
+	// the same tetrahedron in binary, with a header which also starts with "solid"
+	var buf bytes.Buffer
+	header := make([]byte, 80)
+	copy(header, "solid but actually binary")
+	buf.Write(header)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(data.faces)))
+	for _, face := range data.faces {
+		binary.Write(&buf, binary.LittleEndian, [3]float32{})
+		for _, corner := range face.vertices {
+			p := data.positions[corner.position]
+			binary.Write(&buf, binary.LittleEndian, [3]float32{float32(p.X), float32(p.Y), float32(p.Z)})
+		}
+		binary.Write(&buf, binary.LittleEndian, uint16(0))
+	}
+	m, err := (&Mesh{FileName: writeTestFile(t, "tetrahedron.stl", buf.String())}).Setup(2.2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.IsClosed() {
+		t.Errorf("Expected binary tetrahedron to be closed\n")
+	}
+}
+
+// tetrahedronData is a closed tetrahedron whose faces each have their own copies of their corners
+func tetrahedronData() *Data {
+	corners := [][3]geometry.Point{
+		{{X: 0, Y: 0, Z: 0}, {X: 0, Y: 1, Z: 0}, {X: 1, Y: 0, Z: 0}},
+		{{X: 0, Y: 0, Z: 0}, {X: 1, Y: 0, Z: 0}, {X: 0, Y: 0, Z: 1}},
+		{{X: 0, Y: 0, Z: 0}, {X: 0, Y: 0, Z: 1}, {X: 0, Y: 1, Z: 0}},
+		{{X: 1, Y: 0, Z: 0}, {X: 0, Y: 1, Z: 0}, {X: 0, Y: 0, Z: 1}},
+	}
+	d := &Data{}
+	for _, face := range corners {
+		d.Faces = append(d.Faces, [3]int{len(d.Positions), len(d.Positions) + 1, len(d.Positions) + 2})
+		d.Positions = append(d.Positions, face[:]...)
+	}
+	return d
+}
+
+func TestNewIsClosed(t *testing.T) {
+	m, err := New(tetrahedronData(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.IsClosed() {
+		t.Errorf("Expected tetrahedron with separate corners to be closed\n")
+	}
+	open := tetrahedronData()
+	open.Faces = open.Faces[1:]
+	m, err = New(open, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.IsClosed() {
+		t.Errorf("Expected tetrahedron missing a face to be open\n")
+	}
+}
+
+func TestNewSharesVertices(t *testing.T) {
+	m, err := (&Mesh{FileName: writeTestFile(t, "cube.obj", cubeOBJ)}).Setup(2.2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// corners without texture coordinates share a vertex wherever their position is the same
+	if len(m.faces) != 12 || len(m.vertices.positions) != 3*16 {
+		t.Errorf("Expected 12 faces over 16 vertices but got %d faces over %d vertices\n", len(m.faces), len(m.vertices.positions)/3)
+	}
+}
+
+func TestMeshFloat32(t *testing.T) {
+	m, err := (&Mesh{FileName: writeTestFile(t, "cube.obj", cubeOBJ), UseFloat32: true}).Setup(2.2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.vertices.positions) != 0 || len(m.vertices.positions32) == 0 {
+		t.Errorf("Expected vertices to be stored in single precision only\n")
+	}
+	rh, h := m.Intersection(cubeRay(0.3, 0.6), 1e-7, math.MaxFloat64)
+	if !h {
+		t.Fatalf("Expected true (hit) but got %t\n", h)
+	}
+	if math.Abs(rh.Time-4.0) > 1e-6 {
+		t.Errorf("Expected the nearest face at time 4 but got %f\n", rh.Time)
+	}
+	box, _ := m.BoundingBox(0, 0)
+	if box.A.X >= 0.0 || box.B.X <= 1.0 {
+		t.Errorf("Expected bounding box to enclose the cube but got %v\n", box)
+	}
+}
+
+// cubeSTL returns the cube of cubeOBJ as an ASCII .stl file, with each facet's normal
+func cubeSTL(t *testing.T) string {
+	data, err := readOBJ(strings.NewReader(cubeOBJ), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stl := "solid cube\n"
+	for _, f := range data.faces {
+		a, b, c := data.positions[f.vertices[0].position], data.positions[f.vertices[1].position], data.positions[f.vertices[2].position]
+		n := a.To(b).Cross(a.To(c)).Unit()
+		stl += fmt.Sprintf("facet normal %g %g %g\nouter loop\n", n.X, n.Y, n.Z)
+		for _, p := range []geometry.Point{a, b, c} {
+			stl += fmt.Sprintf("vertex %g %g %g\n", p.X, p.Y, p.Z)
+		}
+		stl += "endloop\nendfacet\n"
+	}
+	return stl + "endsolid cube\n"
+}
+
+func TestMeshCreaseAngle(t *testing.T) {
+	// a ray straight down onto the cube's top face, near its corner at (1, 1, 1)
+	r := geometry.Ray{
+		Origin:    geometry.Point{X: 0.99, Y: 5.0, Z: 0.99},
+		Direction: geometry.Vector{X: 0.0, Y: -1.0, Z: 0.0},
+	}
+	// the cube's faces meet at 90 degrees, so are only smoothed into each other with a wider crease angle
+	// the .stl cube has a normal for each facet, which still leaves its vertex normals to the crease angle
+	for _, test := range []struct {
+		fileName    string
+		contents    string
+		creaseAngle float64
+		smooth      bool
+	}{
+		{"cube.obj", cubeOBJ, 0.0, false},
+		{"cube.obj", cubeOBJ, 60.0, false},
+		{"cube.obj", cubeOBJ, 120.0, true},
+		{"cube.stl", cubeSTL(t), 0.0, false},
+		{"cube.stl", cubeSTL(t), 60.0, false},
+		{"cube.stl", cubeSTL(t), 120.0, true},
+	} {
+		m, err := (&Mesh{FileName: writeTestFile(t, test.fileName, test.contents), CreaseAngle: test.creaseAngle}).Setup(2.2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rh, h := m.Intersection(r, 1e-7, math.MaxFloat64)
+		if !h {
+			t.Fatalf("Expected true (hit) but got %t\n", h)
+		}
+		up := geometry.Vector{X: 0.0, Y: 1.0, Z: 0.0}
+		if rh.Geometric() != up {
+			t.Errorf("Expected geometric normal %v for %s with crease angle %f but got %v\n", up, test.fileName, test.creaseAngle, rh.Geometric())
+		}
+		if smooth := rh.NormalAtHit.Sub(up).Magnitude() > 1e-3; smooth != test.smooth {
+			t.Errorf("Expected smooth shading %t for %s with crease angle %f but got normal %v\n", test.smooth, test.fileName, test.creaseAngle, rh.NormalAtHit)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package mesh
+
+import (
+	"bufio"
+	"fluorescence/shading"
+	"fluorescence/shading/material"
+	"fluorescence/shading/texture"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// mtlMaterial holds the properties of a material read from an .mtl library
+type mtlMaterial struct {
+	name            string
+	diffuse         shading.Color // Kd
+	specular        shading.Color // Ks
+	emission        shading.Color // Ke
+	transmission    shading.Color // Tf
+	shininess       float64       // Ns, the specular exponent
+	refractiveIndex float64       // Ni
+	dissolve        float64       // d, or one minus Tr
+	illumination    int           // illum, the illumination model
+	diffuseMap      string        // map_Kd, relative to the library's directory
+}
+
+// readMTL reads the materials of a Wavefront .mtl library
+func readMTL(fileName string) ([]*mtlMaterial, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	materials := []*mtlMaterial{}
+	var current *mtlMaterial
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "newmtl" {
+			current = &mtlMaterial{
+				name:            strings.Join(fields[1:], " "),
+				diffuse:         shading.Color{Red: 0.8, Green: 0.8, Blue: 0.8},
+				transmission:    shading.Color{Red: 1.0, Green: 1.0, Blue: 1.0},
+				refractiveIndex: 1.0,
+				dissolve:        1.0,
+			}
+			materials = append(materials, current)
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		switch fields[0] {
+		case "Kd", "Ks", "Ke", "Tf":
+			values, err := parseFloats(fields[1:], 1)
+			if err != nil {
+				return nil, fmt.Errorf("mtl line %d: %v", lineNumber, err)
+			}
+			// a single value is a grey
+			c := shading.Color{Red: values[0], Green: values[0], Blue: values[0]}
+			if len(values) >= 3 {
+				c = shading.Color{Red: values[0], Green: values[1], Blue: values[2]}
+			}
+			switch fields[0] {
+			case "Kd":
+				current.diffuse = c
+			case "Ks":
+				current.specular = c
+			case "Ke":
+				current.emission = c
+			default:
+				current.transmission = c
+			}
+		case "Ns", "Ni", "d", "Tr":
+			values, err := parseFloats(fields[1:], 1)
+			if err != nil {
+				return nil, fmt.Errorf("mtl line %d: %v", lineNumber, err)
+			}
+			switch fields[0] {
+			case "Ns":
+				current.shininess = values[0]
+			case "Ni":
+				current.refractiveIndex = values[0]
+			case "d":
+				current.dissolve = values[0]
+			default:
+				current.dissolve = 1.0 - values[0]
+			}
+		case "illum":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("mtl line %d: illum has no value", lineNumber)
+			}
+			illumination, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("mtl line %d: illum (%s) is not an integer", lineNumber, fields[1])
+			}
+			current.illumination = illumination
+		case "map_Kd":
+			// options such as -s and -o come before the file name, which is always last
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("mtl line %d: map_Kd has no file name", lineNumber)
+			}
+			current.diffuseMap = filepath.Join(filepath.Dir(fileName), fields[len(fields)-1])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return materials, nil
+}
+
+// toMaterial maps the .mtl material onto the closest of the existing material types
+// transparent materials become Dielectrics, mirror-like ones become Metals, and everything else is Lambertian
+func (mm *mtlMaterial) toMaterial(textureGamma float64, cache *texture.Cache) (material.Material, error) {
+	emittance := &texture.Color{Color: mm.emission}
+	switch {
+	case mm.dissolve < 1.0 || mm.illumination == 4 || mm.illumination == 6 || mm.illumination == 7 || mm.illumination == 9:
+		refractiveIndex := mm.refractiveIndex
+		if refractiveIndex <= 1.0 {
+			refractiveIndex = 1.5
+		}
+		d := &material.Dielectric{
+			ReflectanceTexture: &texture.Color{Color: mm.transmission},
+			EmittanceTexture:   emittance,
+			RefractiveIndex:    refractiveIndex,
+		}
+		return d, d.UVTransform.SetupUVTransform()
+	case (mm.illumination == 3 || mm.illumination == 5) && luminance(mm.specular) > luminance(mm.diffuse):
+		// a higher specular exponent is a tighter highlight, and so a less fuzzy reflection
+		m := &material.Metal{
+			ReflectanceTexture: &texture.Color{Color: mm.specular},
+			EmittanceTexture:   emittance,
+			Fuzziness:          clamp(1.0/(1.0+mm.shininess/10.0), 0.0, 1.0),
+		}
+		return m, m.UVTransform.SetupUVTransform()
+	default:
+		l := &material.Lambertian{
+			ReflectanceTexture: &texture.Color{Color: mm.diffuse},
+			EmittanceTexture:   emittance,
+		}
+		if mm.diffuseMap != "" {
+			i := &texture.Image{
+				FileName:  mm.diffuseMap,
+				Gamma:     textureGamma,
+				Magnitude: 1.0,
+			}
+			err := i.Load(cache)
+			if err != nil {
+				return nil, err
+			}
+			l.ReflectanceTexture = i
+		}
+		return l, l.UVTransform.SetupUVTransform()
+	}
+}
+
+func luminance(c shading.Color) float64 {
+	return 0.2126*c.Red + 0.7152*c.Green + 0.0722*c.Blue
+}
+
+func clamp(x, low, high float64) float64 {
+	if x < low {
+		return low
+	}
+	if x > high {
+		return high
+	}
+	return x
+}
@@ -0,0 +1,69 @@
+package mesh
+
+import (
+	"fluorescence/geometry"
+	"math"
+)
+
+// generateNormals gives every face corner a normal blended from the faces around its position,
+// leaving out faces which meet the corner's own face at more than the crease angle, in degrees, so sharper edges stay sharp
+// faces are weighted by their area, and corners of faces with no area are left without a normal
+func (data *meshData) generateNormals(creaseAngle float64) {
+	// faces are listed by the positions at their corners, with the faces at position i from offsets[i] up to offsets[i+1]
+	offsets := make([]int, len(data.positions)+1)
+	for _, f := range data.faces {
+		for _, corner := range f.vertices {
+			offsets[corner.position+1]++
+		}
+	}
+	for i := 1; i < len(offsets); i++ {
+		offsets[i] += offsets[i-1]
+	}
+	incident := make([]int, offsets[len(offsets)-1])
+	next := append([]int{}, offsets[:len(data.positions)]...)
+	// face normals are left unnormalized, as their length is twice their face's area
+	faceNormals := make([]geometry.Vector, len(data.faces))
+	for i, f := range data.faces {
+		a, b, c := data.positions[f.vertices[0].position], data.positions[f.vertices[1].position], data.positions[f.vertices[2].position]
+		faceNormals[i] = a.To(b).Cross(a.To(c))
+		for _, corner := range f.vertices {
+			incident[next[corner.position]] = i
+			next[corner.position]++
+		}
+	}
+
+	cosCrease := math.Cos(creaseAngle * math.Pi / 180.0)
+	// corners on the same side of the same crease end up with the same normal, which is stored once
+	indices := map[geometry.Vector]int{}
+	data.normals = nil
+	for i := range data.faces {
+		f := &data.faces[i]
+		if faceNormals[i].Magnitude() == 0.0 {
+			for j := range f.vertices {
+				f.vertices[j].normal = -1
+			}
+			continue
+		}
+		own := faceNormals[i].Unit()
+		for j, corner := range f.vertices {
+			sum := geometry.VectorZero
+			for _, k := range incident[offsets[corner.position]:offsets[corner.position+1]] {
+				if faceNormals[k].Magnitude() > 0.0 && faceNormals[k].Unit().Dot(own) >= cosCrease {
+					sum = sum.Add(faceNormals[k])
+				}
+			}
+			// faces folded back onto each other can cancel out, leaving only the face's own normal to use
+			normal := own
+			if sum.Magnitude() > 0.0 {
+				normal = sum.Unit()
+			}
+			index, ok := indices[normal]
+			if !ok {
+				index = len(data.normals)
+				indices[normal] = index
+				data.normals = append(data.normals, normal)
+			}
+			f.vertices[j].normal = index
+		}
+	}
+}
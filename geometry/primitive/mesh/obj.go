@@ -0,0 +1,165 @@
+package mesh
+
+import (
+	"bufio"
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// readOBJ reads the vertices and faces of a Wavefront OBJ file
+// only faces in one of the given groups or objects are kept, or every face if no groups are given
+func readOBJ(r io.Reader, groups []string) (*meshData, error) {
+	data := &meshData{}
+	wanted := map[string]bool{}
+	for _, g := range groups {
+		wanted[g] = true
+	}
+	currentGroups := []string{"default"}
+	currentObject := ""
+	currentMaterial := ""
+	inWantedGroup := func() bool {
+		if len(wanted) == 0 || wanted[currentObject] {
+			return true
+		}
+		for _, g := range currentGroups {
+			if wanted[g] {
+				return true
+			}
+		}
+		return false
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "v":
+			values, err := parseFloats(fields[1:], 3)
+			if err != nil {
+				return nil, fmt.Errorf("obj line %d: %v", lineNumber, err)
+			}
+			data.positions = append(data.positions, geometry.Point{X: values[0], Y: values[1], Z: values[2]})
+			// some exporters follow the position with a vertex color
+			if len(values) >= 6 {
+				data.colors = append(data.colors, shading.Color{Red: values[3], Green: values[4], Blue: values[5]})
+			}
+		case "vt":
+			values, err := parseFloats(fields[1:], 1)
+			if err != nil {
+				return nil, fmt.Errorf("obj line %d: %v", lineNumber, err)
+			}
+			uv := [2]float64{values[0], 0.0}
+			if len(values) > 1 {
+				uv[1] = values[1]
+			}
+			data.uvs = append(data.uvs, uv)
+		case "vn":
+			values, err := parseFloats(fields[1:], 3)
+			if err != nil {
+				return nil, fmt.Errorf("obj line %d: %v", lineNumber, err)
+			}
+			data.normals = append(data.normals, geometry.Vector{X: values[0], Y: values[1], Z: values[2]})
+		case "f":
+			if len(fields) < 4 {
+				return nil, fmt.Errorf("obj line %d: face has fewer than 3 vertices", lineNumber)
+			}
+			if !inWantedGroup() {
+				continue
+			}
+			corners := make([]meshVertex, len(fields)-1)
+			for i, field := range fields[1:] {
+				corner, err := data.parseVertex(field)
+				if err != nil {
+					return nil, fmt.Errorf("obj line %d: %v", lineNumber, err)
+				}
+				corners[i] = corner
+			}
+			// polygons are split into a fan of triangles around their first vertex
+			for i := 1; i+1 < len(corners); i++ {
+				data.faces = append(data.faces, meshFace{
+					vertices:     [3]meshVertex{corners[0], corners[i], corners[i+1]},
+					materialName: currentMaterial,
+				})
+			}
+		case "g":
+			currentGroups = fields[1:]
+			if len(currentGroups) == 0 {
+				currentGroups = []string{"default"}
+			}
+		case "o":
+			currentObject = strings.Join(fields[1:], " ")
+		case "usemtl":
+			currentMaterial = strings.Join(fields[1:], " ")
+		case "mtllib":
+			data.libraries = append(data.libraries, fields[1:]...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// parseVertex reads a face corner of the form position[/[uv][/normal]]
+// negative indices count back from the most recently read vertex
+func (data *meshData) parseVertex(field string) (meshVertex, error) {
+	corner := meshVertex{position: -1, uv: -1, normal: -1}
+	parts := strings.Split(field, "/")
+	if len(parts) > 3 {
+		return corner, fmt.Errorf("face vertex (%s) has too many parts", field)
+	}
+	lengths := []int{len(data.positions), len(data.uvs), len(data.normals)}
+	indices := []*int{&corner.position, &corner.uv, &corner.normal}
+	for i, part := range parts {
+		if part == "" {
+			if i == 0 {
+				return corner, fmt.Errorf("face vertex (%s) has no position", field)
+			}
+			continue
+		}
+		index, err := strconv.Atoi(part)
+		if err != nil {
+			return corner, fmt.Errorf("face vertex (%s) is not a list of integers", field)
+		}
+		if index < 0 {
+			index += lengths[i]
+		} else {
+			index--
+		}
+		if index < 0 || index >= lengths[i] {
+			return corner, fmt.Errorf("face vertex (%s) refers to a vertex which is not defined", field)
+		}
+		*indices[i] = index
+	}
+	return corner, nil
+}
+
+// parseFloats parses a list of at least minimum numbers
+func parseFloats(fields []string, minimum int) ([]float64, error) {
+	if len(fields) < minimum {
+		return nil, fmt.Errorf("expected at least %d values but got %d", minimum, len(fields))
+	}
+	values := make([]float64, len(fields))
+	for i, field := range fields {
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("value (%s) is not a number", field)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
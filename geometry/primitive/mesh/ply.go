@@ -0,0 +1,349 @@
+package mesh
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// plyProperty describes one value, or list of values, stored for each item of a PLY element
+type plyProperty struct {
+	name      string
+	valueType string
+	countType string // type of a list's length, or empty for a single value
+}
+
+// plyElement describes a kind of item stored in a PLY file, such as its vertices or faces
+type plyElement struct {
+	name       string
+	count      int
+	properties []plyProperty
+}
+
+// plyListCapacity is the most values set aside for a list before they are read, which covers most polygons
+const plyListCapacity = 16
+
+// plyValueReader reads the values of a PLY file's body one at a time
+type plyValueReader interface {
+	next(valueType string) (float64, error)
+}
+
+// readPLY reads the vertices and faces of a Stanford PLY file, in its ASCII or either binary format
+// vertices may carry normals, texture coordinates, and colors, and polygons are split into triangles
+func readPLY(r io.Reader) (*meshData, error) {
+	br := bufio.NewReader(r)
+	format, elements, err := readPLYHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	var values plyValueReader
+	switch format {
+	case "ascii":
+		scanner := bufio.NewScanner(br)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		scanner.Split(bufio.ScanWords)
+		values = &plyASCIIReader{scanner: scanner}
+	case "binary_little_endian":
+		values = &plyBinaryReader{r: br, order: binary.LittleEndian}
+	case "binary_big_endian":
+		values = &plyBinaryReader{r: br, order: binary.BigEndian}
+	default:
+		return nil, fmt.Errorf("ply format (%s) is not one of ascii, binary_little_endian, or binary_big_endian", format)
+	}
+
+	data := &meshData{}
+	for _, element := range elements {
+		switch element.name {
+		case "vertex":
+			err = data.readPLYVertices(element, values)
+		case "face":
+			err = data.readPLYFaces(element, values)
+		default:
+			// elements we don't use still have to be read past
+			for i := 0; i < element.count; i++ {
+				_, err = readPLYItem(element, values)
+				if err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ply %s: %v", element.name, err)
+		}
+	}
+	return data, nil
+}
+
+// readPLYHeader reads a PLY file's header, returning its format and the elements its body holds in order
+func readPLYHeader(br *bufio.Reader) (string, []*plyElement, error) {
+	line, err := br.ReadString('\n')
+	if err != nil || strings.TrimSpace(line) != "ply" {
+		return "", nil, fmt.Errorf("file does not start with ply")
+	}
+	format := ""
+	elements := []*plyElement{}
+	for {
+		line, err = br.ReadString('\n')
+		if err != nil {
+			return "", nil, fmt.Errorf("ply header has no end_header")
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "format":
+			if len(fields) < 2 {
+				return "", nil, fmt.Errorf("ply format has no name")
+			}
+			format = fields[1]
+		case "element":
+			if len(fields) < 3 {
+				return "", nil, fmt.Errorf("ply element has no name or count")
+			}
+			count, err := strconv.Atoi(fields[2])
+			if err != nil || count < 0 {
+				return "", nil, fmt.Errorf("ply element (%s) count (%s) is not a count", fields[1], fields[2])
+			}
+			elements = append(elements, &plyElement{name: fields[1], count: count})
+		case "property":
+			if len(elements) == 0 {
+				return "", nil, fmt.Errorf("ply property comes before any element")
+			}
+			element := elements[len(elements)-1]
+			if len(fields) == 5 && fields[1] == "list" {
+				element.properties = append(element.properties, plyProperty{name: fields[4], valueType: fields[3], countType: fields[2]})
+			} else if len(fields) == 3 {
+				element.properties = append(element.properties, plyProperty{name: fields[2], valueType: fields[1]})
+			} else {
+				return "", nil, fmt.Errorf("ply property (%s) is malformed", strings.TrimSpace(line))
+			}
+		case "end_header":
+			return format, elements, nil
+		}
+	}
+}
+
+// readPLYItem reads the values of one item of an element, with each list's values following its length
+// a list's length comes from the file, so its values are only kept as they are read, and a length
+// which runs past the end of the file is an error rather than an allocation of that size
+func readPLYItem(element *plyElement, values plyValueReader) ([][]float64, error) {
+	item := make([][]float64, len(element.properties))
+	for i, property := range element.properties {
+		count := 1
+		if property.countType != "" {
+			n, err := values.next(property.countType)
+			if err != nil {
+				return nil, err
+			}
+			if n < 0 {
+				return nil, fmt.Errorf("ply list (%s) has a negative length (%d)", property.name, int(n))
+			}
+			count = int(n)
+		}
+		capacity := count
+		if capacity > plyListCapacity {
+			capacity = plyListCapacity
+		}
+		item[i] = make([]float64, 0, capacity)
+		for j := 0; j < count; j++ {
+			value, err := values.next(property.valueType)
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil, fmt.Errorf("ply list (%s) of %d values runs past the end of the file", property.name, count)
+			} else if err != nil {
+				return nil, err
+			}
+			item[i] = append(item[i], value)
+		}
+	}
+	return item, nil
+}
+
+// readPLYVertices reads the positions, and any normals, texture coordinates, and colors, of the vertex element
+func (data *meshData) readPLYVertices(element *plyElement, values plyValueReader) error {
+	index := map[string]int{}
+	for i, property := range element.properties {
+		index[property.name] = i
+	}
+	has := func(names ...string) bool {
+		for _, name := range names {
+			if _, ok := index[name]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+	if !has("x", "y", "z") {
+		return fmt.Errorf("vertices have no x, y, and z")
+	}
+	uName, vName := "", ""
+	for _, names := range [][2]string{{"u", "v"}, {"s", "t"}, {"texture_u", "texture_v"}} {
+		if has(names[0], names[1]) {
+			uName, vName = names[0], names[1]
+			break
+		}
+	}
+	colorNames := [3]string{}
+	for _, names := range [][3]string{{"red", "green", "blue"}, {"r", "g", "b"}, {"diffuse_red", "diffuse_green", "diffuse_blue"}} {
+		if has(names[0], names[1], names[2]) {
+			colorNames = names
+			break
+		}
+	}
+
+	for i := 0; i < element.count; i++ {
+		item, err := readPLYItem(element, values)
+		if err != nil {
+			return err
+		}
+		value := func(name string) float64 {
+			return item[index[name]][0]
+		}
+		data.positions = append(data.positions, geometry.Point{X: value("x"), Y: value("y"), Z: value("z")})
+		if has("nx", "ny", "nz") {
+			data.normals = append(data.normals, geometry.Vector{X: value("nx"), Y: value("ny"), Z: value("nz")})
+		}
+		if uName != "" {
+			data.uvs = append(data.uvs, [2]float64{value(uName), value(vName)})
+		}
+		if colorNames[0] != "" {
+			// integer colors span their type's whole range
+			scale := plyColorScale(element.properties[index[colorNames[0]]].valueType)
+			data.colors = append(data.colors, shading.Color{
+				Red:   value(colorNames[0]) * scale,
+				Green: value(colorNames[1]) * scale,
+				Blue:  value(colorNames[2]) * scale,
+			})
+		}
+	}
+	return nil
+}
+
+// readPLYFaces reads the corners of the face element, splitting polygons into a fan of triangles
+// corners use the normal and texture coordinates of their vertex, if the vertices have them
+func (data *meshData) readPLYFaces(element *plyElement, values plyValueReader) error {
+	listIndex := -1
+	for i, property := range element.properties {
+		if property.countType != "" && (property.name == "vertex_indices" || property.name == "vertex_index") {
+			listIndex = i
+		}
+	}
+	if listIndex < 0 {
+		return fmt.Errorf("faces have no vertex_indices list")
+	}
+	for i := 0; i < element.count; i++ {
+		item, err := readPLYItem(element, values)
+		if err != nil {
+			return err
+		}
+		indices := item[listIndex]
+		if len(indices) < 3 {
+			return fmt.Errorf("face %d has fewer than 3 vertices", i)
+		}
+		corners := make([]meshVertex, len(indices))
+		for j, value := range indices {
+			index := int(value)
+			if index < 0 || index >= len(data.positions) {
+				return fmt.Errorf("face %d refers to a vertex which is not defined", i)
+			}
+			corners[j] = meshVertex{position: index, uv: -1, normal: -1}
+			if len(data.uvs) == len(data.positions) {
+				corners[j].uv = index
+			}
+			if len(data.normals) == len(data.positions) {
+				corners[j].normal = index
+			}
+		}
+		for j := 1; j+1 < len(corners); j++ {
+			data.faces = append(data.faces, meshFace{
+				vertices: [3]meshVertex{corners[0], corners[j], corners[j+1]},
+			})
+		}
+	}
+	return nil
+}
+
+// plyColorScale returns the factor bringing a color of the given type into [0, 1]
+func plyColorScale(valueType string) float64 {
+	switch valueType {
+	case "uchar", "uint8", "char", "int8":
+		return 1.0 / 255.0
+	case "ushort", "uint16", "short", "int16":
+		return 1.0 / 65535.0
+	case "uint", "uint32", "int", "int32":
+		return 1.0 / 4294967295.0
+	default:
+		return 1.0
+	}
+}
+
+// plyASCIIReader reads the values of an ASCII PLY body, separated by whitespace
+type plyASCIIReader struct {
+	scanner *bufio.Scanner
+}
+
+func (ar *plyASCIIReader) next(valueType string) (float64, error) {
+	if !ar.scanner.Scan() {
+		if err := ar.scanner.Err(); err != nil {
+			return 0, err
+		}
+		return 0, io.ErrUnexpectedEOF
+	}
+	value, err := strconv.ParseFloat(ar.scanner.Text(), 64)
+	if err != nil {
+		return 0, fmt.Errorf("value (%s) is not a number", ar.scanner.Text())
+	}
+	return value, nil
+}
+
+// plyBinaryReader reads the values of a binary PLY body, packed with no padding
+type plyBinaryReader struct {
+	r     io.Reader
+	order binary.ByteOrder
+	bytes [8]byte
+}
+
+func (br *plyBinaryReader) next(valueType string) (float64, error) {
+	var size int
+	switch valueType {
+	case "char", "int8", "uchar", "uint8":
+		size = 1
+	case "short", "int16", "ushort", "uint16":
+		size = 2
+	case "int", "int32", "uint", "uint32", "float", "float32":
+		size = 4
+	case "double", "float64":
+		size = 8
+	default:
+		return 0, fmt.Errorf("value type (%s) is not a PLY type", valueType)
+	}
+	b := br.bytes[:size]
+	_, err := io.ReadFull(br.r, b)
+	if err != nil {
+		return 0, err
+	}
+	switch valueType {
+	case "char", "int8":
+		return float64(int8(b[0])), nil
+	case "uchar", "uint8":
+		return float64(b[0]), nil
+	case "short", "int16":
+		return float64(int16(br.order.Uint16(b))), nil
+	case "ushort", "uint16":
+		return float64(br.order.Uint16(b)), nil
+	case "int", "int32":
+		return float64(int32(br.order.Uint32(b))), nil
+	case "uint", "uint32":
+		return float64(br.order.Uint32(b)), nil
+	case "float", "float32":
+		return float64(math.Float32frombits(br.order.Uint32(b))), nil
+	default:
+		return math.Float64frombits(br.order.Uint64(b)), nil
+	}
+}
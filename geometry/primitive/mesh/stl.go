@@ -0,0 +1,107 @@
+package mesh
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fluorescence/geometry"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+)
+
+// readSTL reads the triangles of a binary or ASCII STL file
+// STL files repeat each vertex for every triangle using it, so identical positions are joined back together
+func readSTL(r io.Reader) (*meshData, error) {
+	contents, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data := &meshData{}
+	welded := map[geometry.Point]int{}
+	addFacet := func(normal geometry.Vector, corners [3]geometry.Point) {
+		// the facet's normal only orients the triangle, unless it is left as zero,
+		// so the faces are shaded flat or have normals made from them like any other file without normals
+		a, b, c := corners[0], corners[1], corners[2]
+		if a.To(b).Cross(a.To(c)).Dot(normal) < 0 {
+			corners[1], corners[2] = corners[2], corners[1]
+		}
+		face := meshFace{}
+		for i, p := range corners {
+			index, ok := welded[p]
+			if !ok {
+				index = len(data.positions)
+				welded[p] = index
+				data.positions = append(data.positions, p)
+			}
+			face.vertices[i] = meshVertex{position: index, uv: -1, normal: -1}
+		}
+		data.faces = append(data.faces, face)
+	}
+
+	// binary files may also begin with "solid", so their exact length is checked first
+	if len(contents) >= 84 {
+		count := int(binary.LittleEndian.Uint32(contents[80:84]))
+		if len(contents) == 84+50*count {
+			for i := 0; i < count; i++ {
+				facet := contents[84+50*i:]
+				values := [12]float64{}
+				for j := range values {
+					values[j] = float64(math.Float32frombits(binary.LittleEndian.Uint32(facet[4*j:])))
+				}
+				addFacet(
+					geometry.Vector{X: values[0], Y: values[1], Z: values[2]},
+					[3]geometry.Point{
+						{X: values[3], Y: values[4], Z: values[5]},
+						{X: values[6], Y: values[7], Z: values[8]},
+						{X: values[9], Y: values[10], Z: values[11]},
+					})
+			}
+			return data, nil
+		}
+	}
+	if !bytes.HasPrefix(bytes.TrimSpace(contents), []byte("solid")) {
+		return nil, fmt.Errorf("stl file is neither binary nor ASCII")
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	lineNumber := 0
+	var normal geometry.Vector
+	corners := []geometry.Point{}
+	for scanner.Scan() {
+		lineNumber++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "facet":
+			if len(fields) != 5 || fields[1] != "normal" {
+				return nil, fmt.Errorf("stl line %d: facet has no normal", lineNumber)
+			}
+			values, err := parseFloats(fields[2:], 3)
+			if err != nil {
+				return nil, fmt.Errorf("stl line %d: %v", lineNumber, err)
+			}
+			normal = geometry.Vector{X: values[0], Y: values[1], Z: values[2]}
+			corners = corners[:0]
+		case "vertex":
+			values, err := parseFloats(fields[1:], 3)
+			if err != nil {
+				return nil, fmt.Errorf("stl line %d: %v", lineNumber, err)
+			}
+			corners = append(corners, geometry.Point{X: values[0], Y: values[1], Z: values[2]})
+		case "endfacet":
+			if len(corners) != 3 {
+				return nil, fmt.Errorf("stl line %d: facet has %d vertices instead of 3", lineNumber, len(corners))
+			}
+			addFacet(normal, [3]geometry.Point{corners[0], corners[1], corners[2]})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,303 @@
+package mesh
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/geometry/primitive/aabb"
+	"math"
+)
+
+// leafSize is the most faces kept in one leaf of a tree
+const leafSize = 4
+
+// treeNode is a node of a mesh's bounding volume hierarchy, stored in a flat list in which an interior node's first child directly follows it
+// bounds are kept in single precision, rounded outward, so each node takes only 32 bytes
+type treeNode struct {
+	min    [3]float32
+	max    [3]float32
+	offset uint32 // index of the second child of an interior node, or of the first face of a leaf
+	count  uint32 // number of faces in a leaf, zero for interior nodes
+}
+
+// treeItem is a face waiting to be placed in a tree
+// bounding boxes are computed once while building, rather than on every comparison
+type treeItem struct {
+	box      aabb.AABB
+	centroid geometry.Point
+	face     face
+}
+
+// treeBuilder collects the nodes of a tree, and its faces in the order its leaves use them
+type treeBuilder struct {
+	nodes []treeNode
+	faces []face
+}
+
+// buildTree builds a tree over the faces, splitting each node at the median along its widest axis
+// the faces are reordered so the faces of each leaf are next to each other
+func buildTree(faces []face, va *vertexArrays) []treeNode {
+	items := make([]treeItem, len(faces))
+	for i, f := range faces {
+		a, b, c := va.position(f.vertices[0]), va.position(f.vertices[1]), va.position(f.vertices[2])
+		box := aabb.AABB{
+			A: geometry.Point{
+				X: math.Min(math.Min(a.X, b.X), c.X),
+				Y: math.Min(math.Min(a.Y, b.Y), c.Y),
+				Z: math.Min(math.Min(a.Z, b.Z), c.Z),
+			},
+			B: geometry.Point{
+				X: math.Max(math.Max(a.X, b.X), c.X),
+				Y: math.Max(math.Max(a.Y, b.Y), c.Y),
+				Z: math.Max(math.Max(a.Z, b.Z), c.Z),
+			},
+		}
+		items[i] = treeItem{
+			box: box,
+			centroid: geometry.Point{
+				X: (box.A.X + box.B.X) / 2.0,
+				Y: (box.A.Y + box.B.Y) / 2.0,
+				Z: (box.A.Z + box.B.Z) / 2.0,
+			},
+			face: f,
+		}
+	}
+	tb := &treeBuilder{
+		nodes: make([]treeNode, 0, 2*len(items)/leafSize+1),
+		// the items hold their own copies of the faces, so the faces can be written back over in leaf order
+		faces: faces[:0],
+	}
+	tb.build(items)
+	return tb.nodes
+}
+
+// build adds a node for the items and its children to the tree, returning the node's index
+func (tb *treeBuilder) build(items []treeItem) uint32 {
+	index := uint32(len(tb.nodes))
+	tb.nodes = append(tb.nodes, treeNode{})
+
+	box := items[0].box
+	centroidBox := aabb.AABB{A: items[0].centroid, B: items[0].centroid}
+	for _, item := range items[1:] {
+		box = surround(box, item.box)
+		centroidBox = surround(centroidBox, aabb.AABB{A: item.centroid, B: item.centroid})
+	}
+
+	extent := centroidBox.A.To(centroidBox.B)
+	if len(items) <= leafSize || (extent.X == 0 && extent.Y == 0 && extent.Z == 0) {
+		tb.nodes[index] = newTreeNode(box, uint32(len(tb.faces)), uint32(len(items)))
+		for _, item := range items {
+			tb.faces = append(tb.faces, item.face)
+		}
+		return index
+	}
+
+	axis := 2
+	if extent.X >= extent.Y && extent.X >= extent.Z {
+		axis = 0
+	} else if extent.Y >= extent.Z {
+		axis = 1
+	}
+	// only the median needs to be in place, with smaller items before it and larger ones after
+	selectNth(items, len(items)/2, axis)
+
+	middle := len(items) / 2
+	tb.build(items[:middle])
+	right := tb.build(items[middle:])
+	tb.nodes[index] = newTreeNode(box, right, 0)
+	return index
+}
+
+// newTreeNode returns a node bounding the box, rounded out to the nearest single precision values around it
+func newTreeNode(box aabb.AABB, offset, count uint32) treeNode {
+	node := treeNode{
+		offset: offset,
+		count:  count,
+	}
+	for axis := 0; axis < 3; axis++ {
+		low, high := coordinate(box.A, axis), coordinate(box.B, axis)
+		node.min[axis] = float32(low)
+		if float64(node.min[axis]) > low {
+			node.min[axis] = math.Nextafter32(node.min[axis], float32(math.Inf(-1)))
+		}
+		node.max[axis] = float32(high)
+		if float64(node.max[axis]) < high {
+			node.max[axis] = math.Nextafter32(node.max[axis], float32(math.Inf(1)))
+		}
+	}
+	return node
+}
+
+// hit returns whether the ray passes through the node between tMin and tMax
+// the ray is given by its origin and the inverse of each component of its direction, which are shared by every node tested
+// rays only touching the node still hit it, as the nodes around flat groups of faces have no thickness
+func (node *treeNode) hit(origin, inverseDirection *[3]float64, tMin, tMax float64) bool {
+	var t0, t1 float64
+	t0 = (float64(node.min[0]) - origin[0]) * inverseDirection[0]
+	t1 = (float64(node.max[0]) - origin[0]) * inverseDirection[0]
+	if inverseDirection[0] < 0.0 {
+		t0, t1 = t1, t0
+	}
+	if t0 > tMin {
+		tMin = t0
+	}
+	if t1 < tMax {
+		tMax = t1
+	}
+	if tMax < tMin {
+		return false
+	}
+	t0 = (float64(node.min[1]) - origin[1]) * inverseDirection[1]
+	t1 = (float64(node.max[1]) - origin[1]) * inverseDirection[1]
+	if inverseDirection[1] < 0.0 {
+		t0, t1 = t1, t0
+	}
+	if t0 > tMin {
+		tMin = t0
+	}
+	if t1 < tMax {
+		tMax = t1
+	}
+	if tMax < tMin {
+		return false
+	}
+	t0 = (float64(node.min[2]) - origin[2]) * inverseDirection[2]
+	t1 = (float64(node.max[2]) - origin[2]) * inverseDirection[2]
+	if inverseDirection[2] < 0.0 {
+		t0, t1 = t1, t0
+	}
+	if t0 > tMin {
+		tMin = t0
+	}
+	if t1 < tMax {
+		tMax = t1
+	}
+	return tMin <= tMax
+}
+
+// closestFace returns the index of the face the ray hits first between tMin and tMax
+func (m *Mesh) closestFace(ray geometry.Ray, tMin, tMax float64) (int, bool) {
+	origin := [3]float64{ray.Origin.X, ray.Origin.Y, ray.Origin.Z}
+	inverseDirection := [3]float64{1.0 / ray.Direction.X, 1.0 / ray.Direction.Y, 1.0 / ray.Direction.Z}
+	closest := -1
+	closestTime := tMax
+	// a tree split at the median is never deeper than this, even for 2^32 faces
+	var stack [64]uint32
+	size := 1
+	for size > 0 {
+		size--
+		index := stack[size]
+		node := &m.nodes[index]
+		if !node.hit(&origin, &inverseDirection, tMin, closestTime) {
+			continue
+		}
+		if node.count == 0 {
+			stack[size] = node.offset
+			stack[size+1] = index + 1
+			size += 2
+			continue
+		}
+		for i := node.offset; i < node.offset+node.count; i++ {
+			if time, ok := m.hitFace(&m.faces[i], ray, tMin, closestTime); ok {
+				closest = int(i)
+				closestTime = time
+			}
+		}
+	}
+	return closest, closest >= 0
+}
+
+// hitFace returns the time at which the ray hits a face between tMin and tMax, if it does
+// this is the same test Triangles use, so the face's Triangle hits wherever its face does
+func (m *Mesh) hitFace(f *face, ray geometry.Ray, tMin, tMax float64) (float64, bool) {
+	a := m.vertices.position(f.vertices[0])
+	ab := a.To(m.vertices.position(f.vertices[1]))
+	ac := a.To(m.vertices.position(f.vertices[2]))
+	pVector := ray.Direction.Cross(ac)
+	determinant := ab.Dot(pVector)
+	if m.IsCulled && determinant < 1e-7 {
+		return 0, false
+	} else if determinant > -1e-7 && determinant < 1e-7 {
+		return 0, false
+	}
+	inverseDeterminant := 1.0 / determinant
+
+	tVector := a.To(ray.Origin)
+	u := inverseDeterminant * (tVector.Dot(pVector))
+	if u < 0.0 || u > 1.0 {
+		return 0, false
+	}
+	qVector := tVector.Cross(ab)
+	v := inverseDeterminant * (ray.Direction.Dot(qVector))
+	if v < 0.0 || u+v > 1.0 {
+		return 0, false
+	}
+	time := inverseDeterminant * (ac.Dot(qVector))
+	return time, time >= tMin && time <= tMax
+}
+
+// boundingBox returns the box around all of the mesh's faces
+// it is padded as a Triangle's is, so that flat meshes still have a box with some thickness
+func (m *Mesh) boundingBox() *aabb.AABB {
+	root := &m.nodes[0]
+	return &aabb.AABB{
+		A: geometry.Point{X: float64(root.min[0]) - 1e-7, Y: float64(root.min[1]) - 1e-7, Z: float64(root.min[2]) - 1e-7},
+		B: geometry.Point{X: float64(root.max[0]) + 1e-7, Y: float64(root.max[1]) + 1e-7, Z: float64(root.max[2]) + 1e-7},
+	}
+}
+
+// surround returns the box around two boxes
+func surround(a, b aabb.AABB) aabb.AABB {
+	return aabb.AABB{
+		A: geometry.Point{
+			X: math.Min(a.A.X, b.A.X),
+			Y: math.Min(a.A.Y, b.A.Y),
+			Z: math.Min(a.A.Z, b.A.Z),
+		},
+		B: geometry.Point{
+			X: math.Max(a.B.X, b.B.X),
+			Y: math.Max(a.B.Y, b.B.Y),
+			Z: math.Max(a.B.Z, b.B.Z),
+		},
+	}
+}
+
+// selectNth reorders the items so the nth is where sorting by the centroids' axis coordinate would put it,
+// with no item before it larger and no item after it smaller
+func selectNth(items []treeItem, n int, axis int) {
+	low, high := 0, len(items)-1
+	for low < high {
+		pivot := coordinate(items[(low+high)/2].centroid, axis)
+		i, j := low, high
+		for i <= j {
+			for coordinate(items[i].centroid, axis) < pivot {
+				i++
+			}
+			for coordinate(items[j].centroid, axis) > pivot {
+				j--
+			}
+			if i <= j {
+				items[i], items[j] = items[j], items[i]
+				i++
+				j--
+			}
+		}
+		if n <= j {
+			high = j
+		} else if n >= i {
+			low = i
+		} else {
+			return
+		}
+	}
+}
+
+// coordinate returns the X, Y or Z coordinate of a point for an axis of 0, 1 or 2
+func coordinate(p geometry.Point, axis int) float64 {
+	switch axis {
+	case 0:
+		return p.X
+	case 1:
+		return p.Y
+	default:
+		return p.Z
+	}
+}
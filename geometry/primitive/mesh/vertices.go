@@ -0,0 +1,115 @@
+package mesh
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+)
+
+// vertexArrays holds the vertices of a mesh in flat arrays shared by all of its faces
+// each array holds either double or single precision values, and the other is left empty
+type vertexArrays struct {
+	positions   []float64 // x, y, and z of each vertex
+	uvs         []float64 // u and v of each vertex, or empty if the mesh has no texture coordinates
+	normals     []float64 // x, y, and z of each vertex's normal, or empty if the mesh has no normals
+	positions32 []float32
+	uvs32       []float32
+	normals32   []float32
+	colors      []float32 // red, green, and blue of each vertex, or empty if the mesh has no vertex colors
+}
+
+// newVertexArrays packs the vertices into flat arrays, in single precision if asked to
+func newVertexArrays(positions []geometry.Point, uvs [][2]float64, normals []geometry.Vector, colors []shading.Color, useFloat32 bool) vertexArrays {
+	va := vertexArrays{}
+	if useFloat32 {
+		va.positions32 = make([]float32, 0, 3*len(positions))
+		for _, p := range positions {
+			va.positions32 = append(va.positions32, float32(p.X), float32(p.Y), float32(p.Z))
+		}
+		if len(uvs) > 0 {
+			va.uvs32 = make([]float32, 0, 2*len(uvs))
+			for _, uv := range uvs {
+				va.uvs32 = append(va.uvs32, float32(uv[0]), float32(uv[1]))
+			}
+		}
+		if len(normals) > 0 {
+			va.normals32 = make([]float32, 0, 3*len(normals))
+			for _, n := range normals {
+				va.normals32 = append(va.normals32, float32(n.X), float32(n.Y), float32(n.Z))
+			}
+		}
+	} else {
+		va.positions = make([]float64, 0, 3*len(positions))
+		for _, p := range positions {
+			va.positions = append(va.positions, p.X, p.Y, p.Z)
+		}
+		if len(uvs) > 0 {
+			va.uvs = make([]float64, 0, 2*len(uvs))
+			for _, uv := range uvs {
+				va.uvs = append(va.uvs, uv[0], uv[1])
+			}
+		}
+		if len(normals) > 0 {
+			va.normals = make([]float64, 0, 3*len(normals))
+			for _, n := range normals {
+				va.normals = append(va.normals, n.X, n.Y, n.Z)
+			}
+		}
+	}
+	// colors are stored in single precision either way, as they rarely carry more than 8 bits a channel
+	if len(colors) > 0 {
+		va.colors = make([]float32, 0, 3*len(colors))
+		for _, c := range colors {
+			va.colors = append(va.colors, float32(c.Red), float32(c.Green), float32(c.Blue))
+		}
+	}
+	return va
+}
+
+// position returns the position of a vertex
+func (va *vertexArrays) position(i uint32) geometry.Point {
+	if va.positions32 != nil {
+		p := va.positions32[3*i : 3*i+3]
+		return geometry.Point{X: float64(p[0]), Y: float64(p[1]), Z: float64(p[2])}
+	}
+	p := va.positions[3*i : 3*i+3]
+	return geometry.Point{X: p[0], Y: p[1], Z: p[2]}
+}
+
+// hasUVs returns whether the vertices have texture coordinates
+func (va *vertexArrays) hasUVs() bool {
+	return len(va.uvs) > 0 || len(va.uvs32) > 0
+}
+
+// uv returns the texture coordinates of a vertex, which must have them
+func (va *vertexArrays) uv(i uint32) [2]float64 {
+	if va.uvs32 != nil {
+		return [2]float64{float64(va.uvs32[2*i]), float64(va.uvs32[2*i+1])}
+	}
+	return [2]float64{va.uvs[2*i], va.uvs[2*i+1]}
+}
+
+// hasNormals returns whether the vertices have normals
+func (va *vertexArrays) hasNormals() bool {
+	return len(va.normals) > 0 || len(va.normals32) > 0
+}
+
+// normal returns the normal of a vertex, which must have one
+func (va *vertexArrays) normal(i uint32) geometry.Vector {
+	if va.normals32 != nil {
+		n := va.normals32[3*i : 3*i+3]
+		return geometry.Vector{X: float64(n[0]), Y: float64(n[1]), Z: float64(n[2])}
+	}
+	n := va.normals[3*i : 3*i+3]
+	return geometry.Vector{X: n[0], Y: n[1], Z: n[2]}
+}
+
+// hasColors returns whether the vertices have colors
+func (va *vertexArrays) hasColors() bool {
+	return len(va.colors) > 0
+}
+
+// color returns the color of a vertex, which must have one
+func (va *vertexArrays) color(i uint32) shading.Color {
+	c := va.colors[3*i : 3*i+3]
+	return shading.Color{Red: float64(c[0]), Green: float64(c[1]), Blue: float64(c[2])}
+}
@@ -13,6 +13,7 @@ type Plane struct {
 	Point    geometry.Point  `json:"point"`
 	Normal   geometry.Vector `json:"normal"`
 	IsCulled bool            `json:"is_culled"`
+	basis    geometry.ONB    // tangent frame of the plane's surface
 	mat      material.Material
 }
 
@@ -22,6 +23,7 @@ func (p *Plane) Setup() (*Plane, error) {
 		return nil, fmt.Errorf("Plane normal is zero vector")
 	}
 	p.Normal = p.Normal.Unit()
+	p.basis = geometry.NewONB(p.Normal)
 	return p, nil
 }
 
@@ -40,11 +42,22 @@ func (p *Plane) Intersection(ray geometry.Ray, tMin, tMax float64) (*material.Ra
 		return nil, false
 	}
 
+	// texture coordinates are distances across the plane from its point, so textures repeat every unit
+	hitPoint := ray.PointAt(t)
+	planeVector := p.Point.To(hitPoint)
+
 	return &material.RayHit{
-		Ray:         ray,
-		NormalAtHit: p.Normal,
-		Time:        t,
-		Material:    p.mat,
+		Ray:             ray,
+		Point:           hitPoint,
+		ObjectPoint:     hitPoint,
+		NormalAtHit:     p.Normal,
+		GeometricNormal: p.Normal,
+		Time:            t,
+		U:               planeVector.Dot(p.basis.U),
+		V:               planeVector.Dot(p.basis.V),
+		Tangent:         p.basis.U,
+		Bitangent:       p.basis.V,
+		Material:        p.mat,
 	}, true
 }
 
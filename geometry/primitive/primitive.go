@@ -15,3 +15,9 @@ type Primitive interface {
 	IsClosed() bool
 	Copy() Primitive
 }
+
+// Surface is implemented by Primitives which can measure their own surface area
+// the returned bool is false if the area is unknown or infinite
+type Surface interface {
+	SurfaceArea() (float64, bool)
+}
@@ -135,6 +135,23 @@ func (pl *PrimitiveList) IsClosed() bool {
 	return false
 }
 
+// SurfaceArea returns the total surface area of the objects in this list
+func (pl *PrimitiveList) SurfaceArea() (float64, bool) {
+	total := 0.0
+	for _, p := range pl.List {
+		surface, ok := p.(primitive.Surface)
+		if !ok {
+			return 0.0, false
+		}
+		area, ok := surface.SurfaceArea()
+		if !ok {
+			return 0.0, false
+		}
+		total += area
+	}
+	return total, true
+}
+
 // Copy returns a shallow copy of this object
 func (pl *PrimitiveList) Copy() primitive.Primitive {
 	newPL := &PrimitiveList{}
@@ -136,6 +136,11 @@ func (p *Pyramid) IsClosed() bool {
 	return true
 }
 
+// SurfaceArea returns the surface area of this object
+func (p *Pyramid) SurfaceArea() (float64, bool) {
+	return p.list.SurfaceArea()
+}
+
 // Copy returns a shallow copy of this object
 func (p *Pyramid) Copy() primitive.Primitive {
 	newP := *p
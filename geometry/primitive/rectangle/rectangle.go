@@ -69,6 +69,11 @@ func (r *Rectangle) IsClosed() bool {
 	return r.axisAlignedRectangle.IsClosed()
 }
 
+// SurfaceArea returns the surface area of this object
+func (r *Rectangle) SurfaceArea() (float64, bool) {
+	return r.axisAlignedRectangle.(primitive.Surface).SurfaceArea()
+}
+
 // Copy returns a shallow copy of this object
 func (r *Rectangle) Copy() primitive.Primitive {
 	newR := *r
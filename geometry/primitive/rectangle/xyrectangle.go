@@ -84,13 +84,29 @@ func (r *xyRectangle) Intersection(ray geometry.Ray, tMin, tMax float64) (*mater
 	u := (x - r.x0) / (r.x1 - r.x0)
 	v := (y - r.y0) / (r.y1 - r.y0)
 
+	// the hit point is placed exactly on the rectangle's plane
+	hitPoint := geometry.Point{X: x, Y: y, Z: r.z}
+
 	return &material.RayHit{
-		Ray:         ray,
-		NormalAtHit: r.normal,
-		Time:        t,
-		U:           u,
-		V:           v,
-		Material:    r.mat,
+		Ray:             ray,
+		Point:           hitPoint,
+		ObjectPoint:     hitPoint,
+		NormalAtHit:     r.normal,
+		GeometricNormal: r.normal,
+		Time:            t,
+		U:               u,
+		V:               v,
+		Tangent: geometry.Vector{
+			X: r.x1 - r.x0,
+			Y: 0.0,
+			Z: 0.0,
+		},
+		Bitangent: geometry.Vector{
+			X: 0.0,
+			Y: r.y1 - r.y0,
+			Z: 0.0,
+		},
+		Material: r.mat,
 	}, true
 }
 
@@ -125,6 +141,11 @@ func (r *xyRectangle) IsClosed() bool {
 	return false
 }
 
+// SurfaceArea returns the surface area of this object
+func (r *xyRectangle) SurfaceArea() (float64, bool) {
+	return (r.x1 - r.x0) * (r.y1 - r.y0), true
+}
+
 // Copy returns a shallow copy of this object
 func (r *xyRectangle) Copy() primitive.Primitive {
 	newR := *r
@@ -84,13 +84,29 @@ func (r *xzRectangle) Intersection(ray geometry.Ray, tMin, tMax float64) (*mater
 	u := (x - r.x0) / (r.x1 - r.x0)
 	v := (z - r.z0) / (r.z1 - r.z0)
 
+	// the hit point is placed exactly on the rectangle's plane
+	hitPoint := geometry.Point{X: x, Y: r.y, Z: z}
+
 	return &material.RayHit{
-		Ray:         ray,
-		NormalAtHit: r.normal,
-		Time:        t,
-		U:           u,
-		V:           v,
-		Material:    r.mat,
+		Ray:             ray,
+		Point:           hitPoint,
+		ObjectPoint:     hitPoint,
+		NormalAtHit:     r.normal,
+		GeometricNormal: r.normal,
+		Time:            t,
+		U:               u,
+		V:               v,
+		Tangent: geometry.Vector{
+			X: r.x1 - r.x0,
+			Y: 0.0,
+			Z: 0.0,
+		},
+		Bitangent: geometry.Vector{
+			X: 0.0,
+			Y: 0.0,
+			Z: r.z1 - r.z0,
+		},
+		Material: r.mat,
 	}, true
 }
 
@@ -125,6 +141,11 @@ func (r *xzRectangle) IsClosed() bool {
 	return false
 }
 
+// SurfaceArea returns the surface area of this object
+func (r *xzRectangle) SurfaceArea() (float64, bool) {
+	return (r.x1 - r.x0) * (r.z1 - r.z0), true
+}
+
 // Copy returns a shallow copy of this object
 func (r *xzRectangle) Copy() primitive.Primitive {
 	newR := *r
@@ -58,44 +58,12 @@ func (s *Sphere) Intersection(ray geometry.Ray, tMin, tMax float64) (*material.R
 		t1 := (-b - root) / a
 		// return if within range
 		if t1 >= tMin && t1 <= tMax {
-			hitPoint := ray.PointAt(t1)
-			unitHitPoint := s.Center.To(hitPoint).DivScalar(s.Radius)
-
-			phi := math.Atan2(unitHitPoint.Z, unitHitPoint.X)
-			theta := math.Asin(unitHitPoint.Y)
-
-			u := 1 - (phi+math.Pi)/(2*math.Pi)
-			v := (theta + math.Pi/2) / math.Pi
-
-			return &material.RayHit{
-				Ray:         ray,
-				NormalAtHit: s.normalAt(hitPoint),
-				Time:        t1,
-				U:           u,
-				V:           v,
-				Material:    s.mat,
-			}, true
+			return s.rayHitAt(ray, t1), true
 		}
 		// evaluate and return second solution if in range
 		t2 := (-b + root) / a
 		if t2 >= tMin && t2 <= tMax {
-			hitPoint := ray.PointAt(t1)
-			unitHitPoint := s.Center.To(hitPoint).DivScalar(s.Radius)
-
-			phi := math.Atan2(unitHitPoint.Z, unitHitPoint.X)
-			theta := math.Asin(unitHitPoint.Y)
-
-			u := 1.0 - (phi+math.Pi)/(2*math.Pi)
-			v := (theta + math.Pi/2) / math.Pi
-
-			return &material.RayHit{
-				Ray:         ray,
-				NormalAtHit: s.normalAt(ray.PointAt(t2)),
-				Time:        t2,
-				U:           u,
-				V:           v,
-				Material:    s.mat,
-			}, true
+			return s.rayHitAt(ray, t2), true
 		}
 	}
 
@@ -133,12 +101,56 @@ func (s *Sphere) IsClosed() bool {
 	return true
 }
 
+// SurfaceArea returns the surface area of this object
+func (s *Sphere) SurfaceArea() (float64, bool) {
+	return 4.0 * math.Pi * s.Radius * s.Radius, true
+}
+
 // Copy returns a shallow copy of this object
 func (s *Sphere) Copy() primitive.Primitive {
 	newS := *s
 	return &newS
 }
 
+// rayHitAt builds the RayHit for the ray striking this sphere at time t
+func (s *Sphere) rayHitAt(ray geometry.Ray, t float64) *material.RayHit {
+	hitPoint := ray.PointAt(t)
+	unitHitPoint := s.Center.To(hitPoint).DivScalar(s.Radius)
+
+	phi := math.Atan2(unitHitPoint.Z, unitHitPoint.X)
+	theta := math.Asin(math.Max(-1.0, math.Min(unitHitPoint.Y, 1.0)))
+
+	u := 1.0 - (phi+math.Pi)/(2*math.Pi)
+	v := (theta + math.Pi/2) / math.Pi
+
+	// u runs clockwise around the y axis and v from the bottom pole to the top
+	tangent := geometry.Vector{
+		X: math.Sin(phi),
+		Y: 0.0,
+		Z: -math.Cos(phi),
+	}.MultScalar(2.0 * math.Pi * s.Radius * math.Cos(theta))
+	bitangent := geometry.Vector{
+		X: -math.Sin(theta) * math.Cos(phi),
+		Y: math.Cos(theta),
+		Z: -math.Sin(theta) * math.Sin(phi),
+	}.MultScalar(math.Pi * s.Radius)
+
+	normal := s.normalAt(hitPoint)
+	return &material.RayHit{
+		Ray:             ray,
+		Point:           hitPoint,
+		ObjectPoint:     hitPoint,
+		NormalAtHit:     normal,
+		GeometricNormal: normal,
+		Time:            t,
+		U:               u,
+		V:               v,
+		Tangent:         tangent,
+		Bitangent:       bitangent,
+		Material:        s.mat,
+	}
+}
+
 func (s *Sphere) normalAt(p geometry.Point) geometry.Vector {
 	if s.HasInvertedNormals {
 		return p.To(s.Center).Unit()
@@ -0,0 +1,284 @@
+package affine
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/geometry/primitive/aabb"
+	"fluorescence/shading/material"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// Step is one step in building a transform, as read from a list in JSON
+type Step struct {
+	TypeName string          `json:"type"`   // translate, rotate, scale, look_at, or matrix
+	Vector   geometry.Vector `json:"vector"` // offset to translate by, or factors to scale each axis by
+	Axis     geometry.Vector `json:"axis"`   // axis to rotate about
+	Angle    float64         `json:"angle"`  // angle in degrees to rotate by, counterclockwise looking down the axis
+	From     geometry.Point  `json:"from"`   // point to move the origin to when looking at a target
+	To       geometry.Point  `json:"to"`     // point the forward (negative Z) axis is turned to face
+	Up       geometry.Vector `json:"up"`     // direction the Y axis is turned toward, positive Y if zero
+	Elements [16]float64     `json:"matrix"` // any affine matrix, listed by rows
+}
+
+// Matrix returns the 4x4 matrix of a step
+func (s *Step) Matrix() (mgl64.Mat4, error) {
+	switch strings.ToLower(s.TypeName) {
+	case "translate":
+		return mgl64.Translate3D(s.Vector.X, s.Vector.Y, s.Vector.Z), nil
+	case "rotate":
+		if s.Axis.Magnitude() == 0.0 {
+			return mgl64.Mat4{}, fmt.Errorf("rotation has no axis")
+		}
+		axis := s.Axis.Unit()
+		return mgl64.HomogRotate3D(mgl64.DegToRad(s.Angle), mgl64.Vec3{axis.X, axis.Y, axis.Z}), nil
+	case "scale":
+		if s.Vector.X == 0.0 || s.Vector.Y == 0.0 || s.Vector.Z == 0.0 {
+			return mgl64.Mat4{}, fmt.Errorf("scale (%v) flattens an axis", s.Vector)
+		}
+		return mgl64.Scale3D(s.Vector.X, s.Vector.Y, s.Vector.Z), nil
+	case "look_at":
+		up := s.Up
+		if up == geometry.VectorZero {
+			up = geometry.VectorUp
+		}
+		// the object faces along its forward axis like a camera does, so its Z axis points back from the target
+		back := s.To.To(s.From)
+		if back.Magnitude() == 0.0 {
+			return mgl64.Mat4{}, fmt.Errorf("look at target (%v) is the same as its origin", s.To)
+		}
+		back = back.Unit()
+		right := up.Cross(back)
+		if right.Magnitude() == 0.0 {
+			return mgl64.Mat4{}, fmt.Errorf("look at up vector (%v) is parallel to the view direction", up)
+		}
+		right = right.Unit()
+		newUp := back.Cross(right)
+		return mgl64.Mat4{
+			right.X, right.Y, right.Z, 0.0,
+			newUp.X, newUp.Y, newUp.Z, 0.0,
+			back.X, back.Y, back.Z, 0.0,
+			s.From.X, s.From.Y, s.From.Z, 1.0,
+		}, nil
+	case "matrix":
+		// mgl64 matrices are stored by columns
+		return mgl64.Mat4(s.Elements).Transpose(), nil
+	default:
+		return mgl64.Mat4{}, fmt.Errorf("transform step type (%s) not a valid type", s.TypeName)
+	}
+}
+
+// Compose returns the matrix applying each step in turn, with the first step in the list applied to the object first
+func Compose(steps []Step) (mgl64.Mat4, error) {
+	m := mgl64.Ident4()
+	for _, s := range steps {
+		stepMatrix, err := s.Matrix()
+		if err != nil {
+			return mgl64.Mat4{}, err
+		}
+		m = stepMatrix.Mul4(m)
+	}
+	return m, nil
+}
+
+// Matrix is an affine transform from an object's space into world space, along with what is needed to undo it
+type Matrix struct {
+	toWorld  mgl64.Mat4
+	toObject mgl64.Mat4
+	linear   mgl64.Mat3 // the transform without its translation, which carries directions
+	inverse  mgl64.Mat3 // the inverse of linear, which carries directions back
+	normal   mgl64.Mat3 // the inverse transpose of linear, which keeps normals perpendicular to the surface
+}
+
+// New returns the Matrix for a 4x4 transform, which must be affine and must not flatten space
+func New(m mgl64.Mat4) (*Matrix, error) {
+	if m.Row(3) != (mgl64.Vec4{0.0, 0.0, 0.0, 1.0}) {
+		return nil, fmt.Errorf("transform matrix has a bottom row of %v rather than [0 0 0 1]", m.Row(3))
+	}
+	linear := m.Mat3()
+	if math.Abs(linear.Det()) < 1e-12 {
+		return nil, fmt.Errorf("transform matrix flattens space")
+	}
+	return newMatrix(m), nil
+}
+
+// newMatrix returns the Matrix for a 4x4 transform already known to be affine and invertible
+func newMatrix(m mgl64.Mat4) *Matrix {
+	linear := m.Mat3()
+	inverse := linear.Inv()
+	return &Matrix{
+		toWorld:  m,
+		toObject: m.Inv(),
+		linear:   linear,
+		inverse:  inverse,
+		normal:   inverse.Transpose(),
+	}
+}
+
+// ToObjectRay carries a ray into the object's space
+// its direction is not normalized, so a hit is at the same time along the ray in both spaces
+func (m *Matrix) ToObjectRay(ray geometry.Ray) geometry.Ray {
+	ray.Origin = transformPoint(m.toObject, ray.Origin)
+	ray.Direction = transformVector(m.inverse, ray.Direction)
+	return ray
+}
+
+// ToWorldHit carries a hit on the object back into world space, as a hit along the world space ray
+// the object space point is left as the primitive which was hit placed it
+func (m *Matrix) ToWorldHit(rayHit *material.RayHit, ray geometry.Ray) {
+	rayHit.Ray = ray
+	rayHit.Point = m.Point(rayHit.Point)
+	rayHit.NormalAtHit = m.Normal(rayHit.NormalAtHit)
+	rayHit.GeometricNormal = m.Normal(rayHit.GeometricNormal)
+	// tangents are rates of change across the surface, which stretch with it
+	rayHit.ObjectTangent, rayHit.ObjectBitangent = rayHit.ObjectFrame()
+	rayHit.Tangent = m.Vector(rayHit.Tangent)
+	rayHit.Bitangent = m.Vector(rayHit.Bitangent)
+}
+
+// Point carries a point from the object's space into world space
+func (m *Matrix) Point(p geometry.Point) geometry.Point {
+	return transformPoint(m.toWorld, p)
+}
+
+// Vector carries a direction, or a rate of change across a surface, from the object's space into world space
+func (m *Matrix) Vector(v geometry.Vector) geometry.Vector {
+	return transformVector(m.linear, v)
+}
+
+// Normal carries a unit normal from the object's space into world space, leaving a zero normal as it is
+func (m *Matrix) Normal(n geometry.Vector) geometry.Vector {
+	if n == geometry.VectorZero {
+		return n
+	}
+	return transformVector(m.normal, n).Unit()
+}
+
+// BoundingBox returns the box around an object's box once carried into world space
+func (m *Matrix) BoundingBox(box *aabb.AABB) *aabb.AABB {
+	minPoint := geometry.PointMax
+	maxPoint := geometry.PointMax.Negate()
+	for i := 0.0; i < 2; i++ {
+		for j := 0.0; j < 2; j++ {
+			for k := 0.0; k < 2; k++ {
+				corner := m.Point(geometry.Point{
+					X: i*box.B.X + (1-i)*box.A.X,
+					Y: j*box.B.Y + (1-j)*box.A.Y,
+					Z: k*box.B.Z + (1-k)*box.A.Z,
+				})
+				maxPoint = geometry.MaxComponents(maxPoint, corner)
+				minPoint = geometry.MinComponents(minPoint, corner)
+			}
+		}
+	}
+	return &aabb.AABB{
+		A: minPoint,
+		B: maxPoint,
+	}
+}
+
+// AreaScale returns how much the transform scales areas by
+// this is only the same everywhere on a surface when the transform scales equally in every direction
+func (m *Matrix) AreaScale() (float64, bool) {
+	gram := m.linear.Transpose().Mul3(m.linear)
+	scaleSquared := gram.At(0, 0)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			expected := 0.0
+			if i == j {
+				expected = scaleSquared
+			}
+			if math.Abs(gram.At(i, j)-expected) > 1e-9*scaleSquared {
+				return 0.0, false
+			}
+		}
+	}
+	return scaleSquared, true
+}
+
+// pose is an affine transform split into parts which can each be blended between two transforms
+type pose struct {
+	translation    mgl64.Vec3
+	rotation       mgl64.Quat
+	stretch        mgl64.Mat3 // the scaling and shearing applied before rotating, which is symmetric
+	inverseStretch mgl64.Mat3 // the inverse of stretch
+}
+
+// decompose splits an affine transform into a stretch, then a rotation, then a translation
+// the rotation is the nearest one to the transform's linear part, found by polar decomposition,
+// and a mirroring transform keeps its mirroring in the stretch
+func decompose(m mgl64.Mat4) pose {
+	linear := m.Mat3()
+	orthogonal := linear
+	for i := 0; i < 64; i++ {
+		// averaging a matrix with its inverse transpose converges on the nearest orthogonal matrix
+		next := orthogonal.Add(orthogonal.Inv().Transpose()).Mul(0.5)
+		converged := next.ApproxEqualThreshold(orthogonal, 1e-12)
+		orthogonal = next
+		if converged {
+			break
+		}
+	}
+	if orthogonal.Det() < 0.0 {
+		orthogonal = orthogonal.Mul(-1.0)
+	}
+	translation := m.Col(3)
+	stretch := orthogonal.Transpose().Mul3(linear)
+	return pose{
+		translation:    translation.Vec3(),
+		rotation:       mgl64.Mat4ToQuat(orthogonal.Mat4()).Normalize(),
+		stretch:        stretch,
+		inverseStretch: stretch.Inv(),
+	}
+}
+
+// interpolate returns the Matrix a fraction of the way from one pose to another
+// the rotation turns along the shortest arc while the stretch and translation change linearly
+// the inverse is built from the same parts undone in reverse, so no 4x4 inverse is needed,
+// and the stretch is only inverted when it changes over the motion
+func interpolate(start, end pose, time float64) Matrix {
+	stretch := start.stretch
+	inverseStretch := start.inverseStretch
+	if start.stretch != end.stretch {
+		stretch = start.stretch.Mul(1.0 - time).Add(end.stretch.Mul(time))
+		inverseStretch = stretch.Inv()
+	}
+	rotation := mgl64.QuatSlerp(start.rotation, end.rotation, time).Normalize()
+	translation := start.translation.Mul(1.0 - time).Add(end.translation.Mul(time))
+
+	linear := rotation.Mat4().Mat3().Mul3(stretch)
+	inverse := inverseStretch.Mul3(rotation.Conjugate().Mat4().Mat3())
+	toWorld := linear.Mat4()
+	toWorld.SetCol(3, translation.Vec4(1.0))
+	toObject := inverse.Mat4()
+	toObject.SetCol(3, inverse.Mul3x1(translation).Mul(-1.0).Vec4(1.0))
+	return Matrix{
+		toWorld:  toWorld,
+		toObject: toObject,
+		linear:   linear,
+		inverse:  inverse,
+		normal:   inverse.Transpose(),
+	}
+}
+
+// transformPoint applies a transform, including its translation, to a point
+func transformPoint(m mgl64.Mat4, p geometry.Point) geometry.Point {
+	transformed := m.Mul4x1(mgl64.Vec4{p.X, p.Y, p.Z, 1.0})
+	return geometry.Point{
+		X: transformed.X(),
+		Y: transformed.Y(),
+		Z: transformed.Z(),
+	}
+}
+
+// transformVector applies a linear transform to a vector
+func transformVector(m mgl64.Mat3, v geometry.Vector) geometry.Vector {
+	transformed := m.Mul3x1(mgl64.Vec3{v.X, v.Y, v.Z})
+	return geometry.Vector{
+		X: transformed.X(),
+		Y: transformed.Y(),
+		Z: transformed.Z(),
+	}
+}
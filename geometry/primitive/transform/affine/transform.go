@@ -0,0 +1,133 @@
+package affine
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/geometry/primitive"
+	"fluorescence/geometry/primitive/aabb"
+	"fluorescence/shading/material"
+	"fmt"
+	"math"
+)
+
+// Transform is a primitive with any affine transform attached, built from a list of steps
+// a whole chain of translations, rotations and scalings costs a single wrapper
+// a moving transform blends from its steps at time 0 to its end steps at time 1
+type Transform struct {
+	Steps     []Step      `json:"transforms"`
+	EndSteps  []Step      `json:"end_transforms"` // steps at time 1, if the object moves
+	TypeName  string      `json:"type"`
+	Data      interface{} `json:"data"`
+	Primitive primitive.Primitive
+	matrix    *Matrix
+	start     pose
+	end       pose
+}
+
+// Setup sets up a Transform's internal fields
+func (t *Transform) Setup() (*Transform, error) {
+	m, err := Compose(t.Steps)
+	if err != nil {
+		return nil, err
+	}
+	t.matrix, err = New(m)
+	if err != nil {
+		return nil, err
+	}
+	// an empty list of end steps leaves the object still, as no list does
+	if len(t.EndSteps) == 0 {
+		t.EndSteps = nil
+		return t, nil
+	}
+	end, err := Compose(t.EndSteps)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := New(end); err != nil {
+		return nil, err
+	}
+	// blending between a mirrored and an unmirrored transform would flatten the object on the way
+	if (m.Mat3().Det() < 0.0) != (end.Mat3().Det() < 0.0) {
+		return nil, fmt.Errorf("transform mirrors the object at only one end of its motion")
+	}
+	t.start = decompose(m)
+	t.end = decompose(end)
+	return t, nil
+}
+
+// Intersection computer the intersection of this object and a given ray if it exists
+func (t *Transform) Intersection(ray geometry.Ray, tMin, tMax float64) (*material.RayHit, bool) {
+	matrix := t.matrixAt(ray.Time)
+	rayHit, wasHit := t.Primitive.Intersection(matrix.ToObjectRay(ray), tMin, tMax)
+	if !wasHit {
+		return nil, false
+	}
+	matrix.ToWorldHit(rayHit, ray)
+	return rayHit, true
+}
+
+// BoundingBox returns an AABB for this object
+func (t *Transform) BoundingBox(t0, t1 float64) (*aabb.AABB, bool) {
+	box, ok := t.Primitive.BoundingBox(t0, t1)
+	if !ok {
+		return nil, false
+	}
+	if t.EndSteps != nil {
+		return aabb.Swept(box, t0, t1, func(p geometry.Point, time float64) geometry.Point {
+			matrix := t.matrixAt(time)
+			return matrix.Point(p)
+		}), true
+	}
+	return t.matrix.BoundingBox(box), true
+}
+
+// matrixAt returns the transform at a time
+// it is returned by value, so that a moving transform need not allocate for every ray
+func (t *Transform) matrixAt(time float64) Matrix {
+	if t.EndSteps == nil {
+		return *t.matrix
+	}
+	time = math.Max(0.0, math.Min(time, 1.0))
+	return interpolate(t.start, t.end, time)
+}
+
+// SetMaterial sets the material of this object
+func (t *Transform) SetMaterial(m material.Material) {
+	t.Primitive.SetMaterial(m)
+}
+
+// IsInfinite returns whether this object is infinite
+func (t *Transform) IsInfinite() bool {
+	return t.Primitive.IsInfinite()
+}
+
+// IsClosed returns whether this object is closed
+func (t *Transform) IsClosed() bool {
+	return t.Primitive.IsClosed()
+}
+
+// SurfaceArea returns the surface area of this object
+// the area is only known when the transform scales equally in every direction, and does not change as it moves
+func (t *Transform) SurfaceArea() (float64, bool) {
+	if t.EndSteps != nil && !t.start.stretch.ApproxEqualThreshold(t.end.stretch, 1e-9) {
+		return 0.0, false
+	}
+	surface, ok := t.Primitive.(primitive.Surface)
+	if !ok {
+		return 0.0, false
+	}
+	scale, ok := t.matrix.AreaScale()
+	if !ok {
+		return 0.0, false
+	}
+	area, ok := surface.SurfaceArea()
+	if !ok {
+		return 0.0, false
+	}
+	return area * scale, true
+}
+
+// Copy returns a shallow copy of this object
+func (t *Transform) Copy() primitive.Primitive {
+	newT := *t
+	return &newT
+}
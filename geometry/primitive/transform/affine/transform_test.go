@@ -0,0 +1,375 @@
+package affine
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/geometry/primitive/rectangle"
+	"fluorescence/geometry/primitive/sphere"
+	"math"
+	"testing"
+)
+
+var transformHit bool
+
+func TestComposeOrder(t *testing.T) {
+	// scaling first and translating second must not scale the translation
+	m, err := Compose([]Step{
+		{TypeName: "scale", Vector: geometry.Vector{X: 2.0, Y: 2.0, Z: 2.0}},
+		{TypeName: "translate", Vector: geometry.Vector{X: 1.0, Y: 0.0, Z: 0.0}},
+	})
+	if err != nil {
+		t.Fatalf("Error composing transform: %s\n", err)
+	}
+	matrix, err := New(m)
+	if err != nil {
+		t.Fatalf("Error creating matrix: %s\n", err)
+	}
+	p := matrix.Point(geometry.Point{X: 1.0, Y: 1.0, Z: 1.0})
+	expected := geometry.Point{X: 3.0, Y: 2.0, Z: 2.0}
+	if p.To(expected).Magnitude() > 1e-9 {
+		t.Errorf("Expected %v but got %v\n", expected, p)
+	}
+}
+
+func TestStepLookAt(t *testing.T) {
+	s := Step{
+		TypeName: "look_at",
+		From:     geometry.Point{X: 1.0, Y: 2.0, Z: 3.0},
+		To:       geometry.Point{X: 6.0, Y: 2.0, Z: 3.0},
+	}
+	m, err := s.Matrix()
+	if err != nil {
+		t.Fatalf("Error creating look at matrix: %s\n", err)
+	}
+	matrix, err := New(m)
+	if err != nil {
+		t.Fatalf("Error creating matrix: %s\n", err)
+	}
+	forward := matrix.Vector(geometry.VectorForward)
+	if forward.Sub(geometry.Vector{X: 1.0}).Magnitude() > 1e-9 {
+		t.Errorf("Expected forward to face the target along (1, 0, 0) but got %v\n", forward)
+	}
+	up := matrix.Vector(geometry.VectorUp)
+	if up.Sub(geometry.VectorUp).Magnitude() > 1e-9 {
+		t.Errorf("Expected up to stay (0, 1, 0) but got %v\n", up)
+	}
+	origin := matrix.Point(geometry.PointZero)
+	if origin.To(s.From).Magnitude() > 1e-9 {
+		t.Errorf("Expected the origin to move to %v but got %v\n", s.From, origin)
+	}
+	s.Up = geometry.Vector{X: 2.0}
+	if _, err := s.Matrix(); err == nil {
+		t.Errorf("Expected an error for an up vector parallel to the view direction\n")
+	}
+}
+
+func TestNewRejectsFlatMatrix(t *testing.T) {
+	m, err := (&Step{TypeName: "matrix", Elements: [16]float64{
+		1, 0, 0, 0,
+		0, 1, 0, 0,
+		1, 1, 0, 0,
+		0, 0, 0, 1,
+	}}).Matrix()
+	if err != nil {
+		t.Fatalf("Error creating matrix: %s\n", err)
+	}
+	if _, err := New(m); err == nil {
+		t.Errorf("Expected an error for a matrix which flattens space\n")
+	}
+}
+
+func TestTransformIntersectionShear(t *testing.T) {
+	// a flat rectangle facing +Z, sheared so that z grows with x, then moved back
+	r, err := (&rectangle.Rectangle{
+		A: geometry.Point{X: -1.0, Y: -1.0, Z: 0.0},
+		B: geometry.Point{X: 1.0, Y: 1.0, Z: 0.0},
+	}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up rectangle: %s\n", err)
+	}
+	tr, err := (&Transform{
+		Steps: []Step{
+			{TypeName: "matrix", Elements: [16]float64{
+				1, 0, 0, 0,
+				0, 1, 0, 0,
+				1, 0, 1, 0,
+				0, 0, 0, 1,
+			}},
+			{TypeName: "translate", Vector: geometry.Vector{X: 0.0, Y: 0.0, Z: -5.0}},
+		},
+		Primitive: r,
+	}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up transform: %s\n", err)
+	}
+	ray := geometry.Ray{
+		Origin:    geometry.Point{X: 0.5, Y: 0.0, Z: 5.0},
+		Direction: geometry.Vector{X: 0.0, Y: 0.0, Z: -1.0},
+	}
+	rh, h := tr.Intersection(ray, 1e-7, math.MaxFloat64)
+	if !h {
+		t.Fatalf("Expected true (hit) but got %t\n", h)
+	}
+	if math.Abs(rh.Time-9.5) > 1e-9 {
+		t.Errorf("Expected hit at time 9.5 but got %f\n", rh.Time)
+	}
+	// the sheared plane z = x - 5 has its normal along (-1, 0, 1)
+	expectedNormal := geometry.Vector{X: -1.0, Y: 0.0, Z: 1.0}.Unit()
+	if rh.NormalAtHit.Sub(expectedNormal).Magnitude() > 1e-9 {
+		t.Errorf("Expected normal %v but got %v\n", expectedNormal, rh.NormalAtHit)
+	}
+	if math.Abs(rh.Tangent.Dot(rh.NormalAtHit)) > 1e-9 || math.Abs(rh.Bitangent.Dot(rh.NormalAtHit)) > 1e-9 {
+		t.Errorf("Expected tangents %v and %v to lie in the surface\n", rh.Tangent, rh.Bitangent)
+	}
+}
+
+func TestTransformBoundingBox(t *testing.T) {
+	s, err := (&sphere.Sphere{Radius: 1.0}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up sphere: %s\n", err)
+	}
+	tr, err := (&Transform{
+		Steps: []Step{
+			{TypeName: "scale", Vector: geometry.Vector{X: 3.0, Y: 1.0, Z: 1.0}},
+			{TypeName: "rotate", Axis: geometry.Vector{Z: 1.0}, Angle: 90.0},
+			{TypeName: "translate", Vector: geometry.Vector{X: 10.0, Y: 0.0, Z: 0.0}},
+		},
+		Primitive: s,
+	}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up transform: %s\n", err)
+	}
+	box, ok := tr.BoundingBox(0, 0)
+	if !ok {
+		t.Fatalf("Expected a bounding box\n")
+	}
+	expectedA := geometry.Point{X: 9.0, Y: -3.0, Z: -1.0}
+	expectedB := geometry.Point{X: 11.0, Y: 3.0, Z: 1.0}
+	if box.A.To(expectedA).Magnitude() > 1e-6 || box.B.To(expectedB).Magnitude() > 1e-6 {
+		t.Errorf("Expected box from %v to %v but got %v to %v\n", expectedA, expectedB, box.A, box.B)
+	}
+	if _, ok := tr.SurfaceArea(); ok {
+		t.Errorf("Expected no surface area for a non-uniformly scaled transform\n")
+	}
+}
+
+func TestTransformSurfaceArea(t *testing.T) {
+	s, err := (&sphere.Sphere{Radius: 1.0}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up sphere: %s\n", err)
+	}
+	tr, err := (&Transform{
+		Steps: []Step{
+			{TypeName: "rotate", Axis: geometry.Vector{X: 1.0, Y: 1.0, Z: 0.0}, Angle: 33.0},
+			{TypeName: "scale", Vector: geometry.Vector{X: -2.0, Y: 2.0, Z: 2.0}},
+		},
+		Primitive: s,
+	}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up transform: %s\n", err)
+	}
+	area, ok := tr.SurfaceArea()
+	if !ok || math.Abs(area-16.0*math.Pi) > 1e-9 {
+		t.Errorf("Expected surface area %f but got %f (%t)\n", 16.0*math.Pi, area, ok)
+	}
+}
+
+func TestTransformMotion(t *testing.T) {
+	s, err := (&sphere.Sphere{Radius: 1.0}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up sphere: %s\n", err)
+	}
+	// a stretched sphere lying along the X axis, turning a quarter turn about the Z axis and moving up
+	tr, err := (&Transform{
+		Steps: []Step{
+			{TypeName: "scale", Vector: geometry.Vector{X: 3.0, Y: 1.0, Z: 1.0}},
+		},
+		EndSteps: []Step{
+			{TypeName: "scale", Vector: geometry.Vector{X: 3.0, Y: 1.0, Z: 1.0}},
+			{TypeName: "rotate", Axis: geometry.Vector{Z: 1.0}, Angle: 90.0},
+			{TypeName: "translate", Vector: geometry.Vector{X: 0.0, Y: 2.0, Z: 0.0}},
+		},
+		Primitive: s,
+	}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up transform: %s\n", err)
+	}
+	ray := geometry.Ray{
+		Origin:    geometry.Point{X: 1.5, Y: 2.5, Z: 5.0},
+		Direction: geometry.Vector{X: 0.0, Y: 0.0, Z: -1.0},
+	}
+	// halfway, the sphere lies along the diagonal and is centered on (0, 1, 0)
+	for _, c := range []struct {
+		time float64
+		hit  bool
+	}{
+		{0.0, false},
+		{0.5, true},
+		{1.0, false},
+	} {
+		ray.Time = c.time
+		rh, h := tr.Intersection(ray, 1e-7, math.MaxFloat64)
+		if h != c.hit {
+			t.Fatalf("Expected %t (hit) at time %f but got %t\n", c.hit, c.time, h)
+		}
+		// the ray meets the long axis 3/sqrt(2) from the center, half of its length, which leaves z squared at 1 - 1/2
+		if h && math.Abs(rh.Point.Z-math.Sqrt(0.5)) > 1e-6 {
+			t.Errorf("Expected hit at Z %f but got %v\n", math.Sqrt(0.5), rh.Point)
+		}
+	}
+	box, ok := tr.BoundingBox(0.0, 1.0)
+	if !ok {
+		t.Fatalf("Expected a bounding box\n")
+	}
+	// the tip of the long axis passes through (3 cos 45, 1 + 3 sin 45, 0) halfway
+	tip := geometry.Point{X: 3.0 * math.Sqrt2 / 2.0, Y: 1.0 + 3.0*math.Sqrt2/2.0, Z: 0.0}
+	if tip.X > box.B.X || tip.Y > box.B.Y || box.A.X > -3.0 || box.B.Y < 5.0 {
+		t.Errorf("Expected box from %v to %v to hold the whole motion\n", box.A, box.B)
+	}
+	if _, ok := tr.SurfaceArea(); ok {
+		t.Errorf("Expected no surface area for a non-uniformly scaled transform\n")
+	}
+}
+
+func TestInterpolateInverse(t *testing.T) {
+	start, err := Compose([]Step{
+		{TypeName: "scale", Vector: geometry.Vector{X: 3.0, Y: 1.0, Z: 0.5}},
+		{TypeName: "rotate", Axis: geometry.Vector{X: 1.0, Y: 1.0}, Angle: 30.0},
+		{TypeName: "translate", Vector: geometry.Vector{X: 1.0, Y: -2.0, Z: 4.0}},
+	})
+	if err != nil {
+		t.Fatalf("Error composing transform: %s\n", err)
+	}
+	end, err := Compose([]Step{
+		{TypeName: "scale", Vector: geometry.Vector{X: 1.0, Y: 2.0, Z: 2.0}},
+		{TypeName: "rotate", Axis: geometry.Vector{Z: 1.0}, Angle: 120.0},
+		{TypeName: "translate", Vector: geometry.Vector{X: -3.0, Y: 0.0, Z: 1.0}},
+	})
+	if err != nil {
+		t.Fatalf("Error composing transform: %s\n", err)
+	}
+	startPose, endPose := decompose(start), decompose(end)
+	// the ends of the motion are the transforms the poses came from
+	if m := interpolate(startPose, endPose, 0.0); !m.toWorld.ApproxEqualThreshold(start, 1e-6) {
+		t.Errorf("Expected the start transform %v but got %v\n", start, m.toWorld)
+	}
+	if m := interpolate(startPose, endPose, 1.0); !m.toWorld.ApproxEqualThreshold(end, 1e-6) {
+		t.Errorf("Expected the end transform %v but got %v\n", end, m.toWorld)
+	}
+	// the inverse built from the poses' parts undoes the blended transform all along the motion
+	for _, time := range []float64{0.0, 0.3, 0.5, 0.8, 1.0} {
+		m := interpolate(startPose, endPose, time)
+		if !m.toObject.ApproxEqualThreshold(m.toWorld.Inv(), 1e-9) {
+			t.Errorf("Expected the inverse %v at time %f but got %v\n", m.toWorld.Inv(), time, m.toObject)
+		}
+		if !m.normal.ApproxEqualThreshold(m.linear.Inv().Transpose(), 1e-9) {
+			t.Errorf("Expected the normal transform %v at time %f but got %v\n", m.linear.Inv().Transpose(), time, m.normal)
+		}
+	}
+}
+
+func TestTransformEmptyEndSteps(t *testing.T) {
+	s, err := (&sphere.Sphere{Radius: 1.0}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up sphere: %s\n", err)
+	}
+	// an empty list of end steps is the same as none, rather than a motion back to where the object started
+	tr, err := (&Transform{
+		Steps: []Step{
+			{TypeName: "translate", Vector: geometry.Vector{X: 5.0}},
+		},
+		EndSteps:  []Step{},
+		Primitive: s,
+	}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up transform: %s\n", err)
+	}
+	ray := geometry.Ray{
+		Origin:    geometry.Point{X: 5.0, Z: 5.0},
+		Direction: geometry.Vector{Z: -1.0},
+		Time:      1.0,
+	}
+	if _, h := tr.Intersection(ray, 1e-7, math.MaxFloat64); !h {
+		t.Errorf("Expected true (hit) at the end of the shutter but got %t\n", h)
+	}
+	if box, _ := tr.BoundingBox(0.0, 1.0); box.A.X < 3.9 {
+		t.Errorf("Expected the box to stay around the still sphere but got %v to %v\n", box.A, box.B)
+	}
+}
+
+func TestTransformMotionAllocations(t *testing.T) {
+	s, err := (&sphere.Sphere{Radius: 1.0}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up sphere: %s\n", err)
+	}
+	tr, err := (&Transform{
+		Steps: []Step{
+			{TypeName: "scale", Vector: geometry.Vector{X: 2.0, Y: 1.0, Z: 1.0}},
+		},
+		EndSteps: []Step{
+			{TypeName: "scale", Vector: geometry.Vector{X: 1.0, Y: 2.0, Z: 1.0}},
+			{TypeName: "rotate", Axis: geometry.Vector{Y: 1.0}, Angle: 45.0},
+		},
+		Primitive: s,
+	}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up transform: %s\n", err)
+	}
+	ray := geometry.Ray{
+		Origin:    geometry.Point{Z: 5.0},
+		Direction: geometry.Vector{Z: -1.0},
+		Time:      0.4,
+	}
+	// the blended transform is made on the stack, leaving only the RayHit itself
+	allocations := testing.AllocsPerRun(100, func() {
+		_, transformHit = tr.Intersection(ray, 1e-7, math.MaxFloat64)
+	})
+	if allocations > 1.0 {
+		t.Errorf("Expected at most 1 allocation per hit but got %f\n", allocations)
+	}
+}
+
+func TestTransformMotionMirrorMismatch(t *testing.T) {
+	s, err := (&sphere.Sphere{Radius: 1.0}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up sphere: %s\n", err)
+	}
+	_, err = (&Transform{
+		Steps: []Step{
+			{TypeName: "scale", Vector: geometry.Vector{X: 1.0, Y: 1.0, Z: 1.0}},
+		},
+		EndSteps: []Step{
+			{TypeName: "scale", Vector: geometry.Vector{X: -1.0, Y: 1.0, Z: 1.0}},
+		},
+		Primitive: s,
+	}).Setup()
+	if err == nil {
+		t.Errorf("Expected an error for a transform which mirrors at only one end of its motion\n")
+	}
+}
+
+func BenchmarkTransformIntersectionHit(b *testing.B) {
+	s, err := (&sphere.Sphere{Radius: 1.0}).Setup()
+	if err != nil {
+		b.Fatalf("Error setting up sphere: %s\n", err)
+	}
+	tr, err := (&Transform{
+		Steps: []Step{
+			{TypeName: "scale", Vector: geometry.Vector{X: 3.0, Y: 1.0, Z: 1.0}},
+			{TypeName: "translate", Vector: geometry.Vector{X: 0.0, Y: 0.0, Z: -5.0}},
+		},
+		Primitive: s,
+	}).Setup()
+	if err != nil {
+		b.Fatalf("Error setting up transform: %s\n", err)
+	}
+	ray := geometry.Ray{
+		Origin:    geometry.Point{X: 0.0, Y: 0.0, Z: 5.0},
+		Direction: geometry.Vector{X: 0.0, Y: 0.0, Z: -1.0},
+	}
+	var h bool
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, h = tr.Intersection(ray, 1e-7, math.MaxFloat64)
+	}
+	transformHit = h
+}
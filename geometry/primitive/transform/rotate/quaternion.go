@@ -6,6 +6,7 @@ import (
 	"fluorescence/geometry/primitive/aabb"
 	"fluorescence/shading/material"
 	"fmt"
+	"math"
 	"strings"
 
 	"github.com/go-gl/mathgl/mgl64"
@@ -13,13 +14,15 @@ import (
 
 // Quaternion is a quaternion rotation
 type Quaternion struct {
-	AxisAngles [3]float64  `json:"axis_angles"`
-	Order      string      `json:"order"`
-	TypeName   string      `json:"type"`
-	Data       interface{} `json:"data"`
-	Primitive  primitive.Primitive
-	quaternion mgl64.Quat
-	inverse    mgl64.Quat
+	AxisAngles    [3]float64  `json:"axis_angles"`
+	EndAxisAngles *[3]float64 `json:"end_axis_angles"` // angles at time 1, if the object turns, from AxisAngles at time 0
+	Order         string      `json:"order"`
+	TypeName      string      `json:"type"`
+	Data          interface{} `json:"data"`
+	Primitive     primitive.Primitive
+	quaternion    mgl64.Quat
+	inverse       mgl64.Quat
+	endQuaternion mgl64.Quat
 }
 
 // Setup sets up some internal fields of a rotation
@@ -63,19 +66,28 @@ func (q *Quaternion) Setup() (*Quaternion, error) {
 		rotationOrder,
 	)
 	q.inverse = q.quaternion.Inverse()
+	if q.EndAxisAngles != nil {
+		q.endQuaternion = mgl64.AnglesToQuat(
+			mgl64.DegToRad(q.EndAxisAngles[0]),
+			mgl64.DegToRad(q.EndAxisAngles[1]),
+			mgl64.DegToRad(q.EndAxisAngles[2]),
+			rotationOrder,
+		)
+	}
 	return q, nil
 }
 
 // Intersection computer the intersection of this object and a given ray if it exists
 func (q *Quaternion) Intersection(ray geometry.Ray, tMin, tMax float64) (*material.RayHit, bool) {
 
+	quaternion, inverse := q.quaternionAt(ray.Time)
 	rotatedRay := ray
 
 	originMGL := mgl64.Vec3{rotatedRay.Origin.X, rotatedRay.Origin.Y, rotatedRay.Origin.Z}
 	directionMGL := mgl64.Vec3{rotatedRay.Direction.X, rotatedRay.Direction.Y, rotatedRay.Direction.Z}
 
-	rotatedOriginMGL := q.inverse.Rotate(originMGL)
-	rotatedDirectionMGL := q.inverse.Rotate(directionMGL)
+	rotatedOriginMGL := inverse.Rotate(originMGL)
+	rotatedDirectionMGL := inverse.Rotate(directionMGL)
 
 	rotatedRay.Origin = geometry.Point{
 		X: rotatedOriginMGL.X(),
@@ -91,25 +103,52 @@ func (q *Quaternion) Intersection(ray geometry.Ray, tMin, tMax float64) (*materi
 
 	rayHit, wasHit := q.Primitive.Intersection(rotatedRay, tMin, tMax)
 	if wasHit {
-		rotatedNormalMGL := mgl64.Vec3{rayHit.NormalAtHit.X, rayHit.NormalAtHit.Y, rayHit.NormalAtHit.Z}
-		unrotatedNormalMGL := q.quaternion.Rotate(rotatedNormalMGL)
-		unrotatedNormal := geometry.Vector{
-			X: unrotatedNormalMGL.X(),
-			Y: unrotatedNormalMGL.Y(),
-			Z: unrotatedNormalMGL.Z(),
-		}
+		objectTangent, objectBitangent := rayHit.ObjectFrame()
 		return &material.RayHit{
-			Ray:         ray,
-			NormalAtHit: unrotatedNormal,
-			Time:        rayHit.Time,
-			U:           rayHit.U,
-			V:           rayHit.V,
-			Material:    rayHit.Material,
+			Ray:             ray,
+			Point:           q.unrotatePoint(rayHit.Point, quaternion),
+			ObjectPoint:     rayHit.ObjectPoint,
+			NormalAtHit:     q.unrotate(rayHit.NormalAtHit, quaternion),
+			GeometricNormal: q.unrotate(rayHit.GeometricNormal, quaternion),
+			Time:            rayHit.Time,
+			U:               rayHit.U,
+			V:               rayHit.V,
+			Tangent:         q.unrotate(rayHit.Tangent, quaternion),
+			Bitangent:       q.unrotate(rayHit.Bitangent, quaternion),
+			ObjectTangent:   objectTangent,
+			ObjectBitangent: objectBitangent,
+			Material:        rayHit.Material,
 		}, true
 	}
 	return nil, false
 }
 
+// unrotate rotates a direction from the wrapped object's space back into world space
+func (q *Quaternion) unrotate(v geometry.Vector, quaternion mgl64.Quat) geometry.Vector {
+	unrotatedMGL := quaternion.Rotate(mgl64.Vec3{v.X, v.Y, v.Z})
+	return geometry.Vector{
+		X: unrotatedMGL.X(),
+		Y: unrotatedMGL.Y(),
+		Z: unrotatedMGL.Z(),
+	}
+}
+
+// unrotatePoint rotates a point from the wrapped object's space back into world space
+func (q *Quaternion) unrotatePoint(p geometry.Point, quaternion mgl64.Quat) geometry.Point {
+	return geometry.PointZero.AddVector(q.unrotate(geometry.PointZero.To(p), quaternion))
+}
+
+// quaternionAt returns the rotation at a time, and its inverse
+// a turning rotation follows the shortest path between its start and end
+func (q *Quaternion) quaternionAt(time float64) (mgl64.Quat, mgl64.Quat) {
+	if q.EndAxisAngles == nil {
+		return q.quaternion, q.inverse
+	}
+	time = math.Max(0.0, math.Min(time, 1.0))
+	quaternion := mgl64.QuatSlerp(q.quaternion, q.endQuaternion, time)
+	return quaternion, quaternion.Inverse()
+}
+
 // BoundingBox returns an AABB for this object
 func (q *Quaternion) BoundingBox(t0, t1 float64) (*aabb.AABB, bool) {
 
@@ -117,6 +156,12 @@ func (q *Quaternion) BoundingBox(t0, t1 float64) (*aabb.AABB, bool) {
 	if !ok {
 		return nil, false
 	}
+	if q.EndAxisAngles != nil {
+		return aabb.Swept(box, t0, t1, func(p geometry.Point, time float64) geometry.Point {
+			quaternion, _ := q.quaternionAt(time)
+			return q.unrotatePoint(p, quaternion)
+		}), true
+	}
 	minPoint := geometry.PointMax
 	maxPoint := geometry.PointMax.Negate()
 	for i := 0.0; i < 2; i++ {
@@ -161,6 +206,16 @@ func (q *Quaternion) IsClosed() bool {
 	return q.Primitive.IsClosed()
 }
 
+// SurfaceArea returns the surface area of this object
+// a rigid transformation does not change the area of the underlying Primitive
+func (q *Quaternion) SurfaceArea() (float64, bool) {
+	surface, ok := q.Primitive.(primitive.Surface)
+	if !ok {
+		return 0.0, false
+	}
+	return surface.SurfaceArea()
+}
+
 // Copy returns a shallow copy of this object
 func (q *Quaternion) Copy() primitive.Primitive {
 	newRX := *q
@@ -10,13 +10,14 @@ import (
 
 // RotationX is a primitive with a rotations around the y axis attached
 type RotationX struct {
-	AngleDegrees float64     `json:"angle"`
-	TypeName     string      `json:"type"`
-	Data         interface{} `json:"data"`
-	Primitive    primitive.Primitive
-	theta        float64
-	sinTheta     float64
-	cosTheta     float64
+	AngleDegrees    float64     `json:"angle"`
+	EndAngleDegrees *float64    `json:"end_angle"` // angle at time 1, if the object turns, from AngleDegrees at time 0
+	TypeName        string      `json:"type"`
+	Data            interface{} `json:"data"`
+	Primitive       primitive.Primitive
+	theta           float64
+	sinTheta        float64
+	cosTheta        float64
 }
 
 // Setup sets up some internal fields of a rotation
@@ -33,31 +34,60 @@ func (rx *RotationX) Setup() (*RotationX, error) {
 // Intersection computer the intersection of this object and a given ray if it exists
 func (rx *RotationX) Intersection(ray geometry.Ray, tMin, tMax float64) (*material.RayHit, bool) {
 
+	sinTheta, cosTheta := rx.sinCosAt(ray.Time)
 	rotatedRay := ray
 
-	rotatedRay.Origin.Y = rx.cosTheta*ray.Origin.Y + rx.sinTheta*ray.Origin.Z
-	rotatedRay.Origin.Z = -rx.sinTheta*ray.Origin.Y + rx.cosTheta*ray.Origin.Z
+	rotatedRay.Origin.Y = cosTheta*ray.Origin.Y + sinTheta*ray.Origin.Z
+	rotatedRay.Origin.Z = -sinTheta*ray.Origin.Y + cosTheta*ray.Origin.Z
 
-	rotatedRay.Direction.Y = rx.cosTheta*ray.Direction.Y + rx.sinTheta*ray.Direction.Z
-	rotatedRay.Direction.Z = -rx.sinTheta*ray.Direction.Y + rx.cosTheta*ray.Direction.Z
+	rotatedRay.Direction.Y = cosTheta*ray.Direction.Y + sinTheta*ray.Direction.Z
+	rotatedRay.Direction.Z = -sinTheta*ray.Direction.Y + cosTheta*ray.Direction.Z
 
 	rayHit, wasHit := rx.Primitive.Intersection(rotatedRay, tMin, tMax)
 	if wasHit {
-		unrotatedNormal := rayHit.NormalAtHit
-		unrotatedNormal.Y = rx.cosTheta*rayHit.NormalAtHit.Y - rx.sinTheta*rayHit.NormalAtHit.Z
-		unrotatedNormal.Z = rx.sinTheta*rayHit.NormalAtHit.Y + rx.cosTheta*rayHit.NormalAtHit.Z
+		objectTangent, objectBitangent := rayHit.ObjectFrame()
 		return &material.RayHit{
-			Ray:         ray,
-			NormalAtHit: unrotatedNormal,
-			Time:        rayHit.Time,
-			U:           rayHit.U,
-			V:           rayHit.V,
-			Material:    rayHit.Material,
+			Ray:             ray,
+			Point:           rx.unrotatePoint(rayHit.Point, sinTheta, cosTheta),
+			ObjectPoint:     rayHit.ObjectPoint,
+			NormalAtHit:     rx.unrotate(rayHit.NormalAtHit, sinTheta, cosTheta),
+			GeometricNormal: rx.unrotate(rayHit.GeometricNormal, sinTheta, cosTheta),
+			Time:            rayHit.Time,
+			U:               rayHit.U,
+			V:               rayHit.V,
+			Tangent:         rx.unrotate(rayHit.Tangent, sinTheta, cosTheta),
+			Bitangent:       rx.unrotate(rayHit.Bitangent, sinTheta, cosTheta),
+			ObjectTangent:   objectTangent,
+			ObjectBitangent: objectBitangent,
+			Material:        rayHit.Material,
 		}, true
 	}
 	return nil, false
 }
 
+// unrotate rotates a direction from the wrapped object's space back into world space
+func (rx *RotationX) unrotate(v geometry.Vector, sinTheta, cosTheta float64) geometry.Vector {
+	unrotated := v
+	unrotated.Y = cosTheta*v.Y - sinTheta*v.Z
+	unrotated.Z = sinTheta*v.Y + cosTheta*v.Z
+	return unrotated
+}
+
+// unrotatePoint rotates a point from the wrapped object's space back into world space
+func (rx *RotationX) unrotatePoint(p geometry.Point, sinTheta, cosTheta float64) geometry.Point {
+	return geometry.PointZero.AddVector(rx.unrotate(geometry.PointZero.To(p), sinTheta, cosTheta))
+}
+
+// sinCosAt returns the sine and cosine of the rotation's angle at a time
+func (rx *RotationX) sinCosAt(time float64) (float64, float64) {
+	if rx.EndAngleDegrees == nil {
+		return rx.sinTheta, rx.cosTheta
+	}
+	time = math.Max(0.0, math.Min(time, 1.0))
+	theta := (math.Pi / 180.0) * (rx.AngleDegrees*(1.0-time) + *rx.EndAngleDegrees*time)
+	return math.Sin(theta), math.Cos(theta)
+}
+
 // BoundingBox returns an AABB for this object
 func (rx *RotationX) BoundingBox(t0, t1 float64) (*aabb.AABB, bool) {
 
@@ -65,6 +95,12 @@ func (rx *RotationX) BoundingBox(t0, t1 float64) (*aabb.AABB, bool) {
 	if !ok {
 		return nil, false
 	}
+	if rx.EndAngleDegrees != nil {
+		return aabb.Swept(box, t0, t1, func(p geometry.Point, time float64) geometry.Point {
+			sinTheta, cosTheta := rx.sinCosAt(time)
+			return rx.unrotatePoint(p, sinTheta, cosTheta)
+		}), true
+	}
 	minPoint := geometry.PointMax
 	maxPoint := geometry.PointMax.Negate()
 	for i := 0.0; i < 2; i++ {
@@ -109,6 +145,16 @@ func (rx *RotationX) IsClosed() bool {
 	return rx.Primitive.IsClosed()
 }
 
+// SurfaceArea returns the surface area of this object
+// a rigid transformation does not change the area of the underlying Primitive
+func (rx *RotationX) SurfaceArea() (float64, bool) {
+	surface, ok := rx.Primitive.(primitive.Surface)
+	if !ok {
+		return 0.0, false
+	}
+	return surface.SurfaceArea()
+}
+
 // Copy returns a shallow copy of this object
 func (rx *RotationX) Copy() primitive.Primitive {
 	newRX := *rx
@@ -10,13 +10,14 @@ import (
 
 // RotationY is a primitive with a rotations around the y axis attached
 type RotationY struct {
-	AngleDegrees float64     `json:"angle"`
-	TypeName     string      `json:"type"`
-	Data         interface{} `json:"data"`
-	Primitive    primitive.Primitive
-	theta        float64
-	sinTheta     float64
-	cosTheta     float64
+	AngleDegrees    float64     `json:"angle"`
+	EndAngleDegrees *float64    `json:"end_angle"` // angle at time 1, if the object turns, from AngleDegrees at time 0
+	TypeName        string      `json:"type"`
+	Data            interface{} `json:"data"`
+	Primitive       primitive.Primitive
+	theta           float64
+	sinTheta        float64
+	cosTheta        float64
 }
 
 // Setup sets up some internal fields of a rotation
@@ -33,31 +34,60 @@ func (ry *RotationY) Setup() (*RotationY, error) {
 // Intersection computer the intersection of this object and a given ray if it exists
 func (ry *RotationY) Intersection(ray geometry.Ray, tMin, tMax float64) (*material.RayHit, bool) {
 
+	sinTheta, cosTheta := ry.sinCosAt(ray.Time)
 	rotatedRay := ray
 
-	rotatedRay.Origin.X = ry.cosTheta*ray.Origin.X - ry.sinTheta*ray.Origin.Z
-	rotatedRay.Origin.Z = ry.sinTheta*ray.Origin.X + ry.cosTheta*ray.Origin.Z
+	rotatedRay.Origin.X = cosTheta*ray.Origin.X - sinTheta*ray.Origin.Z
+	rotatedRay.Origin.Z = sinTheta*ray.Origin.X + cosTheta*ray.Origin.Z
 
-	rotatedRay.Direction.X = ry.cosTheta*ray.Direction.X - ry.sinTheta*ray.Direction.Z
-	rotatedRay.Direction.Z = ry.sinTheta*ray.Direction.X + ry.cosTheta*ray.Direction.Z
+	rotatedRay.Direction.X = cosTheta*ray.Direction.X - sinTheta*ray.Direction.Z
+	rotatedRay.Direction.Z = sinTheta*ray.Direction.X + cosTheta*ray.Direction.Z
 
 	rayHit, wasHit := ry.Primitive.Intersection(rotatedRay, tMin, tMax)
 	if wasHit {
-		unrotatedNormal := rayHit.NormalAtHit
-		unrotatedNormal.X = ry.cosTheta*rayHit.NormalAtHit.X + ry.sinTheta*rayHit.NormalAtHit.Z
-		unrotatedNormal.Z = -ry.sinTheta*rayHit.NormalAtHit.X + ry.cosTheta*rayHit.NormalAtHit.Z
+		objectTangent, objectBitangent := rayHit.ObjectFrame()
 		return &material.RayHit{
-			Ray:         ray,
-			NormalAtHit: unrotatedNormal,
-			Time:        rayHit.Time,
-			U:           rayHit.U,
-			V:           rayHit.V,
-			Material:    rayHit.Material,
+			Ray:             ray,
+			Point:           ry.unrotatePoint(rayHit.Point, sinTheta, cosTheta),
+			ObjectPoint:     rayHit.ObjectPoint,
+			NormalAtHit:     ry.unrotate(rayHit.NormalAtHit, sinTheta, cosTheta),
+			GeometricNormal: ry.unrotate(rayHit.GeometricNormal, sinTheta, cosTheta),
+			Time:            rayHit.Time,
+			U:               rayHit.U,
+			V:               rayHit.V,
+			Tangent:         ry.unrotate(rayHit.Tangent, sinTheta, cosTheta),
+			Bitangent:       ry.unrotate(rayHit.Bitangent, sinTheta, cosTheta),
+			ObjectTangent:   objectTangent,
+			ObjectBitangent: objectBitangent,
+			Material:        rayHit.Material,
 		}, true
 	}
 	return nil, false
 }
 
+// unrotate rotates a direction from the wrapped object's space back into world space
+func (ry *RotationY) unrotate(v geometry.Vector, sinTheta, cosTheta float64) geometry.Vector {
+	unrotated := v
+	unrotated.X = cosTheta*v.X + sinTheta*v.Z
+	unrotated.Z = -sinTheta*v.X + cosTheta*v.Z
+	return unrotated
+}
+
+// unrotatePoint rotates a point from the wrapped object's space back into world space
+func (ry *RotationY) unrotatePoint(p geometry.Point, sinTheta, cosTheta float64) geometry.Point {
+	return geometry.PointZero.AddVector(ry.unrotate(geometry.PointZero.To(p), sinTheta, cosTheta))
+}
+
+// sinCosAt returns the sine and cosine of the rotation's angle at a time
+func (ry *RotationY) sinCosAt(time float64) (float64, float64) {
+	if ry.EndAngleDegrees == nil {
+		return ry.sinTheta, ry.cosTheta
+	}
+	time = math.Max(0.0, math.Min(time, 1.0))
+	theta := (math.Pi / 180.0) * (ry.AngleDegrees*(1.0-time) + *ry.EndAngleDegrees*time)
+	return math.Sin(theta), math.Cos(theta)
+}
+
 // BoundingBox returns an AABB for this object
 func (ry *RotationY) BoundingBox(t0, t1 float64) (*aabb.AABB, bool) {
 
@@ -65,6 +95,12 @@ func (ry *RotationY) BoundingBox(t0, t1 float64) (*aabb.AABB, bool) {
 	if !ok {
 		return nil, false
 	}
+	if ry.EndAngleDegrees != nil {
+		return aabb.Swept(box, t0, t1, func(p geometry.Point, time float64) geometry.Point {
+			sinTheta, cosTheta := ry.sinCosAt(time)
+			return ry.unrotatePoint(p, sinTheta, cosTheta)
+		}), true
+	}
 	minPoint := geometry.PointMax
 	maxPoint := geometry.PointMax.Negate()
 	for i := 0.0; i < 2; i++ {
@@ -109,6 +145,16 @@ func (ry *RotationY) IsClosed() bool {
 	return ry.Primitive.IsClosed()
 }
 
+// SurfaceArea returns the surface area of this object
+// a rigid transformation does not change the area of the underlying Primitive
+func (ry *RotationY) SurfaceArea() (float64, bool) {
+	surface, ok := ry.Primitive.(primitive.Surface)
+	if !ok {
+		return 0.0, false
+	}
+	return surface.SurfaceArea()
+}
+
 // Copy returns a shallow copy of this object
 func (ry *RotationY) Copy() primitive.Primitive {
 	newRY := *ry
@@ -10,13 +10,14 @@ import (
 
 // RotationZ is a primitive with a rotations around the y axis attached
 type RotationZ struct {
-	AngleDegrees float64     `json:"angle"`
-	TypeName     string      `json:"type"`
-	Data         interface{} `json:"data"`
-	Primitive    primitive.Primitive
-	theta        float64
-	sinTheta     float64
-	cosTheta     float64
+	AngleDegrees    float64     `json:"angle"`
+	EndAngleDegrees *float64    `json:"end_angle"` // angle at time 1, if the object turns, from AngleDegrees at time 0
+	TypeName        string      `json:"type"`
+	Data            interface{} `json:"data"`
+	Primitive       primitive.Primitive
+	theta           float64
+	sinTheta        float64
+	cosTheta        float64
 }
 
 // Setup sets up some internal fields of a rotation
@@ -33,31 +34,60 @@ func (rz *RotationZ) Setup() (*RotationZ, error) {
 // Intersection computer the intersection of this object and a given ray if it exists
 func (rz *RotationZ) Intersection(ray geometry.Ray, tMin, tMax float64) (*material.RayHit, bool) {
 
+	sinTheta, cosTheta := rz.sinCosAt(ray.Time)
 	rotatedRay := ray
 
-	rotatedRay.Origin.X = rz.cosTheta*ray.Origin.X + rz.sinTheta*ray.Origin.Y
-	rotatedRay.Origin.Y = -rz.sinTheta*ray.Origin.X + rz.cosTheta*ray.Origin.Y
+	rotatedRay.Origin.X = cosTheta*ray.Origin.X + sinTheta*ray.Origin.Y
+	rotatedRay.Origin.Y = -sinTheta*ray.Origin.X + cosTheta*ray.Origin.Y
 
-	rotatedRay.Direction.X = rz.cosTheta*ray.Direction.X + rz.sinTheta*ray.Direction.Y
-	rotatedRay.Direction.Y = -rz.sinTheta*ray.Direction.X + rz.cosTheta*ray.Direction.Y
+	rotatedRay.Direction.X = cosTheta*ray.Direction.X + sinTheta*ray.Direction.Y
+	rotatedRay.Direction.Y = -sinTheta*ray.Direction.X + cosTheta*ray.Direction.Y
 
 	rayHit, wasHit := rz.Primitive.Intersection(rotatedRay, tMin, tMax)
 	if wasHit {
-		unrotatedNormal := rayHit.NormalAtHit
-		unrotatedNormal.X = rz.cosTheta*rayHit.NormalAtHit.X - rz.sinTheta*rayHit.NormalAtHit.Y
-		unrotatedNormal.Y = rz.sinTheta*rayHit.NormalAtHit.X + rz.cosTheta*rayHit.NormalAtHit.Y
+		objectTangent, objectBitangent := rayHit.ObjectFrame()
 		return &material.RayHit{
-			Ray:         ray,
-			NormalAtHit: unrotatedNormal,
-			Time:        rayHit.Time,
-			U:           rayHit.U,
-			V:           rayHit.V,
-			Material:    rayHit.Material,
+			Ray:             ray,
+			Point:           rz.unrotatePoint(rayHit.Point, sinTheta, cosTheta),
+			ObjectPoint:     rayHit.ObjectPoint,
+			NormalAtHit:     rz.unrotate(rayHit.NormalAtHit, sinTheta, cosTheta),
+			GeometricNormal: rz.unrotate(rayHit.GeometricNormal, sinTheta, cosTheta),
+			Time:            rayHit.Time,
+			U:               rayHit.U,
+			V:               rayHit.V,
+			Tangent:         rz.unrotate(rayHit.Tangent, sinTheta, cosTheta),
+			Bitangent:       rz.unrotate(rayHit.Bitangent, sinTheta, cosTheta),
+			ObjectTangent:   objectTangent,
+			ObjectBitangent: objectBitangent,
+			Material:        rayHit.Material,
 		}, true
 	}
 	return nil, false
 }
 
+// unrotate rotates a direction from the wrapped object's space back into world space
+func (rz *RotationZ) unrotate(v geometry.Vector, sinTheta, cosTheta float64) geometry.Vector {
+	unrotated := v
+	unrotated.X = cosTheta*v.X - sinTheta*v.Y
+	unrotated.Y = sinTheta*v.X + cosTheta*v.Y
+	return unrotated
+}
+
+// unrotatePoint rotates a point from the wrapped object's space back into world space
+func (rz *RotationZ) unrotatePoint(p geometry.Point, sinTheta, cosTheta float64) geometry.Point {
+	return geometry.PointZero.AddVector(rz.unrotate(geometry.PointZero.To(p), sinTheta, cosTheta))
+}
+
+// sinCosAt returns the sine and cosine of the rotation's angle at a time
+func (rz *RotationZ) sinCosAt(time float64) (float64, float64) {
+	if rz.EndAngleDegrees == nil {
+		return rz.sinTheta, rz.cosTheta
+	}
+	time = math.Max(0.0, math.Min(time, 1.0))
+	theta := (math.Pi / 180.0) * (rz.AngleDegrees*(1.0-time) + *rz.EndAngleDegrees*time)
+	return math.Sin(theta), math.Cos(theta)
+}
+
 // BoundingBox returns an AABB for this object
 func (rz *RotationZ) BoundingBox(t0, t1 float64) (*aabb.AABB, bool) {
 
@@ -65,6 +95,12 @@ func (rz *RotationZ) BoundingBox(t0, t1 float64) (*aabb.AABB, bool) {
 	if !ok {
 		return nil, false
 	}
+	if rz.EndAngleDegrees != nil {
+		return aabb.Swept(box, t0, t1, func(p geometry.Point, time float64) geometry.Point {
+			sinTheta, cosTheta := rz.sinCosAt(time)
+			return rz.unrotatePoint(p, sinTheta, cosTheta)
+		}), true
+	}
 	minPoint := geometry.PointMax
 	maxPoint := geometry.PointMax.Negate()
 	for i := 0.0; i < 2; i++ {
@@ -109,6 +145,16 @@ func (rz *RotationZ) IsClosed() bool {
 	return rz.Primitive.IsClosed()
 }
 
+// SurfaceArea returns the surface area of this object
+// a rigid transformation does not change the area of the underlying Primitive
+func (rz *RotationZ) SurfaceArea() (float64, bool) {
+	surface, ok := rz.Primitive.(primitive.Surface)
+	if !ok {
+		return 0.0, false
+	}
+	return surface.SurfaceArea()
+}
+
 // Copy returns a shallow copy of this object
 func (rz *RotationZ) Copy() primitive.Primitive {
 	newRZ := *rz
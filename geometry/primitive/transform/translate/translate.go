@@ -5,14 +5,16 @@ import (
 	"fluorescence/geometry/primitive"
 	"fluorescence/geometry/primitive/aabb"
 	"fluorescence/shading/material"
+	"math"
 )
 
 // Translation is a primitive with a translation attached
 type Translation struct {
-	Displacement geometry.Vector `json:"displacement"`
-	TypeName     string          `json:"type"`
-	Data         interface{}     `json:"data"`
-	Primitive    primitive.Primitive
+	Displacement    geometry.Vector  `json:"displacement"`
+	EndDisplacement *geometry.Vector `json:"end_displacement"` // displacement at time 1, if the object moves, from Displacement at time 0
+	TypeName        string           `json:"type"`
+	Data            interface{}      `json:"data"`
+	Primitive       primitive.Primitive
 }
 
 // Setup sets up a Translation's internal fields
@@ -24,11 +26,14 @@ func (t *Translation) Setup() (*Translation, error) {
 func (t *Translation) Intersection(ray geometry.Ray, tMin, tMax float64) (*material.RayHit, bool) {
 
 	// translate the ray to the object
-	ray.Origin = ray.Origin.SubVector(t.Displacement)
+	displacement := t.displacementAt(ray.Time)
+	objectRay := ray
+	objectRay.Origin = ray.Origin.SubVector(displacement)
 
-	rh, ok := t.Primitive.Intersection(ray, tMin, tMax)
+	rh, ok := t.Primitive.Intersection(objectRay, tMin, tMax)
 	if ok {
-		rh.Ray.Origin = rh.Ray.Origin.AddVector(t.Displacement)
+		rh.Ray = ray
+		rh.Point = rh.Point.AddVector(displacement)
 	}
 	return rh, ok
 }
@@ -36,13 +41,31 @@ func (t *Translation) Intersection(ray geometry.Ray, tMin, tMax float64) (*mater
 // BoundingBox returns an AABB for this object
 func (t *Translation) BoundingBox(t0, t1 float64) (*aabb.AABB, bool) {
 	box, ok := t.Primitive.BoundingBox(t0, t1)
-	if ok {
-		box = &aabb.AABB{
-			A: box.A.AddVector(t.Displacement),
-			B: box.B.AddVector(t.Displacement),
-		}
+	if !ok {
+		return nil, false
+	}
+	// a straight line motion is bounded by the boxes at either end of it
+	start := t.displacementAt(t0)
+	end := t.displacementAt(t1)
+	return aabb.SurroundingBox(
+		&aabb.AABB{
+			A: box.A.AddVector(start),
+			B: box.B.AddVector(start),
+		},
+		&aabb.AABB{
+			A: box.A.AddVector(end),
+			B: box.B.AddVector(end),
+		},
+	), true
+}
+
+// displacementAt returns the displacement at a time
+func (t *Translation) displacementAt(time float64) geometry.Vector {
+	if t.EndDisplacement == nil {
+		return t.Displacement
 	}
-	return box, ok
+	time = math.Max(0.0, math.Min(time, 1.0))
+	return t.Displacement.MultScalar(1.0 - time).Add(t.EndDisplacement.MultScalar(time))
 }
 
 // SetMaterial sets the material of this object
@@ -60,6 +83,16 @@ func (t *Translation) IsClosed() bool {
 	return t.Primitive.IsClosed()
 }
 
+// SurfaceArea returns the surface area of this object
+// a rigid transformation does not change the area of the underlying Primitive
+func (t *Translation) SurfaceArea() (float64, bool) {
+	surface, ok := t.Primitive.(primitive.Surface)
+	if !ok {
+		return 0.0, false
+	}
+	return surface.SurfaceArea()
+}
+
 // Copy returns a shallow copy of this object
 func (t *Translation) Copy() primitive.Primitive {
 	newT := *t
@@ -4,6 +4,7 @@ import (
 	"fluorescence/geometry"
 	"fluorescence/geometry/primitive"
 	"fluorescence/geometry/primitive/aabb"
+	"fluorescence/shading"
 	"fluorescence/shading/material"
 	"fmt"
 	"math"
@@ -11,12 +12,26 @@ import (
 
 // Triangle is an internal representation of a Triangle geometry contruct
 type Triangle struct {
-	A        geometry.Point  `json:"a"`
-	B        geometry.Point  `json:"b"`
-	C        geometry.Point  `json:"c"`
-	normal   geometry.Vector // normal of the Triangle's surface
-	IsCulled bool            `json:"is_culled"` // whether or not the Triangle is culled, or single-sided
-	mat      material.Material
+	A         geometry.Point  `json:"a"`
+	B         geometry.Point  `json:"b"`
+	C         geometry.Point  `json:"c"`
+	UVA       [2]float64      `json:"uv_a"`       // texture coordinates at A
+	UVB       [2]float64      `json:"uv_b"`       // texture coordinates at B
+	UVC       [2]float64      `json:"uv_c"`       // texture coordinates at C
+	HasUVs    bool            `json:"has_uvs"`    // whether the corners' texture coordinates are set, rather than taken from the barycentric coordinates
+	NormalA   geometry.Vector `json:"normal_a"`   // shading normal at A, which with those at B and C is blended across the Triangle
+	NormalB   geometry.Vector `json:"normal_b"`   // shading normal at B
+	NormalC   geometry.Vector `json:"normal_c"`   // shading normal at C
+	ColorA    shading.Color   `json:"color_a"`    // color at A, which with those at B and C tints the material's reflectance
+	ColorB    shading.Color   `json:"color_b"`    // color at B
+	ColorC    shading.Color   `json:"color_c"`    // color at C
+	HasColors bool            `json:"has_colors"` // whether the corners' colors are set
+	normal    geometry.Vector // normal of the Triangle's surface
+	isSmooth  bool            // whether the shading normal is blended from the corners' normals, rather than the surface normal
+	tangent   geometry.Vector // rate of change of the surface point with texture coordinate U
+	bitangent geometry.Vector // rate of change of the surface point with texture coordinate V
+	IsCulled  bool            `json:"is_culled"` // whether or not the Triangle is culled, or single-sided
+	mat       material.Material
 }
 
 // Data holds information needed to contruct a Triangle
@@ -29,13 +44,52 @@ type Triangle struct {
 
 // Setup fills calculated fields in an Triangle
 func (t *Triangle) Setup() (*Triangle, error) {
-	if t.A == t.B || t.A == t.C || t.B == t.C {
+	if IsDegenerate(t.A, t.B, t.C) {
 		return nil, fmt.Errorf("Triangle resolves to line or point")
 	}
-	t.normal = t.A.To(t.B).Cross(t.A.To(t.C)).Unit()
+	ab := t.A.To(t.B)
+	ac := t.A.To(t.C)
+	// without texture coordinates, the barycentric coordinates of B and C are used
+	if !t.HasUVs {
+		t.UVA = [2]float64{0.0, 0.0}
+		t.UVB = [2]float64{1.0, 0.0}
+		t.UVC = [2]float64{0.0, 1.0}
+	}
+	t.normal = ab.Cross(ac).Unit()
+	// corner normals are only used if every corner has one
+	t.isSmooth = t.NormalA != geometry.VectorZero && t.NormalB != geometry.VectorZero && t.NormalC != geometry.VectorZero
+	if t.isSmooth {
+		t.NormalA = t.NormalA.Unit()
+		t.NormalB = t.NormalB.Unit()
+		t.NormalC = t.NormalC.Unit()
+	}
+
+	// solve for the directions along the surface in which each texture coordinate increases
+	du1, dv1 := t.UVB[0]-t.UVA[0], t.UVB[1]-t.UVA[1]
+	du2, dv2 := t.UVC[0]-t.UVA[0], t.UVC[1]-t.UVA[1]
+	determinant := du1*dv2 - du2*dv1
+	if math.Abs(determinant) < 1e-12 {
+		// texture coordinates are degenerate, so pick any frame on the surface
+		t.tangent = ab.Unit()
+		t.bitangent = t.normal.Cross(t.tangent)
+		return t, nil
+	}
+	t.tangent = ab.MultScalar(dv2).Sub(ac.MultScalar(dv1)).DivScalar(determinant)
+	t.bitangent = ac.MultScalar(du1).Sub(ab.MultScalar(du2)).DivScalar(determinant)
 	return t, nil
 }
 
+// IsDegenerate returns whether a triangle with the given corners collapses to a line or point
+// corners in a line leave a triangle with no area, and so no normal
+func IsDegenerate(a, b, c geometry.Point) bool {
+	if a == b || a == c || b == c {
+		return true
+	}
+	ab := a.To(b)
+	ac := a.To(c)
+	return ab.Cross(ac).Magnitude() <= 1e-12*ab.Magnitude()*ac.Magnitude()
+}
+
 // Intersection computer the intersection of this object and a given ray if it exists
 func (t *Triangle) Intersection(ray geometry.Ray, tMin, tMax float64) (*material.RayHit, bool) {
 	ab := t.A.To(t.B)
@@ -66,15 +120,33 @@ func (t *Triangle) Intersection(ray geometry.Ray, tMin, tMax float64) (*material
 	// At this stage we can compute time to find out where the intersection point is on the line.
 	time := inverseDeterminant * (ac.Dot(qVector))
 	if time >= tMin && time <= tMax {
-		// ray intersection
-		return &material.RayHit{
-			Ray:         ray,
-			NormalAtHit: t.normal,
-			Time:        time,
-			U:           0,
-			V:           0,
-			Material:    t.mat,
-		}, true
+		// the hit point is placed by its barycentric coordinates, so it lies exactly on the triangle's plane
+		hitPoint := t.A.AddVector(ab.MultScalar(u)).AddVector(ac.MultScalar(v))
+		rayHit := &material.RayHit{
+			Ray:             ray,
+			Point:           hitPoint,
+			ObjectPoint:     hitPoint,
+			NormalAtHit:     t.normal,
+			GeometricNormal: t.normal,
+			Time:            time,
+			U:               (1.0-u-v)*t.UVA[0] + u*t.UVB[0] + v*t.UVC[0],
+			V:               (1.0-u-v)*t.UVA[1] + u*t.UVB[1] + v*t.UVC[1],
+			Tangent:         t.tangent,
+			Bitangent:       t.bitangent,
+			Material:        t.mat,
+		}
+		// the barycentric coordinates which placed the hit also blend the corners' normals
+		if t.isSmooth {
+			smoothed := t.NormalA.MultScalar(1.0 - u - v).Add(t.NormalB.MultScalar(u)).Add(t.NormalC.MultScalar(v))
+			if smoothed.Magnitude() > 0.0 {
+				rayHit.NormalAtHit = smoothed.Unit()
+			}
+		}
+		if t.HasColors {
+			rayHit.Color = t.ColorA.MultScalar(1.0 - u - v).Add(t.ColorB.MultScalar(u)).Add(t.ColorC.MultScalar(v))
+			rayHit.HasColor = true
+		}
+		return rayHit, true
 	}
 	return nil, false
 }
@@ -83,9 +155,9 @@ func (t *Triangle) Intersection(ray geometry.Ray, tMin, tMax float64) (*material
 func (t *Triangle) BoundingBox(t0, t1 float64) (*aabb.AABB, bool) {
 	return &aabb.AABB{
 		A: geometry.Point{
-			X: math.Min(math.Min(t.A.X, t.A.X), t.C.X) - 1e-7,
-			Y: math.Min(math.Min(t.A.Y, t.A.Y), t.C.Y) - 1e-7,
-			Z: math.Min(math.Min(t.A.Z, t.A.Z), t.C.Z) - 1e-7,
+			X: math.Min(math.Min(t.A.X, t.B.X), t.C.X) - 1e-7,
+			Y: math.Min(math.Min(t.A.Y, t.B.Y), t.C.Y) - 1e-7,
+			Z: math.Min(math.Min(t.A.Z, t.B.Z), t.C.Z) - 1e-7,
 		},
 		B: geometry.Point{
 			X: math.Max(math.Max(t.A.X, t.B.X), t.C.X) + 1e-7,
@@ -110,6 +182,11 @@ func (t *Triangle) IsClosed() bool {
 	return false
 }
 
+// SurfaceArea returns the surface area of this object
+func (t *Triangle) SurfaceArea() (float64, bool) {
+	return t.A.To(t.B).Cross(t.A.To(t.C)).Magnitude() / 2.0, true
+}
+
 // Copy returns a shallow copy of this object
 func (t *Triangle) Copy() primitive.Primitive {
 	newT := *t
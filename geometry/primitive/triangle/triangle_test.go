@@ -2,6 +2,7 @@ package triangle
 
 import (
 	"fluorescence/geometry"
+	"math"
 	"testing"
 )
 
@@ -90,3 +91,104 @@ func BenchmarkTriangleIntersectionMiss(b *testing.B) {
 	}
 	triHit = h
 }
+
+func TestTriangleIntersectionTextureCoordinates(t *testing.T) {
+	tri := Unit(0.0, 0.0, 0.0)
+	tri.UVA = [2]float64{0.0, 0.0}
+	tri.UVB = [2]float64{2.0, 0.0}
+	tri.UVC = [2]float64{0.0, 4.0}
+	tri.HasUVs = true
+	tri, _ = tri.Setup()
+	r := geometry.Ray{
+		Origin: geometry.Point{
+			X: 0.25,
+			Y: 0.5,
+			Z: 1.0,
+		},
+		Direction: geometry.Vector{
+			X: 0.0,
+			Y: 0.0,
+			Z: -1.0,
+		},
+	}
+	rh, h := tri.Intersection(r, 1e-7, 1.797693134862315708145274237317043567981e+308)
+	if !h {
+		t.Fatalf("Expected true (hit) but got %t\n", h)
+	}
+	if math.Abs(rh.U-0.5) > 1e-9 || math.Abs(rh.V-2.0) > 1e-9 {
+		t.Errorf("Expected texture coordinates (0.5, 2) but got (%v, %v)\n", rh.U, rh.V)
+	}
+	if math.Abs(rh.Tangent.X-0.5) > 1e-9 || math.Abs(rh.Bitangent.Y-0.25) > 1e-9 {
+		t.Errorf("Expected tangent (0.5, 0, 0) and bitangent (0, 0.25, 0) but got %v and %v\n", rh.Tangent, rh.Bitangent)
+	}
+}
+
+func TestTriangleIntersectionSingleTexel(t *testing.T) {
+	// every corner mapped to the same spot, as in a solid color atlas, stays on that spot
+	tri := Unit(0.0, 0.0, 0.0)
+	tri.UVA = [2]float64{0.25, 0.75}
+	tri.UVB = [2]float64{0.25, 0.75}
+	tri.UVC = [2]float64{0.25, 0.75}
+	tri.HasUVs = true
+	tri, _ = tri.Setup()
+	r := geometry.Ray{
+		Origin: geometry.Point{
+			X: 0.25,
+			Y: 0.5,
+			Z: 1.0,
+		},
+		Direction: geometry.Vector{
+			X: 0.0,
+			Y: 0.0,
+			Z: -1.0,
+		},
+	}
+	rh, h := tri.Intersection(r, 1e-7, 1.797693134862315708145274237317043567981e+308)
+	if !h {
+		t.Fatalf("Expected true (hit) but got %t\n", h)
+	}
+	if math.Abs(rh.U-0.25) > 1e-9 || math.Abs(rh.V-0.75) > 1e-9 {
+		t.Errorf("Expected texture coordinates (0.25, 0.75) but got (%v, %v)\n", rh.U, rh.V)
+	}
+}
+
+func TestTriangleSetupCollinear(t *testing.T) {
+	_, err := (&Triangle{
+		A: geometry.Point{X: 0.0, Y: 0.0, Z: 0.0},
+		B: geometry.Point{X: 1.0, Y: 1.0, Z: 1.0},
+		C: geometry.Point{X: 2.0, Y: 2.0, Z: 2.0},
+	}).Setup()
+	if err == nil {
+		t.Errorf("Expected error for Triangle with corners in a line but got none\n")
+	}
+}
+
+func TestTriangleIntersectionSmoothNormal(t *testing.T) {
+	tri, err := (&Triangle{
+		A:       geometry.Point{X: 0.0, Y: 0.0, Z: 0.0},
+		B:       geometry.Point{X: 1.0, Y: 0.0, Z: 0.0},
+		C:       geometry.Point{X: 0.0, Y: 1.0, Z: 0.0},
+		NormalA: geometry.Vector{X: 0.0, Y: 0.0, Z: 1.0},
+		NormalB: geometry.Vector{X: 1.0, Y: 0.0, Z: 1.0},
+		NormalC: geometry.Vector{X: 0.0, Y: 0.0, Z: 2.0},
+	}).Setup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// halfway along AB, between the normals at A and B
+	r := geometry.Ray{
+		Origin:    geometry.Point{X: 0.5, Y: 0.0, Z: 1.0},
+		Direction: geometry.Vector{X: 0.0, Y: 0.0, Z: -1.0},
+	}
+	rh, h := tri.Intersection(r, 1e-7, math.MaxFloat64)
+	if !h {
+		t.Fatalf("Expected true (hit) but got %t\n", h)
+	}
+	expected := geometry.Vector{X: math.Sqrt(0.5), Y: 0.0, Z: 1.0 + math.Sqrt(0.5)}.Unit()
+	if rh.NormalAtHit.Sub(expected).Magnitude() > 1e-9 {
+		t.Errorf("Expected shading normal %v but got %v\n", expected, rh.NormalAtHit)
+	}
+	if rh.Geometric() != (geometry.Vector{X: 0.0, Y: 0.0, Z: 1.0}) {
+		t.Errorf("Expected geometric normal (0, 0, 1) but got %v\n", rh.Geometric())
+	}
+}
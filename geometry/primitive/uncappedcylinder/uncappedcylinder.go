@@ -18,6 +18,7 @@ type UncappedCylinder struct {
 	HasInvertedNormals bool           `json:"has_inverted_normals"`
 	ray                geometry.Ray
 	minT, maxT         float64
+	basis              geometry.ONB // frame around the axis, whose U axis is where texture coordinate U starts
 	mat                material.Material
 }
 
@@ -46,6 +47,7 @@ func (uc *UncappedCylinder) Setup() (*UncappedCylinder, error) {
 	}
 	uc.minT = 0.0
 	uc.maxT = uc.ray.ClosestTime(uc.B)
+	uc.basis = geometry.NewONB(uc.ray.Direction)
 	return uc, nil
 }
 
@@ -66,25 +68,43 @@ func (uc *UncappedCylinder) Intersection(ray geometry.Ray, tMin, tMax float64) (
 		root := math.Sqrt(preDiscriminant)
 		// evaluate first solution, which will be smaller
 		t1 := (-b - root) / a
-		cylinderT1 := uc.ray.ClosestTime(ray.PointAt(t1))
+		p1 := ray.PointAt(t1)
+		cylinderT1 := uc.ray.ClosestTime(p1)
 		// return if within range
 		if t1 >= tMin && t1 <= tMax && cylinderT1 >= uc.minT && cylinderT1 <= uc.maxT {
+			normal := uc.normalAt(p1)
 			return &material.RayHit{
-				Ray:         ray,
-				NormalAtHit: uc.normalAt(ray.PointAt(t1)),
-				Time:        t1,
-				Material:    uc.mat,
+				Ray:             ray,
+				Point:           p1,
+				ObjectPoint:     p1,
+				NormalAtHit:     normal,
+				GeometricNormal: normal,
+				Time:            t1,
+				U:               uc.textureUAt(p1),
+				V:               cylinderT1 / uc.maxT,
+				Tangent:         uc.tangentAt(p1),
+				Bitangent:       uc.ray.Direction.MultScalar(uc.maxT),
+				Material:        uc.mat,
 			}, true
 		}
 		// evaluate and return second solution if in range
 		t2 := (-b + root) / a
-		cylinderT2 := uc.ray.ClosestTime(ray.PointAt(t2))
+		p2 := ray.PointAt(t2)
+		cylinderT2 := uc.ray.ClosestTime(p2)
 		if t2 >= tMin && t2 <= tMax && cylinderT2 >= uc.minT && cylinderT2 <= uc.maxT {
+			normal := uc.normalAt(p2)
 			return &material.RayHit{
-				Ray:         ray,
-				NormalAtHit: uc.normalAt(ray.PointAt(t2)),
-				Time:        t2,
-				Material:    uc.mat,
+				Ray:             ray,
+				Point:           p2,
+				ObjectPoint:     p2,
+				NormalAtHit:     normal,
+				GeometricNormal: normal,
+				Time:            t2,
+				U:               uc.textureUAt(p2),
+				V:               cylinderT2 / uc.maxT,
+				Tangent:         uc.tangentAt(p2),
+				Bitangent:       uc.ray.Direction.MultScalar(uc.maxT),
+				Material:        uc.mat,
 			}, true
 		}
 	}
@@ -130,12 +150,29 @@ func (uc *UncappedCylinder) IsClosed() bool {
 	return false
 }
 
+// SurfaceArea returns the surface area of this object
+func (uc *UncappedCylinder) SurfaceArea() (float64, bool) {
+	return 2.0 * math.Pi * uc.Radius * uc.maxT, true
+}
+
 // Copy returns a shallow copy of this cylinder
 func (uc *UncappedCylinder) Copy() primitive.Primitive {
 	newUC := *uc
 	return &newUC
 }
 
+// textureUAt returns the texture coordinate U, the fraction of the way around the axis, at a point
+// the point is assumed to be on the surface of the object
+func (uc *UncappedCylinder) textureUAt(p geometry.Point) float64 {
+	return uc.basis.AngleAround(uc.ray.ClosestPoint(p).To(p))
+}
+
+// tangentAt returns the rate of change of a point on the surface of this object with texture coordinate U
+// the point is assumed to be on the surface of the object
+func (uc *UncappedCylinder) tangentAt(p geometry.Point) geometry.Vector {
+	return uc.ray.Direction.Cross(uc.ray.ClosestPoint(p).To(p)).Unit().MultScalar(2.0 * math.Pi * uc.Radius)
+}
+
 func (uc *UncappedCylinder) normalAt(p geometry.Point) geometry.Vector {
 	if uc.HasInvertedNormals {
 		return uc.ray.ClosestPoint(p).To(p).Unit().Negate()
@@ -2,8 +2,9 @@ package geometry
 
 // Ray defines elements of a parametric ray equation
 type Ray struct {
-	Origin    Point  `json:"origin"`
-	Direction Vector `json:"direction"`
+	Origin    Point   `json:"origin"`
+	Direction Vector  `json:"direction"`
+	Time      float64 `json:"time"` // moment within the camera's shutter interval the ray was sent at, which places moving objects
 }
 
 // RayZero defines the zero ray
@@ -0,0 +1,13 @@
+package geometry
+
+// RayCone approximates the bundle of rays represented by a single ray, such as all those through one pixel,
+// as a cone around it
+type RayCone struct {
+	Width  float64 // width of the cone at the ray's origin
+	Spread float64 // angle (in radians) across the cone, by which its width grows with distance
+}
+
+// WidthAt returns the width of the cone a distance along its ray
+func (rc RayCone) WidthAt(distance float64) float64 {
+	return rc.Width + rc.Spread*distance
+}
@@ -59,6 +59,20 @@ func RandomInUnitSphere(rng *rand.Rand) Vector {
 	}
 }
 
+// RandomCosineDirection returns a new unit Vector pointing into the positive Z
+// hemisphere, distributed proportionally to the cosine of its angle from the Z axis
+func RandomCosineDirection(rng *rand.Rand) Vector {
+	r1 := rng.Float64()
+	r2 := rng.Float64()
+	phi := 2.0 * math.Pi * r1
+	sqrtR2 := math.Sqrt(r2)
+	return Vector{
+		X: math.Cos(phi) * sqrtR2,
+		Y: math.Sin(phi) * sqrtR2,
+		Z: math.Sqrt(1.0 - r2),
+	}
+}
+
 // Magnitude return euclidean length of Vector
 func (v Vector) Magnitude() float64 {
 	return math.Sqrt(v.X*v.X + v.Y*v.Y + v.Z*v.Z)
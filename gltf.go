@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// gltfDocument holds the parts of a glTF 2.0 file used to build a scene
+type gltfDocument struct {
+	Scene       *int             `json:"scene"`
+	Scenes      []gltfSceneNodes `json:"scenes"`
+	Nodes       []gltfNode       `json:"nodes"`
+	Meshes      []gltfMesh       `json:"meshes"`
+	Materials   []gltfMaterial   `json:"materials"`
+	Textures    []gltfTexture    `json:"textures"`
+	Images      []gltfImage      `json:"images"`
+	Samplers    []gltfSampler    `json:"samplers"`
+	Cameras     []gltfCamera     `json:"cameras"`
+	Accessors   []gltfAccessor   `json:"accessors"`
+	BufferViews []gltfBufferView `json:"bufferViews"`
+	Buffers     []gltfBuffer     `json:"buffers"`
+	directory   string           // directory of the file, which relative URIs are resolved against
+	buffers     [][]byte         // contents of each buffer
+	glbBuffer   []byte           // binary chunk of a .glb file, used by its first buffer
+}
+
+type gltfSceneNodes struct {
+	Nodes []int `json:"nodes"`
+}
+
+type gltfNode struct {
+	Name        string    `json:"name"`
+	Mesh        *int      `json:"mesh"`
+	Camera      *int      `json:"camera"`
+	Children    []int     `json:"children"`
+	Matrix      []float64 `json:"matrix"`      // column-major, used instead of translation, rotation and scale if given
+	Translation []float64 `json:"translation"` // x, y, z
+	Rotation    []float64 `json:"rotation"`    // quaternion as x, y, z, w
+	Scale       []float64 `json:"scale"`       // x, y, z
+}
+
+type gltfMesh struct {
+	Name       string          `json:"name"`
+	Primitives []gltfPrimitive `json:"primitives"`
+}
+
+type gltfPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    *int           `json:"indices"`
+	Material   *int           `json:"material"`
+	Mode       *int           `json:"mode"`
+}
+
+type gltfTextureInfo struct {
+	Index    int      `json:"index"`
+	TexCoord int      `json:"texCoord"`
+	Scale    *float64 `json:"scale"` // strength of a normal texture, which is one if not given
+}
+
+type gltfMaterial struct {
+	Name                 string `json:"name"`
+	PBRMetallicRoughness struct {
+		BaseColorFactor          []float64        `json:"baseColorFactor"`
+		BaseColorTexture         *gltfTextureInfo `json:"baseColorTexture"`
+		MetallicFactor           *float64         `json:"metallicFactor"`
+		RoughnessFactor          *float64         `json:"roughnessFactor"`
+		MetallicRoughnessTexture *gltfTextureInfo `json:"metallicRoughnessTexture"`
+	} `json:"pbrMetallicRoughness"`
+	NormalTexture   *gltfTextureInfo `json:"normalTexture"`
+	EmissiveTexture *gltfTextureInfo `json:"emissiveTexture"`
+	EmissiveFactor  []float64        `json:"emissiveFactor"`
+	AlphaMode       string           `json:"alphaMode"`
+	Extensions      struct {
+		Transmission *struct {
+			TransmissionFactor float64 `json:"transmissionFactor"`
+		} `json:"KHR_materials_transmission"`
+		IOR *struct {
+			IOR float64 `json:"ior"`
+		} `json:"KHR_materials_ior"`
+		EmissiveStrength *struct {
+			EmissiveStrength float64 `json:"emissiveStrength"`
+		} `json:"KHR_materials_emissive_strength"`
+	} `json:"extensions"`
+}
+
+type gltfTexture struct {
+	Source  *int `json:"source"`
+	Sampler *int `json:"sampler"`
+}
+
+type gltfImage struct {
+	URI        string `json:"uri"`
+	BufferView *int   `json:"bufferView"`
+	MimeType   string `json:"mimeType"`
+}
+
+type gltfSampler struct {
+	MagFilter int `json:"magFilter"`
+	MinFilter int `json:"minFilter"`
+	WrapS     int `json:"wrapS"` // wrap mode across U
+	WrapT     int `json:"wrapT"` // wrap mode across V
+}
+
+type gltfCamera struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Perspective *struct {
+		YFov float64 `json:"yfov"` // in radians
+	} `json:"perspective"`
+}
+
+type gltfAccessor struct {
+	BufferView    *int            `json:"bufferView"`
+	ByteOffset    int             `json:"byteOffset"`
+	ComponentType int             `json:"componentType"`
+	Normalized    bool            `json:"normalized"`
+	Count         int             `json:"count"`
+	Type          string          `json:"type"`
+	Sparse        json.RawMessage `json:"sparse"`
+}
+
+type gltfBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	ByteStride int `json:"byteStride"`
+}
+
+type gltfBuffer struct {
+	URI        string `json:"uri"`
+	ByteLength int    `json:"byteLength"`
+}
+
+// glTF constants for the components of accessors, primitive modes, and sampler settings
+const (
+	gltfByte          = 5120
+	gltfUnsignedByte  = 5121
+	gltfShort         = 5122
+	gltfUnsignedShort = 5123
+	gltfUnsignedInt   = 5125
+	gltfFloat         = 5126
+
+	gltfTriangles     = 4
+	gltfTriangleStrip = 5
+	gltfTriangleFan   = 6
+
+	gltfClampToEdge    = 33071
+	gltfMirroredRepeat = 33648
+	gltfNearest        = 9728
+)
+
+// readGLTF reads a .gltf or .glb file and the buffers it refers to
+func readGLTF(fileName string) (*gltfDocument, error) {
+	contents, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	doc := &gltfDocument{directory: filepath.Dir(fileName)}
+	jsonChunk := contents
+	if bytes.HasPrefix(contents, []byte("glTF")) {
+		jsonChunk, doc.glbBuffer, err = readGLB(contents)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = json.Unmarshal(jsonChunk, doc)
+	if err != nil {
+		return nil, err
+	}
+	var required struct {
+		ExtensionsRequired []string `json:"extensionsRequired"`
+	}
+	json.Unmarshal(jsonChunk, &required)
+	if len(required.ExtensionsRequired) > 0 {
+		return nil, fmt.Errorf("gltf extensions (%s) are required but not supported", strings.Join(required.ExtensionsRequired, ", "))
+	}
+
+	for i, b := range doc.Buffers {
+		var data []byte
+		if b.URI == "" {
+			if i != 0 || doc.glbBuffer == nil {
+				return nil, fmt.Errorf("gltf buffer %d has no uri", i)
+			}
+			data = doc.glbBuffer
+		} else {
+			data, err = doc.readURI(b.URI)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if len(data) < b.ByteLength {
+			return nil, fmt.Errorf("gltf buffer %d is shorter than its byteLength", i)
+		}
+		doc.buffers = append(doc.buffers, data)
+	}
+	return doc, nil
+}
+
+// readGLB splits a binary .glb file into its JSON chunk and optional binary chunk
+func readGLB(contents []byte) ([]byte, []byte, error) {
+	if len(contents) < 20 || binary.LittleEndian.Uint32(contents[4:8]) != 2 {
+		return nil, nil, fmt.Errorf("glb file is not glTF version 2")
+	}
+	var jsonChunk, binChunk []byte
+	offset := 12
+	for offset+8 <= len(contents) {
+		length := int(binary.LittleEndian.Uint32(contents[offset:]))
+		chunkType := binary.LittleEndian.Uint32(contents[offset+4:])
+		offset += 8
+		if offset+length > len(contents) {
+			return nil, nil, fmt.Errorf("glb chunk runs past the end of the file")
+		}
+		switch chunkType {
+		case 0x4E4F534A: // JSON
+			jsonChunk = contents[offset : offset+length]
+		case 0x004E4942: // BIN
+			binChunk = contents[offset : offset+length]
+		}
+		offset += length
+	}
+	if jsonChunk == nil {
+		return nil, nil, fmt.Errorf("glb file has no JSON chunk")
+	}
+	return jsonChunk, binChunk, nil
+}
+
+// readURI returns the data at a URI, either embedded in a data URI or in a file relative to the glTF file
+func (doc *gltfDocument) readURI(uri string) ([]byte, error) {
+	if strings.HasPrefix(uri, "data:") {
+		comma := strings.IndexByte(uri, ',')
+		if comma < 0 || !strings.HasSuffix(uri[:comma], ";base64") {
+			return nil, fmt.Errorf("gltf data uri is not base64")
+		}
+		return base64.StdEncoding.DecodeString(uri[comma+1:])
+	}
+	path, err := url.PathUnescape(uri)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(filepath.Join(doc.directory, filepath.FromSlash(path)))
+}
+
+// bufferViewData returns the bytes of a buffer view
+func (doc *gltfDocument) bufferViewData(index int) ([]byte, int, error) {
+	if index < 0 || index >= len(doc.BufferViews) {
+		return nil, 0, fmt.Errorf("gltf buffer view %d is not defined", index)
+	}
+	view := doc.BufferViews[index]
+	if view.ByteOffset < 0 || view.ByteLength < 0 || view.ByteStride < 0 {
+		return nil, 0, fmt.Errorf("gltf buffer view %d has a negative byteOffset, byteLength, or byteStride", index)
+	}
+	if view.Buffer < 0 || view.Buffer >= len(doc.buffers) {
+		return nil, 0, fmt.Errorf("gltf buffer %d is not defined", view.Buffer)
+	}
+	buffer := doc.buffers[view.Buffer]
+	if view.ByteOffset > len(buffer) || view.ByteLength > len(buffer)-view.ByteOffset {
+		return nil, 0, fmt.Errorf("gltf buffer view %d runs past the end of its buffer", index)
+	}
+	return buffer[view.ByteOffset : view.ByteOffset+view.ByteLength], view.ByteStride, nil
+}
+
+// readAccessor returns the elements of an accessor, each as a list of its components
+// normalized integer components are brought into [0, 1] or [-1, 1]
+func (doc *gltfDocument) readAccessor(index int) ([][]float64, error) {
+	if index < 0 || index >= len(doc.Accessors) {
+		return nil, fmt.Errorf("gltf accessor %d is not defined", index)
+	}
+	accessor := doc.Accessors[index]
+	if accessor.ByteOffset < 0 || accessor.Count < 0 {
+		return nil, fmt.Errorf("gltf accessor %d has a negative byteOffset or count", index)
+	}
+	if len(accessor.Sparse) > 0 {
+		return nil, fmt.Errorf("gltf accessor %d is sparse, which is not supported", index)
+	}
+	components := map[string]int{"SCALAR": 1, "VEC2": 2, "VEC3": 3, "VEC4": 4}[accessor.Type]
+	if components == 0 {
+		return nil, fmt.Errorf("gltf accessor %d type (%s) is not supported", index, accessor.Type)
+	}
+	size := map[int]int{
+		gltfByte: 1, gltfUnsignedByte: 1, gltfShort: 2, gltfUnsignedShort: 2, gltfUnsignedInt: 4, gltfFloat: 4,
+	}[accessor.ComponentType]
+	if size == 0 {
+		return nil, fmt.Errorf("gltf accessor %d component type (%d) is not supported", index, accessor.ComponentType)
+	}
+	// accessors without a buffer view are all zeros
+	if accessor.BufferView == nil {
+		elements := make([][]float64, accessor.Count)
+		for i := range elements {
+			elements[i] = make([]float64, components)
+		}
+		return elements, nil
+	}
+	data, stride, err := doc.bufferViewData(*accessor.BufferView)
+	if err != nil {
+		return nil, err
+	}
+	if stride == 0 {
+		stride = size * components
+	}
+	// the last element must end within the buffer view, checked without multiplying out a count read from the file
+	if accessor.Count > 0 {
+		last := len(data) - accessor.ByteOffset - size*components
+		if last < 0 || (accessor.Count-1) > last/stride {
+			return nil, fmt.Errorf("gltf accessor %d runs past the end of its buffer view", index)
+		}
+	}
+	elements := make([][]float64, accessor.Count)
+	for i := range elements {
+		elements[i] = make([]float64, components)
+		for j := 0; j < components; j++ {
+			b := data[accessor.ByteOffset+stride*i+size*j:]
+			var value float64
+			switch accessor.ComponentType {
+			case gltfByte:
+				value = float64(int8(b[0]))
+				if accessor.Normalized {
+					value = math.Max(value/127.0, -1.0)
+				}
+			case gltfUnsignedByte:
+				value = float64(b[0])
+				if accessor.Normalized {
+					value /= 255.0
+				}
+			case gltfShort:
+				value = float64(int16(binary.LittleEndian.Uint16(b)))
+				if accessor.Normalized {
+					value = math.Max(value/32767.0, -1.0)
+				}
+			case gltfUnsignedShort:
+				value = float64(binary.LittleEndian.Uint16(b))
+				if accessor.Normalized {
+					value /= 65535.0
+				}
+			case gltfUnsignedInt:
+				value = float64(binary.LittleEndian.Uint32(b))
+			default:
+				value = float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+			}
+			elements[i][j] = value
+		}
+	}
+	return elements, nil
+}
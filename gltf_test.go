@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fluorescence/geometry"
+	"fluorescence/shading/material"
+	"fluorescence/shading/texture"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+// gltfFixture is a glTF file with everything embedded, holding one triangle used by two meshes
+// the first mesh is diffuse with a normal map, placed by a parent node scaled by 2 and a child node moved along X,
+// and the second is metallic, moved back along Z
+// its buffer and image are filled in as data URIs
+const gltfFixture = `{
+	"asset": {"version": "2.0"},
+	"scene": 0,
+	"scenes": [{"nodes": [0, 2]}],
+	"nodes": [
+		{"name": "parent", "translation": [0, 0, -5], "scale": [2, 2, 2], "children": [1]},
+		{"name": "child", "translation": [1, 0, 0], "mesh": 0},
+		{"name": "metal", "translation": [0, 0, -10], "mesh": 1}
+	],
+	"meshes": [
+		{"primitives": [{"attributes": {"POSITION": 0}, "indices": 1, "material": 0}]},
+		{"primitives": [{"attributes": {"POSITION": 0}, "indices": 1, "material": 1}]}
+	],
+	"materials": [
+		{
+			"pbrMetallicRoughness": {"baseColorFactor": [0.5, 0.25, 1.0, 1.0], "metallicFactor": 0.0},
+			"normalTexture": {"index": 0, "scale": 0.5}
+		},
+		{
+			"pbrMetallicRoughness": {"baseColorFactor": [0.9, 0.9, 0.9, 1.0], "metallicFactor": 1.0, "roughnessFactor": 0.5}
+		}
+	],
+	"textures": [{"source": 0, "sampler": 0}],
+	"samplers": [{"wrapS": 33071, "wrapT": 33648}],
+	"images": [{"uri": "%s"}],
+	"accessors": [
+		{"bufferView": 0, "componentType": 5126, "count": 3, "type": "VEC3"},
+		{"bufferView": 1, "componentType": 5123, "count": 3, "type": "SCALAR"}
+	],
+	"bufferViews": [
+		{"buffer": 0, "byteOffset": 0, "byteLength": 36},
+		{"buffer": 0, "byteOffset": 36, "byteLength": 6}
+	],
+	"buffers": [{"uri": "%s", "byteLength": 42}]
+}`
+
+// writeGLTFFixture writes the fixture, with its buffer and image embedded, and returns its file name
+func writeGLTFFixture(t *testing.T) string {
+	var buffer bytes.Buffer
+	binary.Write(&buffer, binary.LittleEndian, []float32{0, 0, 0, 1, 0, 0, 0, 1, 0})
+	binary.Write(&buffer, binary.LittleEndian, []uint16{0, 1, 2})
+	// a flat normal map, facing straight out of the surface
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	for i := 0; i < 4; i++ {
+		img.Set(i%2, i/2, color.RGBA{R: 128, G: 128, B: 255, A: 255})
+	}
+	var pngData bytes.Buffer
+	png.Encode(&pngData, img)
+	contents := fmt.Sprintf(gltfFixture,
+		"data:image/png;base64,"+base64.StdEncoding.EncodeToString(pngData.Bytes()),
+		"data:application/octet-stream;base64,"+base64.StdEncoding.EncodeToString(buffer.Bytes()))
+	fileName := filepath.Join(t.TempDir(), "fixture.gltf")
+	err := ioutil.WriteFile(fileName, []byte(contents), 0644)
+	if err != nil {
+		t.Fatalf("Error writing gltf fixture: %s\n", err)
+	}
+	return fileName
+}
+
+func TestLoadGLTF(t *testing.T) {
+	imported, err := loadGLTF(writeGLTFFixture(t), 2.2)
+	if err != nil {
+		t.Fatalf("Error loading gltf fixture: %s\n", err)
+	}
+	if len(imported.objects) != 2 {
+		t.Fatalf("Expected 2 meshes but got %d\n", len(imported.objects))
+	}
+	// the triangle's corners (0, 0, 0), (1, 0, 0), and (0, 1, 0) are moved by 1 along X,
+	// scaled by 2, and moved by -5 along Z, to (2, 0, -5), (4, 0, -5), and (2, 2, -5)
+	toward := geometry.Vector{Z: -1.0}
+	for _, c := range []struct {
+		origin geometry.Point
+		hit    bool
+	}{
+		{geometry.Point{X: 2.5, Y: 0.5}, true},
+		{geometry.Point{X: 3.9, Y: 0.05}, true},
+		{geometry.Point{X: 0.5, Y: 0.5}, false},
+		{geometry.Point{X: 3.5, Y: 1.5}, false},
+	} {
+		rh, h := imported.objects[0].Intersection(geometry.Ray{Origin: c.origin, Direction: toward}, 1e-7, math.MaxFloat64)
+		if h != c.hit {
+			t.Errorf("Expected hit %t from %v but got %t\n", c.hit, c.origin, h)
+			continue
+		}
+		if h && math.Abs(rh.Point.Z+5.0) > 1e-9 {
+			t.Errorf("Expected the hit at z = -5 but got %v\n", rh.Point)
+		}
+	}
+
+	rh, h := imported.objects[0].Intersection(geometry.Ray{Origin: geometry.Point{X: 2.5, Y: 0.5}, Direction: toward}, 1e-7, math.MaxFloat64)
+	if !h {
+		t.Fatalf("Expected true (hit) but got %t\n", h)
+	}
+	diffuse, ok := rh.Material.(*material.Lambertian)
+	if !ok {
+		t.Fatalf("Expected a non-metallic material to become a Lambertian but got %T\n", rh.Material)
+	}
+	if c := diffuse.Reflectance(*rh); c.Red != 0.5 || c.Green != 0.25 || c.Blue != 1.0 {
+		t.Errorf("Expected the base color factor (0.5, 0.25, 1) but got %v\n", c)
+	}
+	if diffuse.NormalMapScale != 0.5 {
+		t.Errorf("Expected normal map scale 0.5 but got %f\n", diffuse.NormalMapScale)
+	}
+	normalMap, ok := diffuse.NormalMapTexture.(*texture.Image)
+	if !ok {
+		t.Fatalf("Expected the normal map to be an image but got %T\n", diffuse.NormalMapTexture)
+	}
+	if normalMap.Wrap != "clamp" || normalMap.WrapV != "mirror" {
+		t.Errorf("Expected wrap modes clamp and mirror but got %s and %s\n", normalMap.Wrap, normalMap.WrapV)
+	}
+
+	rh, h = imported.objects[1].Intersection(geometry.Ray{Origin: geometry.Point{X: 0.25, Y: 0.25}, Direction: toward}, 1e-7, math.MaxFloat64)
+	if !h {
+		t.Fatalf("Expected true (hit) but got %t\n", h)
+	}
+	if math.Abs(rh.Point.Z+10.0) > 1e-9 {
+		t.Errorf("Expected the hit at z = -10 but got %v\n", rh.Point)
+	}
+	metal, ok := rh.Material.(*material.Metal)
+	if !ok {
+		t.Fatalf("Expected a metallic material to become a Metal but got %T\n", rh.Material)
+	}
+	if math.Abs(metal.Fuzziness-0.25) > 1e-9 {
+		t.Errorf("Expected fuzziness 0.25 from roughness 0.5 but got %f\n", metal.Fuzziness)
+	}
+}
+
+func TestGLTFAccessorBounds(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		change func(doc *gltfDocument)
+	}{
+		{"negative accessor count", func(doc *gltfDocument) { doc.Accessors[0].Count = -1 }},
+		{"negative accessor byteOffset", func(doc *gltfDocument) { doc.Accessors[0].ByteOffset = -12 }},
+		{"accessor past its buffer view", func(doc *gltfDocument) { doc.Accessors[0].Count = 4 }},
+		{"huge accessor count", func(doc *gltfDocument) { doc.Accessors[0].Count = math.MaxInt32 }},
+		{"negative buffer view byteOffset", func(doc *gltfDocument) { doc.BufferViews[0].ByteOffset = -4 }},
+		{"negative buffer view byteLength", func(doc *gltfDocument) { doc.BufferViews[0].ByteLength = -4 }},
+		{"buffer view past its buffer", func(doc *gltfDocument) { doc.BufferViews[0].ByteOffset = 40 }},
+	} {
+		doc, err := readGLTF(writeGLTFFixture(t))
+		if err != nil {
+			t.Fatalf("Error reading gltf fixture: %s\n", err)
+		}
+		if _, err := doc.readAccessor(0); err != nil {
+			t.Fatalf("Error reading accessor: %s\n", err)
+		}
+		c.change(doc)
+		if _, err := doc.readAccessor(0); err == nil {
+			t.Errorf("Expected an error for a %s\n", c.name)
+		}
+	}
+}
@@ -0,0 +1,524 @@
+package main
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/geometry/primitive"
+	"fluorescence/geometry/primitive/mesh"
+	"fluorescence/shading"
+	"fluorescence/shading/material"
+	"fluorescence/shading/texture"
+	"fmt"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// gltfImport holds the objects and cameras built from a glTF file
+type gltfImport struct {
+	objects     []primitive.Primitive
+	cameras     map[string]*Camera
+	cameraNames []string // names of the cameras in the order they were found
+}
+
+// gltfImageKey identifies a glTF image decoded with particular settings
+type gltfImageKey struct {
+	texture int
+	gamma   float64
+}
+
+// gltfBuilder turns the parts of a glTF document into the scene's objects, keeping what is shared between them
+type gltfBuilder struct {
+	doc             *gltfDocument
+	fileName        string
+	textureGamma    float64
+	images          map[gltfImageKey]*texture.Image
+	materials       []material.Material
+	defaultMaterial material.Material // used by primitives without a material of their own
+	degenerateFaces int
+	result          *gltfImport
+}
+
+// loadGLTF builds the meshes and cameras of a .gltf or .glb file's default scene
+// each node with a mesh becomes a Mesh with its triangles placed in world space,
+// and its metallic-roughness materials are mapped onto the closest existing material types
+func loadGLTF(fileName string, tGamma float64) (*gltfImport, error) {
+	doc, err := readGLTF(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("reading gltf (%s): %v", fileName, err)
+	}
+	gb := &gltfBuilder{
+		doc:          doc,
+		fileName:     fileName,
+		textureGamma: tGamma,
+		images:       map[gltfImageKey]*texture.Image{},
+		defaultMaterial: &material.Lambertian{
+			ReflectanceTexture: &texture.Color{Color: shading.Color{Red: 0.8, Green: 0.8, Blue: 0.8}},
+			EmittanceTexture:   &texture.Color{},
+		},
+		result: &gltfImport{
+			cameras: map[string]*Camera{},
+		},
+	}
+	for i := range doc.Materials {
+		newMaterial, err := gb.material(i)
+		if err != nil {
+			return nil, fmt.Errorf("gltf (%s) material %d: %v", fileName, i, err)
+		}
+		gb.materials = append(gb.materials, newMaterial)
+	}
+
+	if len(doc.Scenes) == 0 {
+		return nil, fmt.Errorf("gltf (%s) has no scenes", fileName)
+	}
+	sceneIndex := 0
+	if doc.Scene != nil {
+		sceneIndex = *doc.Scene
+	}
+	if sceneIndex < 0 || sceneIndex >= len(doc.Scenes) {
+		return nil, fmt.Errorf("gltf (%s) scene %d is not defined", fileName, sceneIndex)
+	}
+	for _, node := range doc.Scenes[sceneIndex].Nodes {
+		err = gb.addNode(node, mgl64.Ident4(), map[int]bool{})
+		if err != nil {
+			return nil, fmt.Errorf("gltf (%s): %v", fileName, err)
+		}
+	}
+	if gb.degenerateFaces > 0 {
+		fmt.Printf("\t\tSkipped %d degenerate faces in %s\n", gb.degenerateFaces, fileName)
+	}
+	return gb.result, nil
+}
+
+// addNode adds the mesh and camera of a node and its children, placed by their combined transforms
+func (gb *gltfBuilder) addNode(index int, parent mgl64.Mat4, ancestors map[int]bool) error {
+	if index < 0 || index >= len(gb.doc.Nodes) {
+		return fmt.Errorf("node %d is not defined", index)
+	}
+	if ancestors[index] {
+		return fmt.Errorf("node %d is its own ancestor", index)
+	}
+	node := gb.doc.Nodes[index]
+	world := parent.Mul4(node.localMatrix())
+
+	if node.Mesh != nil {
+		newMesh, err := gb.mesh(*node.Mesh, world)
+		if err != nil {
+			return fmt.Errorf("node %d: %v", index, err)
+		}
+		if newMesh != nil {
+			gb.result.objects = append(gb.result.objects, newMesh)
+		}
+	}
+	if node.Camera != nil {
+		err := gb.camera(*node.Camera, node.Name, world)
+		if err != nil {
+			return fmt.Errorf("node %d: %v", index, err)
+		}
+	}
+
+	ancestors[index] = true
+	for _, child := range node.Children {
+		err := gb.addNode(child, world, ancestors)
+		if err != nil {
+			return err
+		}
+	}
+	delete(ancestors, index)
+	return nil
+}
+
+// localMatrix returns the node's transform relative to its parent
+func (n gltfNode) localMatrix() mgl64.Mat4 {
+	if len(n.Matrix) == 16 {
+		var m mgl64.Mat4
+		copy(m[:], n.Matrix)
+		return m
+	}
+	translation := mgl64.Ident4()
+	if len(n.Translation) == 3 {
+		translation = mgl64.Translate3D(n.Translation[0], n.Translation[1], n.Translation[2])
+	}
+	rotation := mgl64.Ident4()
+	if len(n.Rotation) == 4 {
+		rotation = mgl64.Quat{
+			W: n.Rotation[3],
+			V: mgl64.Vec3{n.Rotation[0], n.Rotation[1], n.Rotation[2]},
+		}.Normalize().Mat4()
+	}
+	scale := mgl64.Ident4()
+	if len(n.Scale) == 3 {
+		scale = mgl64.Scale3D(n.Scale[0], n.Scale[1], n.Scale[2])
+	}
+	return translation.Mul4(rotation).Mul4(scale)
+}
+
+// mesh returns a Mesh of every triangle in a glTF mesh, transformed into world space
+// meshes made only of points or lines have no surface, and nil is returned for them
+func (gb *gltfBuilder) mesh(index int, world mgl64.Mat4) (*mesh.Mesh, error) {
+	if index < 0 || index >= len(gb.doc.Meshes) {
+		return nil, fmt.Errorf("mesh %d is not defined", index)
+	}
+	// transforms which mirror the mesh also reverse its winding, which is undone to keep normals facing out
+	mirrored := world.Det() < 0
+	// normals keep perpendicular to the surface under scaling by using the inverse transpose
+	normalMatrix := world.Mat3().Inv().Transpose()
+	data := &mesh.Data{
+		Materials: gb.materials,
+	}
+	hasUVs, hasNormals := false, false
+	for p, prim := range gb.doc.Meshes[index].Primitives {
+		mode := gltfTriangles
+		if prim.Mode != nil {
+			mode = *prim.Mode
+		}
+		if mode != gltfTriangles && mode != gltfTriangleStrip && mode != gltfTriangleFan {
+			continue
+		}
+		positionAccessor, ok := prim.Attributes["POSITION"]
+		if !ok {
+			return nil, fmt.Errorf("mesh %d primitive %d has no POSITION", index, p)
+		}
+		positions, err := gb.doc.readAccessor(positionAccessor)
+		if err != nil {
+			return nil, err
+		}
+		var uvs [][]float64
+		if uvAccessor, ok := prim.Attributes["TEXCOORD_0"]; ok {
+			uvs, err = gb.doc.readAccessor(uvAccessor)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if len(uvs) != len(positions) {
+			uvs = nil
+		}
+		hasUVs = hasUVs || uvs != nil
+		var normals [][]float64
+		if normalAccessor, ok := prim.Attributes["NORMAL"]; ok {
+			normals, err = gb.doc.readAccessor(normalAccessor)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if len(normals) != len(positions) {
+			normals = nil
+		}
+		hasNormals = hasNormals || normals != nil
+		var indices []int
+		if prim.Indices != nil {
+			elements, err := gb.doc.readAccessor(*prim.Indices)
+			if err != nil {
+				return nil, err
+			}
+			for _, element := range elements {
+				indices = append(indices, int(element[0]))
+			}
+		} else {
+			for i := range positions {
+				indices = append(indices, i)
+			}
+		}
+		materialIndex := -1
+		if prim.Material != nil {
+			if *prim.Material < 0 || *prim.Material >= len(gb.materials) {
+				return nil, fmt.Errorf("material %d is not defined", *prim.Material)
+			}
+			materialIndex = *prim.Material
+		}
+
+		base := len(data.Positions)
+		for i, position := range positions {
+			worldPosition := world.Mul4x1(mgl64.Vec4{position[0], position[1], position[2], 1.0})
+			data.Positions = append(data.Positions, geometry.Point{X: worldPosition[0], Y: worldPosition[1], Z: worldPosition[2]})
+			// glTF texture coordinates start at the top of the image, rather than the bottom
+			// corners without them are all left at zero, so their faces fall back to barycentric coordinates
+			uv := [2]float64{}
+			if uvs != nil {
+				uv = [2]float64{uvs[i][0], 1.0 - uvs[i][1]}
+			}
+			data.UVs = append(data.UVs, uv)
+			// corners without normals are left with a zero one, so their faces are flat
+			normal := geometry.VectorZero
+			if normals != nil {
+				worldNormal := normalMatrix.Mul3x1(mgl64.Vec3{normals[i][0], normals[i][1], normals[i][2]})
+				normal = geometry.Vector{X: worldNormal[0], Y: worldNormal[1], Z: worldNormal[2]}
+			}
+			data.Normals = append(data.Normals, normal)
+		}
+		for _, corners := range triangleCorners(indices, mode) {
+			for _, corner := range corners {
+				if corner < 0 || corner >= len(positions) {
+					return nil, fmt.Errorf("mesh %d primitive %d refers to a vertex which is not defined", index, p)
+				}
+			}
+			if mirrored {
+				corners[1], corners[2] = corners[2], corners[1]
+			}
+			data.Faces = append(data.Faces, [3]int{base + corners[0], base + corners[1], base + corners[2]})
+			data.FaceMaterials = append(data.FaceMaterials, materialIndex)
+		}
+	}
+	if len(data.Faces) == 0 {
+		return nil, nil
+	}
+	if !hasUVs {
+		data.UVs = nil
+	}
+	if !hasNormals {
+		data.Normals = nil
+	}
+	newMesh, err := mesh.New(data, false)
+	if err != nil {
+		return nil, err
+	}
+	gb.degenerateFaces += newMesh.DegenerateFaces()
+	newMesh.SetMaterial(gb.defaultMaterial)
+	return newMesh, nil
+}
+
+// triangleCorners splits a primitive's indices into the corners of its triangles
+func triangleCorners(indices []int, mode int) [][3]int {
+	corners := [][3]int{}
+	switch mode {
+	case gltfTriangleStrip:
+		// every other triangle of a strip is wound the other way around
+		for i := 0; i+2 < len(indices); i++ {
+			if i%2 == 0 {
+				corners = append(corners, [3]int{indices[i], indices[i+1], indices[i+2]})
+			} else {
+				corners = append(corners, [3]int{indices[i+1], indices[i], indices[i+2]})
+			}
+		}
+	case gltfTriangleFan:
+		for i := 1; i+1 < len(indices); i++ {
+			corners = append(corners, [3]int{indices[0], indices[i], indices[i+1]})
+		}
+	default:
+		for i := 0; i+2 < len(indices); i += 3 {
+			corners = append(corners, [3]int{indices[i], indices[i+1], indices[i+2]})
+		}
+	}
+	return corners
+}
+
+// camera adds a Camera looking down the node's negative Z axis, with its positive Y axis up
+// the camera is named after its node, or its own name if the node has none
+func (gb *gltfBuilder) camera(index int, nodeName string, world mgl64.Mat4) error {
+	if index < 0 || index >= len(gb.doc.Cameras) {
+		return fmt.Errorf("camera %d is not defined", index)
+	}
+	gc := gb.doc.Cameras[index]
+	if gc.Type != "perspective" || gc.Perspective == nil {
+		return fmt.Errorf("camera %d is not a perspective camera", index)
+	}
+	name := nodeName
+	if name == "" {
+		name = gc.Name
+	}
+	if name == "" {
+		name = fmt.Sprintf("gltf_camera_%d", index)
+	}
+	if _, ok := gb.result.cameras[name]; ok {
+		return fmt.Errorf("camera (%s) redefined", name)
+	}
+	eye := world.Mul4x1(mgl64.Vec4{0.0, 0.0, 0.0, 1.0})
+	forward := world.Mul4x1(mgl64.Vec4{0.0, 0.0, -1.0, 0.0}).Vec3().Normalize()
+	up := world.Mul4x1(mgl64.Vec4{0.0, 1.0, 0.0, 0.0}).Vec3().Normalize()
+	gb.result.cameras[name] = &Camera{
+		EyeLocation:    geometry.Point{X: eye[0], Y: eye[1], Z: eye[2]},
+		TargetLocation: geometry.Point{X: eye[0] + forward[0], Y: eye[1] + forward[1], Z: eye[2] + forward[2]},
+		UpVector:       geometry.Vector{X: up[0], Y: up[1], Z: up[2]},
+		VerticalFOV:    gc.Perspective.YFov * 180.0 / math.Pi,
+		FocusDistance:  1.0,
+	}
+	gb.result.cameraNames = append(gb.result.cameraNames, name)
+	return nil
+}
+
+// material maps a glTF metallic-roughness material onto the closest material type
+// transmissive or blended materials become Dielectrics, metallic ones become Metals with their roughness as fuzziness,
+// and the rest are Lambertian
+func (gb *gltfBuilder) material(index int) (material.Material, error) {
+	gm := gb.doc.Materials[index]
+	pbr := gm.PBRMetallicRoughness
+
+	baseColorFactor := shading.Color{Red: 1.0, Green: 1.0, Blue: 1.0}
+	alpha := 1.0
+	if len(pbr.BaseColorFactor) == 4 {
+		baseColorFactor = shading.Color{Red: pbr.BaseColorFactor[0], Green: pbr.BaseColorFactor[1], Blue: pbr.BaseColorFactor[2]}
+		alpha = pbr.BaseColorFactor[3]
+	}
+	baseColor, err := gb.factoredTexture(pbr.BaseColorTexture, baseColorFactor, gb.textureGamma)
+	if err != nil {
+		return nil, err
+	}
+
+	emissiveFactor := shading.ColorBlack
+	if len(gm.EmissiveFactor) == 3 {
+		emissiveFactor = shading.Color{Red: gm.EmissiveFactor[0], Green: gm.EmissiveFactor[1], Blue: gm.EmissiveFactor[2]}
+	}
+	if gm.Extensions.EmissiveStrength != nil {
+		emissiveFactor = emissiveFactor.MultScalar(gm.Extensions.EmissiveStrength.EmissiveStrength)
+	}
+	var emission texture.Texture = &texture.Color{}
+	if emissiveFactor != shading.ColorBlack {
+		emission, err = gb.factoredTexture(gm.EmissiveTexture, emissiveFactor, gb.textureGamma)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var bump material.Bump
+	if gm.NormalTexture != nil {
+		// normal maps hold directions rather than colors, so are never gamma corrected
+		normalMap, err := gb.texture(gm.NormalTexture, 1.0)
+		if err != nil {
+			return nil, err
+		}
+		bump.NormalMapTexture = normalMap
+		bump.NormalMapScale = 1.0
+		if gm.NormalTexture.Scale != nil {
+			bump.NormalMapScale = *gm.NormalTexture.Scale
+		}
+	}
+	if pbr.MetallicRoughnessTexture != nil {
+		fmt.Printf("\t\tMaterial %d in %s has a metallicRoughnessTexture, of which only the metallic and roughness factors are used\n", index, gb.fileName)
+	}
+
+	metallic := 1.0
+	if pbr.MetallicFactor != nil {
+		metallic = *pbr.MetallicFactor
+	}
+	roughness := 1.0
+	if pbr.RoughnessFactor != nil {
+		roughness = *pbr.RoughnessFactor
+	}
+	transmission := 0.0
+	if gm.Extensions.Transmission != nil {
+		transmission = gm.Extensions.Transmission.TransmissionFactor
+	}
+	refractiveIndex := 1.5
+	if gm.Extensions.IOR != nil && gm.Extensions.IOR.IOR > 1.0 {
+		refractiveIndex = gm.Extensions.IOR.IOR
+	}
+
+	switch {
+	case transmission > 0.0 || (gm.AlphaMode == "BLEND" && alpha < 1.0):
+		d := &material.Dielectric{
+			ReflectanceTexture: baseColor,
+			EmittanceTexture:   emission,
+			RefractiveIndex:    refractiveIndex,
+			Bump:               bump,
+		}
+		return d, d.UVTransform.SetupUVTransform()
+	case metallic >= 0.5:
+		m := &material.Metal{
+			ReflectanceTexture: baseColor,
+			EmittanceTexture:   emission,
+			Fuzziness:          roughness * roughness,
+			Bump:               bump,
+		}
+		return m, m.UVTransform.SetupUVTransform()
+	default:
+		l := &material.Lambertian{
+			ReflectanceTexture: baseColor,
+			EmittanceTexture:   emission,
+			Bump:               bump,
+		}
+		return l, l.UVTransform.SetupUVTransform()
+	}
+}
+
+// factoredTexture returns a texture's colors scaled by a factor, or just the factor if there is no texture
+func (gb *gltfBuilder) factoredTexture(info *gltfTextureInfo, factor shading.Color, gamma float64) (texture.Texture, error) {
+	factorTexture := &texture.Color{Color: factor}
+	if info == nil {
+		return factorTexture, nil
+	}
+	image, err := gb.texture(info, gamma)
+	if err != nil {
+		return nil, err
+	}
+	if factor == shading.ColorWhite {
+		return image, nil
+	}
+	multiplied, err := (&texture.Multiply{
+		TextureAName: "texture",
+		TextureBName: "factor",
+	}).Setup(map[string]texture.Texture{
+		"texture": image,
+		"factor":  factorTexture,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return multiplied, nil
+}
+
+// texture returns the image of a glTF texture, decoded with the gamma given
+// images are decoded once for each gamma they are used with
+func (gb *gltfBuilder) texture(info *gltfTextureInfo, gamma float64) (*texture.Image, error) {
+	if info.Index < 0 || info.Index >= len(gb.doc.Textures) {
+		return nil, fmt.Errorf("texture %d is not defined", info.Index)
+	}
+	key := gltfImageKey{texture: info.Index, gamma: gamma}
+	if image, ok := gb.images[key]; ok {
+		return image, nil
+	}
+	gt := gb.doc.Textures[info.Index]
+	if gt.Source == nil || *gt.Source < 0 || *gt.Source >= len(gb.doc.Images) {
+		return nil, fmt.Errorf("texture %d has no image", info.Index)
+	}
+	gi := gb.doc.Images[*gt.Source]
+	image := &texture.Image{
+		FileName:  fmt.Sprintf("%s image %d", gb.fileName, *gt.Source),
+		Gamma:     gamma,
+		Magnitude: 1.0,
+		// glTF samples linearly unless a sampler asks otherwise
+		Filter: "bilinear",
+	}
+	if gt.Sampler != nil {
+		if *gt.Sampler < 0 || *gt.Sampler >= len(gb.doc.Samplers) {
+			return nil, fmt.Errorf("sampler %d is not defined", *gt.Sampler)
+		}
+		sampler := gb.doc.Samplers[*gt.Sampler]
+		switch {
+		case sampler.MinFilter >= 9984 && sampler.MinFilter <= 9987:
+			// the mipmapped minification filters
+			image.Filter = "trilinear"
+		case sampler.MagFilter == gltfNearest:
+			image.Filter = "nearest"
+		}
+		image.Wrap = gltfWrap(sampler.WrapS)
+		image.WrapV = gltfWrap(sampler.WrapT)
+	}
+
+	var data []byte
+	var err error
+	if gi.BufferView != nil {
+		data, _, err = gb.doc.bufferViewData(*gi.BufferView)
+	} else {
+		data, err = gb.doc.readURI(gi.URI)
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = image.LoadData(data)
+	if err != nil {
+		return nil, err
+	}
+	gb.images[key] = image
+	return image, nil
+}
+
+// gltfWrap returns the image wrap mode of a glTF sampler's wrap mode, where repeating is the default
+func gltfWrap(mode int) string {
+	switch mode {
+	case gltfClampToEdge:
+		return "clamp"
+	case gltfMirroredRepeat:
+		return "mirror"
+	default:
+		return "repeat"
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fluorescence/shading"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LightGroupImage holds the linear light reaching each pixel from one of the scene's light groups
+// it is kept free of gamma correction and clamping, so groups can be scaled and summed after rendering
+type LightGroupImage struct {
+	Name   string
+	Width  int
+	Height int
+	Pixels []shading.Color // rows from the top of the image down
+}
+
+// NewLightGroupImages creates an empty image for each of the scene's light groups
+func NewLightGroupImages(parameters *Parameters) []*LightGroupImage {
+	groupImages := []*LightGroupImage{}
+	for _, name := range parameters.Scene.LightGroups {
+		groupImages = append(groupImages, &LightGroupImage{
+			Name:   name,
+			Width:  parameters.ImageWidth,
+			Height: parameters.ImageHeight,
+			Pixels: make([]shading.Color, parameters.ImageWidth*parameters.ImageHeight),
+		})
+	}
+	return groupImages
+}
+
+// Set sets the color of the pixel at (x, y), with y counted from the top of the image
+func (lgi *LightGroupImage) Set(x, y int, c shading.Color) {
+	lgi.Pixels[y*lgi.Width+x] = c
+}
+
+// WritePFM writes the image as a little-endian color PFM, which stores rows from the bottom of the image up
+func (lgi *LightGroupImage) WritePFM(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	_, err := fmt.Fprintf(bw, "PF\n%d %d\n-1.0\n", lgi.Width, lgi.Height)
+	if err != nil {
+		return err
+	}
+	texel := make([]byte, 12)
+	for y := lgi.Height - 1; y >= 0; y-- {
+		for x := 0; x < lgi.Width; x++ {
+			c := lgi.Pixels[y*lgi.Width+x]
+			binary.LittleEndian.PutUint32(texel[0:], math.Float32bits(float32(c.Red)))
+			binary.LittleEndian.PutUint32(texel[4:], math.Float32bits(float32(c.Green)))
+			binary.LittleEndian.PutUint32(texel[8:], math.Float32bits(float32(c.Blue)))
+			_, err = bw.Write(texel)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// getLightGroupFile creates the file for a light group's image, named after the main image it sits beside
+func getLightGroupFile(imageFileName string, groupName string) (*os.File, error) {
+	return os.Create(fmt.Sprintf(
+		"%s_%s.pfm",
+		strings.TrimSuffix(imageFileName, filepath.Ext(imageFileName)),
+		strings.ReplaceAll(groupName, " ", "_")))
+}
@@ -33,6 +33,7 @@ func main() {
 	// create image
 	fmt.Printf("Creating in-mem image...\n")
 	img := image.NewRGBA64(image.Rect(0, 0, parameters.ImageWidth, parameters.ImageHeight))
+	groupImages := NewLightGroupImages(parameters)
 
 	// fill image
 	fmt.Printf("Filling in-mem image...\n")
@@ -44,7 +45,7 @@ func main() {
 	runtime.LockOSThread()
 
 	startTime := time.Now()
-	go TraceImage(parameters, img, doneChan, maxThreads)
+	go TraceImage(parameters, img, groupImages, doneChan, maxThreads)
 
 	doneCount := 0
 	printInterval := pixelCount / 1000
@@ -79,6 +80,22 @@ func main() {
 		fmt.Printf("Error encoding to image file: %s\n", err.Error())
 		return
 	}
+
+	// each light group is written beside the image, in linear color so it can be rebalanced later
+	for _, gi := range groupImages {
+		fmt.Printf("Writing light group (%s) to image file...\n", gi.Name)
+		groupFile, err := getLightGroupFile(file.Name(), gi.Name)
+		if err != nil {
+			fmt.Printf("Error creating light group image file: %s\n", err.Error())
+			return
+		}
+		err = gi.WritePFM(groupFile)
+		groupFile.Close()
+		if err != nil {
+			fmt.Printf("Error encoding to light group image file: %s\n", err.Error())
+			return
+		}
+	}
 	fmt.Printf("Done!\n")
 	return
 }
@@ -5,21 +5,27 @@ import (
 	"fluorescence/geometry/primitive"
 	"fluorescence/geometry/primitive/box"
 	"fluorescence/geometry/primitive/bvh"
+	"fluorescence/geometry/primitive/csg"
 	"fluorescence/geometry/primitive/cylinder"
 	"fluorescence/geometry/primitive/disk"
 	"fluorescence/geometry/primitive/hollowcylinder"
 	"fluorescence/geometry/primitive/hollowdisk"
 	"fluorescence/geometry/primitive/infinitecylinder"
+	"fluorescence/geometry/primitive/instance"
+	"fluorescence/geometry/primitive/lightlink"
+	"fluorescence/geometry/primitive/mesh"
 	"fluorescence/geometry/primitive/plane"
 	"fluorescence/geometry/primitive/primitivelist"
 	"fluorescence/geometry/primitive/pyramid"
 	"fluorescence/geometry/primitive/rectangle"
 	"fluorescence/geometry/primitive/sphere"
+	"fluorescence/geometry/primitive/transform/affine"
 	"fluorescence/geometry/primitive/transform/rotate"
 	"fluorescence/geometry/primitive/transform/translate"
 	"fluorescence/geometry/primitive/triangle"
 	"fluorescence/geometry/primitive/uncappedcylinder"
 	"fluorescence/shading"
+	"fluorescence/shading/light"
 	"fluorescence/shading/material"
 	"fluorescence/shading/texture"
 	"fmt"
@@ -52,17 +58,50 @@ type Parameters struct {
 
 // Scene holds information about the pictured scene, such as the objects and camera
 type Scene struct {
-	Name            string              `json:"scene_name"`  // name of the scene
-	CameraName      string              `json:"camera_name"` // name of the camera to use
-	Camera          *Camera             `json:"-"`           // Camera reference
-	ObjectMaterials []*ObjectMaterial   `json:"objects"`     // temporary reference to ObjectMaterials to link geometry to materials
-	Objects         primitive.Primitive `json:"-"`           // reference to Objects in the scene
+	Name              string                `json:"scene_name"`     // name of the scene
+	CameraName        string                `json:"camera_name"`    // name of the camera to use
+	Camera            *Camera               `json:"-"`              // Camera reference
+	ObjectMaterials   []*ObjectMaterial     `json:"objects"`        // temporary reference to ObjectMaterials to link geometry to materials
+	Objects           primitive.Primitive   `json:"-"`              // reference to Objects in the scene
+	EnvironmentData   *EnvironmentData      `json:"environment"`    // temporary reference to the environment's description, if the scene has one
+	Environment       light.Environment     `json:"-"`              // light arriving from outside the scene, the background color if none is given
+	EnvironmentSource *material.LightSource `json:"-"`              // name and light group of the environment's light
+	LightsData        []*LightData          `json:"lights"`         // temporary reference to the descriptions of the scene's lights
+	Lights            []*SceneLight         `json:"-"`              // lights which are not part of the scene's geometry
+	LightGroups       []string              `json:"-"`              // names of the light groups written to their own images, in order of first use
+	GLTFFileName      string                `json:"gltf_file_name"` // glTF file whose meshes and cameras are added to the scene, if any
+}
+
+// SceneLight is a light which is not part of the scene's geometry, along with the names it is referred to by
+type SceneLight struct {
+	Name  string
+	Group int // index into the scene's LightGroups, or -1 if the light is in no group
+	Light light.Light
+}
+
+// LightData holds information about a light which is not part of the scene's geometry
+type LightData struct {
+	Name      string      `json:"name"`
+	GroupName string      `json:"group"` // lights in a group also have their contributions written to a separate image
+	TypeName  string      `json:"type"`
+	Data      interface{} `json:"data"`
+}
+
+// EnvironmentData holds information about the light arriving from outside the scene
+type EnvironmentData struct {
+	Name      string      `json:"name"`  // name objects link to the environment's light by, "environment" if none is given
+	GroupName string      `json:"group"` // light from the environment in a group is also written to a separate image
+	TypeName  string      `json:"type"`
+	Data      interface{} `json:"data"`
 }
 
 // ObjectMaterial is a temporary holding structure to link together geometry objects and materials
 type ObjectMaterial struct {
-	ObjectName   string `json:"object_name"`
-	MaterialName string `json:"material_name"`
+	ObjectName   string   `json:"object_name"`
+	MaterialName string   `json:"material_name"`
+	LightInclude []string `json:"light_include"` // names of the only lights which illuminate the object, all of them if empty
+	LightExclude []string `json:"light_exclude"` // names of lights which do not illuminate the object
+	LightGroup   string   `json:"light_group"`   // light group the object's own light is written to, if it gives out any
 }
 
 // CameraData holds a reference to the Camera struct and name
@@ -80,11 +119,14 @@ type ObjectData struct {
 
 // MaterialData holds information about a material
 type MaterialData struct {
-	Name                   string      `json:"name"`
-	TypeName               string      `json:"type"`
-	ReflectanceTextureName string      `json:"reflectance_texture_name"`
-	EmittanceTextureName   string      `json:"emittance_texture_name"`
-	Data                   interface{} `json:"data"`
+	Name                     string      `json:"name"`
+	TypeName                 string      `json:"type"`
+	ReflectanceTextureName   string      `json:"reflectance_texture_name"`
+	EmittanceTextureName     string      `json:"emittance_texture_name"`
+	FilmThicknessTextureName string      `json:"film_thickness_texture_name"`
+	NormalMapTextureName     string      `json:"normal_map_texture_name"`
+	BumpMapTextureName       string      `json:"bump_map_texture_name"`
+	Data                     interface{} `json:"data"`
 }
 
 // TextureData holds information about a texture
@@ -114,7 +156,7 @@ func LoadConfigs(
 		return nil, err
 	}
 	fmt.Printf("\tLoading Objects...\n")
-	totalObjects, err := loadObjects(objectsFileName)
+	totalObjects, err := loadObjects(objectsFileName, parameters.TextureGamma)
 	if err != nil {
 		return nil, err
 	}
@@ -137,6 +179,25 @@ func LoadConfigs(
 		return nil, err
 	}
 
+	// a glTF file brings its own meshes and cameras, and its first camera is used if the scene names none
+	var imported *gltfImport
+	if parameters.Scene.GLTFFileName != "" {
+		fmt.Printf("\tLoading glTF...\n")
+		imported, err = loadGLTF(parameters.Scene.GLTFFileName, parameters.TextureGamma)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range imported.cameraNames {
+			if _, ok := totalCameras[name]; ok {
+				return nil, fmt.Errorf("camera (%s) in %s redefined", name, parameters.Scene.GLTFFileName)
+			}
+			totalCameras[name] = imported.cameras[name]
+		}
+		if parameters.Scene.CameraName == "" && len(imported.cameraNames) > 0 {
+			parameters.Scene.CameraName = imported.cameraNames[0]
+		}
+	}
+
 	// select the correct camera and initialize it
 	selectedCamera, exists := totalCameras[parameters.Scene.CameraName]
 	if !exists {
@@ -148,6 +209,41 @@ func LoadConfigs(
 		return nil, err
 	}
 
+	// scenes without an environment of their own are surrounded by the background color
+	parameters.Scene.Environment, err = loadEnvironment(parameters.Scene.EnvironmentData, parameters.BackgroundColor, totalTextures)
+	if err != nil {
+		return nil, err
+	}
+	parameters.Scene.Lights, parameters.Scene.LightGroups, err = loadLights(parameters.Scene.LightsData)
+	if err != nil {
+		return nil, err
+	}
+	parameters.Scene.EnvironmentSource, parameters.Scene.LightGroups, err = loadEnvironmentSource(
+		parameters.Scene.EnvironmentData, parameters.Scene.Lights, parameters.Scene.LightGroups)
+	if err != nil {
+		return nil, err
+	}
+	// objects may be linked to the scene's lights, its environment, or other objects giving out light,
+	// and only objects something is linked to, or whose light is grouped, need to carry their names
+	sourceNames := map[string]bool{
+		parameters.Scene.EnvironmentSource.Name: true,
+	}
+	for _, sl := range parameters.Scene.Lights {
+		sourceNames[sl.Name] = true
+	}
+	for _, om := range parameters.Scene.ObjectMaterials {
+		sourceNames[om.ObjectName] = true
+	}
+	linkedNames := map[string]bool{}
+	for _, om := range parameters.Scene.ObjectMaterials {
+		for _, name := range append(append([]string{}, om.LightInclude...), om.LightExclude...) {
+			if !sourceNames[name] {
+				return nil, fmt.Errorf("light (%s) linked to object (%s) is not defined", name, om.ObjectName)
+			}
+			linkedNames[name] = true
+		}
+	}
+
 	// loop over the loosely connected ObjectMaterials and parse the proper materials into the primitives they represent
 
 	// most geometry objects are "bounded" meaning an AABB (Axis-Aligned Bounding Box) can be placed around them.
@@ -156,6 +252,7 @@ func LoadConfigs(
 	// a distinction must be made between these to prevent assembling a BVH or other acceleration structure
 	// without a bounding box around certain primitives
 	unboundedSceneObjects := &primitivelist.PrimitiveList{}
+	unlitMaterials := map[string]bool{}
 	for _, om := range parameters.Scene.ObjectMaterials {
 		// grab the labelled objects and materials
 		selectedObject, exists := totalObjects[om.ObjectName]
@@ -172,15 +269,65 @@ func LoadConfigs(
 		// transmission commponent can be reversed
 		// this is an arbitrary restriction that is likely to be removed in the future with the user choosing to self-restrict
 		// themselves in a similar manner
-		if reflect.TypeOf(selectedMaterial) == reflect.TypeOf(&material.Dielectric{}) {
+		if reflect.TypeOf(selectedMaterial) == reflect.TypeOf(&material.Dielectric{}) ||
+			reflect.TypeOf(selectedMaterial) == reflect.TypeOf(&material.Subsurface{}) {
 			if !selectedObject.IsClosed() {
 				return nil, fmt.Errorf("cannot attach refractive or volumetric materials (%s) to non-closed geometry (%s)",
 					om.MaterialName, om.ObjectName)
 			}
 		}
+		// point, spot, and directional lights are only sampled from surfaces whose scattering can be evaluated
+		if _, ok := directEvaluator(selectedMaterial); !ok && len(parameters.Scene.Lights) > 0 && !unlitMaterials[om.MaterialName] {
+			if _, ok := selectedMaterial.(*material.Emissive); !ok {
+				fmt.Printf("\t\tMaterial (%s) is not lit by the scene's point, spot, or directional lights\n", om.MaterialName)
+				unlitMaterials[om.MaterialName] = true
+			}
+		}
+		// lights specified by their power need to know the size of the object they're attached to,
+		// so each object gets its own copy of the material with the correct radiance
+		if emissive, ok := selectedMaterial.(*material.Emissive); ok && emissive.Power > 0.0 {
+			surface, ok := selectedObject.(primitive.Surface)
+			if !ok {
+				return nil, fmt.Errorf("cannot attach emissive material with power (%s) to geometry without a surface area (%s)",
+					om.MaterialName, om.ObjectName)
+			}
+			area, ok := surface.SurfaceArea()
+			if !ok {
+				return nil, fmt.Errorf("cannot attach emissive material with power (%s) to geometry without a surface area (%s)",
+					om.MaterialName, om.ObjectName)
+			}
+			selectedMaterial, err = emissive.WithArea(area)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		// copy the object so we don't override it's material if it is reused in the scene
 		newPrimitive := selectedObject.Copy()
 		newPrimitive.SetMaterial(selectedMaterial)
+		// objects lit by only some of the scene's lights, and objects whose own light is linked or grouped,
+		// carry the link and their name with every hit
+		var link *material.LightLink
+		if len(om.LightInclude) > 0 || len(om.LightExclude) > 0 {
+			link = &material.LightLink{
+				Include: om.LightInclude,
+				Exclude: om.LightExclude,
+			}
+		}
+		var source *material.LightSource
+		if om.LightGroup != "" || linkedNames[om.ObjectName] {
+			source = &material.LightSource{
+				Name: om.ObjectName,
+			}
+			source.Group, parameters.Scene.LightGroups = lightGroup(parameters.Scene.LightGroups, om.LightGroup)
+		}
+		if link != nil || source != nil {
+			newPrimitive = &lightlink.Linked{
+				Primitive: newPrimitive,
+				Link:      link,
+				Source:    source,
+			}
+		}
 		// added to the cooresponding list based on type
 		if newPrimitive.IsInfinite() {
 			unboundedSceneObjects.List = append(unboundedSceneObjects.List, newPrimitive)
@@ -188,6 +335,10 @@ func LoadConfigs(
 			boundedSceneObjects.List = append(boundedSceneObjects.List, newPrimitive)
 		}
 	}
+	// imported meshes already carry their own materials
+	if imported != nil {
+		boundedSceneObjects.List = append(boundedSceneObjects.List, imported.objects...)
+	}
 
 	// START MANUAL INSERT
 
@@ -216,7 +367,7 @@ func LoadConfigs(
 	// if we are using a BVH ...
 	if parameters.UseBVH {
 		// ... construct it from the bounded objects ..
-		sceneBVH, err := bvh.New(boundedSceneObjects)
+		sceneBVH, err := bvh.New(boundedSceneObjects, parameters.Scene.Camera.ShutterOpen, parameters.Scene.Camera.ShutterClose)
 		if err != nil {
 			return nil, err
 		}
@@ -261,7 +412,7 @@ func loadCameras(fileName string) (map[string]*Camera, error) {
 	return camerasMap, nil
 }
 
-func loadObjects(fileName string) (map[string]primitive.Primitive, error) {
+func loadObjects(fileName string, tGamma float64) (map[string]primitive.Primitive, error) {
 	objectsBytes, err := ioutil.ReadFile(fileName)
 	if err != nil {
 		return nil, err
@@ -276,7 +427,8 @@ func loadObjects(fileName string) (map[string]primitive.Primitive, error) {
 		if _, ok := objectsMap[o.Name]; ok {
 			return nil, fmt.Errorf("object (%s) redefined", o.Name)
 		}
-		newPrimitive, err := decodeObject(o.TypeName, o.Data)
+		// instances can place any object defined before them
+		newPrimitive, err := decodeObject(o.TypeName, o.Data, tGamma, objectsMap)
 		if err != nil {
 			return nil, err
 		}
@@ -285,7 +437,7 @@ func loadObjects(fileName string) (map[string]primitive.Primitive, error) {
 	return objectsMap, nil
 }
 
-func decodeObject(typeName string, data interface{}) (primitive.Primitive, error) {
+func decodeObject(typeName string, data interface{}, tGamma float64, objects map[string]primitive.Primitive) (primitive.Primitive, error) {
 	switch typeName {
 	case "Box":
 		var b box.Box
@@ -371,6 +523,21 @@ func decodeObject(typeName string, data interface{}) (primitive.Primitive, error
 			return nil, err
 		}
 		return newHollowDisk, nil
+	case "Mesh":
+		var m mesh.Mesh
+		dataBytes, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		json.Unmarshal(dataBytes, &m)
+		newMesh, err := m.Setup(tGamma)
+		if err != nil {
+			return nil, err
+		}
+		if newMesh.DegenerateFaces() > 0 {
+			fmt.Printf("\t\tSkipped %d degenerate faces in %s\n", newMesh.DegenerateFaces(), m.FileName)
+		}
+		return newMesh, nil
 	case "Plane":
 		var p plane.Plane
 		dataBytes, err := json.Marshal(data)
@@ -438,7 +605,7 @@ func decodeObject(typeName string, data interface{}) (primitive.Primitive, error
 			return nil, err
 		}
 		json.Unmarshal(dataBytes, &t)
-		corePrimitive, err := decodeObject(t.TypeName, t.Data)
+		corePrimitive, err := decodeObject(t.TypeName, t.Data, tGamma, objects)
 		if err != nil {
 			return nil, err
 		}
@@ -455,7 +622,7 @@ func decodeObject(typeName string, data interface{}) (primitive.Primitive, error
 			return nil, err
 		}
 		json.Unmarshal(dataBytes, &rx)
-		corePrimitive, err := decodeObject(rx.TypeName, rx.Data)
+		corePrimitive, err := decodeObject(rx.TypeName, rx.Data, tGamma, objects)
 		if err != nil {
 			return nil, err
 		}
@@ -472,7 +639,7 @@ func decodeObject(typeName string, data interface{}) (primitive.Primitive, error
 			return nil, err
 		}
 		json.Unmarshal(dataBytes, &ry)
-		corePrimitive, err := decodeObject(ry.TypeName, ry.Data)
+		corePrimitive, err := decodeObject(ry.TypeName, ry.Data, tGamma, objects)
 		if err != nil {
 			return nil, err
 		}
@@ -489,7 +656,7 @@ func decodeObject(typeName string, data interface{}) (primitive.Primitive, error
 			return nil, err
 		}
 		json.Unmarshal(dataBytes, &rz)
-		corePrimitive, err := decodeObject(rz.TypeName, rz.Data)
+		corePrimitive, err := decodeObject(rz.TypeName, rz.Data, tGamma, objects)
 		if err != nil {
 			return nil, err
 		}
@@ -506,7 +673,7 @@ func decodeObject(typeName string, data interface{}) (primitive.Primitive, error
 			return nil, err
 		}
 		json.Unmarshal(dataBytes, &q)
-		corePrimitive, err := decodeObject(q.TypeName, q.Data)
+		corePrimitive, err := decodeObject(q.TypeName, q.Data, tGamma, objects)
 		if err != nil {
 			return nil, err
 		}
@@ -516,6 +683,63 @@ func decodeObject(typeName string, data interface{}) (primitive.Primitive, error
 			return nil, err
 		}
 		return newRotationZ, nil
+	case "Transform":
+		var t affine.Transform
+		dataBytes, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		json.Unmarshal(dataBytes, &t)
+		corePrimitive, err := decodeObject(t.TypeName, t.Data, tGamma, objects)
+		if err != nil {
+			return nil, err
+		}
+		t.Primitive = corePrimitive
+		newTransform, err := (&t).Setup()
+		if err != nil {
+			return nil, err
+		}
+		return newTransform, nil
+	case "Instance":
+		var i instance.Instance
+		dataBytes, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		json.Unmarshal(dataBytes, &i)
+		sharedPrimitive, ok := objects[i.ObjectName]
+		if !ok {
+			return nil, fmt.Errorf("instanced object (%s) is not defined before its instance", i.ObjectName)
+		}
+		// the instance points at the object itself, so its geometry is never copied
+		i.Primitive = sharedPrimitive
+		newInstance, err := (&i).Setup()
+		if err != nil {
+			return nil, err
+		}
+		return newInstance, nil
+	case "CSG":
+		var c csg.CSG
+		dataBytes, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		json.Unmarshal(dataBytes, &c)
+		leftPrimitive, err := decodeObject(c.LeftOperand.TypeName, c.LeftOperand.Data, tGamma, objects)
+		if err != nil {
+			return nil, err
+		}
+		rightPrimitive, err := decodeObject(c.RightOperand.TypeName, c.RightOperand.Data, tGamma, objects)
+		if err != nil {
+			return nil, err
+		}
+		c.Left = leftPrimitive
+		c.Right = rightPrimitive
+		newCSG, err := (&c).Setup()
+		if err != nil {
+			return nil, err
+		}
+		return newCSG, nil
 	default:
 		return nil, fmt.Errorf("type (%s) not a valid primitive type", typeName)
 	}
@@ -532,6 +756,8 @@ func loadTextures(fileName string, tGamma float64) (map[string]texture.Texture,
 		return nil, err
 	}
 	texturesMap := map[string]texture.Texture{}
+	// textures which use the same image file share one decoded copy of it
+	imageCache := texture.NewCache()
 	for _, t := range texturesData {
 		if _, ok := texturesMap[t.Name]; ok {
 			return nil, fmt.Errorf("texture (%s) redefined", t.Name)
@@ -558,15 +784,184 @@ func loadTextures(fileName string, tGamma float64) (map[string]texture.Texture,
 			if i.Gamma == 0.0 {
 				i.Gamma = tGamma
 			}
-			err = i.Load()
+			err = i.Load(imageCache)
 			if err != nil {
 				return nil, err
 			}
 			texturesMap[t.Name] = &i
+		case "Checker":
+			var ct texture.Checker
+			dataBytes, err := json.Marshal(t.Data)
+			if err != nil {
+				return nil, err
+			}
+			json.Unmarshal(dataBytes, &ct)
+			newChecker, err := (&ct).Setup()
+			if err != nil {
+				return nil, err
+			}
+			texturesMap[t.Name] = newChecker
+		case "Gradient":
+			var gt texture.Gradient
+			dataBytes, err := json.Marshal(t.Data)
+			if err != nil {
+				return nil, err
+			}
+			json.Unmarshal(dataBytes, &gt)
+			newGradient, err := (&gt).Setup()
+			if err != nil {
+				return nil, err
+			}
+			texturesMap[t.Name] = newGradient
+		case "Noise":
+			var nt texture.Noise
+			dataBytes, err := json.Marshal(t.Data)
+			if err != nil {
+				return nil, err
+			}
+			json.Unmarshal(dataBytes, &nt)
+			newNoise, err := (&nt).Setup()
+			if err != nil {
+				return nil, err
+			}
+			texturesMap[t.Name] = newNoise
+		case "Marble":
+			var mt texture.Marble
+			dataBytes, err := json.Marshal(t.Data)
+			if err != nil {
+				return nil, err
+			}
+			json.Unmarshal(dataBytes, &mt)
+			newMarble, err := (&mt).Setup()
+			if err != nil {
+				return nil, err
+			}
+			texturesMap[t.Name] = newMarble
+		case "Wood":
+			var wt texture.Wood
+			dataBytes, err := json.Marshal(t.Data)
+			if err != nil {
+				return nil, err
+			}
+			json.Unmarshal(dataBytes, &wt)
+			newWood, err := (&wt).Setup()
+			if err != nil {
+				return nil, err
+			}
+			texturesMap[t.Name] = newWood
+		case "Voronoi":
+			var vt texture.Voronoi
+			dataBytes, err := json.Marshal(t.Data)
+			if err != nil {
+				return nil, err
+			}
+			json.Unmarshal(dataBytes, &vt)
+			newVoronoi, err := (&vt).Setup()
+			if err != nil {
+				return nil, err
+			}
+			texturesMap[t.Name] = newVoronoi
+		case "Multiply":
+			var mt texture.Multiply
+			dataBytes, err := json.Marshal(t.Data)
+			if err != nil {
+				return nil, err
+			}
+			json.Unmarshal(dataBytes, &mt)
+			newMultiply, err := (&mt).Setup(texturesMap)
+			if err != nil {
+				return nil, err
+			}
+			texturesMap[t.Name] = newMultiply
+		case "Add":
+			var at texture.Add
+			dataBytes, err := json.Marshal(t.Data)
+			if err != nil {
+				return nil, err
+			}
+			json.Unmarshal(dataBytes, &at)
+			newAdd, err := (&at).Setup(texturesMap)
+			if err != nil {
+				return nil, err
+			}
+			texturesMap[t.Name] = newAdd
+		case "Mix":
+			var mx texture.Mix
+			dataBytes, err := json.Marshal(t.Data)
+			if err != nil {
+				return nil, err
+			}
+			json.Unmarshal(dataBytes, &mx)
+			newMix, err := (&mx).Setup(texturesMap)
+			if err != nil {
+				return nil, err
+			}
+			texturesMap[t.Name] = newMix
+		case "Invert":
+			var it texture.Invert
+			dataBytes, err := json.Marshal(t.Data)
+			if err != nil {
+				return nil, err
+			}
+			json.Unmarshal(dataBytes, &it)
+			newInvert, err := (&it).Setup(texturesMap)
+			if err != nil {
+				return nil, err
+			}
+			texturesMap[t.Name] = newInvert
+		case "Adjust":
+			var aj texture.Adjust
+			dataBytes, err := json.Marshal(t.Data)
+			if err != nil {
+				return nil, err
+			}
+			json.Unmarshal(dataBytes, &aj)
+			newAdjust, err := (&aj).Setup(texturesMap)
+			if err != nil {
+				return nil, err
+			}
+			texturesMap[t.Name] = newAdjust
+		case "Channel":
+			var ch texture.Channel
+			dataBytes, err := json.Marshal(t.Data)
+			if err != nil {
+				return nil, err
+			}
+			json.Unmarshal(dataBytes, &ch)
+			newChannel, err := (&ch).Setup(texturesMap)
+			if err != nil {
+				return nil, err
+			}
+			texturesMap[t.Name] = newChannel
+		case "ColorRamp":
+			var cr texture.ColorRamp
+			dataBytes, err := json.Marshal(t.Data)
+			if err != nil {
+				return nil, err
+			}
+			json.Unmarshal(dataBytes, &cr)
+			newColorRamp, err := (&cr).Setup(texturesMap)
+			if err != nil {
+				return nil, err
+			}
+			texturesMap[t.Name] = newColorRamp
+		case "Transform":
+			var tt texture.Transform
+			dataBytes, err := json.Marshal(t.Data)
+			if err != nil {
+				return nil, err
+			}
+			json.Unmarshal(dataBytes, &tt)
+			newTransform, err := (&tt).Setup(texturesMap)
+			if err != nil {
+				return nil, err
+			}
+			texturesMap[t.Name] = newTransform
 		default:
 			return nil, fmt.Errorf("type (%s) not a valid texture type", t.TypeName)
 		}
 	}
+	fmt.Printf("\t\tDecoded %d images into %.1f MiB of texels\n", imageCache.Len(), float64(imageCache.Bytes())/(1024.0*1024.0))
 	return texturesMap, nil
 }
 
@@ -617,7 +1012,83 @@ func loadMaterials(fileName, texturesFileName string, texturesMap map[string]tex
 					return nil, fmt.Errorf("selected Texture (%s) not in %s", m.EmittanceTextureName, texturesFileName)
 				}
 			}
+			err = l.UVTransform.SetupUVTransform()
+			if err != nil {
+				return nil, err
+			}
+			err = loadBump(&l.Bump, m, texturesFileName, texturesMap)
+			if err != nil {
+				return nil, err
+			}
 			materialsMap[m.Name] = &l
+		case "OrenNayar":
+			var on material.OrenNayar
+			dataBytes, err := json.Marshal(m.Data)
+			if err != nil {
+				return nil, err
+			}
+			json.Unmarshal(dataBytes, &on)
+			var ok bool
+			if m.ReflectanceTextureName == "" {
+				on.ReflectanceTexture, ok = texturesMap["default"]
+				if !ok {
+					return nil, fmt.Errorf("selected Texture (%s) not in %s", "default", texturesFileName)
+				}
+			} else {
+				on.ReflectanceTexture, ok = texturesMap[m.ReflectanceTextureName]
+				if !ok {
+					return nil, fmt.Errorf("selected Texture (%s) not in %s", m.ReflectanceTextureName, texturesFileName)
+				}
+			}
+
+			if m.EmittanceTextureName == "" {
+				on.EmittanceTexture, ok = texturesMap["default"]
+				if !ok {
+					return nil, fmt.Errorf("selected Texture (%s) not in %s", "default", texturesFileName)
+				}
+			} else {
+				on.EmittanceTexture, ok = texturesMap[m.EmittanceTextureName]
+				if !ok {
+					return nil, fmt.Errorf("selected Texture (%s) not in %s", m.EmittanceTextureName, texturesFileName)
+				}
+			}
+			err = on.UVTransform.SetupUVTransform()
+			if err != nil {
+				return nil, err
+			}
+			err = loadBump(&on.Bump, m, texturesFileName, texturesMap)
+			if err != nil {
+				return nil, err
+			}
+			newOrenNayar, err := (&on).Setup()
+			if err != nil {
+				return nil, err
+			}
+			materialsMap[m.Name] = newOrenNayar
+		case "Emissive":
+			var e material.Emissive
+			dataBytes, err := json.Marshal(m.Data)
+			if err != nil {
+				return nil, err
+			}
+			json.Unmarshal(dataBytes, &e)
+			if m.EmittanceTextureName == "" {
+				return nil, fmt.Errorf("emissive material (%s) has no emittance texture", m.Name)
+			}
+			var ok bool
+			e.EmittanceTexture, ok = texturesMap[m.EmittanceTextureName]
+			if !ok {
+				return nil, fmt.Errorf("selected Texture (%s) not in %s", m.EmittanceTextureName, texturesFileName)
+			}
+			err = e.UVTransform.SetupUVTransform()
+			if err != nil {
+				return nil, err
+			}
+			newEmissive, err := (&e).Setup()
+			if err != nil {
+				return nil, err
+			}
+			materialsMap[m.Name] = newEmissive
 		case "Metal":
 			var mtl material.Metal
 			dataBytes, err := json.Marshal(m.Data)
@@ -650,6 +1121,24 @@ func loadMaterials(fileName, texturesFileName string, texturesMap map[string]tex
 					return nil, fmt.Errorf("selected Texture (%s) not in %s", m.EmittanceTextureName, texturesFileName)
 				}
 			}
+			if m.FilmThicknessTextureName != "" {
+				mtl.FilmThicknessTexture, ok = texturesMap[m.FilmThicknessTextureName]
+				if !ok {
+					return nil, fmt.Errorf("selected Texture (%s) not in %s", m.FilmThicknessTextureName, texturesFileName)
+				}
+			}
+			err = mtl.UVTransform.SetupUVTransform()
+			if err != nil {
+				return nil, err
+			}
+			err = mtl.SetupFilm()
+			if err != nil {
+				return nil, err
+			}
+			err = loadBump(&mtl.Bump, m, texturesFileName, texturesMap)
+			if err != nil {
+				return nil, err
+			}
 			materialsMap[m.Name] = &mtl
 		case "Dielectric":
 			var d material.Dielectric
@@ -681,7 +1170,123 @@ func loadMaterials(fileName, texturesFileName string, texturesMap map[string]tex
 					return nil, fmt.Errorf("selected Texture (%s) not in %s", m.EmittanceTextureName, texturesFileName)
 				}
 			}
+			if m.FilmThicknessTextureName != "" {
+				d.FilmThicknessTexture, ok = texturesMap[m.FilmThicknessTextureName]
+				if !ok {
+					return nil, fmt.Errorf("selected Texture (%s) not in %s", m.FilmThicknessTextureName, texturesFileName)
+				}
+			}
+			err = d.UVTransform.SetupUVTransform()
+			if err != nil {
+				return nil, err
+			}
+			err = d.SetupFilm()
+			if err != nil {
+				return nil, err
+			}
+			err = loadBump(&d.Bump, m, texturesFileName, texturesMap)
+			if err != nil {
+				return nil, err
+			}
 			materialsMap[m.Name] = &d
+		case "Subsurface":
+			var ss material.Subsurface
+			dataBytes, err := json.Marshal(m.Data)
+			if err != nil {
+				return nil, err
+			}
+			json.Unmarshal(dataBytes, &ss)
+			var ok bool
+			if m.ReflectanceTextureName == "" {
+				ss.ReflectanceTexture, ok = texturesMap["default"]
+				if !ok {
+					return nil, fmt.Errorf("selected Texture (%s) not in %s", "default", texturesFileName)
+				}
+			} else {
+				ss.ReflectanceTexture, ok = texturesMap[m.ReflectanceTextureName]
+				if !ok {
+					return nil, fmt.Errorf("selected Texture (%s) not in %s", m.ReflectanceTextureName, texturesFileName)
+				}
+			}
+			if m.EmittanceTextureName == "" {
+				ss.EmittanceTexture, ok = texturesMap["default"]
+				if !ok {
+					return nil, fmt.Errorf("selected Texture (%s) not in %s", "default", texturesFileName)
+				}
+			} else {
+				ss.EmittanceTexture, ok = texturesMap[m.EmittanceTextureName]
+				if !ok {
+					return nil, fmt.Errorf("selected Texture (%s) not in %s", m.EmittanceTextureName, texturesFileName)
+				}
+			}
+			if m.FilmThicknessTextureName != "" {
+				ss.FilmThicknessTexture, ok = texturesMap[m.FilmThicknessTextureName]
+				if !ok {
+					return nil, fmt.Errorf("selected Texture (%s) not in %s", m.FilmThicknessTextureName, texturesFileName)
+				}
+			}
+			err = ss.UVTransform.SetupUVTransform()
+			if err != nil {
+				return nil, err
+			}
+			err = loadBump(&ss.Bump, m, texturesFileName, texturesMap)
+			if err != nil {
+				return nil, err
+			}
+			newSubsurface, err := (&ss).Setup()
+			if err != nil {
+				return nil, err
+			}
+			materialsMap[m.Name] = newSubsurface
+		case "ThinFilm":
+			var tf material.ThinFilm
+			dataBytes, err := json.Marshal(m.Data)
+			if err != nil {
+				return nil, err
+			}
+			json.Unmarshal(dataBytes, &tf)
+			var ok bool
+			if m.ReflectanceTextureName == "" {
+				tf.ReflectanceTexture, ok = texturesMap["default"]
+				if !ok {
+					return nil, fmt.Errorf("selected Texture (%s) not in %s", "default", texturesFileName)
+				}
+			} else {
+				tf.ReflectanceTexture, ok = texturesMap[m.ReflectanceTextureName]
+				if !ok {
+					return nil, fmt.Errorf("selected Texture (%s) not in %s", m.ReflectanceTextureName, texturesFileName)
+				}
+			}
+			if m.EmittanceTextureName == "" {
+				tf.EmittanceTexture, ok = texturesMap["default"]
+				if !ok {
+					return nil, fmt.Errorf("selected Texture (%s) not in %s", "default", texturesFileName)
+				}
+			} else {
+				tf.EmittanceTexture, ok = texturesMap[m.EmittanceTextureName]
+				if !ok {
+					return nil, fmt.Errorf("selected Texture (%s) not in %s", m.EmittanceTextureName, texturesFileName)
+				}
+			}
+			if m.FilmThicknessTextureName != "" {
+				tf.FilmThicknessTexture, ok = texturesMap[m.FilmThicknessTextureName]
+				if !ok {
+					return nil, fmt.Errorf("selected Texture (%s) not in %s", m.FilmThicknessTextureName, texturesFileName)
+				}
+			}
+			err = tf.UVTransform.SetupUVTransform()
+			if err != nil {
+				return nil, err
+			}
+			err = loadBump(&tf.Bump, m, texturesFileName, texturesMap)
+			if err != nil {
+				return nil, err
+			}
+			newThinFilm, err := (&tf).Setup()
+			if err != nil {
+				return nil, err
+			}
+			materialsMap[m.Name] = newThinFilm
 		default:
 			return nil, fmt.Errorf("type (%s) not a valid material type", m.TypeName)
 		}
@@ -689,6 +1294,33 @@ func loadMaterials(fileName, texturesFileName string, texturesMap map[string]tex
 	return materialsMap, nil
 }
 
+// loadBump looks up the normal map and bump map textures of a material, if it has either
+func loadBump(b *material.Bump, m MaterialData, texturesFileName string, texturesMap map[string]texture.Texture) error {
+	if m.NormalMapTextureName != "" && m.BumpMapTextureName != "" {
+		return fmt.Errorf("material (%s) has both a normal map and a bump map", m.Name)
+	}
+	var ok bool
+	if m.NormalMapTextureName != "" {
+		b.NormalMapTexture, ok = texturesMap[m.NormalMapTextureName]
+		if !ok {
+			return fmt.Errorf("selected Texture (%s) not in %s", m.NormalMapTextureName, texturesFileName)
+		}
+		if b.NormalMapScale == 0.0 {
+			b.NormalMapScale = 1.0
+		}
+	}
+	if m.BumpMapTextureName != "" {
+		b.BumpMapTexture, ok = texturesMap[m.BumpMapTextureName]
+		if !ok {
+			return fmt.Errorf("selected Texture (%s) not in %s", m.BumpMapTextureName, texturesFileName)
+		}
+		if b.BumpScale == 0.0 {
+			b.BumpScale = 1.0
+		}
+	}
+	return nil
+}
+
 func loadParameters(fileName string) (*Parameters, error) {
 	parametersBytes, err := ioutil.ReadFile(fileName)
 	if err != nil {
@@ -703,6 +1335,129 @@ func loadParameters(fileName string) (*Parameters, error) {
 	return &parameters, nil
 }
 
+func loadEnvironment(e *EnvironmentData, backgroundColor shading.Color, texturesMap map[string]texture.Texture) (light.Environment, error) {
+	if e == nil {
+		return &light.Uniform{
+			Color: backgroundColor,
+		}, nil
+	}
+	switch e.TypeName {
+	case "Uniform":
+		var u light.Uniform
+		dataBytes, err := json.Marshal(e.Data)
+		if err != nil {
+			return nil, err
+		}
+		json.Unmarshal(dataBytes, &u)
+		return &u, nil
+	case "EnvironmentMap":
+		var em light.EnvironmentMap
+		dataBytes, err := json.Marshal(e.Data)
+		if err != nil {
+			return nil, err
+		}
+		json.Unmarshal(dataBytes, &em)
+		newEnvironmentMap, err := (&em).Setup(texturesMap)
+		if err != nil {
+			return nil, err
+		}
+		return newEnvironmentMap, nil
+	case "Sky":
+		var sky light.Sky
+		dataBytes, err := json.Marshal(e.Data)
+		if err != nil {
+			return nil, err
+		}
+		json.Unmarshal(dataBytes, &sky)
+		newSky, err := (&sky).Setup()
+		if err != nil {
+			return nil, err
+		}
+		return newSky, nil
+	default:
+		return nil, fmt.Errorf("type (%s) not a valid environment type", e.TypeName)
+	}
+}
+
+func loadLights(lightsData []*LightData) ([]*SceneLight, []string, error) {
+	lights := []*SceneLight{}
+	groups := []string{}
+	names := map[string]bool{}
+	for _, l := range lightsData {
+		if names[l.Name] {
+			return nil, nil, fmt.Errorf("light (%s) redefined", l.Name)
+		}
+		names[l.Name] = true
+		dataBytes, err := json.Marshal(l.Data)
+		if err != nil {
+			return nil, nil, err
+		}
+		var newLight light.Light
+		switch l.TypeName {
+		case "Point":
+			var pl light.Point
+			json.Unmarshal(dataBytes, &pl)
+			newLight, err = (&pl).Setup()
+		case "Spot":
+			var sl light.Spot
+			json.Unmarshal(dataBytes, &sl)
+			newLight, err = (&sl).Setup()
+		case "Directional":
+			var dl light.Directional
+			json.Unmarshal(dataBytes, &dl)
+			newLight, err = (&dl).Setup()
+		default:
+			return nil, nil, fmt.Errorf("type (%s) not a valid light type", l.TypeName)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		var group int
+		group, groups = lightGroup(groups, l.GroupName)
+		lights = append(lights, &SceneLight{
+			Name:  l.Name,
+			Group: group,
+			Light: newLight,
+		})
+	}
+	return lights, groups, nil
+}
+
+// lightGroup returns the index of the named light group, adding it to the groups if it is new
+// no name means no group, with index -1
+func lightGroup(groups []string, name string) (int, []string) {
+	if name == "" {
+		return -1, groups
+	}
+	for i, groupName := range groups {
+		if groupName == name {
+			return i, groups
+		}
+	}
+	return len(groups), append(groups, name)
+}
+
+// loadEnvironmentSource names the environment's light and places it in its light group
+func loadEnvironmentSource(e *EnvironmentData, lights []*SceneLight, groups []string) (*material.LightSource, []string, error) {
+	source := &material.LightSource{
+		Name:  "environment",
+		Group: -1,
+	}
+	if e == nil {
+		return source, groups, nil
+	}
+	if e.Name != "" {
+		source.Name = e.Name
+	}
+	for _, sl := range lights {
+		if sl.Name == source.Name {
+			return nil, nil, fmt.Errorf("light (%s) has the same name as the environment", sl.Name)
+		}
+	}
+	source.Group, groups = lightGroup(groups, e.GroupName)
+	return source, groups, nil
+}
+
 func loadScene(fileName string) (*Scene, error) {
 	sceneBytes, err := ioutil.ReadFile(fileName)
 	if err != nil {
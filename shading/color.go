@@ -16,6 +16,9 @@ type Color struct {
 // ColorBlack is a simple reference to an all-black Color
 var ColorBlack = Color{0.0, 0.0, 0.0}
 
+// ColorWhite is a simple reference to an all-white Color
+var ColorWhite = Color{1.0, 1.0, 1.0}
+
 // Add adds values from two Colors together
 func (c Color) Add(d Color) Color {
 	return Color{c.Red + d.Red, c.Green + d.Green, c.Blue + d.Blue}
@@ -0,0 +1,36 @@
+package light
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"fmt"
+	"math"
+)
+
+// Directional is a Light infinitely far away, so that its light arrives everywhere from the same direction, like the sun's
+type Directional struct {
+	Direction geometry.Vector `json:"direction"` // direction the light travels in
+	Color     shading.Color   `json:"color"`
+	Intensity float64         `json:"intensity"` // amount to scale the color by, giving the light's irradiance
+	toLight   geometry.Vector
+}
+
+// Setup validates and defaults the light's fields
+func (dl *Directional) Setup() (*Directional, error) {
+	if dl.Direction == geometry.VectorZero {
+		return nil, fmt.Errorf("directional light direction is the zero vector")
+	}
+	if dl.Intensity < 0.0 {
+		return nil, fmt.Errorf("directional light intensity is negative")
+	}
+	if dl.Intensity == 0.0 {
+		dl.Intensity = 1.0
+	}
+	dl.toLight = dl.Direction.Unit().Negate()
+	return dl, nil
+}
+
+// Illuminate returns the direction to the light, which is infinitely far away, and the light arriving at the point from it
+func (dl *Directional) Illuminate(p geometry.Point) (geometry.Vector, float64, shading.Color) {
+	return dl.toLight, math.Inf(1), dl.Color.MultScalar(dl.Intensity)
+}
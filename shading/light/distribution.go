@@ -0,0 +1,199 @@
+package light
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// distribution1D is a piecewise-constant probability distribution over [0, 1)
+// made of equally wide pieces, each as likely to be chosen as its value is large
+type distribution1D struct {
+	values []float64
+	cdf    []float64 // cumulative probability at the start of each piece, and at the end of the last
+	total  float64   // integral of the values over [0, 1)
+}
+
+// newDistribution1D builds a distribution from the values of its pieces
+// a distribution of no weight at all chooses every piece equally
+func newDistribution1D(values []float64) distribution1D {
+	n := len(values)
+	d := distribution1D{
+		values: values,
+		cdf:    make([]float64, n+1),
+	}
+	for i, value := range values {
+		d.cdf[i+1] = d.cdf[i] + value/float64(n)
+	}
+	d.total = d.cdf[n]
+	for i := 1; i <= n; i++ {
+		if d.total == 0.0 {
+			d.cdf[i] = float64(i) / float64(n)
+		} else {
+			d.cdf[i] /= d.total
+		}
+	}
+	return d
+}
+
+// sample maps a uniform random number in [0, 1) to a point in [0, 1) chosen by the distribution
+// returning the point, its probability density, and the index of the piece holding it
+func (d distribution1D) sample(x float64) (float64, float64, int) {
+	// the last piece whose start is at or before x
+	i := sort.Search(len(d.cdf), func(i int) bool {
+		return d.cdf[i] > x
+	}) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i > len(d.values)-1 {
+		i = len(d.values) - 1
+	}
+	offset := x - d.cdf[i]
+	if width := d.cdf[i+1] - d.cdf[i]; width > 0.0 {
+		offset /= width
+	}
+	return (float64(i) + offset) / float64(len(d.values)), d.pdf(i), i
+}
+
+// pdf returns the probability density of any point within a piece
+func (d distribution1D) pdf(i int) float64 {
+	if d.total == 0.0 {
+		return 1.0
+	}
+	return d.values[i] / d.total
+}
+
+// distribution2D is a piecewise-constant probability distribution over [0, 1) x [0, 1)
+// a row is chosen by its total weight, and then a column within that row
+type distribution2D struct {
+	rows     []distribution1D
+	marginal distribution1D
+}
+
+// newDistribution2D builds a distribution from the values of its pieces, given row by row
+func newDistribution2D(values []float64, width, height int) *distribution2D {
+	d := &distribution2D{
+		rows: make([]distribution1D, height),
+	}
+	rowTotals := make([]float64, height)
+	for y := 0; y < height; y++ {
+		d.rows[y] = newDistribution1D(values[y*width : (y+1)*width])
+		rowTotals[y] = d.rows[y].total
+	}
+	d.marginal = newDistribution1D(rowTotals)
+	return d
+}
+
+// sample maps two uniform random numbers in [0, 1) to a point (u, v) chosen by the distribution
+// returning the point and its probability density
+func (d *distribution2D) sample(x, y float64) (float64, float64, float64) {
+	v, pdfV, row := d.marginal.sample(y)
+	u, pdfU, _ := d.rows[row].sample(x)
+	return u, v, pdfU * pdfV
+}
+
+// pdf returns the probability density of sample choosing the point (u, v)
+func (d *distribution2D) pdf(u, v float64) float64 {
+	row := clampIndex(int(v*float64(len(d.rows))), len(d.rows))
+	column := clampIndex(int(u*float64(len(d.rows[row].values))), len(d.rows[row].values))
+	return d.marginal.pdf(row) * d.rows[row].pdf(column)
+}
+
+// clampIndex keeps an index within [0, n)
+func clampIndex(i, n int) int {
+	if i < 0 {
+		return 0
+	}
+	if i >= n {
+		return n - 1
+	}
+	return i
+}
+
+// sphericalDistribution chooses directions in proportion to the brightness of an environment,
+// tabulated over a latitude-longitude grid
+type sphericalDistribution struct {
+	grid *distribution2D
+}
+
+// newSphericalDistribution tabulates the brightness of an environment over a grid with the given number of columns
+// each cell is weighted by the solid angle it covers, which shrinks towards the poles
+func newSphericalDistribution(resolution int, radiance func(geometry.Vector) shading.Color) *sphericalDistribution {
+	width := resolution
+	height := maxInt(width/2, 1)
+	values := make([]float64, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			// take the brightest of several directions across the cell, including its corners,
+			// so a cell only partly covered by a small bright spot is still likely to be chosen
+			brightest := 0.0
+			for sy := 0; sy <= 2; sy++ {
+				for sx := 0; sx <= 2; sx++ {
+					u := (float64(x) + 0.5*float64(sx)) / float64(width)
+					v := (float64(y) + 0.5*float64(sy)) / float64(height)
+					brightest = math.Max(brightest, luminance(radiance(latLongToDirection(u, v))))
+				}
+			}
+			elevation := math.Pi * ((float64(y)+0.5)/float64(height) - 0.5)
+			values[y*width+x] = brightest * math.Cos(elevation)
+		}
+	}
+	return &sphericalDistribution{
+		grid: newDistribution2D(values, width, height),
+	}
+}
+
+// sample chooses a unit direction, returning it and its probability density per steradian
+func (sd *sphericalDistribution) sample(rng *rand.Rand) (geometry.Vector, float64) {
+	u, v, pdf := sd.grid.sample(rng.Float64(), rng.Float64())
+	d := latLongToDirection(u, v)
+	cosElevation := math.Sqrt(math.Max(0.0, 1.0-d.Y*d.Y))
+	if pdf == 0.0 || cosElevation == 0.0 {
+		return d, 0.0
+	}
+	return d, pdf / (2.0 * math.Pi * math.Pi * cosElevation)
+}
+
+// pdf returns the probability density per steradian of sample choosing a unit direction
+func (sd *sphericalDistribution) pdf(d geometry.Vector) float64 {
+	cosElevation := math.Sqrt(math.Max(0.0, 1.0-d.Y*d.Y))
+	if cosElevation == 0.0 {
+		return 0.0
+	}
+	u, v := directionToLatLong(d)
+	return sd.grid.pdf(u, v) / (2.0 * math.Pi * math.Pi * cosElevation)
+}
+
+// directionToLatLong returns the equirectangular texture coordinates of a unit direction
+// U runs around the horizon starting behind the -Z axis, and V runs from straight down to straight up
+func directionToLatLong(d geometry.Vector) (float64, float64) {
+	u := 0.5 + math.Atan2(d.X, -d.Z)/(2.0*math.Pi)
+	v := 0.5 + math.Asin(math.Max(-1.0, math.Min(1.0, d.Y)))/math.Pi
+	return u, v
+}
+
+// latLongToDirection returns the unit direction with the given equirectangular texture coordinates
+func latLongToDirection(u, v float64) geometry.Vector {
+	azimuth := 2.0 * math.Pi * (u - 0.5)
+	elevation := math.Pi * (v - 0.5)
+	return geometry.Vector{
+		X: math.Cos(elevation) * math.Sin(azimuth),
+		Y: math.Sin(elevation),
+		Z: -math.Cos(elevation) * math.Cos(azimuth),
+	}
+}
+
+// luminance returns the brightness of a color as perceived by the eye
+func luminance(c shading.Color) float64 {
+	return 0.2126*c.Red + 0.7152*c.Green + 0.0722*c.Blue
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,114 @@
+package light
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestDistribution1DFrequencies(t *testing.T) {
+	values := []float64{1.0, 3.0, 0.0, 4.0}
+	d := newDistribution1D(values)
+	const samples = 8000
+	counts := make([]int, len(values))
+	for i := 0; i < samples; i++ {
+		x, pdf, piece := d.sample((float64(i) + 0.5) / samples)
+		if piece != clampIndex(int(x*float64(len(values))), len(values)) {
+			t.Fatalf("Expected point %f to lie in piece %d\n", x, piece)
+		}
+		if expected := values[piece] / 2.0; pdf != expected {
+			t.Fatalf("Expected pdf %f in piece %d but got %f\n", expected, piece, pdf)
+		}
+		counts[piece]++
+	}
+	// each piece covers a quarter of [0, 1), so is chosen a quarter of its density as often
+	for i, count := range counts {
+		frequency := float64(count) / samples
+		if expected := d.pdf(i) / float64(len(values)); math.Abs(frequency-expected) > 1e-3 {
+			t.Errorf("Expected piece %d to be chosen %f of the time but got %f\n", i, expected, frequency)
+		}
+	}
+	// with no weight at all, every piece is equally likely
+	empty := newDistribution1D([]float64{0.0, 0.0})
+	if x, pdf, _ := empty.sample(0.75); math.Abs(x-0.75) > 1e-9 || pdf != 1.0 {
+		t.Errorf("Expected point 0.75 with pdf 1 but got %f with pdf %f\n", x, pdf)
+	}
+}
+
+func TestDistribution2DFrequencies(t *testing.T) {
+	// values given row by row, so the bottom row holds 1 and 2, and the top row 3 and 6
+	d := newDistribution2D([]float64{1.0, 2.0, 3.0, 6.0}, 2, 2)
+	rng := rand.New(rand.NewSource(1))
+	const samples = 200000
+	var counts [2][2]int
+	for i := 0; i < samples; i++ {
+		u, v, pdf := d.sample(rng.Float64(), rng.Float64())
+		if other := d.pdf(u, v); math.Abs(pdf-other) > 1e-9 {
+			t.Fatalf("Expected pdf %f at (%f, %f) to match sample's %f\n", other, u, v, pdf)
+		}
+		counts[int(2.0*v)][int(2.0*u)]++
+	}
+	for row := 0; row < 2; row++ {
+		for column := 0; column < 2; column++ {
+			// every cell covers a quarter of the square
+			expected := d.pdf((float64(column)+0.5)/2.0, (float64(row)+0.5)/2.0) / 4.0
+			frequency := float64(counts[row][column]) / samples
+			if math.Abs(frequency-expected) > 5e-3 {
+				t.Errorf("Expected cell (%d, %d) to be chosen %f of the time but got %f\n", column, row, expected, frequency)
+			}
+		}
+	}
+	if pdf := d.pdf(0.75, 0.75); math.Abs(pdf-2.0) > 1e-9 {
+		t.Errorf("Expected pdf 2 in the brightest cell but got %f\n", pdf)
+	}
+}
+
+func TestSphericalDistributionUniform(t *testing.T) {
+	sd := newSphericalDistribution(64, func(d geometry.Vector) shading.Color {
+		return shading.Color{Red: 1.0, Green: 1.0, Blue: 1.0}
+	})
+	rng := rand.New(rand.NewSource(1))
+	const samples = 10000
+	solidAngle := 0.0
+	for i := 0; i < samples; i++ {
+		d, pdf := sd.sample(rng)
+		if math.Abs(d.Magnitude()-1.0) > 1e-9 {
+			t.Fatalf("Expected a unit direction but got %v\n", d)
+		}
+		if other := sd.pdf(d); math.Abs(pdf-other) > 1e-9*pdf {
+			t.Fatalf("Expected pdf %f at %v to match sample's %f\n", other, d, pdf)
+		}
+		solidAngle += 1.0 / pdf / samples
+	}
+	if math.Abs(solidAngle-4.0*math.Pi) > 0.01*4.0*math.Pi {
+		t.Errorf("Expected the sphere to cover a solid angle of 4 pi but got %f\n", solidAngle)
+	}
+	// the grid weights each row by the cosine at its middle, where the pdf of a uniform map is 1 / (4 pi)
+	expected := 1.0 / (4.0 * math.Pi)
+	for _, v := range []float64{0.5 / 32.0, 8.5 / 32.0, 16.5 / 32.0, 27.5 / 32.0} {
+		d := latLongToDirection(0.3, v)
+		if pdf := sd.pdf(d); math.Abs(pdf-expected) > 2e-3*expected {
+			t.Errorf("Expected pdf %f at %v but got %f\n", expected, d, pdf)
+		}
+	}
+}
+
+func TestLatLong(t *testing.T) {
+	for _, d := range []geometry.Vector{
+		{Z: -1.0},
+		{X: 1.0},
+		{Y: 1.0},
+		geometry.Vector{X: 0.3, Y: -0.5, Z: 0.8}.Unit(),
+	} {
+		u, v := directionToLatLong(d)
+		if back := latLongToDirection(u, v); back.Sub(d).Magnitude() > 1e-9 {
+			t.Errorf("Expected %v back from (%f, %f) but got %v\n", d, u, v, back)
+		}
+	}
+	// -Z is the middle of the map
+	if u, v := directionToLatLong(geometry.Vector{Z: -1.0}); math.Abs(u-0.5) > 1e-9 || math.Abs(v-0.5) > 1e-9 {
+		t.Errorf("Expected (0.5, 0.5) but got (%f, %f)\n", u, v)
+	}
+}
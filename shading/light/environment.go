@@ -0,0 +1,23 @@
+package light
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"math/rand"
+)
+
+// Environment is the light arriving from infinitely far away, seen by rays which leave the scene
+type Environment interface {
+	// Radiance returns the light arriving from the given direction
+	Radiance(direction geometry.Vector) shading.Color
+}
+
+// Sampler is implemented by Environments which can choose directions in proportion to the light arriving from them,
+// so that surfaces can look towards the environment's brightest parts directly
+type Sampler interface {
+	// Sample chooses a direction towards the environment, returning it along with the light arriving from it
+	// and the probability density (per steradian) of choosing it
+	Sample(rng *rand.Rand) (geometry.Vector, shading.Color, float64)
+	// PDF returns the probability density (per steradian) of Sample choosing the direction
+	PDF(direction geometry.Vector) float64
+}
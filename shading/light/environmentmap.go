@@ -0,0 +1,138 @@
+package light
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"fluorescence/shading/texture"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// EnvironmentMap is an Environment whose light is taken from textures wrapped around the scene
+// it is either a single equirectangular (latitude-longitude) texture, or six cube map faces,
+// with the positive Y axis pointing up in both
+type EnvironmentMap struct {
+	TextureName      string    `json:"texture_name"`       // texture of an equirectangular map
+	CubeTextureNames [6]string `json:"cube_texture_names"` // textures of a cube map's faces, in the order +X, -X, +Y, -Y, +Z, -Z
+	Mapping          string    `json:"mapping"`            // how the textures wrap around the scene, either "equirectangular" or "cube"
+	Rotation         float64   `json:"rotation"`           // rotation of the map about the vertical axis, in degrees
+	Intensity        float64   `json:"intensity"`          // amount to scale the textures' colors by
+	Resolution       int       `json:"resolution"`         // number of columns in the grid of directions used to sample the map
+	textures         []texture.Texture
+	cosTheta         float64
+	sinTheta         float64
+	distribution     *sphericalDistribution
+}
+
+// Setup validates the map, finds its textures, and builds the distribution used to sample it
+func (em *EnvironmentMap) Setup(textures map[string]texture.Texture) (*EnvironmentMap, error) {
+	var names []string
+	switch em.Mapping {
+	case "", "equirectangular":
+		em.Mapping = "equirectangular"
+		names = []string{em.TextureName}
+	case "cube":
+		names = em.CubeTextureNames[:]
+	default:
+		return nil, fmt.Errorf("environment mapping (%s) is not one of equirectangular or cube", em.Mapping)
+	}
+	em.textures = make([]texture.Texture, len(names))
+	for i, name := range names {
+		t, ok := textures[name]
+		if !ok {
+			return nil, fmt.Errorf("environment texture (%s) is not defined", name)
+		}
+		em.textures[i] = t
+	}
+	if em.Intensity < 0.0 {
+		return nil, fmt.Errorf("environment intensity is negative")
+	}
+	if em.Intensity == 0.0 {
+		em.Intensity = 1.0
+	}
+	if em.Resolution < 0 {
+		return nil, fmt.Errorf("environment resolution is negative")
+	}
+	if em.Resolution == 0 {
+		em.Resolution = 1024
+	}
+	em.cosTheta = math.Cos((math.Pi / 180.0) * em.Rotation)
+	em.sinTheta = math.Sin((math.Pi / 180.0) * em.Rotation)
+	// the distribution is built in the map's own orientation, so is unaffected by its rotation
+	em.distribution = newSphericalDistribution(em.Resolution, func(d geometry.Vector) shading.Color {
+		return em.Radiance(em.fromMap(d))
+	})
+	return em, nil
+}
+
+// Radiance returns the light arriving from the given direction
+func (em *EnvironmentMap) Radiance(direction geometry.Vector) shading.Color {
+	d := em.toMap(direction.Unit())
+	var c shading.Color
+	if em.Mapping == "cube" {
+		face, u, v := cubeFace(d)
+		c = em.textures[face].Value(u, v)
+	} else {
+		u, v := directionToLatLong(d)
+		c = em.textures[0].Value(u, v)
+	}
+	return c.MultScalar(em.Intensity)
+}
+
+// Sample chooses a direction in proportion to the brightness of the map
+func (em *EnvironmentMap) Sample(rng *rand.Rand) (geometry.Vector, shading.Color, float64) {
+	d, pdf := em.distribution.sample(rng)
+	if pdf == 0.0 {
+		return geometry.Vector{}, shading.ColorBlack, 0.0
+	}
+	direction := em.fromMap(d)
+	return direction, em.Radiance(direction), pdf
+}
+
+// PDF returns the probability density (per steradian) of Sample choosing the direction
+func (em *EnvironmentMap) PDF(direction geometry.Vector) float64 {
+	return em.distribution.pdf(em.toMap(direction.Unit()))
+}
+
+// toMap rotates a direction in the scene into the map's own orientation
+func (em *EnvironmentMap) toMap(d geometry.Vector) geometry.Vector {
+	return geometry.Vector{
+		X: em.cosTheta*d.X - em.sinTheta*d.Z,
+		Y: d.Y,
+		Z: em.sinTheta*d.X + em.cosTheta*d.Z,
+	}
+}
+
+// fromMap rotates a direction in the map's orientation back into the scene
+func (em *EnvironmentMap) fromMap(d geometry.Vector) geometry.Vector {
+	return geometry.Vector{
+		X: em.cosTheta*d.X + em.sinTheta*d.Z,
+		Y: d.Y,
+		Z: -em.sinTheta*d.X + em.cosTheta*d.Z,
+	}
+}
+
+// cubeFace returns which face of a cube map a direction points at, and the texture coordinates on that face
+// faces are laid out as in OpenGL, each seen from inside the cube
+func cubeFace(d geometry.Vector) (int, float64, float64) {
+	ax, ay, az := math.Abs(d.X), math.Abs(d.Y), math.Abs(d.Z)
+	var face int
+	var s, t, major float64
+	switch {
+	case ax >= ay && ax >= az && d.X > 0:
+		face, s, t, major = 0, -d.Z, -d.Y, ax
+	case ax >= ay && ax >= az:
+		face, s, t, major = 1, d.Z, -d.Y, ax
+	case ay >= az && d.Y > 0:
+		face, s, t, major = 2, d.X, d.Z, ay
+	case ay >= az:
+		face, s, t, major = 3, d.X, -d.Z, ay
+	case d.Z > 0:
+		face, s, t, major = 4, d.X, -d.Y, az
+	default:
+		face, s, t, major = 5, -d.X, -d.Y, az
+	}
+	// t runs down the face, while texture coordinate V runs up it
+	return face, 0.5 * (s/major + 1.0), 1.0 - 0.5*(t/major+1.0)
+}
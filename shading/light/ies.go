@@ -0,0 +1,203 @@
+package light
+
+import (
+	"fluorescence/geometry"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// iesProfile is a photometric profile from an IES LM-63 file, describing how a light's intensity varies with direction
+// only type C photometry, the kind used by nearly all architectural lights, is supported
+type iesProfile struct {
+	vertical   []float64   // vertical angles in degrees, from straight down (0) to straight up (180)
+	horizontal []float64   // horizontal angles in degrees, around the vertical axis
+	candela    [][]float64 // candela at each vertical angle, for each horizontal angle, scaled so that the brightest is 1
+}
+
+// loadIESProfile reads and parses an IES file
+func loadIESProfile(fileName string) (*iesProfile, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := parseIESProfile(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("parsing IES profile (%s): %v", fileName, err)
+	}
+	return profile, nil
+}
+
+// parseIESProfile parses the contents of an IES file
+func parseIESProfile(data string) (*iesProfile, error) {
+	lines := strings.Split(strings.Replace(data, "\r", "", -1), "\n")
+	// keywords come before the TILT line, which ends the header
+	tiltLine := -1
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "TILT=") {
+			tiltLine = i
+			break
+		}
+	}
+	if tiltLine < 0 {
+		return nil, fmt.Errorf("no TILT line")
+	}
+	var numbers []float64
+	for _, field := range strings.Fields(strings.Join(lines[tiltLine+1:], " ")) {
+		// values may be separated by commas as well as whitespace
+		for _, value := range strings.Split(field, ",") {
+			if value == "" {
+				continue
+			}
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, err
+			}
+			numbers = append(numbers, number)
+		}
+	}
+	next := func() (float64, error) {
+		if len(numbers) == 0 {
+			return 0.0, fmt.Errorf("file is truncated")
+		}
+		n := numbers[0]
+		numbers = numbers[1:]
+		return n, nil
+	}
+	// a tilt included in the file describes how the lamp's output changes as it is tilted, which is ignored
+	if strings.TrimSpace(lines[tiltLine]) == "TILT=INCLUDE" {
+		if _, err := next(); err != nil {
+			return nil, err
+		}
+		pairs, err := next()
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < 2*int(pairs); i++ {
+			if _, err := next(); err != nil {
+				return nil, err
+			}
+		}
+	} else if strings.TrimSpace(lines[tiltLine]) != "TILT=NONE" {
+		return nil, fmt.Errorf("tilt files are not supported")
+	}
+
+	var header [13]float64
+	for i := range header {
+		n, err := next()
+		if err != nil {
+			return nil, err
+		}
+		header[i] = n
+	}
+	verticalCount := int(header[3])
+	horizontalCount := int(header[4])
+	if header[5] != 1 {
+		return nil, fmt.Errorf("photometric type (%d) is not type C", int(header[5]))
+	}
+	if verticalCount < 1 || horizontalCount < 1 {
+		return nil, fmt.Errorf("no angles")
+	}
+
+	profile := &iesProfile{
+		vertical:   make([]float64, verticalCount),
+		horizontal: make([]float64, horizontalCount),
+		candela:    make([][]float64, horizontalCount),
+	}
+	for i := range profile.vertical {
+		n, err := next()
+		if err != nil {
+			return nil, err
+		}
+		profile.vertical[i] = n
+	}
+	for i := range profile.horizontal {
+		n, err := next()
+		if err != nil {
+			return nil, err
+		}
+		profile.horizontal[i] = n
+	}
+	brightest := 0.0
+	for h := range profile.candela {
+		profile.candela[h] = make([]float64, verticalCount)
+		for v := range profile.candela[h] {
+			n, err := next()
+			if err != nil {
+				return nil, err
+			}
+			profile.candela[h][v] = n
+			brightest = math.Max(brightest, n)
+		}
+	}
+	if brightest <= 0.0 {
+		return nil, fmt.Errorf("no light is given out")
+	}
+	for h := range profile.candela {
+		for v := range profile.candela[h] {
+			profile.candela[h][v] /= brightest
+		}
+	}
+	return profile, nil
+}
+
+// intensity returns the relative intensity, between 0 and 1, of light leaving in a unit direction
+// the basis's W axis is straight down in the profile, and its U axis is at horizontal angle 0
+func (ip *iesProfile) intensity(basis geometry.ONB, direction geometry.Vector) float64 {
+	vertical := (180.0 / math.Pi) * math.Acos(math.Max(-1.0, math.Min(1.0, direction.Dot(basis.W))))
+	horizontal := (180.0 / math.Pi) * math.Atan2(direction.Dot(basis.V), direction.Dot(basis.U))
+	if horizontal < 0.0 {
+		horizontal += 360.0
+	}
+	// profiles only give the part of the horizontal circle which their symmetry does not repeat
+	switch last := ip.horizontal[len(ip.horizontal)-1]; last {
+	case 0.0:
+		horizontal = 0.0
+	case 90.0:
+		horizontal = math.Mod(horizontal, 180.0)
+		if horizontal > 90.0 {
+			horizontal = 180.0 - horizontal
+		}
+	case 180.0:
+		if horizontal > 180.0 {
+			horizontal = 360.0 - horizontal
+		}
+	}
+	v0, v1, vt, ok := bracket(ip.vertical, vertical)
+	if !ok {
+		return 0.0
+	}
+	h0, h1, ht, ok := bracket(ip.horizontal, horizontal)
+	if !ok {
+		// a full circle of angles wraps around from the last back to the first
+		last := len(ip.horizontal) - 1
+		h0, h1 = last, 0
+		ht = (horizontal - ip.horizontal[last]) / (360.0 - ip.horizontal[last] + ip.horizontal[0])
+		if horizontal < ip.horizontal[0] {
+			ht = (horizontal + 360.0 - ip.horizontal[last]) / (360.0 - ip.horizontal[last] + ip.horizontal[0])
+		}
+	}
+	at := func(h int) float64 {
+		return ip.candela[h][v0]*(1.0-vt) + ip.candela[h][v1]*vt
+	}
+	return at(h0)*(1.0-ht) + at(h1)*ht
+}
+
+// bracket finds the two neighbouring angles in a sorted list around an angle, and how far the angle is between them
+// angles outside the list are not bracketed, though a list of a single angle brackets every angle
+func bracket(angles []float64, angle float64) (int, int, float64, bool) {
+	if len(angles) == 1 {
+		return 0, 0, 0.0, true
+	}
+	if angle < angles[0] || angle > angles[len(angles)-1] {
+		return 0, 0, 0.0, false
+	}
+	i := sort.SearchFloat64s(angles, angle)
+	if angles[i] == angle {
+		return i, i, 0.0, true
+	}
+	return i - 1, i, (angle - angles[i-1]) / (angles[i] - angles[i-1]), true
+}
@@ -0,0 +1,130 @@
+package light
+
+import (
+	"fluorescence/geometry"
+	"math"
+	"testing"
+)
+
+// quadrantIES is a profile given for one quadrant of horizontal angles, which the other three repeat
+// its values are separated by commas as well as whitespace, and its brightest value is 200 candela
+const quadrantIES = `IESNA:LM-63-2002
+[TEST] quadrant
+TILT=NONE
+1 1000 1 3 2 1 2 0 0 0
+1 1 100
+0,45,90
+0 90
+100 50 0
+200 100 0
+`
+
+// circleIES is a profile given all the way around, which wraps from its last horizontal angle back to its first
+const circleIES = `IESNA:LM-63-2002
+TILT=INCLUDE
+1
+2
+0 90
+1 1
+1 1000 1 2 3 1 2 0 0 0
+1 1 100
+0 90
+0 120 240
+3 3
+6 6
+9 9
+`
+
+// downward is a basis whose W axis points straight down, with horizontal angle 0 along +X and 90 along +Z
+var downward = geometry.ONB{
+	U: geometry.Vector{X: 1.0},
+	V: geometry.Vector{Z: 1.0},
+	W: geometry.Vector{Y: -1.0},
+}
+
+// iesDirection returns the unit direction at a vertical angle from straight down and a horizontal angle around it, in degrees
+func iesDirection(vertical, horizontal float64) geometry.Vector {
+	v := (math.Pi / 180.0) * vertical
+	h := (math.Pi / 180.0) * horizontal
+	return downward.U.MultScalar(math.Sin(v) * math.Cos(h)).
+		Add(downward.V.MultScalar(math.Sin(v) * math.Sin(h))).
+		Add(downward.W.MultScalar(math.Cos(v)))
+}
+
+func TestIESQuadrantSymmetry(t *testing.T) {
+	profile, err := parseIESProfile(quadrantIES)
+	if err != nil {
+		t.Fatalf("Error parsing IES profile: %s\n", err)
+	}
+	for _, c := range []struct {
+		vertical, horizontal, expected float64
+	}{
+		{0.0, 0.0, 0.5},
+		{45.0, 90.0, 0.5},
+		// the other quadrants mirror the one given
+		{45.0, 270.0, 0.5},
+		{45.0, 180.0, 0.25},
+		// between the given angles, both are interpolated
+		{22.5, 45.0, 0.5625},
+		{22.5, 135.0, 0.5625},
+		{22.5, 315.0, 0.5625},
+		// no light is given above the last vertical angle
+		{120.0, 0.0, 0.0},
+	} {
+		if value := profile.intensity(downward, iesDirection(c.vertical, c.horizontal)); math.Abs(value-c.expected) > 1e-9 {
+			t.Errorf("Expected %f at vertical %f and horizontal %f but got %f\n", c.expected, c.vertical, c.horizontal, value)
+		}
+	}
+}
+
+func TestIESFullCircle(t *testing.T) {
+	profile, err := parseIESProfile(circleIES)
+	if err != nil {
+		t.Fatalf("Error parsing IES profile: %s\n", err)
+	}
+	for _, c := range []struct {
+		horizontal, expected float64
+	}{
+		{120.0, 2.0 / 3.0},
+		{180.0, 5.0 / 6.0},
+		// past the last angle, the profile wraps back around to the first
+		{300.0, 2.0 / 3.0},
+	} {
+		if value := profile.intensity(downward, iesDirection(45.0, c.horizontal)); math.Abs(value-c.expected) > 1e-9 {
+			t.Errorf("Expected %f at horizontal %f but got %f\n", c.expected, c.horizontal, value)
+		}
+	}
+}
+
+func TestBracket(t *testing.T) {
+	angles := []float64{0.0, 45.0, 90.0}
+	if i, j, f, ok := bracket(angles, 60.0); !ok || i != 1 || j != 2 || math.Abs(f-1.0/3.0) > 1e-9 {
+		t.Errorf("Expected 60 between 45 and 90 a third of the way but got %d, %d, %f, %t\n", i, j, f, ok)
+	}
+	if i, j, f, ok := bracket(angles, 45.0); !ok || i != 1 || j != 1 || f != 0.0 {
+		t.Errorf("Expected 45 exactly but got %d, %d, %f, %t\n", i, j, f, ok)
+	}
+	if _, _, _, ok := bracket(angles, 100.0); ok {
+		t.Errorf("Expected 100 to be outside the angles\n")
+	}
+	if _, _, _, ok := bracket([]float64{30.0}, 100.0); !ok {
+		t.Errorf("Expected a single angle to bracket every angle\n")
+	}
+}
+
+func TestIESErrors(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		data string
+	}{
+		{"missing tilt", "IESNA:LM-63-2002\n1 1000 1 1 1 1 2 0 0 0\n1 1 100\n0\n0\n1\n"},
+		{"photometric type", "TILT=NONE\n1 1000 1 1 1 2 2 0 0 0\n1 1 100\n0\n0\n1\n"},
+		{"truncated candela", "TILT=NONE\n1 1000 1 2 1 1 2 0 0 0\n1 1 100\n0 90\n0\n1\n"},
+		{"darkness", "TILT=NONE\n1 1000 1 1 1 1 2 0 0 0\n1 1 100\n0\n0\n0\n"},
+		{"number", "TILT=NONE\n1 1000 1 1 1 1 2 0 0 0\n1 1 100\n0\n0\nbright\n"},
+	} {
+		if _, err := parseIESProfile(c.data); err == nil {
+			t.Errorf("Expected an error for IES profile with bad %s\n", c.name)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package light
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+)
+
+// Light is a source of light which is not part of the scene's geometry, so can only be reached by looking at it directly
+// only surfaces whose materials can evaluate their scattering in any direction (material.Evaluator, such as
+// Lambertian and Oren-Nayar) look at Lights; metals, dielectrics, thin films, and subsurface materials choose
+// and weight their own rays, so only see a Light's effect on the other surfaces they reflect or refract
+type Light interface {
+	// Illuminate returns the unit direction from a point towards the light, the distance to the light
+	// (infinite for lights infinitely far away), and the light arriving at the point from it
+	Illuminate(p geometry.Point) (geometry.Vector, float64, shading.Color)
+}
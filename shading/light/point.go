@@ -0,0 +1,53 @@
+package light
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"fmt"
+)
+
+// Point is a Light shining from a single point, equally in every direction unless shaped by a photometric profile
+// a profile's straight-down angle is along -Y
+type Point struct {
+	Position    geometry.Point `json:"position"`
+	Color       shading.Color  `json:"color"`
+	Intensity   float64        `json:"intensity"`     // amount to scale the color by, giving the light's radiant intensity
+	IESFileName string         `json:"ies_file_name"` // IES photometric profile shaping the light, if any
+	profile     *iesProfile
+	basis       geometry.ONB
+}
+
+// Setup validates and defaults the light's fields and loads its profile
+func (pl *Point) Setup() (*Point, error) {
+	if pl.Intensity < 0.0 {
+		return nil, fmt.Errorf("point light intensity is negative")
+	}
+	if pl.Intensity == 0.0 {
+		pl.Intensity = 1.0
+	}
+	if pl.IESFileName != "" {
+		profile, err := loadIESProfile(pl.IESFileName)
+		if err != nil {
+			return nil, err
+		}
+		pl.profile = profile
+		pl.basis = geometry.NewONB(geometry.VectorUp.Negate())
+	}
+	return pl, nil
+}
+
+// Illuminate returns the direction and distance to the light, and the light arriving at the point from it
+// the light falls off with the square of the distance
+func (pl *Point) Illuminate(p geometry.Point) (geometry.Vector, float64, shading.Color) {
+	toLight := p.To(pl.Position)
+	distance := toLight.Magnitude()
+	if distance == 0.0 {
+		return geometry.Vector{}, 0.0, shading.ColorBlack
+	}
+	direction := toLight.DivScalar(distance)
+	radiance := pl.Color.MultScalar(pl.Intensity / (distance * distance))
+	if pl.profile != nil {
+		radiance = radiance.MultScalar(pl.profile.intensity(pl.basis, direction.Negate()))
+	}
+	return direction, distance, radiance
+}
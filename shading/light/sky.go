@@ -0,0 +1,296 @@
+package light
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Sky is an Environment of daylight, following the analytic sky model of Preetham, Shirley, and Smits,
+// with the sun as a small bright disk within it
+// radiance is in kilocandelas per square metre, so most scenes want an intensity well below one
+// the positive Y axis points up, north is along -Z, and east is along +X
+type Sky struct {
+	Turbidity    float64       `json:"turbidity"`     // haziness of the atmosphere, from 2 for a clear sky to 10 for a hazy one
+	GroundAlbedo shading.Color `json:"ground_albedo"` // color of the ground below the horizon, lit by the sky and sun
+	SunElevation float64       `json:"sun_elevation"` // angle of the sun above the horizon, in degrees
+	SunAzimuth   float64       `json:"sun_azimuth"`   // angle of the sun clockwise from north, in degrees
+	DateTime     string        `json:"date_time"`     // RFC 3339 date and time which, if given, places the sun in place of its elevation and azimuth
+	Latitude     float64       `json:"latitude"`      // latitude of the scene in degrees, north being positive, used with the date and time
+	Longitude    float64       `json:"longitude"`     // longitude of the scene in degrees, east being positive, used with the date and time
+	SunSize      float64       `json:"sun_size"`      // angular diameter of the sun in degrees; larger suns cast softer shadows without giving more light
+	Intensity    float64       `json:"intensity"`     // amount to scale the sky and sun by
+	Resolution   int           `json:"resolution"`    // number of columns in the grid of directions used to sample the sky
+	sunDirection geometry.Vector
+	sunCosRadius float64       // cosine of the sun's angular radius
+	sunRadiance  shading.Color // radiance of every point on the sun's disk
+	sunChance    float64       // probability of Sample choosing the sun rather than the sky
+	zenith       [3]float64    // luminance and chromaticity x and y straight up
+	perez        [3][5]float64 // coefficients of the Perez distribution for luminance and chromaticity x and y
+	perezSun     [3]float64    // Perez distribution for luminance and chromaticity x and y, evaluated at the zenith
+	ground       shading.Color // radiance of the ground
+	distribution *sphericalDistribution
+}
+
+// sunAngularDiameter is the angular diameter of the sun seen from the earth, in degrees
+const sunAngularDiameter = 0.53
+
+// sunLuminance is the luminance of the sun's disk before the atmosphere dims it, in kilocandelas per square metre
+const sunLuminance = 1.6e6
+
+// Setup validates and defaults the sky's fields, places the sun, and builds the distribution used to sample the sky
+func (s *Sky) Setup() (*Sky, error) {
+	if s.Turbidity == 0.0 {
+		s.Turbidity = 3.0
+	}
+	if s.Turbidity < 1.7 || s.Turbidity > 10.0 {
+		return nil, fmt.Errorf("sky turbidity (%f) is not within [1.7, 10]", s.Turbidity)
+	}
+	if s.GroundAlbedo == shading.ColorBlack {
+		s.GroundAlbedo = shading.Color{Red: 0.3, Green: 0.3, Blue: 0.3}
+	}
+	if s.SunSize < 0.0 {
+		return nil, fmt.Errorf("sun size is negative")
+	}
+	if s.SunSize == 0.0 {
+		s.SunSize = sunAngularDiameter
+	}
+	if s.Intensity < 0.0 {
+		return nil, fmt.Errorf("sky intensity is negative")
+	}
+	if s.Intensity == 0.0 {
+		s.Intensity = 1.0
+	}
+	if s.Resolution < 0 {
+		return nil, fmt.Errorf("sky resolution is negative")
+	}
+	if s.Resolution == 0 {
+		s.Resolution = 512
+	}
+	if s.DateTime != "" {
+		t, err := time.Parse(time.RFC3339, s.DateTime)
+		if err != nil {
+			return nil, err
+		}
+		s.SunElevation, s.SunAzimuth = sunPosition(t, s.Latitude, s.Longitude)
+	}
+	elevation := (math.Pi / 180.0) * s.SunElevation
+	azimuth := (math.Pi / 180.0) * s.SunAzimuth
+	s.sunDirection = geometry.Vector{
+		X: math.Cos(elevation) * math.Sin(azimuth),
+		Y: math.Sin(elevation),
+		Z: -math.Cos(elevation) * math.Cos(azimuth),
+	}
+	s.sunCosRadius = math.Cos((math.Pi / 180.0) * s.SunSize / 2.0)
+
+	// the model does not hold once the sun has set, so the sky is kept as it is at sunset
+	thetaSun := math.Min(math.Pi/2.0-elevation, math.Pi/2.0-0.01)
+	s.setupPerez(thetaSun)
+	s.sunRadiance = sunTransmittance(thetaSun, s.Turbidity).MultScalar(sunLuminance * s.sunScale())
+	if s.SunElevation < 0.0 {
+		s.sunRadiance = shading.ColorBlack
+	}
+
+	// the ground reflects the light falling on it from the sky and sun
+	s.ground = s.GroundAlbedo.MultColor(s.groundIrradiance()).DivScalar(math.Pi)
+
+	s.distribution = newSphericalDistribution(s.Resolution, s.skyRadiance)
+	s.sunChance = s.sunShare()
+	return s, nil
+}
+
+// setupPerez fills the coefficients of the Perez distributions and the zenith color for a sun at angle thetaSun from the zenith
+func (s *Sky) setupPerez(thetaSun float64) {
+	t := s.Turbidity
+	s.perez = [3][5]float64{
+		{0.1787*t - 1.4630, -0.3554*t + 0.4275, -0.0227*t + 5.3251, 0.1206*t - 2.5771, -0.0670*t + 0.3703},
+		{-0.0193*t - 0.2592, -0.0665*t + 0.0008, -0.0004*t + 0.2125, -0.0641*t - 0.8989, -0.0033*t + 0.0452},
+		{-0.0167*t - 0.2608, -0.0950*t + 0.0092, -0.0079*t + 0.2102, -0.0441*t - 1.6537, -0.0109*t + 0.0529},
+	}
+	chi := (4.0/9.0 - t/120.0) * (math.Pi - 2.0*thetaSun)
+	s.zenith[0] = (4.0453*t-4.9710)*math.Tan(chi) - 0.2155*t + 2.4192
+	theta := [4]float64{thetaSun * thetaSun * thetaSun, thetaSun * thetaSun, thetaSun, 1.0}
+	turbidity := [3]float64{t * t, t, 1.0}
+	zenithX := [3][4]float64{
+		{0.00166, -0.00375, 0.00209, 0.0},
+		{-0.02903, 0.06377, -0.03202, 0.00394},
+		{0.11693, -0.21196, 0.06052, 0.25886},
+	}
+	zenithY := [3][4]float64{
+		{0.00275, -0.00610, 0.00317, 0.0},
+		{-0.04214, 0.08970, -0.04153, 0.00516},
+		{0.15346, -0.26756, 0.06670, 0.26688},
+	}
+	s.zenith[1] = 0.0
+	s.zenith[2] = 0.0
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 4; j++ {
+			s.zenith[1] += turbidity[i] * zenithX[i][j] * theta[j]
+			s.zenith[2] += turbidity[i] * zenithY[i][j] * theta[j]
+		}
+	}
+	for i := 0; i < 3; i++ {
+		s.perezSun[i] = perez(s.perez[i], 0.0, thetaSun)
+	}
+}
+
+// perez returns the Perez distribution with the given coefficients, for a direction at angle theta from the zenith and gamma from the sun
+func perez(c [5]float64, theta, gamma float64) float64 {
+	cosGamma := math.Cos(gamma)
+	return (1.0 + c[0]*math.Exp(c[1]/math.Max(math.Cos(theta), 0.01))) * (1.0 + c[2]*math.Exp(c[3]*gamma) + c[4]*cosGamma*cosGamma)
+}
+
+// skyRadiance returns the light arriving from a unit direction, from the sky alone without the sun's disk
+func (s *Sky) skyRadiance(d geometry.Vector) shading.Color {
+	if d.Y < 0.0 {
+		return s.ground
+	}
+	theta := math.Acos(math.Min(d.Y, 1.0))
+	gamma := math.Acos(math.Max(-1.0, math.Min(1.0, d.Dot(s.sunDirection))))
+	var xyY [3]float64
+	for i := 0; i < 3; i++ {
+		xyY[i] = s.zenith[i] * perez(s.perez[i], theta, gamma) / s.perezSun[i]
+	}
+	return xyYToColor(xyY[1], xyY[2], xyY[0]).MultScalar(s.Intensity)
+}
+
+// Radiance returns the light arriving from the given direction
+func (s *Sky) Radiance(direction geometry.Vector) shading.Color {
+	d := direction.Unit()
+	c := s.skyRadiance(d)
+	if d.Dot(s.sunDirection) >= s.sunCosRadius && d.Y >= 0.0 {
+		c = c.Add(s.sunRadiance)
+	}
+	return c
+}
+
+// Sample chooses a direction towards either the sun's disk or the sky, in proportion to the light each gives
+func (s *Sky) Sample(rng *rand.Rand) (geometry.Vector, shading.Color, float64) {
+	var d geometry.Vector
+	if rng.Float64() < s.sunChance {
+		d = geometry.NewONB(s.sunDirection).FromLocal(uniformCone(s.sunCosRadius, rng))
+	} else {
+		d, _ = s.distribution.sample(rng)
+	}
+	pdf := s.PDF(d)
+	if pdf == 0.0 {
+		return geometry.Vector{}, shading.ColorBlack, 0.0
+	}
+	return d, s.Radiance(d), pdf
+}
+
+// PDF returns the probability density (per steradian) of Sample choosing the direction
+func (s *Sky) PDF(direction geometry.Vector) float64 {
+	d := direction.Unit()
+	pdf := (1.0 - s.sunChance) * s.distribution.pdf(d)
+	if d.Dot(s.sunDirection) >= s.sunCosRadius {
+		pdf += s.sunChance / s.sunSolidAngle()
+	}
+	return pdf
+}
+
+// sunSolidAngle returns the solid angle covered by the sun's disk
+func (s *Sky) sunSolidAngle() float64 {
+	return 2.0 * math.Pi * (1.0 - s.sunCosRadius)
+}
+
+// sunScale returns how much the sun's radiance is scaled so that it gives the same light whatever its size
+func (s *Sky) sunScale() float64 {
+	realSolidAngle := 2.0 * math.Pi * (1.0 - math.Cos((math.Pi/180.0)*sunAngularDiameter/2.0))
+	return s.Intensity * realSolidAngle / s.sunSolidAngle()
+}
+
+// groundIrradiance returns the light falling on the ground from the sky and the sun
+func (s *Sky) groundIrradiance() shading.Color {
+	const steps = 64
+	irradiance := shading.Color{}
+	// midpoint integration of the sky's radiance over the upper hemisphere, weighted by the cosine to the zenith
+	for i := 0; i < steps; i++ {
+		theta := (float64(i) + 0.5) * (math.Pi / 2.0) / steps
+		for j := 0; j < 2*steps; j++ {
+			phi := (float64(j) + 0.5) * math.Pi / steps
+			d := geometry.Vector{
+				X: math.Sin(theta) * math.Cos(phi),
+				Y: math.Cos(theta),
+				Z: math.Sin(theta) * math.Sin(phi),
+			}
+			solidAngle := math.Sin(theta) * (math.Pi / 2.0 / steps) * (math.Pi / steps)
+			irradiance = irradiance.Add(s.skyRadiance(d).MultScalar(math.Cos(theta) * solidAngle))
+		}
+	}
+	sunCosine := math.Max(s.sunDirection.Y, 0.0)
+	return irradiance.Add(s.sunRadiance.MultScalar(s.sunSolidAngle() * sunCosine))
+}
+
+// sunShare returns the fraction of the environment's light which comes from the sun's disk
+func (s *Sky) sunShare() float64 {
+	sunPower := luminance(s.sunRadiance) * s.sunSolidAngle()
+	skyPower := 0.0
+	const steps = 64
+	for i := 0; i < steps; i++ {
+		theta := (float64(i) + 0.5) * math.Pi / steps
+		for j := 0; j < 2*steps; j++ {
+			phi := (float64(j) + 0.5) * math.Pi / steps
+			d := geometry.Vector{
+				X: math.Sin(theta) * math.Cos(phi),
+				Y: math.Cos(theta),
+				Z: math.Sin(theta) * math.Sin(phi),
+			}
+			skyPower += luminance(s.skyRadiance(d)) * math.Sin(theta) * (math.Pi / steps) * (math.Pi / steps)
+		}
+	}
+	if sunPower+skyPower == 0.0 {
+		return 0.0
+	}
+	return sunPower / (sunPower + skyPower)
+}
+
+// sunTransmittance returns the fraction of sunlight, per color channel, passing through the atmosphere
+// for a sun at angle thetaSun from the zenith, from Rayleigh scattering by air and Mie scattering by haze
+func sunTransmittance(thetaSun, turbidity float64) shading.Color {
+	// relative optical mass of air along the path to the sun
+	mass := 1.0 / (math.Cos(thetaSun) + 0.15*math.Pow(93.885-(180.0/math.Pi)*thetaSun, -1.253))
+	beta := 0.04608*turbidity - 0.04586
+	// representative wavelengths of red, green, and blue, in micrometres
+	wavelengths := [3]float64{0.65, 0.55, 0.45}
+	var transmittance [3]float64
+	for i, lambda := range wavelengths {
+		rayleigh := math.Exp(-0.008735 * math.Pow(lambda, -4.08) * mass)
+		aerosol := math.Exp(-beta * math.Pow(lambda, -1.3) * mass)
+		transmittance[i] = rayleigh * aerosol
+	}
+	return shading.Color{
+		Red:   transmittance[0],
+		Green: transmittance[1],
+		Blue:  transmittance[2],
+	}
+}
+
+// xyYToColor converts a CIE xyY color to linear sRGB
+func xyYToColor(x, y, luminance float64) shading.Color {
+	if y <= 0.0 {
+		return shading.ColorBlack
+	}
+	cx := x / y * luminance
+	cz := (1.0 - x - y) / y * luminance
+	return shading.Color{
+		Red:   math.Max(3.2406*cx-1.5372*luminance-0.4986*cz, 0.0),
+		Green: math.Max(-0.9689*cx+1.8758*luminance+0.0415*cz, 0.0),
+		Blue:  math.Max(0.0557*cx-0.2040*luminance+1.0570*cz, 0.0),
+	}
+}
+
+// uniformCone returns a unit direction chosen uniformly within a cone around the Z axis with the given cosine of its half angle
+func uniformCone(cosMax float64, rng *rand.Rand) geometry.Vector {
+	cosTheta := 1.0 - rng.Float64()*(1.0-cosMax)
+	sinTheta := math.Sqrt(math.Max(0.0, 1.0-cosTheta*cosTheta))
+	phi := 2.0 * math.Pi * rng.Float64()
+	return geometry.Vector{
+		X: math.Cos(phi) * sinTheta,
+		Y: math.Sin(phi) * sinTheta,
+		Z: cosTheta,
+	}
+}
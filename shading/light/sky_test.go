@@ -0,0 +1,145 @@
+package light
+
+import (
+	"fluorescence/geometry"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestSunPosition(t *testing.T) {
+	for _, c := range []struct {
+		name                string
+		time                string
+		latitude, longitude float64
+		elevation, azimuth  float64
+	}{
+		// at solar noon on the June solstice the sun stands 23.44 degrees above the equator, due south of northern latitudes
+		{"solstice noon", "2021-06-21T12:02:00Z", 40.0, 0.0, 90.0 - 40.0 + 23.44, 180.0},
+		// the same moment seen from 75 degrees west, five hours of solar time away
+		{"solstice noon to the west", "2021-06-21T17:02:00Z", 40.0, -75.0, 90.0 - 40.0 + 23.44, 180.0},
+		// at the March equinox the sun rises due east on the equator at six in the morning solar time
+		{"equinox sunrise", "2021-03-20T06:08:00Z", 0.0, 0.0, 0.0, 90.0},
+		// and is below the horizon by the latitude's complement at solar midnight
+		{"solstice midnight", "2021-06-21T00:02:00Z", 40.0, 0.0, -(90.0 - 40.0 - 23.44), 0.0},
+	} {
+		moment, err := time.Parse(time.RFC3339, c.time)
+		if err != nil {
+			t.Fatalf("Error parsing time: %s\n", err)
+		}
+		elevation, azimuth := sunPosition(moment, c.latitude, c.longitude)
+		if math.Abs(elevation-c.elevation) > 0.3 {
+			t.Errorf("Expected %s elevation %f but got %f\n", c.name, c.elevation, elevation)
+		}
+		if math.Abs(math.Remainder(azimuth-c.azimuth, 360.0)) > 1.0 {
+			t.Errorf("Expected %s azimuth %f but got %f\n", c.name, c.azimuth, azimuth)
+		}
+	}
+}
+
+// setupSky returns a Sky set up with a coarse sampling grid
+func setupSky(t *testing.T, s Sky) *Sky {
+	s.Resolution = 64
+	sky, err := s.Setup()
+	if err != nil {
+		t.Fatalf("Error setting up sky: %s\n", err)
+	}
+	return sky
+}
+
+func TestSkyZenith(t *testing.T) {
+	sky := setupSky(t, Sky{Turbidity: 3.0, SunElevation: 60.0})
+	// the zenith luminance of Preetham et al., for turbidity 3 and the sun 30 degrees from the zenith
+	chi := (4.0/9.0 - 3.0/120.0) * (math.Pi - 2.0*math.Pi/6.0)
+	expected := (4.0453*3.0-4.9710)*math.Tan(chi) - 0.2155*3.0 + 2.4192
+	if math.Abs(expected-10.413) > 1e-3 {
+		t.Fatalf("Expected zenith luminance near 10.413 but got %f\n", expected)
+	}
+	if value := luminance(sky.Radiance(geometry.Vector{Y: 1.0})); math.Abs(value-expected) > 1e-3*expected {
+		t.Errorf("Expected zenith luminance %f but got %f\n", expected, value)
+	}
+}
+
+func TestSkyShape(t *testing.T) {
+	sky := setupSky(t, Sky{Turbidity: 3.0, SunElevation: 30.0, SunAzimuth: 90.0})
+	// the sun sits to the east, 30 degrees up
+	sun := geometry.Vector{X: math.Cos(math.Pi / 6.0), Y: math.Sin(math.Pi / 6.0)}
+	if sun.Sub(sky.sunDirection).Magnitude() > 1e-9 {
+		t.Fatalf("Expected sun direction %v but got %v\n", sun, sky.sunDirection)
+	}
+	// the sky is brightest around the sun, and its disk is far brighter again
+	nearSun := geometry.Vector{X: math.Cos(math.Pi / 4.0), Y: math.Sin(math.Pi / 4.0)}
+	awayFromSun := geometry.Vector{X: -math.Cos(math.Pi / 4.0), Y: math.Sin(math.Pi / 4.0)}
+	if luminance(sky.Radiance(nearSun)) <= luminance(sky.Radiance(awayFromSun)) {
+		t.Errorf("Expected the sky near the sun to be brighter than opposite it\n")
+	}
+	if luminance(sky.Radiance(sun)) < 1000.0*luminance(sky.Radiance(nearSun)) {
+		t.Errorf("Expected the sun's disk to be far brighter than the sky\n")
+	}
+	// a clear sky is blue
+	if c := sky.Radiance(awayFromSun); c.Blue <= c.Red {
+		t.Errorf("Expected a blue sky but got %v\n", c)
+	}
+	if sky.sunChance <= 0.0 || sky.sunChance >= 1.0 {
+		t.Errorf("Expected the sun to be chosen some but not all of the time but got %f\n", sky.sunChance)
+	}
+
+	night := setupSky(t, Sky{Turbidity: 3.0, SunElevation: -10.0})
+	if c := night.Radiance(geometry.Vector{Y: -1.0}); c != night.ground {
+		t.Errorf("Expected the ground below the horizon but got %v\n", c)
+	}
+	if night.sunRadiance.Red != 0.0 || night.sunRadiance.Green != 0.0 || night.sunRadiance.Blue != 0.0 {
+		t.Errorf("Expected no sun below the horizon but got %v\n", night.sunRadiance)
+	}
+}
+
+func TestSkySampling(t *testing.T) {
+	sky := setupSky(t, Sky{Turbidity: 3.0, SunElevation: 40.0, SunAzimuth: 200.0, SunSize: 5.0})
+	// the power of the sky and sun, by midpoint integration over the sphere
+	const steps = 256
+	power := luminance(sky.sunRadiance) * sky.sunSolidAngle()
+	for i := 0; i < steps; i++ {
+		theta := (float64(i) + 0.5) * math.Pi / steps
+		for j := 0; j < 2*steps; j++ {
+			phi := (float64(j) + 0.5) * math.Pi / steps
+			d := geometry.Vector{
+				X: math.Sin(theta) * math.Cos(phi),
+				Y: math.Cos(theta),
+				Z: math.Sin(theta) * math.Sin(phi),
+			}
+			power += luminance(sky.skyRadiance(d)) * math.Sin(theta) * (math.Pi / steps) * (math.Pi / steps)
+		}
+	}
+	// the same power, estimated from the directions the sky samples
+	rng := rand.New(rand.NewSource(1))
+	const samples = 20000
+	estimate := 0.0
+	for i := 0; i < samples; i++ {
+		d, radiance, pdf := sky.Sample(rng)
+		if pdf == 0.0 {
+			continue
+		}
+		if other := sky.PDF(d); math.Abs(pdf-other) > 1e-9*pdf {
+			t.Fatalf("Expected pdf %f at %v to match Sample's %f\n", other, d, pdf)
+		}
+		estimate += luminance(radiance) / pdf / samples
+	}
+	if math.Abs(estimate-power) > 0.03*power {
+		t.Errorf("Expected sampled power %f but got %f\n", power, estimate)
+	}
+}
+
+func TestSkyDateTime(t *testing.T) {
+	sky := setupSky(t, Sky{DateTime: "2021-06-21T12:02:00Z", Latitude: 40.0})
+	// north is along -Z, so the noon sun in the northern summer is towards +Z
+	if sky.sunDirection.Z <= 0.0 || math.Abs(sky.SunElevation-73.44) > 0.3 {
+		t.Errorf("Expected the sun high in the south but got %v at elevation %f\n", sky.sunDirection, sky.SunElevation)
+	}
+	if _, err := (&Sky{DateTime: "midsummer"}).Setup(); err == nil {
+		t.Errorf("Expected an error for a date and time which is not RFC 3339\n")
+	}
+	if _, err := (&Sky{Turbidity: 12.0}).Setup(); err == nil {
+		t.Errorf("Expected an error for a turbidity above 10\n")
+	}
+}
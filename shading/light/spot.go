@@ -0,0 +1,87 @@
+package light
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"fmt"
+	"math"
+)
+
+// Spot is a Light shining from a single point within a cone
+// it is fully bright inside the inner angle, and fades away to nothing at the outer angle
+type Spot struct {
+	Position    geometry.Point  `json:"position"`
+	Direction   geometry.Vector `json:"direction"`   // direction the spot points in
+	InnerAngle  float64         `json:"inner_angle"` // angle from the spot's direction, in degrees, within which it is fully bright
+	OuterAngle  float64         `json:"outer_angle"` // angle from the spot's direction, in degrees, beyond which it is dark
+	Color       shading.Color   `json:"color"`
+	Intensity   float64         `json:"intensity"`     // amount to scale the color by, giving the light's radiant intensity along its direction
+	IESFileName string          `json:"ies_file_name"` // IES photometric profile shaping the light, if any, with its straight-down angle along the spot's direction
+	cosInner    float64
+	cosOuter    float64
+	profile     *iesProfile
+	basis       geometry.ONB
+}
+
+// Setup validates and defaults the light's fields and loads its profile
+func (sl *Spot) Setup() (*Spot, error) {
+	if sl.Direction == geometry.VectorZero {
+		return nil, fmt.Errorf("spot light direction is the zero vector")
+	}
+	if sl.OuterAngle == 0.0 {
+		sl.OuterAngle = 30.0
+	}
+	if sl.InnerAngle < 0.0 || sl.InnerAngle > sl.OuterAngle || sl.OuterAngle > 180.0 {
+		return nil, fmt.Errorf("spot light angles (%f, %f) are not within 0 <= inner <= outer <= 180", sl.InnerAngle, sl.OuterAngle)
+	}
+	if sl.Intensity < 0.0 {
+		return nil, fmt.Errorf("spot light intensity is negative")
+	}
+	if sl.Intensity == 0.0 {
+		sl.Intensity = 1.0
+	}
+	sl.Direction = sl.Direction.Unit()
+	sl.cosInner = math.Cos((math.Pi / 180.0) * sl.InnerAngle)
+	sl.cosOuter = math.Cos((math.Pi / 180.0) * sl.OuterAngle)
+	sl.basis = geometry.NewONB(sl.Direction)
+	if sl.IESFileName != "" {
+		profile, err := loadIESProfile(sl.IESFileName)
+		if err != nil {
+			return nil, err
+		}
+		sl.profile = profile
+	}
+	return sl, nil
+}
+
+// Illuminate returns the direction and distance to the light, and the light arriving at the point from it
+// the light falls off with the square of the distance, and towards the edge of the cone
+func (sl *Spot) Illuminate(p geometry.Point) (geometry.Vector, float64, shading.Color) {
+	toLight := p.To(sl.Position)
+	distance := toLight.Magnitude()
+	if distance == 0.0 {
+		return geometry.Vector{}, 0.0, shading.ColorBlack
+	}
+	direction := toLight.DivScalar(distance)
+	falloff := smoothStep(sl.cosOuter, sl.cosInner, direction.Negate().Dot(sl.Direction))
+	if falloff == 0.0 {
+		return direction, distance, shading.ColorBlack
+	}
+	radiance := sl.Color.MultScalar(falloff * sl.Intensity / (distance * distance))
+	if sl.profile != nil {
+		radiance = radiance.MultScalar(sl.profile.intensity(sl.basis, direction.Negate()))
+	}
+	return direction, distance, radiance
+}
+
+// smoothStep eases from 0 at edge0 to 1 at edge1
+func smoothStep(edge0, edge1, x float64) float64 {
+	if edge0 == edge1 {
+		if x < edge0 {
+			return 0.0
+		}
+		return 1.0
+	}
+	t := math.Min(math.Max((x-edge0)/(edge1-edge0), 0.0), 1.0)
+	return t * t * (3.0 - 2.0*t)
+}
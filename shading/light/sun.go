@@ -0,0 +1,38 @@
+package light
+
+import (
+	"math"
+	"time"
+)
+
+// sunPosition returns the elevation above the horizon and the azimuth clockwise from north, both in degrees,
+// of the sun at a moment in time, seen from the given latitude and longitude
+// it follows the NOAA approximation, which is accurate to within a small fraction of a degree
+func sunPosition(t time.Time, latitude, longitude float64) (float64, float64) {
+	t = t.UTC()
+	// fractional year, in radians
+	hours := float64(t.Hour()) + float64(t.Minute())/60.0 + float64(t.Second())/3600.0
+	daysInYear := 365.0
+	if time.Date(t.Year(), time.December, 31, 0, 0, 0, 0, time.UTC).YearDay() == 366 {
+		daysInYear = 366.0
+	}
+	gamma := 2.0 * math.Pi / daysInYear * (float64(t.YearDay()-1) + (hours-12.0)/24.0)
+
+	// equation of time, in minutes, and declination, in radians
+	equationOfTime := 229.18 * (0.000075 + 0.001868*math.Cos(gamma) - 0.032077*math.Sin(gamma) -
+		0.014615*math.Cos(2.0*gamma) - 0.040849*math.Sin(2.0*gamma))
+	declination := 0.006918 - 0.399912*math.Cos(gamma) + 0.070257*math.Sin(gamma) -
+		0.006758*math.Cos(2.0*gamma) + 0.000907*math.Sin(2.0*gamma) -
+		0.002697*math.Cos(3.0*gamma) + 0.00148*math.Sin(3.0*gamma)
+
+	// true solar time, in minutes, gives the hour angle
+	solarTime := hours*60.0 + equationOfTime + 4.0*longitude
+	hourAngle := (math.Pi / 180.0) * (solarTime/4.0 - 180.0)
+
+	phi := (math.Pi / 180.0) * latitude
+	cosZenith := math.Sin(phi)*math.Sin(declination) + math.Cos(phi)*math.Cos(declination)*math.Cos(hourAngle)
+	zenith := math.Acos(math.Max(-1.0, math.Min(1.0, cosZenith)))
+	azimuth := math.Atan2(math.Sin(hourAngle),
+		math.Cos(hourAngle)*math.Sin(phi)-math.Tan(declination)*math.Cos(phi)) + math.Pi
+	return 90.0 - (180.0/math.Pi)*zenith, math.Mod((180.0/math.Pi)*azimuth, 360.0)
+}
@@ -0,0 +1,16 @@
+package light
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+)
+
+// Uniform is an Environment of the same color in every direction
+type Uniform struct {
+	Color shading.Color `json:"color"`
+}
+
+// Radiance returns the color of the environment, which is the same in every direction
+func (u *Uniform) Radiance(direction geometry.Vector) shading.Color {
+	return u.Color
+}
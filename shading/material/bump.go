@@ -0,0 +1,84 @@
+package material
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading/texture"
+)
+
+// bumpDelta is the step in texture coordinates used to estimate the slope of a bump map
+const bumpDelta = 1.0 / 1024.0
+
+// Bump describes fine surface detail which changes the shading normal without changing the geometry
+// detail may come from either a tangent-space normal map or a height (bump) map
+type Bump struct {
+	NormalMapTexture texture.Texture `json:"-"`
+	BumpMapTexture   texture.Texture `json:"-"`
+	BumpScale        float64         `json:"bump_scale"`       // height of the bump map's white relative to its black, in texture space
+	NormalMapScale   float64         `json:"normal_map_scale"` // how far the normal map tilts the normal, scaling its tangent-space X and Y
+}
+
+// HasBump returns whether a normal map or bump map is present
+func (b Bump) HasBump() bool {
+	return b.NormalMapTexture != nil || b.BumpMapTexture != nil
+}
+
+// PerturbNormal returns a copy of the RayHit with its normal replaced by the shading normal
+// if the perturbed normal would face away from the ray, the unperturbed normal is kept
+func (b Bump) PerturbNormal(rayHit RayHit) RayHit {
+	if !b.HasBump() {
+		return rayHit
+	}
+	normal := rayHit.NormalAtHit.Unit()
+	tangent, bitangent := tangentFrame(rayHit, normal)
+
+	var perturbed geometry.Vector
+	if b.NormalMapTexture != nil {
+		// normal maps store tangent-space directions, remapped from [-1, 1] to [0, 1]
+		c := lookup(b.NormalMapTexture, rayHit)
+		perturbed = tangent.MultScalar(b.NormalMapScale * (2.0*c.Red - 1.0)).
+			Add(bitangent.MultScalar(b.NormalMapScale * (2.0*c.Green - 1.0))).
+			Add(normal.MultScalar(2.0*c.Blue - 1.0))
+	} else {
+		// tilt the normal against the slope of the height field
+		dhdu := (b.height(rayHit, bumpDelta, 0.0) - b.height(rayHit, -bumpDelta, 0.0)) / (2.0 * bumpDelta)
+		dhdv := (b.height(rayHit, 0.0, bumpDelta) - b.height(rayHit, 0.0, -bumpDelta)) / (2.0 * bumpDelta)
+		perturbed = normal.Sub(tangent.MultScalar(b.BumpScale * dhdu)).Sub(bitangent.MultScalar(b.BumpScale * dhdv))
+	}
+	if perturbed.Magnitude() == 0.0 {
+		return rayHit
+	}
+	perturbed = perturbed.Unit()
+	// keep the shading normal on the same side of the surface as the geometric normal, as seen by the ray
+	geometric := rayHit.Geometric()
+	if (perturbed.Dot(rayHit.Ray.Direction) < 0) != (geometric.Dot(rayHit.Ray.Direction) < 0) {
+		return rayHit
+	}
+	rayHit.GeometricNormal = geometric
+	rayHit.NormalAtHit = perturbed
+	return rayHit
+}
+
+// height returns the height of the bump map a step of (du, dv) in texture coordinates away from the hit
+// solid textures are stepped the same distance across the surface in the object's own space, where they are sampled
+func (b Bump) height(rayHit RayHit, du, dv float64) float64 {
+	objectTangent, objectBitangent := rayHit.ObjectFrame()
+	p := rayHit.ObjectPoint.AddVector(objectTangent.MultScalar(du)).AddVector(objectBitangent.MultScalar(dv))
+	return channelAverage(texture.Sample(b.BumpMapTexture, rayHit.U+du, rayHit.V+dv, p, textureFootprint(rayHit)))
+}
+
+// tangentFrame returns unit tangent and bitangent vectors perpendicular to the normal,
+// following the RayHit's texture directions where it has them
+func tangentFrame(rayHit RayHit, normal geometry.Vector) (geometry.Vector, geometry.Vector) {
+	tangent := rayHit.Tangent.Sub(normal.MultScalar(rayHit.Tangent.Dot(normal)))
+	if tangent.Magnitude() < 1e-7 {
+		basis := geometry.NewONB(normal)
+		return basis.U, basis.V
+	}
+	tangent = tangent.Unit()
+	bitangent := normal.Cross(tangent)
+	// texture coordinates may run either way around the normal
+	if bitangent.Dot(rayHit.Bitangent) < 0 {
+		bitangent = bitangent.Negate()
+	}
+	return tangent, bitangent
+}
@@ -0,0 +1,85 @@
+package material
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"fluorescence/shading/texture"
+	"testing"
+)
+
+// rampX is a solid texture whose brightness is the X coordinate of the point being shaded
+type rampX struct{}
+
+// Value returns black, as the ramp only depends on the point
+func (r rampX) Value(u, v float64) shading.Color {
+	return shading.Color{}
+}
+
+// SolidValue returns the X coordinate of the point as a grey
+func (r rampX) SolidValue(u, v float64, p geometry.Point) shading.Color {
+	return shading.Color{Red: p.X, Green: p.X, Blue: p.X}
+}
+
+func TestBumpSolidObjectFrame(t *testing.T) {
+	b := Bump{
+		BumpMapTexture: rampX{},
+		BumpScale:      1.0,
+	}
+	// a floor facing up, turned a quarter turn about Y into the world so its object X runs along world -Z
+	rayHit := RayHit{
+		Ray: geometry.Ray{
+			Origin:    geometry.Point{Y: 1.0},
+			Direction: geometry.Vector{Y: -1.0},
+		},
+		NormalAtHit:     geometry.Vector{Y: 1.0},
+		Tangent:         geometry.Vector{Z: -1.0},
+		Bitangent:       geometry.Vector{X: -1.0},
+		ObjectTangent:   geometry.Vector{X: 1.0},
+		ObjectBitangent: geometry.Vector{Z: -1.0},
+	}
+	// the height rises along U at a slope of 1, so the normal tilts halfway back against the tangent
+	perturbed := b.PerturbNormal(rayHit).NormalAtHit
+	expected := geometry.Vector{Y: 1.0, Z: 1.0}.Unit()
+	if perturbed.Sub(expected).Magnitude() > 1e-6 {
+		t.Errorf("Expected normal %v but got %v\n", expected, perturbed)
+	}
+	// without an object frame the world frame is stepped along, where X runs along the bitangent
+	rayHit.ObjectTangent = geometry.Vector{}
+	rayHit.ObjectBitangent = geometry.Vector{}
+	perturbed = b.PerturbNormal(rayHit).NormalAtHit
+	expected = geometry.Vector{X: -1.0, Y: 1.0}.Unit()
+	if perturbed.Sub(expected).Magnitude() > 1e-6 {
+		t.Errorf("Expected normal %v but got %v\n", expected, perturbed)
+	}
+}
+
+func TestNormalMapScale(t *testing.T) {
+	// a normal map tilted halfway over towards the tangent
+	b := Bump{
+		NormalMapTexture: &texture.Color{Color: shading.Color{Red: 1.0, Green: 0.5, Blue: 1.0}},
+		NormalMapScale:   1.0,
+	}
+	rayHit := RayHit{
+		Ray: geometry.Ray{
+			Origin:    geometry.Point{Y: 1.0},
+			Direction: geometry.Vector{Y: -1.0},
+		},
+		NormalAtHit: geometry.Vector{Y: 1.0},
+		Tangent:     geometry.Vector{X: 1.0},
+		Bitangent:   geometry.Vector{Z: 1.0},
+	}
+	for _, c := range []struct {
+		scale    float64
+		expected geometry.Vector
+	}{
+		{1.0, geometry.Vector{X: 1.0, Y: 1.0}.Unit()},
+		// the scale shortens the tilt along the surface, leaving the part along the normal
+		{0.5, geometry.Vector{X: 0.5, Y: 1.0}.Unit()},
+		{0.0, geometry.Vector{Y: 1.0}},
+	} {
+		b.NormalMapScale = c.scale
+		if perturbed := b.PerturbNormal(rayHit).NormalAtHit; perturbed.Sub(c.expected).Magnitude() > 1e-6 {
+			t.Errorf("Expected normal %v at scale %f but got %v\n", c.expected, c.scale, perturbed)
+		}
+	}
+}
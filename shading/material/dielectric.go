@@ -10,20 +10,24 @@ import (
 
 // Dielectric is an implementation of a Material
 // It represents a partially reflective, partially transmissive material, such as glass
+// it may optionally be coated with a thin Film
 type Dielectric struct {
 	ReflectanceTexture texture.Texture `json:"-"`
 	EmittanceTexture   texture.Texture `json:"-"`
 	RefractiveIndex    float64         `json:"refractive_index"`
+	Film
+	Bump
+	UVTransform
 }
 
-// Reflectance returns the reflective color at texture coordinates (u, v)
-func (d Dielectric) Reflectance(u, v float64) shading.Color {
-	return d.ReflectanceTexture.Value(u, v)
+// Reflectance returns the reflective color at the hit
+func (d Dielectric) Reflectance(rayHit RayHit) shading.Color {
+	return reflectance(d.ReflectanceTexture, rayHit)
 }
 
-// Emittance returns the emissive color at texture coordinates (u, v)
-func (d Dielectric) Emittance(u, v float64) shading.Color {
-	return d.EmittanceTexture.Value(u, v)
+// Emittance returns the emissive color at the hit's texture coordinates
+func (d Dielectric) Emittance(rayHit RayHit) shading.Color {
+	return lookup(d.EmittanceTexture, rayHit)
 }
 
 // IsSpecular returns whether this material is specular in nature (vs. diffuse)
@@ -34,7 +38,7 @@ func (d Dielectric) IsSpecular() bool {
 
 // Scatter returns an incoming ray given a RayHit representing the outgoing ray
 func (d Dielectric) Scatter(rayHit RayHit, rng *rand.Rand) (geometry.Ray, bool) {
-	hitPoint := rayHit.Ray.PointAt(rayHit.Time)
+	hitPoint := rayHit.Point
 	normal := rayHit.NormalAtHit
 	reflectionVector := rayHit.Ray.Direction.Unit().ReflectAround(normal)
 
@@ -54,23 +58,61 @@ func (d Dielectric) Scatter(rayHit RayHit, rng *rand.Rand) (geometry.Ray, bool)
 
 	refractedVector, ok := rayHit.Ray.Direction.RefractAround(refractiveNormal, ratioOfRefractiveIndices)
 	var reflectionProbability float64
-	reflectionProbability = schlick(cosine, d.RefractiveIndex)
+	if d.HasFilm() {
+		reflectionProbability = channelAverage(d.coatedReflectance(rayHit))
+	} else {
+		reflectionProbability = schlick(cosine, d.RefractiveIndex)
+	}
 
 	if !ok || rng.Float64() < reflectionProbability {
 		// fmt.Println("reflect!")
 		return geometry.Ray{
 			Origin:    hitPoint,
 			Direction: reflectionVector,
+			Time:      rayHit.Ray.Time,
 		}, true
 	}
 	// fmt.Println("refract!")
 	return geometry.Ray{
 		Origin:    hitPoint,
 		Direction: refractedVector,
+		Time:      rayHit.Ray.Time,
 	}, true
 
 }
 
+// Attenuation returns the fraction of light carried from the scattered ray to the outgoing ray
+// without a film, reflection and refraction are chosen in proportion to their share of light, so no extra weight is needed
+func (d Dielectric) Attenuation(rayHit RayHit, scattered geometry.Ray) shading.Color {
+	reflectance := d.Reflectance(rayHit)
+	if !d.HasFilm() {
+		return reflectance
+	}
+	// total internal reflection happens regardless of the film
+	normal := rayHit.NormalAtHit
+	ratioOfRefractiveIndices := 1.0 / d.RefractiveIndex
+	if rayHit.Ray.Direction.Dot(normal) > 0 {
+		normal = normal.Negate()
+		ratioOfRefractiveIndices = d.RefractiveIndex
+	}
+	if _, ok := rayHit.Ray.Direction.RefractAround(normal, ratioOfRefractiveIndices); !ok {
+		return reflectance
+	}
+	return reflectance.MultColor(splitWeight(d.coatedReflectance(rayHit), isReflection(rayHit, scattered)))
+}
+
+// coatedReflectance returns the reflectance of the film coating the outside of this Dielectric
+func (d Dielectric) coatedReflectance(rayHit RayHit) shading.Color {
+	cosine := rayHit.Ray.Direction.Unit().Dot(rayHit.NormalAtHit)
+	if cosine > 0 {
+		// leaving the dielectric, from inside to air
+		return d.filmReflectance(rayHit, cosine, d.RefractiveIndex, airIndices)
+	}
+	// entering the dielectric, from air to inside
+	index := d.RefractiveIndex
+	return d.filmReflectance(rayHit, -cosine, 1.0, [3]float64{index, index, index})
+}
+
 // schlick is a polynomial approximation to the chance a ray is reflected or transmitted via a dielectric
 func schlick(cosine, refractiveIndex float64) float64 {
 	r0 := (1.0 - refractiveIndex) / (1.0 + refractiveIndex)
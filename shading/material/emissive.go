@@ -0,0 +1,152 @@
+package material
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"fluorescence/shading/texture"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Emissive is an implementation of a Material
+// It represents a pure light source, which emits light but never reflects it
+type Emissive struct {
+	EmittanceTexture texture.Texture `json:"-"`
+	Power            float64         `json:"power"`           // total emitted power in watts, if given the emittance texture only provides the light's color
+	IsTwoSided       bool            `json:"is_two_sided"`    // whether light is emitted from the back of the surface as well as the front
+	ConeAngle        float64         `json:"cone_angle"`      // angle from the normal (in degrees) past which no light is emitted
+	ConeFalloff      float64         `json:"cone_falloff"`    // width (in degrees) of the smooth transition at the edge of the cone
+	AngularProfile   []float64       `json:"angular_profile"` // relative intensities at evenly spaced angles from the normal (0) to the tangent plane (90 degrees)
+	coneCosine       float64
+	falloffCosine    float64
+	radianceScale    float64
+	UVTransform
+}
+
+// Setup validates and fills the internal fields of an Emissive
+func (e *Emissive) Setup() (*Emissive, error) {
+	if e.Power < 0.0 {
+		return nil, fmt.Errorf("emissive power is negative")
+	}
+	if e.ConeAngle < 0.0 || e.ConeAngle > 90.0 {
+		return nil, fmt.Errorf("emissive cone angle is not within [0, 90] degrees")
+	}
+	if e.ConeFalloff < 0.0 || e.ConeFalloff > e.ConeAngle {
+		return nil, fmt.Errorf("emissive cone falloff is negative or larger than the cone angle")
+	}
+	for _, intensity := range e.AngularProfile {
+		if intensity < 0.0 {
+			return nil, fmt.Errorf("emissive angular profile contains a negative intensity")
+		}
+	}
+	// no cone means the full hemisphere
+	if e.ConeAngle == 0.0 {
+		e.ConeAngle = 90.0
+	}
+	e.coneCosine = math.Cos((math.Pi / 180.0) * e.ConeAngle)
+	e.falloffCosine = math.Cos((math.Pi / 180.0) * (e.ConeAngle - e.ConeFalloff))
+	e.radianceScale = 1.0
+	return e, nil
+}
+
+// WithArea returns a copy of this Emissive whose radiance is scaled so that a
+// surface of the given area emits exactly Power watts
+// if no Power is specified, the Emissive is returned unchanged
+func (e *Emissive) WithArea(area float64) (*Emissive, error) {
+	if e.Power == 0.0 {
+		return e, nil
+	}
+	if area <= 0.0 {
+		return nil, fmt.Errorf("emissive surface area is 0 or negative")
+	}
+	sides := 1.0
+	if e.IsTwoSided {
+		sides = 2.0
+	}
+	projectedSolidAngle := e.projectedSolidAngle()
+	if projectedSolidAngle == 0.0 {
+		return nil, fmt.Errorf("emissive does not emit in any direction")
+	}
+	newE := *e
+	newE.radianceScale = e.Power / (area * sides * projectedSolidAngle)
+	return &newE, nil
+}
+
+// Reflectance returns the reflective color at the hit
+// an Emissive never reflects light, so this is always black
+func (e Emissive) Reflectance(rayHit RayHit) shading.Color {
+	return shading.ColorBlack
+}
+
+// Emittance returns the emitted radiance at the hit, towards the ray's origin
+func (e Emissive) Emittance(rayHit RayHit) shading.Color {
+	cosine := -rayHit.Ray.Direction.Unit().Dot(rayHit.NormalAtHit.Unit())
+	if cosine < 0.0 {
+		if !e.IsTwoSided {
+			return shading.ColorBlack
+		}
+		cosine = -cosine
+	}
+	intensity := e.intensity(cosine)
+	if intensity == 0.0 {
+		return shading.ColorBlack
+	}
+	return lookup(e.EmittanceTexture, rayHit).MultScalar(intensity * e.radianceScale)
+}
+
+// IsSpecular returns whether this material is specular in nature (vs. diffuse)
+// This is currently unused and is likely to be deprecated in the future
+func (e Emissive) IsSpecular() bool {
+	return false
+}
+
+// Scatter returns an incoming ray given a RayHit representing the outgoing ray
+// an Emissive absorbs all light, so nothing is ever scattered
+func (e Emissive) Scatter(rayHit RayHit, rng *rand.Rand) (geometry.Ray, bool) {
+	return geometry.RayZero, false
+}
+
+// intensity returns the relative intensity of emission at an angle from the normal, given by its cosine
+func (e Emissive) intensity(cosine float64) float64 {
+	if cosine <= e.coneCosine {
+		return 0.0
+	}
+	intensity := 1.0
+	if cosine < e.falloffCosine {
+		// smoothly fade out towards the edge of the cone
+		x := (cosine - e.coneCosine) / (e.falloffCosine - e.coneCosine)
+		intensity = x * x * (3.0 - 2.0*x)
+	}
+	if len(e.AngularProfile) > 0 {
+		intensity *= e.profileAt(math.Acos(math.Min(cosine, 1.0)))
+	}
+	return intensity
+}
+
+// profileAt linearly interpolates the angular profile at an angle (in radians) from the normal
+func (e Emissive) profileAt(theta float64) float64 {
+	if len(e.AngularProfile) == 1 {
+		return e.AngularProfile[0]
+	}
+	position := theta / (math.Pi / 2.0) * float64(len(e.AngularProfile)-1)
+	index := int(position)
+	if index >= len(e.AngularProfile)-1 {
+		return e.AngularProfile[len(e.AngularProfile)-1]
+	}
+	fraction := position - float64(index)
+	return e.AngularProfile[index]*(1.0-fraction) + e.AngularProfile[index+1]*fraction
+}
+
+// projectedSolidAngle integrates the emission intensity against the cosine over one hemisphere
+// a uniform, un-coned emitter integrates to pi
+func (e Emissive) projectedSolidAngle() float64 {
+	steps := 1024
+	stepSize := (math.Pi / 2.0) / float64(steps)
+	total := 0.0
+	for i := 0; i < steps; i++ {
+		theta := (float64(i) + 0.5) * stepSize
+		total += e.intensity(math.Cos(theta)) * math.Cos(theta) * math.Sin(theta)
+	}
+	return 2.0 * math.Pi * total * stepSize
+}
@@ -0,0 +1,101 @@
+package material
+
+import (
+	"fluorescence/shading"
+	"fluorescence/shading/texture"
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+// bandWavelengths holds the wavelengths (in nanometers) sampled across the red, green, and blue bands
+// when computing wavelength-dependent reflectance
+var bandWavelengths = [3][4]float64{
+	{612.5, 637.5, 662.5, 687.5},
+	{512.5, 537.5, 562.5, 587.5},
+	{412.5, 437.5, 462.5, 487.5},
+}
+
+// Film describes a thin transparent coating on a surface
+// light reflected from the top and bottom of the film interferes, producing iridescent colors
+type Film struct {
+	FilmThicknessTexture texture.Texture `json:"-"`
+	FilmThickness        float64         `json:"film_thickness"`        // thickness of the film in nanometers, scaled by the thickness texture if present
+	FilmRefractiveIndex  float64         `json:"film_refractive_index"` // refractive index of the film itself
+}
+
+// HasFilm returns whether a film is present
+func (f Film) HasFilm() bool {
+	return f.FilmThickness > 0.0
+}
+
+// SetupFilm validates a Film, which must have a refractive index if it is present
+// it is named apart from Setup so that it is not promoted as the Setup of the Materials embedding it
+func (f *Film) SetupFilm() error {
+	if f.HasFilm() && f.FilmRefractiveIndex <= 0.0 {
+		return fmt.Errorf("film refractive index is 0 or negative")
+	}
+	return nil
+}
+
+// filmReflectance returns the fraction of light reflected, per color channel, by a film at the hit
+// cosine is that of the angle between the incoming light and the normal, outerIndex is the refractive index
+// of the medium the light arrives from, and innerIndices are those of the medium beneath the film, per channel
+func (f Film) filmReflectance(rayHit RayHit, cosine, outerIndex float64, innerIndices [3]float64) shading.Color {
+	thickness := f.FilmThickness
+	if f.FilmThicknessTexture != nil {
+		tc := lookup(f.FilmThicknessTexture, rayHit)
+		thickness *= (tc.Red + tc.Green + tc.Blue) / 3.0
+	}
+	var channels [3]float64
+	for c := 0; c < 3; c++ {
+		total := 0.0
+		for _, wavelength := range bandWavelengths[c] {
+			total += airyReflectance(cosine, outerIndex, f.FilmRefractiveIndex, innerIndices[c], thickness, wavelength)
+		}
+		channels[c] = total / float64(len(bandWavelengths[c]))
+	}
+	return shading.Color{
+		Red:   channels[0],
+		Green: channels[1],
+		Blue:  channels[2],
+	}
+}
+
+// airyReflectance returns the reflectance of unpolarized light of a single wavelength from a film of
+// refractive index n2 and the given thickness, lying between media of refractive index n1 and n3
+func airyReflectance(cosine, n1, n2, n3, thickness, wavelength float64) float64 {
+	sinSquared := 1.0 - cosine*cosine
+	// angles inside the film and substrate, which may be complex if light is totally internally reflected
+	cos1 := complex(cosine, 0)
+	cos2 := cmplx.Sqrt(complex(1.0-(n1/n2)*(n1/n2)*sinSquared, 0))
+	cos3 := cmplx.Sqrt(complex(1.0-(n1/n3)*(n1/n3)*sinSquared, 0))
+	c1 := complex(n1, 0)
+	c2 := complex(n2, 0)
+	c3 := complex(n3, 0)
+
+	// fresnel amplitude coefficients at both interfaces, for both polarizations
+	r12s := (c1*cos1 - c2*cos2) / (c1*cos1 + c2*cos2)
+	r23s := (c2*cos2 - c3*cos3) / (c2*cos2 + c3*cos3)
+	r12p := (c2*cos1 - c1*cos2) / (c2*cos1 + c1*cos2)
+	r23p := (c3*cos2 - c2*cos3) / (c3*cos2 + c2*cos3)
+
+	// phase difference accumulated by a round trip through the film
+	phase := complex(4.0*math.Pi*n2*thickness/wavelength, 0) * cos2
+	shift := cmplx.Exp(complex(0, 1) * phase)
+
+	rs := (r12s + r23s*shift) / (1 + r12s*r23s*shift)
+	rp := (r12p + r23p*shift) / (1 + r12p*r23p*shift)
+	reflectance := (absSquared(rs) + absSquared(rp)) / 2.0
+	return math.Min(math.Max(reflectance, 0.0), 1.0)
+}
+
+// absSquared returns the squared magnitude of a complex number
+func absSquared(c complex128) float64 {
+	return real(c)*real(c) + imag(c)*imag(c)
+}
+
+// channelAverage returns the mean of a Color's channels
+func channelAverage(c shading.Color) float64 {
+	return (c.Red + c.Green + c.Blue) / 3.0
+}
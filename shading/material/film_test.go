@@ -0,0 +1,40 @@
+package material
+
+import (
+	"fluorescence/shading"
+	"math"
+	"testing"
+)
+
+func TestSetupFilm(t *testing.T) {
+	for _, c := range []struct {
+		film  Film
+		valid bool
+	}{
+		{Film{}, true},
+		{Film{FilmThickness: 300.0, FilmRefractiveIndex: 1.33}, true},
+		// a film without an index would divide by zero when light enters it
+		{Film{FilmThickness: 300.0}, false},
+		{Film{FilmThickness: 300.0, FilmRefractiveIndex: -1.0}, false},
+	} {
+		if err := c.film.SetupFilm(); (err == nil) != c.valid {
+			t.Errorf("Expected valid %t for film %v but got error %v\n", c.valid, c.film, err)
+		}
+	}
+	if _, err := (&Subsurface{
+		Dielectric:   Dielectric{RefractiveIndex: 1.5, Film: Film{FilmThickness: 300.0}},
+		MeanFreePath: shading.ColorWhite,
+		Albedo:       shading.ColorWhite,
+	}).Setup(); err == nil {
+		t.Errorf("Expected an error for a subsurface film without a refractive index\n")
+	}
+}
+
+func TestAiryReflectanceWithoutFilm(t *testing.T) {
+	// a film of no thickness leaves only the reflection between the outer and inner media
+	r := airyReflectance(1.0, 1.0, 1.33, 1.5, 0.0, 550.0)
+	expected := math.Pow((1.0-1.5)/(1.0+1.5), 2.0)
+	if math.Abs(r-expected) > 1e-9 {
+		t.Errorf("Expected reflectance %f but got %f\n", expected, r)
+	}
+}
@@ -0,0 +1,63 @@
+package material
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"fluorescence/shading/texture"
+	"math"
+)
+
+// lookup returns the color of a texture at the hit, filtered over the area of the texture the ray's cone covers
+// solid textures are sampled at the object space point, so they move with the object when it is transformed
+func lookup(t texture.Texture, rayHit RayHit) shading.Color {
+	return texture.Sample(t, rayHit.U, rayHit.V, rayHit.ObjectPoint, textureFootprint(rayHit))
+}
+
+// reflectance returns the color of a material's reflectance texture at the hit
+// surfaces with colors at their corners tint it by the color blended from them
+func reflectance(t texture.Texture, rayHit RayHit) shading.Color {
+	c := lookup(t, rayHit)
+	if rayHit.HasColor {
+		return c.MultColor(rayHit.Color)
+	}
+	return c
+}
+
+// textureFootprint returns the area of texture space covered by the ray's cone where it meets the surface
+// the cone's circular cross-section is stretched into an ellipse along the ray's path over the surface,
+// and the ellipse's axes are then carried into texture space by the surface's tangent and bitangent
+func textureFootprint(rayHit RayHit) texture.Footprint {
+	if rayHit.ConeWidth <= 0.0 {
+		return texture.Footprint{}
+	}
+	normal := rayHit.NormalAtHit.Unit()
+	direction := rayHit.Ray.Direction.Unit()
+	cosine := math.Max(math.Abs(direction.Dot(normal)), 1e-2)
+
+	// axes of the ellipse on the surface, the first along the ray's path and the second across it
+	along := direction.Sub(normal.MultScalar(direction.Dot(normal)))
+	var across geometry.Vector
+	if along.Magnitude() < 1e-7 {
+		basis := geometry.NewONB(normal)
+		along, across = basis.U, basis.V
+	} else {
+		along = along.Unit()
+		across = normal.Cross(along)
+	}
+	along = along.MultScalar(rayHit.ConeWidth / cosine)
+	across = across.MultScalar(rayHit.ConeWidth)
+
+	// the dual of the tangent frame takes a vector on the surface to its change in texture coordinates
+	determinant := rayHit.Tangent.Cross(rayHit.Bitangent).Dot(normal)
+	if math.Abs(determinant) < 1e-12 {
+		return texture.Footprint{}
+	}
+	dualU := rayHit.Bitangent.Cross(normal).DivScalar(determinant)
+	dualV := normal.Cross(rayHit.Tangent).DivScalar(determinant)
+	return texture.Footprint{
+		DUDX: along.Dot(dualU),
+		DVDX: along.Dot(dualV),
+		DUDY: across.Dot(dualU),
+		DVDY: across.Dot(dualV),
+	}
+}
@@ -4,24 +4,26 @@ import (
 	"fluorescence/geometry"
 	"fluorescence/shading"
 	"fluorescence/shading/texture"
+	"math"
 	"math/rand"
 )
 
-// Lambertian represents an approximation to a ideally-diffuse material
-// (which is not physically accurate)
+// Lambertian represents an ideally-diffuse material, which reflects light equally in all directions
 type Lambertian struct {
 	ReflectanceTexture texture.Texture `json:"-"`
 	EmittanceTexture   texture.Texture `json:"-"`
+	Bump
+	UVTransform
 }
 
-// Reflectance returns the reflective color at texture coordinates (u, v)
-func (l Lambertian) Reflectance(u, v float64) shading.Color {
-	return l.ReflectanceTexture.Value(u, v)
+// Reflectance returns the reflective color at the hit
+func (l Lambertian) Reflectance(rayHit RayHit) shading.Color {
+	return reflectance(l.ReflectanceTexture, rayHit)
 }
 
-// Emittance returns the emissive color at texture coordinates (u, v)
-func (l Lambertian) Emittance(u, v float64) shading.Color {
-	return l.EmittanceTexture.Value(u, v)
+// Emittance returns the emissive color at the hit's texture coordinates
+func (l Lambertian) Emittance(rayHit RayHit) shading.Color {
+	return lookup(l.EmittanceTexture, rayHit)
 }
 
 // IsSpecular returns whether this material is specular in nature (vs. diffuse)
@@ -31,11 +33,41 @@ func (l Lambertian) IsSpecular() bool {
 }
 
 // Scatter returns an incoming ray given a RayHit representing the outgoing ray
+// incoming directions are cosine-weighted around the normal
 func (l Lambertian) Scatter(rayHit RayHit, rng *rand.Rand) (geometry.Ray, bool) {
-	hitPoint := rayHit.Ray.PointAt(rayHit.Time)
-	target := hitPoint.AddVector(rayHit.NormalAtHit).AddVector(geometry.RandomInUnitSphere(rng))
+	return scatterCosine(rayHit, rng), true
+}
+
+// BRDF returns the ratio of reflected to incoming light for the incoming direction
+func (l Lambertian) BRDF(rayHit RayHit, incoming geometry.Vector) shading.Color {
+	if incoming.Dot(rayHit.NormalAtHit) <= 0 {
+		return shading.ColorBlack
+	}
+	return l.Reflectance(rayHit).DivScalar(math.Pi)
+}
+
+// PDF returns the probability density of Scatter choosing the incoming direction
+func (l Lambertian) PDF(rayHit RayHit, incoming geometry.Vector) float64 {
+	return pdfCosine(rayHit, incoming)
+}
+
+// scatterCosine returns a ray leaving the hit point in a cosine-weighted
+// random direction around the normal
+func scatterCosine(rayHit RayHit, rng *rand.Rand) geometry.Ray {
+	hitPoint := rayHit.Point
+	basis := geometry.NewONB(rayHit.NormalAtHit)
 	return geometry.Ray{
 		Origin:    hitPoint,
-		Direction: hitPoint.To(target),
-	}, true
+		Direction: basis.FromLocal(geometry.RandomCosineDirection(rng)),
+		Time:      rayHit.Ray.Time,
+	}
+}
+
+// pdfCosine returns the probability density of scatterCosine choosing the incoming direction
+func pdfCosine(rayHit RayHit, incoming geometry.Vector) float64 {
+	cosine := incoming.Unit().Dot(rayHit.NormalAtHit.Unit())
+	if cosine <= 0 {
+		return 0
+	}
+	return cosine / math.Pi
 }
@@ -8,18 +8,131 @@ import (
 
 // Material described the implementation of a surface material
 type Material interface {
-	Reflectance(u, v float64) shading.Color
-	Emittance(u, v float64) shading.Color
+	Reflectance(RayHit) shading.Color
+	Emittance(RayHit) shading.Color
 	IsSpecular() bool
 	Scatter(RayHit, *rand.Rand) (geometry.Ray, bool)
 }
 
+// Evaluator is implemented by Materials whose scattering distribution can be evaluated
+// for an arbitrary incoming direction, rather than only sampled through Scatter
+type Evaluator interface {
+	// BRDF returns the ratio of light reflected towards the outgoing ray
+	// to the light arriving from the incoming direction
+	BRDF(rayHit RayHit, incoming geometry.Vector) shading.Color
+	// PDF returns the probability density (per steradian) of Scatter
+	// choosing the incoming direction
+	PDF(rayHit RayHit, incoming geometry.Vector) float64
+}
+
 // RayHit is a loose gathering of information about a ray's intersection with a surface
 type RayHit struct {
-	Ray         geometry.Ray
-	NormalAtHit geometry.Vector
-	Time        float64
-	U           float64 // texture coordinate U
-	V           float64 // texture coordinate V
-	Material    Material
+	Ray             geometry.Ray
+	Point           geometry.Point  // hit point in world space
+	ObjectPoint     geometry.Point  // hit point in the space of the primitive that was hit, before any transforms placed it in the scene
+	NormalAtHit     geometry.Vector // normal used for shading, which may be smoothed across the surface or perturbed by its material
+	GeometricNormal geometry.Vector // normal of the surface itself, or zero if it is the same as NormalAtHit
+	Time            float64
+	U               float64         // texture coordinate U
+	V               float64         // texture coordinate V
+	Tangent         geometry.Vector // rate of change of the hit point with texture coordinate U
+	Bitangent       geometry.Vector // rate of change of the hit point with texture coordinate V
+	ObjectTangent   geometry.Vector // rate of change of the object space hit point with texture coordinate U, or zero if the same as Tangent
+	ObjectBitangent geometry.Vector // rate of change of the object space hit point with texture coordinate V, or zero if the same as Bitangent
+	ConeWidth       float64         // width of the ray's cone of influence at the hit, used to filter textures
+	Color           shading.Color   // color blended from the corners of the surface, which tints its material's reflectance
+	HasColor        bool            // whether the surface has colors at its corners
+	Material        Material
+	LightLink       *LightLink   // which of the scene's lights illuminate the surface, or nil if all of them do
+	Source          *LightSource // the named source of light the surface is part of, or nil if it has no name
+}
+
+// Geometric returns the normal of the surface itself, rather than the normal used for shading
+func (rh RayHit) Geometric() geometry.Vector {
+	if rh.GeometricNormal == geometry.VectorZero {
+		return rh.NormalAtHit
+	}
+	return rh.GeometricNormal
+}
+
+// ObjectFrame returns the rates of change of the object space hit point with texture coordinates U and V
+func (rh RayHit) ObjectFrame() (geometry.Vector, geometry.Vector) {
+	if rh.ObjectTangent == geometry.VectorZero && rh.ObjectBitangent == geometry.VectorZero {
+		return rh.Tangent, rh.Bitangent
+	}
+	return rh.ObjectTangent, rh.ObjectBitangent
+}
+
+// IsConsistent returns whether a direction leaves the surface on the same side by both the shading and geometric normals
+// directions which the shading normal puts on one side, but which really go through the surface to the other,
+// would otherwise carry light through surfaces with smoothed or perturbed normals
+func (rh RayHit) IsConsistent(direction geometry.Vector) bool {
+	return (direction.Dot(rh.NormalAtHit) > 0) == (direction.Dot(rh.Geometric()) > 0)
+}
+
+// LightLink restricts which of the scene's lights, by name, illuminate a surface
+// when lights are included, only they illuminate it, and excluded lights never do
+type LightLink struct {
+	Include []string `json:"light_include"`
+	Exclude []string `json:"light_exclude"`
+}
+
+// Illuminates returns whether the named light illuminates a surface with this link
+// a nil LightLink is lit by every light
+func (ll *LightLink) Illuminates(name string) bool {
+	if ll == nil {
+		return true
+	}
+	for _, excluded := range ll.Exclude {
+		if excluded == name {
+			return false
+		}
+	}
+	if len(ll.Include) == 0 {
+		return true
+	}
+	for _, included := range ll.Include {
+		if included == name {
+			return true
+		}
+	}
+	return false
+}
+
+// LightSource names something in the scene which gives out light other than a light itself, such as a glowing
+// surface or the environment, so it can be linked to surfaces and counted in a light group like any other light
+type LightSource struct {
+	Name  string
+	Group int // index into the scene's light groups, or -1 if the source is in no group
+}
+
+// Attenuator is implemented by Materials whose reflected color depends on the direction chosen by Scatter
+type Attenuator interface {
+	// Attenuation returns the fraction of light, per color channel, carried from the scattered ray to the outgoing ray
+	Attenuation(rayHit RayHit, scattered geometry.Ray) shading.Color
+}
+
+// Medium is implemented by Materials whose interior scatters and absorbs light passing through it
+type Medium interface {
+	// Transmit advances a ray through the medium, for at most the given distance before it reaches a surface
+	// it returns the weight to apply to the light carried along the ray and, if the ray scattered
+	// inside the medium before reaching that surface, the new ray to follow
+	Transmit(ray geometry.Ray, distance float64, rng *rand.Rand) (shading.Color, geometry.Ray, bool)
+}
+
+// Mapper is implemented by Materials which transform the texture coordinates of the surfaces they cover
+type Mapper interface {
+	// MapUV returns a copy of the RayHit with its texture coordinates transformed
+	MapUV(rayHit RayHit) RayHit
+}
+
+// Perturber is implemented by Materials which alter the shading normal of the surfaces they cover
+type Perturber interface {
+	// PerturbNormal returns a copy of the RayHit with its normal replaced by the shading normal
+	PerturbNormal(rayHit RayHit) RayHit
+}
+
+// isReflection returns whether a scattered ray left the surface on the same side the outgoing ray arrived from
+func isReflection(rayHit RayHit, scattered geometry.Ray) bool {
+	return (rayHit.Ray.Direction.Dot(rayHit.NormalAtHit) < 0) == (scattered.Direction.Dot(rayHit.NormalAtHit) > 0)
 }
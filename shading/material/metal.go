@@ -4,25 +4,30 @@ import (
 	"fluorescence/geometry"
 	"fluorescence/shading"
 	"fluorescence/shading/texture"
+	"math"
 	"math/rand"
 )
 
 // Metal is an implementation of a Material
 // It represents a perfect or near-perfect specularly reflective material
+// it may optionally be coated with a thin Film
 type Metal struct {
 	ReflectanceTexture texture.Texture `json:"-"`
 	EmittanceTexture   texture.Texture `json:"-"`
 	Fuzziness          float64         `json:"fuzziness"`
+	Film
+	Bump
+	UVTransform
 }
 
-// Reflectance returns the reflective color at texture coordinates (u, v)
-func (m Metal) Reflectance(u, v float64) shading.Color {
-	return m.ReflectanceTexture.Value(u, v)
+// Reflectance returns the reflective color at the hit
+func (m Metal) Reflectance(rayHit RayHit) shading.Color {
+	return reflectance(m.ReflectanceTexture, rayHit)
 }
 
-// Emittance returns the emissive color at texture coordinates (u, v)
-func (m Metal) Emittance(u, v float64) shading.Color {
-	return m.EmittanceTexture.Value(u, v)
+// Emittance returns the emissive color at the hit's texture coordinates
+func (m Metal) Emittance(rayHit RayHit) shading.Color {
+	return lookup(m.EmittanceTexture, rayHit)
 }
 
 // IsSpecular returns whether this material is specular in nature (vs. diffuse)
@@ -33,7 +38,7 @@ func (m Metal) IsSpecular() bool {
 
 // Scatter returns an incoming ray given a RayHit representing the outgoing ray
 func (m Metal) Scatter(rayHit RayHit, rng *rand.Rand) (geometry.Ray, bool) {
-	hitPoint := rayHit.Ray.PointAt(rayHit.Time)
+	hitPoint := rayHit.Point
 	normal := rayHit.NormalAtHit
 
 	reflectionVector := rayHit.Ray.Direction.Unit().ReflectAround(normal)
@@ -42,7 +47,24 @@ func (m Metal) Scatter(rayHit RayHit, rng *rand.Rand) (geometry.Ray, bool) {
 		return geometry.Ray{
 			Origin:    hitPoint,
 			Direction: reflectionVector,
+			Time:      rayHit.Ray.Time,
 		}, true
 	}
 	return geometry.RayZero, false
 }
+
+// Attenuation returns the fraction of light carried from the scattered ray to the outgoing ray
+func (m Metal) Attenuation(rayHit RayHit, scattered geometry.Ray) shading.Color {
+	reflectance := m.Reflectance(rayHit)
+	if !m.HasFilm() {
+		return reflectance
+	}
+	// the metal beneath the film is approximated by the dielectric with the same reflectance at normal incidence
+	var indices [3]float64
+	for c, r := range [3]float64{reflectance.Red, reflectance.Green, reflectance.Blue} {
+		root := math.Sqrt(math.Min(math.Max(r, 0.0), 0.999))
+		indices[c] = (1.0 + root) / (1.0 - root)
+	}
+	cosine := math.Abs(rayHit.Ray.Direction.Unit().Dot(rayHit.NormalAtHit))
+	return m.filmReflectance(rayHit, cosine, 1.0, indices)
+}
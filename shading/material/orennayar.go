@@ -0,0 +1,92 @@
+package material
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"fluorescence/shading/texture"
+	"math"
+	"math/rand"
+)
+
+// OrenNayar is an implementation of a Material
+// It represents a rough diffuse material, such as clay or plaster, made up of
+// many tiny Lambertian facets whose slopes have a standard deviation of Sigma
+type OrenNayar struct {
+	ReflectanceTexture texture.Texture `json:"-"`
+	EmittanceTexture   texture.Texture `json:"-"`
+	SigmaDegrees       float64         `json:"sigma"`
+	a                  float64
+	b                  float64
+	Bump
+	UVTransform
+}
+
+// Setup calculates the Oren-Nayar coefficients from Sigma
+func (on *OrenNayar) Setup() (*OrenNayar, error) {
+	sigma := (math.Pi / 180.0) * on.SigmaDegrees
+	sigmaSquared := sigma * sigma
+	on.a = 1.0 - (sigmaSquared / (2.0 * (sigmaSquared + 0.33)))
+	on.b = 0.45 * sigmaSquared / (sigmaSquared + 0.09)
+	return on, nil
+}
+
+// Reflectance returns the reflective color at the hit
+func (on OrenNayar) Reflectance(rayHit RayHit) shading.Color {
+	return reflectance(on.ReflectanceTexture, rayHit)
+}
+
+// Emittance returns the emissive color at the hit's texture coordinates
+func (on OrenNayar) Emittance(rayHit RayHit) shading.Color {
+	return lookup(on.EmittanceTexture, rayHit)
+}
+
+// IsSpecular returns whether this material is specular in nature (vs. diffuse)
+// This is currently unused and is likely to be deprecated in the future
+func (on OrenNayar) IsSpecular() bool {
+	return false
+}
+
+// Scatter returns an incoming ray given a RayHit representing the outgoing ray
+// incoming directions are cosine-weighted around the normal
+func (on OrenNayar) Scatter(rayHit RayHit, rng *rand.Rand) (geometry.Ray, bool) {
+	return scatterCosine(rayHit, rng), true
+}
+
+// BRDF returns the ratio of reflected to incoming light for the incoming direction
+func (on OrenNayar) BRDF(rayHit RayHit, incoming geometry.Vector) shading.Color {
+	basis := geometry.NewONB(rayHit.NormalAtHit)
+	localIn := basis.ToLocal(incoming.Unit())
+	localOut := basis.ToLocal(rayHit.Ray.Direction.Unit().Negate())
+	// like Lambertian, light arriving at the back of the surface is reflected off the front
+	localOut.Z = math.Abs(localOut.Z)
+	if localIn.Z <= 0 || localOut.Z <= 1e-7 {
+		return shading.ColorBlack
+	}
+
+	sinThetaIn := math.Sqrt(math.Max(0, 1.0-localIn.Z*localIn.Z))
+	sinThetaOut := math.Sqrt(math.Max(0, 1.0-localOut.Z*localOut.Z))
+
+	// cosine of the azimuthal angle between the two directions
+	maxCos := 0.0
+	if sinThetaIn > 1e-7 && sinThetaOut > 1e-7 {
+		cosPhiDifference := (localIn.X*localOut.X + localIn.Y*localOut.Y) / (sinThetaIn * sinThetaOut)
+		maxCos = math.Max(0, cosPhiDifference)
+	}
+
+	// sin(alpha) * tan(beta), where alpha is the larger of the two polar angles
+	var sinAlpha, tanBeta float64
+	if localIn.Z < localOut.Z {
+		sinAlpha = sinThetaIn
+		tanBeta = sinThetaOut / localOut.Z
+	} else {
+		sinAlpha = sinThetaOut
+		tanBeta = sinThetaIn / localIn.Z
+	}
+
+	return on.Reflectance(rayHit).DivScalar(math.Pi).MultScalar(on.a + on.b*maxCos*sinAlpha*tanBeta)
+}
+
+// PDF returns the probability density of Scatter choosing the incoming direction
+func (on OrenNayar) PDF(rayHit RayHit, incoming geometry.Vector) float64 {
+	return pdfCosine(rayHit, incoming)
+}
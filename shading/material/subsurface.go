@@ -0,0 +1,126 @@
+package material
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Subsurface is an implementation of a Material
+// It represents a translucent material, such as marble, skin, or wax, where light refracts through
+// a dielectric boundary and then performs a random walk inside the object before leaving it again
+type Subsurface struct {
+	Dielectric
+	Albedo       shading.Color `json:"albedo"`         // fraction of light scattered (rather than absorbed) at each interaction inside the medium
+	MeanFreePath shading.Color `json:"mean_free_path"` // average distance light travels between interactions inside the medium, per channel
+	Anisotropy   float64       `json:"anisotropy"`     // Henyey-Greenstein asymmetry of scattering inside the medium, from -1 (backward) to 1 (forward)
+	extinction   [3]float64
+	albedo       [3]float64
+}
+
+// Setup validates and fills the internal fields of a Subsurface
+func (s *Subsurface) Setup() (*Subsurface, error) {
+	if s.RefractiveIndex <= 0.0 {
+		return nil, fmt.Errorf("subsurface refractive index is 0 or negative")
+	}
+	if s.MeanFreePath.Red <= 0.0 || s.MeanFreePath.Green <= 0.0 || s.MeanFreePath.Blue <= 0.0 {
+		return nil, fmt.Errorf("subsurface mean free path is 0 or negative")
+	}
+	if s.Albedo.Red < 0.0 || s.Albedo.Green < 0.0 || s.Albedo.Blue < 0.0 ||
+		s.Albedo.Red > 1.0 || s.Albedo.Green > 1.0 || s.Albedo.Blue > 1.0 {
+		return nil, fmt.Errorf("subsurface albedo is not within [0, 1]")
+	}
+	if s.Anisotropy <= -1.0 || s.Anisotropy >= 1.0 {
+		return nil, fmt.Errorf("subsurface anisotropy is not within (-1, 1)")
+	}
+	if err := s.SetupFilm(); err != nil {
+		return nil, err
+	}
+	s.extinction = [3]float64{
+		1.0 / s.MeanFreePath.Red,
+		1.0 / s.MeanFreePath.Green,
+		1.0 / s.MeanFreePath.Blue,
+	}
+	s.albedo = [3]float64{s.Albedo.Red, s.Albedo.Green, s.Albedo.Blue}
+	return s, nil
+}
+
+// Transmit advances a ray through the interior of this Subsurface
+// a distance is sampled using the extinction of a randomly chosen channel, and every channel
+// is weighted by the average probability of all channels choosing that distance
+func (s Subsurface) Transmit(ray geometry.Ray, distance float64, rng *rand.Rand) (shading.Color, geometry.Ray, bool) {
+	channel := rng.Intn(3)
+	sampledDistance := -math.Log(1.0-rng.Float64()) / s.extinction[channel]
+
+	var transmittance [3]float64
+	if sampledDistance >= distance {
+		// the ray reached the surface without interacting
+		probability := 0.0
+		for c := 0; c < 3; c++ {
+			transmittance[c] = math.Exp(-s.extinction[c] * distance)
+			probability += transmittance[c] / 3.0
+		}
+		if probability == 0.0 {
+			return shading.ColorBlack, geometry.RayZero, false
+		}
+		return shading.Color{
+			Red:   transmittance[0] / probability,
+			Green: transmittance[1] / probability,
+			Blue:  transmittance[2] / probability,
+		}, geometry.RayZero, false
+	}
+
+	// the ray interacted with the medium before reaching the surface
+	probability := 0.0
+	for c := 0; c < 3; c++ {
+		transmittance[c] = math.Exp(-s.extinction[c] * sampledDistance)
+		probability += s.extinction[c] * transmittance[c] / 3.0
+	}
+	var weight [3]float64
+	maxWeight := 0.0
+	for c := 0; c < 3; c++ {
+		weight[c] = s.albedo[c] * s.extinction[c] * transmittance[c] / probability
+		maxWeight = math.Max(maxWeight, weight[c])
+	}
+
+	// russian roulette ends the walk, as a walk inside a medium is not limited by the bounce count
+	survival := math.Min(maxWeight, 0.99)
+	if survival <= 0.0 || rng.Float64() >= survival {
+		return shading.ColorBlack, geometry.RayZero, true
+	}
+
+	direction := ray.Direction.Unit()
+	basis := geometry.NewONB(direction)
+	return shading.Color{
+		Red:   weight[0] / survival,
+		Green: weight[1] / survival,
+		Blue:  weight[2] / survival,
+	}, geometry.Ray{
+		Origin:    ray.Origin.AddVector(direction.MultScalar(sampledDistance)),
+		Direction: basis.FromLocal(s.samplePhase(rng)),
+		Time:      ray.Time,
+	}, true
+}
+
+// samplePhase returns a direction, relative to the positive Z axis as the direction of travel,
+// distributed according to the Henyey-Greenstein phase function
+func (s Subsurface) samplePhase(rng *rand.Rand) geometry.Vector {
+	g := s.Anisotropy
+	var cosTheta float64
+	if math.Abs(g) < 1e-3 {
+		cosTheta = 1.0 - 2.0*rng.Float64()
+	} else {
+		term := (1.0 - g*g) / (1.0 - g + 2.0*g*rng.Float64())
+		cosTheta = (1.0 + g*g - term*term) / (2.0 * g)
+	}
+	cosTheta = math.Max(-1.0, math.Min(1.0, cosTheta))
+	sinTheta := math.Sqrt(1.0 - cosTheta*cosTheta)
+	phi := 2.0 * math.Pi * rng.Float64()
+	return geometry.Vector{
+		X: sinTheta * math.Cos(phi),
+		Y: sinTheta * math.Sin(phi),
+		Z: cosTheta,
+	}
+}
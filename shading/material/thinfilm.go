@@ -0,0 +1,95 @@
+package material
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"fluorescence/shading/texture"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// airIndices holds the refractive index of air for each color channel
+var airIndices = [3]float64{1.0, 1.0, 1.0}
+
+// ThinFilm is an implementation of a Material
+// It represents a free-standing film surrounded by air on both sides, such as a soap bubble
+// light is either reflected with interference or passes straight through the film
+type ThinFilm struct {
+	ReflectanceTexture texture.Texture `json:"-"`
+	EmittanceTexture   texture.Texture `json:"-"`
+	Film
+	Bump
+	UVTransform
+}
+
+// Setup validates the fields of a ThinFilm
+func (tf *ThinFilm) Setup() (*ThinFilm, error) {
+	if !tf.HasFilm() {
+		return nil, fmt.Errorf("thin film thickness is 0 or negative")
+	}
+	if tf.FilmRefractiveIndex <= 0.0 {
+		return nil, fmt.Errorf("thin film refractive index is 0 or negative")
+	}
+	return tf, nil
+}
+
+// Reflectance returns the reflective color at the hit
+func (tf ThinFilm) Reflectance(rayHit RayHit) shading.Color {
+	return reflectance(tf.ReflectanceTexture, rayHit)
+}
+
+// Emittance returns the emissive color at the hit's texture coordinates
+func (tf ThinFilm) Emittance(rayHit RayHit) shading.Color {
+	return lookup(tf.EmittanceTexture, rayHit)
+}
+
+// IsSpecular returns whether this material is specular in nature (vs. diffuse)
+// This is currently unused and is likely to be deprecated in the future
+func (tf ThinFilm) IsSpecular() bool {
+	return true
+}
+
+// Scatter returns an incoming ray given a RayHit representing the outgoing ray
+func (tf ThinFilm) Scatter(rayHit RayHit, rng *rand.Rand) (geometry.Ray, bool) {
+	hitPoint := rayHit.Point
+	if rng.Float64() < channelAverage(tf.reflectance(rayHit)) {
+		return geometry.Ray{
+			Origin:    hitPoint,
+			Direction: rayHit.Ray.Direction.Unit().ReflectAround(rayHit.NormalAtHit),
+			Time:      rayHit.Ray.Time,
+		}, true
+	}
+	return geometry.Ray{
+		Origin:    hitPoint,
+		Direction: rayHit.Ray.Direction,
+		Time:      rayHit.Ray.Time,
+	}, true
+}
+
+// Attenuation returns the fraction of light carried from the scattered ray to the outgoing ray
+func (tf ThinFilm) Attenuation(rayHit RayHit, scattered geometry.Ray) shading.Color {
+	reflectance := tf.reflectance(rayHit)
+	return tf.Reflectance(rayHit).MultColor(
+		splitWeight(reflectance, isReflection(rayHit, scattered)))
+}
+
+// reflectance returns the film's reflectance for the outgoing ray's angle of incidence
+func (tf ThinFilm) reflectance(rayHit RayHit) shading.Color {
+	cosine := math.Abs(rayHit.Ray.Direction.Unit().Dot(rayHit.NormalAtHit))
+	return tf.filmReflectance(rayHit, cosine, 1.0, airIndices)
+}
+
+// splitWeight returns the weight of a ray which was reflected (or transmitted) with a probability equal
+// to the average of the per-channel reflectance, so that each channel receives its own share of light
+func splitWeight(reflectance shading.Color, wasReflected bool) shading.Color {
+	probability := channelAverage(reflectance)
+	if wasReflected {
+		return reflectance.DivScalar(probability)
+	}
+	return shading.Color{
+		Red:   1.0 - reflectance.Red,
+		Green: 1.0 - reflectance.Green,
+		Blue:  1.0 - reflectance.Blue,
+	}.DivScalar(1.0 - probability)
+}
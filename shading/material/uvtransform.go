@@ -0,0 +1,46 @@
+package material
+
+import (
+	"fluorescence/shading/texture"
+	"fmt"
+)
+
+// UVTransform describes how a material's textures are placed on a surface
+// texture coordinates are rotated, then scaled, then offset, and finally wrapped back into [0, 1)
+type UVTransform struct {
+	UVScale    [2]float64 `json:"uv_scale"`    // number of times the texture repeats across the surface in U and V
+	UVOffset   [2]float64 `json:"uv_offset"`   // shift applied to the texture coordinates after scaling
+	UVRotation float64    `json:"uv_rotation"` // rotation of the texture coordinates about the origin, in degrees
+	UVWrap     string     `json:"uv_wrap"`     // how coordinates outside [0, 1) are brought back in, one of "repeat", "clamp", or "mirror"
+	placement  texture.Placement
+}
+
+// SetupUVTransform validates and fills the internal fields of a UVTransform
+// it is named apart from Setup so that it is not promoted as the Setup of the Materials embedding it
+func (t *UVTransform) SetupUVTransform() error {
+	var err error
+	t.UVWrap, err = texture.CheckWrap(t.UVWrap)
+	if err != nil {
+		return fmt.Errorf("uv %v", err)
+	}
+	t.placement, err = texture.NewPlacement(t.UVScale, t.UVOffset, t.UVRotation)
+	if err != nil {
+		return fmt.Errorf("uv %v", err)
+	}
+	return nil
+}
+
+// MapUV returns a copy of the RayHit with its texture coordinates, and the rates at which the hit point changes with them, transformed
+func (t UVTransform) MapUV(rayHit RayHit) RayHit {
+	// a UVTransform that was never set up leaves coordinates alone
+	if t.UVWrap == "" {
+		return rayHit
+	}
+	u, v := t.placement.Map(rayHit.U, rayHit.V)
+	objectTangent, objectBitangent := rayHit.ObjectFrame()
+	rayHit.Tangent, rayHit.Bitangent = t.placement.MapFrame(rayHit.Tangent, rayHit.Bitangent)
+	rayHit.ObjectTangent, rayHit.ObjectBitangent = t.placement.MapFrame(objectTangent, objectBitangent)
+	rayHit.U = texture.WrapCoordinate(t.UVWrap, u)
+	rayHit.V = texture.WrapCoordinate(t.UVWrap, v)
+	return rayHit
+}
@@ -0,0 +1,38 @@
+package texture
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+)
+
+// Add holds information about a texture which is the sum of two other textures
+type Add struct {
+	TextureAName string `json:"texture_a_name"`
+	TextureBName string `json:"texture_b_name"`
+	textureA     Texture
+	textureB     Texture
+}
+
+// Setup finds the textures being added
+func (at *Add) Setup(textures map[string]Texture) (*Add, error) {
+	var err error
+	at.textureA, err = resolve(textures, at.TextureAName, "first added")
+	if err != nil {
+		return nil, err
+	}
+	at.textureB, err = resolve(textures, at.TextureBName, "second added")
+	if err != nil {
+		return nil, err
+	}
+	return at, nil
+}
+
+// Value returns the sum of the two textures at the given texture coordinates
+func (at *Add) Value(u, v float64) shading.Color {
+	return at.CompositeValue(u, v, surfacePoint(u, v), Footprint{})
+}
+
+// CompositeValue returns the sum of the two textures at a point
+func (at *Add) CompositeValue(u, v float64, p geometry.Point, footprint Footprint) shading.Color {
+	return Sample(at.textureA, u, v, p, footprint).Add(Sample(at.textureB, u, v, p, footprint))
+}
@@ -0,0 +1,101 @@
+package texture
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"math"
+)
+
+// Adjust holds information about a texture which shifts the hue, saturation, and brightness of another texture
+// every adjustment is relative, so an Adjust with no fields set leaves the texture unchanged
+type Adjust struct {
+	TextureName string  `json:"texture_name"`
+	Hue         float64 `json:"hue"`        // rotation of the hue around the color wheel, in degrees
+	Saturation  float64 `json:"saturation"` // amount added to the saturation, so -1 removes all color
+	Brightness  float64 `json:"brightness"` // fraction by which the brightness grows, so -1 makes the texture black
+	texture     Texture
+}
+
+// Setup finds the texture being adjusted
+func (at *Adjust) Setup(textures map[string]Texture) (*Adjust, error) {
+	var err error
+	at.texture, err = resolve(textures, at.TextureName, "adjusted")
+	if err != nil {
+		return nil, err
+	}
+	return at, nil
+}
+
+// Value returns the adjusted color of the texture at the given texture coordinates
+func (at *Adjust) Value(u, v float64) shading.Color {
+	return at.CompositeValue(u, v, surfacePoint(u, v), Footprint{})
+}
+
+// CompositeValue returns the adjusted color of the texture at a point
+func (at *Adjust) CompositeValue(u, v float64, p geometry.Point, footprint Footprint) shading.Color {
+	h, s, b := toHSB(Sample(at.texture, u, v, p, footprint))
+	h = math.Mod(h+at.Hue, 360.0)
+	if h < 0.0 {
+		h += 360.0
+	}
+	s = math.Min(math.Max(s+at.Saturation, 0.0), 1.0)
+	b = math.Max(b*(1.0+at.Brightness), 0.0)
+	return fromHSB(h, s, b)
+}
+
+// toHSB converts a color to its hue (in degrees), saturation, and brightness
+// brightness is the largest channel, so is not limited to 1 for colors brighter than white
+func toHSB(c shading.Color) (float64, float64, float64) {
+	maximum := math.Max(c.Red, math.Max(c.Green, c.Blue))
+	minimum := math.Min(c.Red, math.Min(c.Green, c.Blue))
+	chroma := maximum - minimum
+	if maximum <= 0.0 {
+		return 0.0, 0.0, 0.0
+	}
+	saturation := chroma / maximum
+	if chroma == 0.0 {
+		return 0.0, saturation, maximum
+	}
+	var hue float64
+	switch maximum {
+	case c.Red:
+		hue = math.Mod((c.Green-c.Blue)/chroma, 6.0)
+	case c.Green:
+		hue = (c.Blue-c.Red)/chroma + 2.0
+	default:
+		hue = (c.Red-c.Green)/chroma + 4.0
+	}
+	hue *= 60.0
+	if hue < 0.0 {
+		hue += 360.0
+	}
+	return hue, saturation, maximum
+}
+
+// fromHSB converts a hue (in degrees), saturation, and brightness back to a color
+func fromHSB(h, s, b float64) shading.Color {
+	chroma := b * s
+	sector := h / 60.0
+	x := chroma * (1.0 - math.Abs(math.Mod(sector, 2.0)-1.0))
+	var r, g, bl float64
+	switch {
+	case sector < 1.0:
+		r, g, bl = chroma, x, 0.0
+	case sector < 2.0:
+		r, g, bl = x, chroma, 0.0
+	case sector < 3.0:
+		r, g, bl = 0.0, chroma, x
+	case sector < 4.0:
+		r, g, bl = 0.0, x, chroma
+	case sector < 5.0:
+		r, g, bl = x, 0.0, chroma
+	default:
+		r, g, bl = chroma, 0.0, x
+	}
+	m := b - chroma
+	return shading.Color{
+		Red:   r + m,
+		Green: g + m,
+		Blue:  bl + m,
+	}
+}
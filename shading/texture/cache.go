@@ -0,0 +1,58 @@
+package texture
+
+import "path/filepath"
+
+// cacheKey identifies a decoded image
+// the same file decoded with a different gamma holds different linear colors, so is cached separately
+type cacheKey struct {
+	fileName string
+	gamma    float64
+}
+
+// Cache shares decoded images between the Image textures which load them
+type Cache struct {
+	entries map[cacheKey]*texels
+}
+
+// NewCache returns an empty Cache
+func NewCache() *Cache {
+	return &Cache{
+		entries: map[cacheKey]*texels{},
+	}
+}
+
+// Len returns the number of decoded images held by the cache
+func (c *Cache) Len() int {
+	return len(c.entries)
+}
+
+// Bytes returns the memory used by the texels of all decoded images held by the cache, including their mipmaps
+func (c *Cache) Bytes() int {
+	total := 0
+	for _, t := range c.entries {
+		total += t.bytes()
+	}
+	return total
+}
+
+// load returns the decoded image for a file, decoding it only if it is not already cached
+// a nil Cache always decodes the file
+func (c *Cache) load(fileName string, gamma float64) (*texels, error) {
+	key := cacheKey{
+		fileName: filepath.Clean(fileName),
+		gamma:    gamma,
+	}
+	if c != nil {
+		if t, ok := c.entries[key]; ok {
+			return t, nil
+		}
+	}
+	t, err := decodeTexels(fileName, gamma)
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		c.entries[key] = t
+	}
+	return t, nil
+}
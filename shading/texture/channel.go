@@ -0,0 +1,55 @@
+package texture
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"fmt"
+)
+
+// Channel holds information about a gray texture taken from a single channel of another texture
+type Channel struct {
+	TextureName string `json:"texture_name"`
+	Channel     string `json:"channel"` // the channel kept, one of "red", "green", "blue", or "luminance"
+	texture     Texture
+}
+
+// Setup validates the channel and finds the texture it is taken from
+func (ct *Channel) Setup(textures map[string]Texture) (*Channel, error) {
+	switch ct.Channel {
+	case "red", "green", "blue", "luminance":
+	default:
+		return nil, fmt.Errorf("channel (%s) is not one of red, green, blue, or luminance", ct.Channel)
+	}
+	var err error
+	ct.texture, err = resolve(textures, ct.TextureName, "channel source")
+	if err != nil {
+		return nil, err
+	}
+	return ct, nil
+}
+
+// Value returns the selected channel of the texture, as a gray color, at the given texture coordinates
+func (ct *Channel) Value(u, v float64) shading.Color {
+	return ct.CompositeValue(u, v, surfacePoint(u, v), Footprint{})
+}
+
+// CompositeValue returns the selected channel of the texture, as a gray color, at a point
+func (ct *Channel) CompositeValue(u, v float64, p geometry.Point, footprint Footprint) shading.Color {
+	c := Sample(ct.texture, u, v, p, footprint)
+	var x float64
+	switch ct.Channel {
+	case "red":
+		x = c.Red
+	case "green":
+		x = c.Green
+	case "blue":
+		x = c.Blue
+	default:
+		x = luminance(c)
+	}
+	return shading.Color{
+		Red:   x,
+		Green: x,
+		Blue:  x,
+	}
+}
@@ -0,0 +1,49 @@
+package texture
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"fmt"
+	"math"
+)
+
+// Checker holds information about a checkerboard texture of two alternating colors
+// it is laid out over the surface's texture coordinates, or through space if solid
+type Checker struct {
+	Even    shading.Color `json:"even"`
+	Odd     shading.Color `json:"odd"`
+	Scale   float64       `json:"scale"`    // number of squares per unit of texture coordinates, or cubes per unit of distance if solid
+	IsSolid bool          `json:"is_solid"` // whether the checks are cubes filling space rather than squares on the surface
+}
+
+// Setup validates and defaults the fields of a Checker
+func (ct *Checker) Setup() (*Checker, error) {
+	if ct.Scale < 0.0 {
+		return nil, fmt.Errorf("checker scale is negative")
+	}
+	if ct.Scale == 0.0 {
+		ct.Scale = 10.0
+	}
+	return ct, nil
+}
+
+// Value returns the color of the checkerboard at the given texture coordinates
+func (ct *Checker) Value(u, v float64) shading.Color {
+	if (int(math.Floor(u*ct.Scale))+int(math.Floor(v*ct.Scale)))%2 == 0 {
+		return ct.Even
+	}
+	return ct.Odd
+}
+
+// SolidValue returns the color of the checkerboard at a point
+// a checkerboard which is not solid only uses the texture coordinates
+func (ct *Checker) SolidValue(u, v float64, p geometry.Point) shading.Color {
+	if !ct.IsSolid {
+		return ct.Value(u, v)
+	}
+	sum := int(math.Floor(p.X*ct.Scale)) + int(math.Floor(p.Y*ct.Scale)) + int(math.Floor(p.Z*ct.Scale))
+	if sum%2 == 0 {
+		return ct.Even
+	}
+	return ct.Odd
+}
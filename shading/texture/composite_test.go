@@ -0,0 +1,174 @@
+package texture
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"math"
+	"testing"
+)
+
+// compositeTextures returns the named textures composite textures are built from in these tests
+// "ramp" is the gradient from black at u 0 to white at u 1, and "solid" is white where X is odd and black elsewhere
+func compositeTextures(t *testing.T) map[string]Texture {
+	ramp, err := (&Gradient{StartColor: black, EndColor: white}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up gradient: %s\n", err)
+	}
+	solid, err := (&Checker{Even: black, Odd: white, Scale: 1.0, IsSolid: true}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up checker: %s\n", err)
+	}
+	return map[string]Texture{
+		"red":   &Color{Color: shading.Color{Red: 1.0}},
+		"half":  &Color{Color: shading.Color{Red: 0.5, Green: 0.5, Blue: 0.5}},
+		"color": &Color{Color: shading.Color{Red: 0.2, Green: 0.4, Blue: 0.6}},
+		"ramp":  ramp,
+		"solid": solid,
+	}
+}
+
+func TestCompositeArithmetic(t *testing.T) {
+	textures := compositeTextures(t)
+	add, err := (&Add{TextureAName: "red", TextureBName: "color"}).Setup(textures)
+	if err != nil {
+		t.Fatalf("Error setting up add: %s\n", err)
+	}
+	if c := add.Value(0.3, 0.3); !colorsClose(c, shading.Color{Red: 1.2, Green: 0.4, Blue: 0.6}) {
+		t.Errorf("Expected the sum but got %v\n", c)
+	}
+	multiply, err := (&Multiply{TextureAName: "half", TextureBName: "color"}).Setup(textures)
+	if err != nil {
+		t.Fatalf("Error setting up multiply: %s\n", err)
+	}
+	if c := multiply.Value(0.3, 0.3); !colorsClose(c, shading.Color{Red: 0.1, Green: 0.2, Blue: 0.3}) {
+		t.Errorf("Expected the product but got %v\n", c)
+	}
+	invert, err := (&Invert{TextureName: "color"}).Setup(textures)
+	if err != nil {
+		t.Fatalf("Error setting up invert: %s\n", err)
+	}
+	if c := invert.Value(0.3, 0.3); !colorsClose(c, shading.Color{Red: 0.8, Green: 0.6, Blue: 0.4}) {
+		t.Errorf("Expected the negative but got %v\n", c)
+	}
+	channel, err := (&Channel{TextureName: "color", Channel: "green"}).Setup(textures)
+	if err != nil {
+		t.Fatalf("Error setting up channel: %s\n", err)
+	}
+	if c := channel.Value(0.3, 0.3); !colorsClose(c, shading.Color{Red: 0.4, Green: 0.4, Blue: 0.4}) {
+		t.Errorf("Expected the green channel but got %v\n", c)
+	}
+}
+
+func TestAdjust(t *testing.T) {
+	textures := compositeTextures(t)
+	unchanged, err := (&Adjust{TextureName: "color"}).Setup(textures)
+	if err != nil {
+		t.Fatalf("Error setting up adjust: %s\n", err)
+	}
+	if c := unchanged.Value(0.3, 0.3); !colorsClose(c, textures["color"].Value(0.3, 0.3)) {
+		t.Errorf("Expected no change but got %v\n", c)
+	}
+	for _, c := range []struct {
+		adjust   Adjust
+		expected shading.Color
+	}{
+		{Adjust{Hue: 120.0}, shading.Color{Green: 1.0}},
+		{Adjust{Hue: -120.0}, shading.Color{Blue: 1.0}},
+		{Adjust{Saturation: -1.0}, shading.Color{Red: 1.0, Green: 1.0, Blue: 1.0}},
+		{Adjust{Brightness: -0.5}, shading.Color{Red: 0.5}},
+	} {
+		c.adjust.TextureName = "red"
+		at, err := c.adjust.Setup(textures)
+		if err != nil {
+			t.Fatalf("Error setting up adjust: %s\n", err)
+		}
+		if value := at.Value(0.3, 0.3); !colorsClose(value, c.expected) {
+			t.Errorf("Expected %v but got %v\n", c.expected, value)
+		}
+	}
+}
+
+func TestMixAndRamp(t *testing.T) {
+	textures := compositeTextures(t)
+	mix, err := (&Mix{TextureAName: "red", TextureBName: "color", MaskTextureName: "ramp"}).Setup(textures)
+	if err != nil {
+		t.Fatalf("Error setting up mix: %s\n", err)
+	}
+	if c := mix.Value(0.0, 0.5); !colorsClose(c, shading.Color{Red: 1.0}) {
+		t.Errorf("Expected the first texture where the mask is black but got %v\n", c)
+	}
+	if c := mix.Value(0.5, 0.5); !colorsClose(c, shading.Color{Red: 0.6, Green: 0.2, Blue: 0.3}) {
+		t.Errorf("Expected an even blend where the mask is grey but got %v\n", c)
+	}
+	cr, err := (&ColorRamp{
+		TextureName: "ramp",
+		Stops: []RampStop{
+			{Position: 0.75, Color: shading.Color{Blue: 1.0}},
+			{Position: 0.25, Color: shading.Color{Red: 1.0}},
+		},
+	}).Setup(textures)
+	if err != nil {
+		t.Fatalf("Error setting up color ramp: %s\n", err)
+	}
+	for _, c := range []struct {
+		u        float64
+		expected shading.Color
+	}{
+		{0.1, shading.Color{Red: 1.0}},
+		{0.5, shading.Color{Red: 0.5, Blue: 0.5}},
+		{0.9, shading.Color{Blue: 1.0}},
+	} {
+		if value := cr.Value(c.u, 0.5); !colorsClose(value, c.expected) {
+			t.Errorf("Expected %v at u %f but got %v\n", c.expected, c.u, value)
+		}
+	}
+}
+
+func TestTransformComposite(t *testing.T) {
+	textures := compositeTextures(t)
+	tt, err := (&Transform{TextureName: "ramp", Scale: [2]float64{0.5, 1.0}, Offset: [2]float64{0.25, 0.0}}).Setup(textures)
+	if err != nil {
+		t.Fatalf("Error setting up transform: %s\n", err)
+	}
+	// u 1 is halved, then offset to 0.75 along the ramp
+	if value := tt.Value(1.0, 0.5).Red; math.Abs(value-0.75) > 1e-9 {
+		t.Errorf("Expected 0.75 but got %f\n", value)
+	}
+}
+
+func TestCompositePassesPoint(t *testing.T) {
+	textures := compositeTextures(t)
+	it, err := (&Invert{TextureName: "solid"}).Setup(textures)
+	if err != nil {
+		t.Fatalf("Error setting up invert: %s\n", err)
+	}
+	textures["inverted"] = it
+	mt, err := (&Multiply{TextureAName: "inverted", TextureBName: "half"}).Setup(textures)
+	if err != nil {
+		t.Fatalf("Error setting up multiply: %s\n", err)
+	}
+	// a solid texture two composites deep is still sampled at the point, not the texture coordinates
+	p := geometry.Point{X: 1.5, Y: 0.5, Z: 0.5}
+	if c := Sample(mt, 0.5, 0.5, p, Footprint{}); !colorsClose(c, black) {
+		t.Errorf("Expected %v but got %v\n", black, c)
+	}
+	if c := Sample(mt, 1.5, 0.5, geometry.Point{X: 0.5, Y: 0.5, Z: 0.5}, Footprint{}); !colorsClose(c, textures["half"].Value(0.0, 0.0)) {
+		t.Errorf("Expected %v but got %v\n", textures["half"].Value(0.0, 0.0), c)
+	}
+}
+
+func TestCompositeSetupErrors(t *testing.T) {
+	textures := compositeTextures(t)
+	if _, err := (&Add{TextureAName: "red", TextureBName: "missing"}).Setup(textures); err == nil {
+		t.Errorf("Expected an error for a texture which is not defined\n")
+	}
+	if _, err := (&Invert{}).Setup(textures); err == nil {
+		t.Errorf("Expected an error for a missing texture name\n")
+	}
+	if _, err := (&Channel{TextureName: "red", Channel: "alpha"}).Setup(textures); err == nil {
+		t.Errorf("Expected an error for an unknown channel\n")
+	}
+	if _, err := (&ColorRamp{TextureName: "red"}).Setup(textures); err == nil {
+		t.Errorf("Expected an error for a ramp with no stops\n")
+	}
+}
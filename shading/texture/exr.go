@@ -0,0 +1,351 @@
+package texture
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
+)
+
+// exrMagic begins every OpenEXR file
+var exrMagic = []byte{0x76, 0x2f, 0x31, 0x01}
+
+// OpenEXR compression methods which can be decoded
+const (
+	exrCompressionNone = 0
+	exrCompressionRLE  = 1
+	exrCompressionZIPS = 2
+	exrCompressionZIP  = 3
+)
+
+// exrMaxCompression is more than the most any decodable compression shrinks pixels by, which for zlib is about 1032 times
+const exrMaxCompression = 1100
+
+// OpenEXR channel pixel types
+const (
+	exrPixelUint  = 0
+	exrPixelHalf  = 1
+	exrPixelFloat = 2
+)
+
+// exrChannel describes one channel of an OpenEXR image
+type exrChannel struct {
+	name      string
+	pixelType int32
+	xSampling int32
+	ySampling int32
+}
+
+// size returns the number of bytes taken by one value of the channel
+func (c exrChannel) size() int {
+	if c.pixelType == exrPixelHalf {
+		return 2
+	}
+	return 4
+}
+
+// decodeEXR decodes a single-part, scanline OpenEXR image into linear colors
+// uncompressed, RLE, and ZIP compressed images are supported; images with only a Y channel become gray
+func decodeEXR(data []byte) (mipLevel, error) {
+	if len(data) < 8 || !bytes.Equal(data[:4], exrMagic) {
+		return mipLevel{}, fmt.Errorf("exr magic number is missing")
+	}
+	version := binary.LittleEndian.Uint32(data[4:8])
+	if version&0xff != 2 {
+		return mipLevel{}, fmt.Errorf("exr version (%d) is not supported", version&0xff)
+	}
+	if version&0x200 != 0 {
+		return mipLevel{}, fmt.Errorf("tiled exr images are not supported")
+	}
+	if version&0x1800 != 0 {
+		return mipLevel{}, fmt.Errorf("deep and multi-part exr images are not supported")
+	}
+
+	// the header is a list of attributes, ended by an empty name
+	var channels []exrChannel
+	compression := -1
+	var xMin, yMin, xMax, yMax int32
+	hasDataWindow := false
+	offset := 8
+	for {
+		name, next, err := exrString(data, offset)
+		if err != nil {
+			return mipLevel{}, err
+		}
+		offset = next
+		if name == "" {
+			break
+		}
+		attributeType, next, err := exrString(data, offset)
+		if err != nil {
+			return mipLevel{}, err
+		}
+		offset = next
+		if offset+4 > len(data) {
+			return mipLevel{}, fmt.Errorf("exr header is truncated")
+		}
+		size := int(binary.LittleEndian.Uint32(data[offset:]))
+		offset += 4
+		if size < 0 || offset+size > len(data) {
+			return mipLevel{}, fmt.Errorf("exr header is truncated")
+		}
+		value := data[offset : offset+size]
+		offset += size
+		switch {
+		case name == "channels" && attributeType == "chlist":
+			channels, err = exrChannels(value)
+			if err != nil {
+				return mipLevel{}, err
+			}
+		case name == "compression" && attributeType == "compression" && size == 1:
+			compression = int(value[0])
+		case name == "dataWindow" && attributeType == "box2i" && size == 16:
+			xMin = int32(binary.LittleEndian.Uint32(value[0:]))
+			yMin = int32(binary.LittleEndian.Uint32(value[4:]))
+			xMax = int32(binary.LittleEndian.Uint32(value[8:]))
+			yMax = int32(binary.LittleEndian.Uint32(value[12:]))
+			hasDataWindow = true
+		}
+	}
+	if len(channels) == 0 || compression < 0 || !hasDataWindow {
+		return mipLevel{}, fmt.Errorf("exr header is missing its channels, compression, or data window")
+	}
+	linesPerChunk := 1
+	switch compression {
+	case exrCompressionNone, exrCompressionRLE, exrCompressionZIPS:
+	case exrCompressionZIP:
+		linesPerChunk = 16
+	default:
+		return mipLevel{}, fmt.Errorf("exr compression (%d) is not supported", compression)
+	}
+	// the corners are widened before subtracting, so a window spanning the whole int32 range does not wrap around
+	width := int(xMax) - int(xMin) + 1
+	height := int(yMax) - int(yMin) + 1
+	if width <= 0 || height <= 0 {
+		return mipLevel{}, fmt.Errorf("exr image has no pixels")
+	}
+	// every chunk of scanlines has an entry in the offset table, checked before the table is read
+	chunkCount := (height + linesPerChunk - 1) / linesPerChunk
+	if chunkCount > (len(data)-offset)/8 {
+		return mipLevel{}, fmt.Errorf("exr offset table is truncated")
+	}
+
+	// channels are stored in alphabetical order, each as a run of values across the scanline
+	sort.Slice(channels, func(i, j int) bool {
+		return channels[i].name < channels[j].name
+	})
+	lineSize := 0
+	channelOffsets := make([]int, len(channels))
+	sources := [3]int{-1, -1, -1}
+	for i, c := range channels {
+		if c.xSampling != 1 || c.ySampling != 1 {
+			return mipLevel{}, fmt.Errorf("exr channel (%s) is subsampled", c.name)
+		}
+		channelOffsets[i] = lineSize
+		lineSize += width * c.size()
+		switch c.name {
+		case "R":
+			sources[0] = i
+		case "G":
+			sources[1] = i
+		case "B":
+			sources[2] = i
+		}
+	}
+	if sources[0] < 0 || sources[1] < 0 || sources[2] < 0 {
+		for i, c := range channels {
+			if c.name == "Y" {
+				sources = [3]int{i, i, i}
+			}
+		}
+	}
+	if sources[0] < 0 || sources[1] < 0 || sources[2] < 0 {
+		return mipLevel{}, fmt.Errorf("exr image has neither R, G, and B nor Y channels")
+	}
+	// no compression shrinks the pixels by more than exrMaxCompression, so they can be at most that many times the data
+	// the scanline is divided into the data rather than multiplied by the height, so the product can not overflow
+	if lineSize > exrMaxCompression*(len(data)/height+1) {
+		return mipLevel{}, fmt.Errorf("exr data window is larger than its pixels")
+	}
+
+	level := mipLevel{
+		width:  width,
+		height: height,
+		texels: make([]float32, 3*width*height),
+	}
+	for chunk := 0; chunk < chunkCount; chunk++ {
+		chunkOffset := int(binary.LittleEndian.Uint64(data[offset+8*chunk:]))
+		if chunkOffset < 0 || chunkOffset+8 > len(data) {
+			return mipLevel{}, fmt.Errorf("exr chunk offset is out of range")
+		}
+		y := int(int32(binary.LittleEndian.Uint32(data[chunkOffset:]))) - int(yMin)
+		size := int(binary.LittleEndian.Uint32(data[chunkOffset+4:]))
+		if y < 0 || y >= height || size < 0 || chunkOffset+8+size > len(data) {
+			return mipLevel{}, fmt.Errorf("exr chunk is out of range")
+		}
+		lines := minInt(linesPerChunk, height-y)
+		pixels, err := exrDecompress(compression, data[chunkOffset+8:chunkOffset+8+size], lines*lineSize)
+		if err != nil {
+			return mipLevel{}, err
+		}
+		for line := 0; line < lines; line++ {
+			row := pixels[line*lineSize : (line+1)*lineSize]
+			for x := 0; x < width; x++ {
+				i := 3 * ((y+line)*width + x)
+				for c, source := range sources {
+					level.texels[i+c] = nonNegative(exrValue(channels[source], row[channelOffsets[source]:], x))
+				}
+			}
+		}
+	}
+	return level, nil
+}
+
+// exrString reads a null-terminated string from the header, returning it and the offset after it
+func exrString(data []byte, offset int) (string, int, error) {
+	end := bytes.IndexByte(data[offset:], 0)
+	if end < 0 {
+		return "", 0, fmt.Errorf("exr header is truncated")
+	}
+	return string(data[offset : offset+end]), offset + end + 1, nil
+}
+
+// exrChannels parses a channel list attribute
+func exrChannels(value []byte) ([]exrChannel, error) {
+	var channels []exrChannel
+	offset := 0
+	for {
+		name, next, err := exrString(value, offset)
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			return channels, nil
+		}
+		if next+16 > len(value) {
+			return nil, fmt.Errorf("exr channel list is truncated")
+		}
+		c := exrChannel{
+			name:      name,
+			pixelType: int32(binary.LittleEndian.Uint32(value[next:])),
+			xSampling: int32(binary.LittleEndian.Uint32(value[next+8:])),
+			ySampling: int32(binary.LittleEndian.Uint32(value[next+12:])),
+		}
+		if c.pixelType != exrPixelUint && c.pixelType != exrPixelHalf && c.pixelType != exrPixelFloat {
+			return nil, fmt.Errorf("exr channel (%s) has unknown pixel type (%d)", name, c.pixelType)
+		}
+		channels = append(channels, c)
+		offset = next + 16
+	}
+}
+
+// exrValue returns the x'th value of a channel's run of values within a scanline
+func exrValue(c exrChannel, values []byte, x int) float32 {
+	switch c.pixelType {
+	case exrPixelHalf:
+		return halfToFloat32(binary.LittleEndian.Uint16(values[2*x:]))
+	case exrPixelFloat:
+		return math.Float32frombits(binary.LittleEndian.Uint32(values[4*x:]))
+	default:
+		return float32(binary.LittleEndian.Uint32(values[4*x:]))
+	}
+}
+
+// exrDecompress returns the pixels of a chunk, which uncompressed take the given number of bytes
+func exrDecompress(compression int, compressed []byte, size int) ([]byte, error) {
+	// chunks which compression would have grown are stored as they are
+	if compression == exrCompressionNone || len(compressed) == size {
+		if len(compressed) != size {
+			return nil, fmt.Errorf("exr chunk is the wrong size")
+		}
+		return compressed, nil
+	}
+	var packed []byte
+	if compression == exrCompressionRLE {
+		var err error
+		packed, err = exrUnpackRLE(compressed, size)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		r, err := zlib.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return nil, err
+		}
+		packed, err = ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(packed) != size {
+		return nil, fmt.Errorf("exr chunk is the wrong size")
+	}
+	// undo the delta predictor, then reinterleave the two halves, which hold the even and odd bytes
+	for i := 1; i < len(packed); i++ {
+		packed[i] = packed[i-1] + packed[i] - 128
+	}
+	pixels := make([]byte, size)
+	half := (size + 1) / 2
+	for i := 0; i < size; i++ {
+		if i%2 == 0 {
+			pixels[i] = packed[i/2]
+		} else {
+			pixels[i] = packed[half+i/2]
+		}
+	}
+	return pixels, nil
+}
+
+// exrUnpackRLE expands run-length encoded bytes
+// a negative count is followed by that many literal bytes, while any other count repeats the next byte count+1 times
+func exrUnpackRLE(compressed []byte, size int) ([]byte, error) {
+	out := make([]byte, 0, size)
+	for i := 0; i < len(compressed); {
+		count := int(int8(compressed[i]))
+		i++
+		if count < 0 {
+			if i-count > len(compressed) {
+				return nil, fmt.Errorf("exr run is truncated")
+			}
+			out = append(out, compressed[i:i-count]...)
+			i -= count
+		} else {
+			if i >= len(compressed) {
+				return nil, fmt.Errorf("exr run is truncated")
+			}
+			for j := 0; j <= count; j++ {
+				out = append(out, compressed[i])
+			}
+			i++
+		}
+		if len(out) > size {
+			return nil, fmt.Errorf("exr chunk is the wrong size")
+		}
+	}
+	return out, nil
+}
+
+// halfToFloat32 converts an IEEE 754 half precision value to single precision
+func halfToFloat32(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exponent := uint32(h>>10) & 0x1f
+	mantissa := uint32(h) & 0x3ff
+	switch {
+	case exponent == 0 && mantissa == 0:
+		return math.Float32frombits(sign)
+	case exponent == 0:
+		// subnormal halves are normal singles
+		for mantissa&0x400 == 0 {
+			mantissa <<= 1
+			exponent--
+		}
+		exponent++
+		mantissa &= 0x3ff
+	case exponent == 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mantissa<<13)
+	}
+	return math.Float32frombits(sign | (exponent+127-15)<<23 | mantissa<<13)
+}
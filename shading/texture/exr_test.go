@@ -0,0 +1,249 @@
+package texture
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// exrPixels are the half precision red, green, and blue values of a 2 by 2 image used by these tests, row by row from the top
+var exrPixels = [2][2][3]uint16{
+	{{0x3c00, 0x3800, 0x0000}, {0x4000, 0x3800, 0x0000}},
+	{{0x3800, 0x3800, 0x0000}, {0x3400, 0x3800, 0x4400}},
+}
+
+// exrExpected are the values of exrPixels as texels
+var exrExpected = []float32{
+	1.0, 0.5, 0.0, 2.0, 0.5, 0.0,
+	0.5, 0.5, 0.0, 0.25, 0.5, 4.0,
+}
+
+// exrAttribute returns a header attribute
+func exrAttribute(name, attributeType string, value []byte) []byte {
+	data := append([]byte(name), 0)
+	data = append(data, attributeType...)
+	data = append(data, 0)
+	data = appendUint32(data, uint32(len(value)))
+	return append(data, value...)
+}
+
+// appendUint32 appends a little-endian 32 bit value
+func appendUint32(data []byte, x uint32) []byte {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], x)
+	return append(data, b[:]...)
+}
+
+// exrScanline returns one uncompressed scanline of exrPixels, holding the blue, green, and then red values
+func exrScanline(y int) []byte {
+	var line []byte
+	for _, c := range []int{2, 1, 0} {
+		for x := 0; x < 2; x++ {
+			line = append(line, byte(exrPixels[y][x][c]), byte(exrPixels[y][x][c]>>8))
+		}
+	}
+	return line
+}
+
+// exrCompress compresses a chunk's pixels the way an encoder would
+// the bytes are split into even and odd halves and delta encoded, then run-length encoded or deflated
+func exrCompress(t *testing.T, compression int, pixels []byte) []byte {
+	if compression == exrCompressionNone {
+		return pixels
+	}
+	packed := make([]byte, 0, len(pixels))
+	for i := 0; i < len(pixels); i += 2 {
+		packed = append(packed, pixels[i])
+	}
+	for i := 1; i < len(pixels); i += 2 {
+		packed = append(packed, pixels[i])
+	}
+	for i := len(packed) - 1; i > 0; i-- {
+		packed[i] = packed[i] - packed[i-1] + 128
+	}
+	if compression == exrCompressionRLE {
+		// runs of three or more repeat a byte, and the bytes between them are written as literals
+		var compressed []byte
+		runAt := func(i int) int {
+			run := 1
+			for i+run < len(packed) && packed[i+run] == packed[i] && run < 128 {
+				run++
+			}
+			return run
+		}
+		for i := 0; i < len(packed); {
+			if run := runAt(i); run >= 3 {
+				compressed = append(compressed, byte(run-1), packed[i])
+				i += run
+				continue
+			}
+			start := i
+			for i < len(packed) && i-start < 127 && runAt(i) < 3 {
+				i++
+			}
+			compressed = append(compressed, byte(int8(start-i)))
+			compressed = append(compressed, packed[start:i]...)
+		}
+		return compressed
+	}
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	if _, err := w.Write(packed); err != nil {
+		t.Fatalf("Error compressing chunk: %s\n", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Error compressing chunk: %s\n", err)
+	}
+	return b.Bytes()
+}
+
+// exrFile returns a scanline OpenEXR file of exrPixels with the given compression
+func exrFile(t *testing.T, compression int) []byte {
+	data := append([]byte{}, exrMagic...)
+	data = appendUint32(data, 2)
+	var channels []byte
+	for _, name := range []string{"R", "G", "B"} {
+		channels = append(channels, name...)
+		channels = append(channels, 0)
+		channels = appendUint32(channels, exrPixelHalf)
+		channels = append(channels, 0, 0, 0, 0)
+		channels = appendUint32(channels, 1)
+		channels = appendUint32(channels, 1)
+	}
+	channels = append(channels, 0)
+	data = append(data, exrAttribute("channels", "chlist", channels)...)
+	data = append(data, exrAttribute("compression", "compression", []byte{byte(compression)})...)
+	window := appendUint32(appendUint32(appendUint32(appendUint32(nil, 0), 0), 1), 1)
+	data = append(data, exrAttribute("dataWindow", "box2i", window)...)
+	data = append(data, 0)
+
+	// ZIP compression packs sixteen scanlines into each chunk, where the others take one
+	var chunks [][]byte
+	if compression == exrCompressionZIP {
+		chunks = append(chunks, append(exrScanline(0), exrScanline(1)...))
+	} else {
+		chunks = append(chunks, exrScanline(0), exrScanline(1))
+	}
+	chunkOffset := len(data) + 8*len(chunks)
+	var table, body []byte
+	for i, chunk := range chunks {
+		compressed := exrCompress(t, compression, chunk)
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], uint64(chunkOffset+len(body)))
+		table = append(table, b[:]...)
+		body = appendUint32(body, uint32(i))
+		body = appendUint32(body, uint32(len(compressed)))
+		body = append(body, compressed...)
+	}
+	return append(append(data, table...), body...)
+}
+
+func TestDecodeEXR(t *testing.T) {
+	for _, c := range []struct {
+		name        string
+		compression int
+	}{
+		{"uncompressed", exrCompressionNone},
+		{"RLE", exrCompressionRLE},
+		{"ZIPS", exrCompressionZIPS},
+		{"ZIP", exrCompressionZIP},
+	} {
+		level, err := decodeEXR(exrFile(t, c.compression))
+		if err != nil {
+			t.Fatalf("Error decoding %s exr: %s\n", c.name, err)
+		}
+		if level.width != 2 || level.height != 2 {
+			t.Fatalf("Expected a 2 by 2 %s image but got %d by %d\n", c.name, level.width, level.height)
+		}
+		for i := range exrExpected {
+			if level.texels[i] != exrExpected[i] {
+				t.Errorf("Expected %s texel value %d to be %f but got %f\n", c.name, i, exrExpected[i], level.texels[i])
+			}
+		}
+	}
+}
+
+func TestEXRDecompressKnownAnswer(t *testing.T) {
+	// two literals, then a run of three, unpacked
+	unpacked, err := exrUnpackRLE([]byte{0xfe, 1, 2, 2, 7}, 5)
+	if err != nil {
+		t.Fatalf("Error unpacking run: %s\n", err)
+	}
+	if !bytes.Equal(unpacked, []byte{1, 2, 7, 7, 7}) {
+		t.Errorf("Expected [1 2 7 7 7] but got %v\n", unpacked)
+	}
+	// a predictor of 10, 12, 15 then 16 gives the even bytes 10, 12 and the odd bytes 15, 16
+	pixels, err := exrDecompress(exrCompressionRLE, []byte{0xfc, 10, 130, 131, 129}, 4)
+	if err != nil {
+		t.Fatalf("Error decompressing chunk: %s\n", err)
+	}
+	if !bytes.Equal(pixels, []byte{10, 15, 12, 16}) {
+		t.Errorf("Expected [10 15 12 16] but got %v\n", pixels)
+	}
+}
+
+func TestHalfToFloat32(t *testing.T) {
+	for _, c := range []struct {
+		half     uint16
+		expected float32
+	}{
+		{0x0000, 0.0},
+		{0x3c00, 1.0},
+		{0xc000, -2.0},
+		{0x3555, 0.333251953125},
+		{0x7bff, 65504.0},
+		{0x0400, float32(math.Ldexp(1.0, -14))},
+		{0x0001, float32(math.Ldexp(1.0, -24))},
+		{0x03ff, float32(1023.0 * math.Ldexp(1.0, -24))},
+		{0x7c00, float32(math.Inf(1))},
+	} {
+		if value := halfToFloat32(c.half); value != c.expected {
+			t.Errorf("Expected half %#04x to be %g but got %g\n", c.half, c.expected, value)
+		}
+	}
+	if value := halfToFloat32(0x8000); value != 0.0 || !math.Signbit(float64(value)) {
+		t.Errorf("Expected negative zero but got %g\n", value)
+	}
+	if value := halfToFloat32(0x7e00); !math.IsNaN(float64(value)) {
+		t.Errorf("Expected NaN but got %g\n", value)
+	}
+}
+
+func TestDecodeEXRErrors(t *testing.T) {
+	valid := exrFile(t, exrCompressionZIPS)
+	unsupported := exrFile(t, exrCompressionZIPS)
+	unsupported[bytes.Index(unsupported, []byte("compression\x00compression\x00"))+28] = 4
+	// the last chunk of an uncompressed file, cut short by a byte
+	uncompressed := exrFile(t, exrCompressionNone)
+	wrongSize := appendUint32(append([]byte{}, uncompressed[:len(uncompressed)-16]...), 11)
+	wrongSize = append(wrongSize, uncompressed[len(uncompressed)-12:len(uncompressed)-1]...)
+	// data windows far larger than the file, from their corners
+	box := bytes.Index(valid, []byte("box2i\x00")) + 10
+	tall := append([]byte{}, valid...)
+	binary.LittleEndian.PutUint32(tall[box+12:], math.MaxInt32)
+	wide := append([]byte{}, valid...)
+	binary.LittleEndian.PutUint32(wide[box:], 1<<31)
+	binary.LittleEndian.PutUint32(wide[box+8:], math.MaxInt32)
+	corrupt := exrFile(t, exrCompressionZIPS)
+	corrupt[len(corrupt)-3] ^= 0xff
+	for _, c := range []struct {
+		name string
+		data []byte
+	}{
+		{"magic number", append([]byte{0, 0, 0, 0}, valid[4:]...)},
+		{"header", valid[:40]},
+		{"offset table", valid[:bytes.Index(valid, []byte("box2i"))+27]},
+		{"chunk", valid[:len(valid)-4]},
+		{"compression", unsupported},
+		{"chunk size", wrongSize},
+		{"compressed data", corrupt},
+		{"data window height", tall},
+		{"data window width", wide},
+	} {
+		if _, err := decodeEXR(c.data); err == nil {
+			t.Errorf("Expected an error for exr with bad %s\n", c.name)
+		}
+	}
+}
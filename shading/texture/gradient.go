@@ -0,0 +1,46 @@
+package texture
+
+import (
+	"fluorescence/shading"
+	"fmt"
+	"math"
+)
+
+// Gradient holds information about a texture which blends between two colors across the surface
+type Gradient struct {
+	StartColor   shading.Color `json:"start_color"`
+	EndColor     shading.Color `json:"end_color"`
+	GradientType string        `json:"gradient_type"` // either "linear", across the texture, or "radial", out from its center
+	Angle        float64       `json:"angle"`         // direction (in degrees, counterclockwise from U) in which a linear gradient runs
+	directionU   float64
+	directionV   float64
+}
+
+// Setup validates and fills the internal fields of a Gradient
+func (gt *Gradient) Setup() (*Gradient, error) {
+	switch gt.GradientType {
+	case "":
+		gt.GradientType = "linear"
+	case "linear", "radial":
+	default:
+		return nil, fmt.Errorf("gradient type (%s) is not one of linear or radial", gt.GradientType)
+	}
+	gt.directionU = math.Cos((math.Pi / 180.0) * gt.Angle)
+	gt.directionV = math.Sin((math.Pi / 180.0) * gt.Angle)
+	return gt, nil
+}
+
+// Value returns the color of the gradient at the given texture coordinates
+// both kinds of gradient run from the start color at one side (or the center) to the end color at the other (or the edge)
+func (gt *Gradient) Value(u, v float64) shading.Color {
+	u -= 0.5
+	v -= 0.5
+	var t float64
+	if gt.GradientType == "radial" {
+		t = 2.0 * math.Hypot(u, v)
+	} else {
+		// scaled so that the corners of the texture land on the ends of the gradient whatever the angle
+		t = (u*gt.directionU+v*gt.directionV)/(math.Abs(gt.directionU)+math.Abs(gt.directionV)) + 0.5
+	}
+	return mixColors(gt.StartColor, gt.EndColor, t)
+}
@@ -0,0 +1,33 @@
+package texture
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"fmt"
+)
+
+// resolve finds a texture used by a composite texture by name
+// textures may only use textures defined before them, which keeps the graph free of cycles
+func resolve(textures map[string]Texture, name, role string) (Texture, error) {
+	if name == "" {
+		return nil, fmt.Errorf("no %s texture given", role)
+	}
+	t, ok := textures[name]
+	if !ok {
+		return nil, fmt.Errorf("%s texture (%s) is not defined before it is used", role, name)
+	}
+	return t, nil
+}
+
+// surfacePoint returns the point used when a composite texture is asked for its color from texture coordinates alone
+func surfacePoint(u, v float64) geometry.Point {
+	return geometry.Point{
+		X: u,
+		Y: v,
+	}
+}
+
+// luminance returns the brightness of a color as perceived by the eye
+func luminance(c shading.Color) float64 {
+	return 0.2126*c.Red + 0.7152*c.Green + 0.0722*c.Blue
+}
@@ -1,53 +1,372 @@
 package texture
 
 import (
+	"bytes"
 	"fluorescence/shading"
 	"fmt"
 	"image"
 	"image/jpeg"
 	"image/png"
-	"os"
-	"strings"
+	"io/ioutil"
+	"math"
+
+	"golang.org/x/image/tiff"
 )
 
 // Image holds information about a texture based on an image
 type Image struct {
-	FileName  string      `json:"image_file_name"`
-	Gamma     float64     `json:"gamma"`
-	Magnitude float64     `json:"magnitude"`
-	Image     image.Image `json:"-"`
+	FileName  string  `json:"image_file_name"`
+	Gamma     float64 `json:"gamma"` // removed from low dynamic range images, while high dynamic range images are already linear
+	Magnitude float64 `json:"magnitude"`
+	Filter    string  `json:"filter"` // how texels are combined, one of "nearest" (the default), "bilinear", "bicubic", "trilinear", or "ewa"
+	Wrap      string  `json:"wrap"`   // how texels outside the image are found, one of "repeat", "clamp", or "mirror"
+	WrapV     string  `json:"wrap_v"` // how texels above and below the image are found, if not the same as Wrap
+	texels    *texels // the decoded image, which may be shared with other Images
 }
 
 // Load decodes the image from the given filename and performs other setup actions
-func (it *Image) Load() error {
-	imageFile, err := os.Open(it.FileName)
+// PNG (including 16-bit), JPEG, TIFF, Radiance RGBE, PFM, and OpenEXR images can be decoded
+// images already decoded with the same gamma are taken from the cache, if one is given
+func (it *Image) Load(cache *Cache) error {
+	err := it.checkModes()
 	if err != nil {
 		return err
 	}
-	if strings.HasSuffix(it.FileName, ".png") {
-		it.Image, err = png.Decode(imageFile)
-		if err != nil {
-			return err
+	t, err := cache.load(it.FileName, it.Gamma)
+	if err != nil {
+		return err
+	}
+	it.setTexels(t)
+	return nil
+}
+
+// LoadData decodes the image from data already in memory, such as an image embedded in a model file
+// the FileName is only used to describe the image in errors
+func (it *Image) LoadData(data []byte) error {
+	err := it.checkModes()
+	if err != nil {
+		return err
+	}
+	t, err := decodeTexelData(it.FileName, data, it.Gamma)
+	if err != nil {
+		return err
+	}
+	it.setTexels(t)
+	return nil
+}
+
+// checkModes validates the filter and wrap modes, filling in the defaults if they are not given
+func (it *Image) checkModes() error {
+	switch it.Filter {
+	case "":
+		it.Filter = "nearest"
+	case "nearest", "bilinear", "bicubic", "trilinear", "ewa":
+	default:
+		return fmt.Errorf("image filter (%s) is not one of nearest, bilinear, bicubic, trilinear, or ewa", it.Filter)
+	}
+	var err error
+	it.Wrap, err = CheckWrap(it.Wrap)
+	if err != nil {
+		return fmt.Errorf("image %v", err)
+	}
+	if it.WrapV == "" {
+		it.WrapV = it.Wrap
+	}
+	it.WrapV, err = CheckWrap(it.WrapV)
+	if err != nil {
+		return fmt.Errorf("image %v", err)
+	}
+	return nil
+}
+
+// setTexels uses the decoded image, building its mipmap if the filter needs one
+func (it *Image) setTexels(t *texels) {
+	it.texels = t
+	if it.Filter == "trilinear" || it.Filter == "ewa" {
+		it.texels.buildMipmap()
+	}
+}
+
+// decodeTexels reads an image file and decodes it into linear colors
+func decodeTexels(fileName string, gamma float64) (*texels, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return decodeTexelData(fileName, data, gamma)
+}
+
+// decodeTexelData decodes an image into linear colors
+// the format is found from the image's contents rather than its name
+// low dynamic range formats have their gamma removed, while high dynamic range formats are already linear
+func decodeTexelData(fileName string, data []byte, gamma float64) (*texels, error) {
+	var err error
+	var level mipLevel
+	format := sniffFormat(data)
+	switch format {
+	case "png", "jpeg", "tiff":
+		var img image.Image
+		switch format {
+		case "png":
+			img, err = png.Decode(bytes.NewReader(data))
+		case "jpeg":
+			img, err = jpeg.Decode(bytes.NewReader(data))
+		default:
+			img, err = tiff.Decode(bytes.NewReader(data))
 		}
-	} else if strings.HasSuffix(it.FileName, ".jpg") || strings.HasSuffix(it.FileName, ".jpeg") {
-		it.Image, err = jpeg.Decode(imageFile)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("decoding %s (%s): %v", format, fileName, err)
 		}
-	} else {
-		return fmt.Errorf("unknown image filetype (%s)", it.FileName)
+		return newTexels(img, gamma), nil
+	case "radiance":
+		level, err = decodeRadiance(data)
+	case "pfm":
+		level, err = decodePFM(data)
+	case "exr":
+		level, err = decodeEXR(data)
+	default:
+		return nil, fmt.Errorf("unknown image filetype (%s)", fileName)
 	}
-	return nil
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s (%s): %v", format, fileName, err)
+	}
+	return &texels{
+		levels: []mipLevel{level},
+	}, nil
+}
+
+// sniffFormat returns the format of an image file from the magic number at its start
+func sniffFormat(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return "png"
+	case bytes.HasPrefix(data, []byte{0xff, 0xd8, 0xff}):
+		return "jpeg"
+	case bytes.HasPrefix(data, []byte("II*\x00")) || bytes.HasPrefix(data, []byte("MM\x00*")):
+		return "tiff"
+	case bytes.HasPrefix(data, []byte("#?")):
+		return "radiance"
+	case bytes.HasPrefix(data, []byte("PF")) || bytes.HasPrefix(data, []byte("Pf")):
+		return "pfm"
+	case bytes.HasPrefix(data, exrMagic):
+		return "exr"
+	}
+	return ""
 }
 
 // Value returns the color of the image at the given texture coordinates
-// parameters u and v have a valid range [0.0, 1.0)
+// coordinates outside [0.0, 1.0) are brought back in by the wrap mode
 func (it *Image) Value(u, v float64) shading.Color {
-	// convert to image coordinates
-	x := int(u * float64(it.Image.Bounds().Dx()-1))
-	y := int((1.0 - v) * float64(it.Image.Bounds().Dy()-1))
-	// get the color of the image at that point
-	color := it.Image.At(x, y)
-	// convert to a color, de-gamma, and apply magnitude
-	return shading.MakeColor(color).Pow(it.Gamma).MultScalar(it.Magnitude)
+	var c shading.Color
+	switch it.Filter {
+	case "bilinear", "trilinear", "ewa":
+		c = it.bilinear(0, u, v)
+	case "bicubic":
+		c = it.bicubic(0, u, v)
+	default:
+		c = it.nearest(0, u, v)
+	}
+	return c.MultScalar(it.Magnitude)
+}
+
+// FilteredValue returns the average color of the image over the footprint centered on (u, v)
+// only trilinear and EWA filtering make use of the footprint
+func (it *Image) FilteredValue(u, v float64, footprint Footprint) shading.Color {
+	switch it.Filter {
+	case "trilinear":
+		return it.trilinear(u, v, footprint).MultScalar(it.Magnitude)
+	case "ewa":
+		return it.ewa(u, v, footprint).MultScalar(it.Magnitude)
+	default:
+		return it.Value(u, v)
+	}
+}
+
+// levelSize returns the width and height, in texels, of a mipmap level
+// level 0 is the image itself
+func (it *Image) levelSize(level int) (int, int) {
+	return it.texels.levels[level].width, it.texels.levels[level].height
+}
+
+// texel returns the linear color of a single texel of a mipmap level
+// texel coordinates outside the level are brought back in by the wrap modes
+func (it *Image) texel(level, x, y int) shading.Color {
+	width, height := it.levelSize(level)
+	return it.texels.levels[level].at(wrapTexel(it.Wrap, x, width), wrapTexel(it.WrapV, y, height))
+}
+
+// levelCount returns the number of mipmap levels below the image itself
+func (it *Image) levelCount() int {
+	return len(it.texels.levels) - 1
+}
+
+// nearest returns the color of the texel containing (u, v) on a mipmap level
+func (it *Image) nearest(level int, u, v float64) shading.Color {
+	width, height := it.levelSize(level)
+	x := int(math.Floor(u * float64(width)))
+	y := int(math.Floor((1.0 - v) * float64(height)))
+	return it.texel(level, x, y)
+}
+
+// bilinear returns the color at (u, v) on a mipmap level, linearly interpolated between the four nearest texels
+func (it *Image) bilinear(level int, u, v float64) shading.Color {
+	width, height := it.levelSize(level)
+	// texel centers lie halfway between integer coordinates
+	x := u*float64(width) - 0.5
+	y := (1.0-v)*float64(height) - 0.5
+	x0 := math.Floor(x)
+	y0 := math.Floor(y)
+	fx := x - x0
+	fy := y - y0
+	ix := int(x0)
+	iy := int(y0)
+	top := it.texel(level, ix, iy).MultScalar(1.0 - fx).Add(it.texel(level, ix+1, iy).MultScalar(fx))
+	bottom := it.texel(level, ix, iy+1).MultScalar(1.0 - fx).Add(it.texel(level, ix+1, iy+1).MultScalar(fx))
+	return top.MultScalar(1.0 - fy).Add(bottom.MultScalar(fy))
+}
+
+// bicubic returns the color at (u, v) on a mipmap level, interpolated by a Catmull-Rom spline through the sixteen nearest texels
+func (it *Image) bicubic(level int, u, v float64) shading.Color {
+	width, height := it.levelSize(level)
+	x := u*float64(width) - 0.5
+	y := (1.0-v)*float64(height) - 0.5
+	x0 := math.Floor(x)
+	y0 := math.Floor(y)
+	wx := catmullRomWeights(x - x0)
+	wy := catmullRomWeights(y - y0)
+	ix := int(x0)
+	iy := int(y0)
+	c := shading.Color{}
+	for j := 0; j < 4; j++ {
+		row := shading.Color{}
+		for i := 0; i < 4; i++ {
+			row = row.Add(it.texel(level, ix+i-1, iy+j-1).MultScalar(wx[i]))
+		}
+		c = c.Add(row.MultScalar(wy[j]))
+	}
+	// the spline overshoots near sharp edges, which must not produce negative light
+	return shading.Color{
+		Red:   math.Max(c.Red, 0.0),
+		Green: math.Max(c.Green, 0.0),
+		Blue:  math.Max(c.Blue, 0.0),
+	}
+}
+
+// trilinear returns the color at (u, v) interpolated between the two mipmap levels
+// whose texels are closest in size to the footprint
+func (it *Image) trilinear(u, v float64, footprint Footprint) shading.Color {
+	width, height := it.levelSize(0)
+	lengthX := math.Hypot(footprint.DUDX*float64(width), footprint.DVDX*float64(height))
+	lengthY := math.Hypot(footprint.DUDY*float64(width), footprint.DVDY*float64(height))
+	lod := it.clampLOD(math.Log2(math.Max(lengthX, lengthY)))
+	level := int(lod)
+	if level == it.levelCount() {
+		return it.bilinear(level, u, v)
+	}
+	fraction := lod - float64(level)
+	return it.bilinear(level, u, v).MultScalar(1.0 - fraction).Add(it.bilinear(level+1, u, v).MultScalar(fraction))
+}
+
+// maxAnisotropy limits how elongated a footprint EWA filtering will follow
+// longer footprints are widened, trading sharpness for a bounded number of texel reads
+const maxAnisotropy = 16.0
+
+// ewa returns the color at (u, v) averaged with a Gaussian-weighted elliptical filter
+// shaped to the footprint, on the two mipmap levels closest to the footprint's minor axis
+func (it *Image) ewa(u, v float64, footprint Footprint) shading.Color {
+	du0, dv0 := footprint.DUDX, footprint.DVDX
+	du1, dv1 := footprint.DUDY, footprint.DVDY
+	// make the first axis the major axis
+	if du0*du0+dv0*dv0 < du1*du1+dv1*dv1 {
+		du0, dv0, du1, dv1 = du1, dv1, du0, dv0
+	}
+	majorLength := math.Hypot(du0, dv0)
+	minorLength := math.Hypot(du1, dv1)
+	if minorLength*maxAnisotropy < majorLength && minorLength > 0.0 {
+		scale := majorLength / (minorLength * maxAnisotropy)
+		du1 *= scale
+		dv1 *= scale
+		minorLength *= scale
+	}
+	if minorLength == 0.0 {
+		return it.bilinear(0, u, v)
+	}
+	width, height := it.levelSize(0)
+	lod := it.clampLOD(math.Log2(minorLength * math.Max(float64(width), float64(height))))
+	level := int(lod)
+	if level == it.levelCount() {
+		return it.ewaLevel(level, u, v, du0, dv0, du1, dv1)
+	}
+	fraction := lod - float64(level)
+	return it.ewaLevel(level, u, v, du0, dv0, du1, dv1).MultScalar(1.0 - fraction).Add(
+		it.ewaLevel(level+1, u, v, du0, dv0, du1, dv1).MultScalar(fraction))
+}
+
+// ewaLevel applies an elliptical Gaussian filter with the given axes, centered on (u, v), to a single mipmap level
+func (it *Image) ewaLevel(level int, u, v, du0, dv0, du1, dv1 float64) shading.Color {
+	width, height := it.levelSize(level)
+	// convert to texel space, where y runs down the image
+	x := u*float64(width) - 0.5
+	y := (1.0-v)*float64(height) - 0.5
+	dx0, dy0 := du0*float64(width), -dv0*float64(height)
+	dx1, dy1 := du1*float64(width), -dv1*float64(height)
+
+	// coefficients of the implicit ellipse a*x^2 + b*x*y + c*y^2 = f, padded by a texel so it covers at least one
+	a := dy0*dy0 + dy1*dy1 + 1.0
+	b := -2.0 * (dx0*dy0 + dx1*dy1)
+	c := dx0*dx0 + dx1*dx1 + 1.0
+	f := a*c - b*b/4.0
+	a /= f
+	b /= f
+	c /= f
+
+	// bounding box of the ellipse
+	determinant := -b*b + 4.0*a*c
+	halfWidth := 2.0 * math.Sqrt(determinant*c) / determinant
+	halfHeight := 2.0 * math.Sqrt(determinant*a) / determinant
+	x0 := int(math.Ceil(x - halfWidth))
+	x1 := int(math.Floor(x + halfWidth))
+	y0 := int(math.Ceil(y - halfHeight))
+	y1 := int(math.Floor(y + halfHeight))
+
+	sum := shading.Color{}
+	totalWeight := 0.0
+	for ty := y0; ty <= y1; ty++ {
+		offsetY := float64(ty) - y
+		for tx := x0; tx <= x1; tx++ {
+			offsetX := float64(tx) - x
+			radiusSquared := a*offsetX*offsetX + b*offsetX*offsetY + c*offsetY*offsetY
+			if radiusSquared < 1.0 {
+				// gaussian falloff, reaching zero at the edge of the ellipse
+				weight := math.Exp(-2.0*radiusSquared) - math.Exp(-2.0)
+				sum = sum.Add(it.texel(level, tx, ty).MultScalar(weight))
+				totalWeight += weight
+			}
+		}
+	}
+	if totalWeight == 0.0 {
+		return it.bilinear(level, u, v)
+	}
+	return sum.DivScalar(totalWeight)
+}
+
+// clampLOD clamps a level of detail to the levels present in the mipmap
+func (it *Image) clampLOD(lod float64) float64 {
+	if math.IsNaN(lod) || lod < 0.0 {
+		return 0.0
+	}
+	return math.Min(lod, float64(it.levelCount()))
+}
+
+// catmullRomWeights returns the weights of the four texels around a point
+// a fraction t of the way between the middle two
+func catmullRomWeights(t float64) [4]float64 {
+	t2 := t * t
+	t3 := t2 * t
+	return [4]float64{
+		0.5 * (-t3 + 2.0*t2 - t),
+		0.5 * (3.0*t3 - 5.0*t2 + 2.0),
+		0.5 * (-3.0*t3 + 4.0*t2 + t),
+		0.5 * (t3 - t2),
+	}
 }
@@ -0,0 +1,178 @@
+package texture
+
+import (
+	"math"
+	"testing"
+)
+
+// greyImage returns an Image of grey texels, listed row by row from the top, set up with the given filter and wrap mode
+func greyImage(t *testing.T, width, height int, values []float32, filter, wrap string) *Image {
+	level := mipLevel{
+		width:  width,
+		height: height,
+		texels: make([]float32, 3*width*height),
+	}
+	for i, value := range values {
+		level.texels[3*i] = value
+		level.texels[3*i+1] = value
+		level.texels[3*i+2] = value
+	}
+	it := &Image{
+		Magnitude: 1.0,
+		Filter:    filter,
+		Wrap:      wrap,
+	}
+	if err := it.checkModes(); err != nil {
+		t.Fatalf("Error checking image modes: %s\n", err)
+	}
+	it.setTexels(&texels{
+		levels: []mipLevel{level},
+	})
+	return it
+}
+
+// stripes returns the values of a square image whose columns alternate between black and white, starting with white
+func stripes(size int) []float32 {
+	values := make([]float32, size*size)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x += 2 {
+			values[y*size+x] = 1.0
+		}
+	}
+	return values
+}
+
+// checkerboard returns the values of a square image of alternating black and white texels
+func checkerboard(size int) []float32 {
+	values := make([]float32, size*size)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			values[y*size+x] = float32((x + y) % 2)
+		}
+	}
+	return values
+}
+
+func TestImageDefaultFilterIsNearest(t *testing.T) {
+	it := greyImage(t, 2, 1, []float32{0.0, 1.0}, "", "clamp")
+	if it.Filter != "nearest" {
+		t.Errorf("Expected filter nearest but got %s\n", it.Filter)
+	}
+	if c := it.Value(0.45, 0.5); c.Red != 0.0 {
+		t.Errorf("Expected the left texel (0) but got %f\n", c.Red)
+	}
+}
+
+func TestImageBilinear(t *testing.T) {
+	it := greyImage(t, 2, 1, []float32{0.0, 1.0}, "bilinear", "clamp")
+	for _, c := range []struct {
+		u, expected float64
+	}{
+		{0.25, 0.0},
+		{0.5, 0.5},
+		{0.625, 0.75},
+		{1.0, 1.0},
+	} {
+		if value := it.Value(c.u, 0.5).Red; math.Abs(value-c.expected) > 1e-6 {
+			t.Errorf("Expected %f at u %f but got %f\n", c.expected, c.u, value)
+		}
+	}
+}
+
+func TestImageBicubic(t *testing.T) {
+	it := greyImage(t, 4, 1, []float32{0.0, 0.0, 1.0, 1.0}, "bicubic", "clamp")
+	// the spline passes through texel centers, and halfway along a symmetric step
+	for _, c := range []struct {
+		u, expected float64
+	}{
+		{0.375, 0.0},
+		{0.5, 0.5},
+		{0.625, 1.0},
+	} {
+		if value := it.Value(c.u, 0.5).Red; math.Abs(value-c.expected) > 1e-6 {
+			t.Errorf("Expected %f at u %f but got %f\n", c.expected, c.u, value)
+		}
+	}
+	// the spline undershoots before the step, which is clamped to zero
+	for u := 0.0; u < 1.0; u += 0.01 {
+		if value := it.Value(u, 0.5).Red; value < 0.0 {
+			t.Fatalf("Expected no negative values but got %f at u %f\n", value, u)
+		}
+	}
+}
+
+func TestImageTrilinear(t *testing.T) {
+	it := greyImage(t, 4, 4, checkerboard(4), "trilinear", "repeat")
+	// a footprint the size of a texel reads the image itself
+	texel := Footprint{DUDX: 0.25, DVDY: 0.25}
+	if value := it.FilteredValue(0.125, 0.875, texel).Red; math.Abs(value-0.0) > 1e-6 {
+		t.Errorf("Expected the top left texel (0) but got %f\n", value)
+	}
+	if value := it.FilteredValue(0.375, 0.875, texel).Red; math.Abs(value-1.0) > 1e-6 {
+		t.Errorf("Expected the second texel (1) but got %f\n", value)
+	}
+	// a footprint covering the image reads its average
+	whole := Footprint{DUDX: 1.0, DVDY: 1.0}
+	if value := it.FilteredValue(0.3, 0.6, whole).Red; math.Abs(value-0.5) > 1e-6 {
+		t.Errorf("Expected the average (0.5) but got %f\n", value)
+	}
+}
+
+func TestImageEWA(t *testing.T) {
+	it := greyImage(t, 8, 8, stripes(8), "ewa", "repeat")
+	// a footprint long along the stripes stays sharp across them, where trilinear filtering would blur them together
+	along := Footprint{DUDX: 1e-4, DVDY: 0.5}
+	if value := it.FilteredValue(0.0625, 0.5, along).Red; value < 0.9 {
+		t.Errorf("Expected a white stripe (near 1) but got %f\n", value)
+	}
+	if value := it.FilteredValue(0.1875, 0.5, along).Red; value > 0.1 {
+		t.Errorf("Expected a black stripe (near 0) but got %f\n", value)
+	}
+	// a footprint wide across the stripes averages them
+	across := Footprint{DUDX: 0.5, DVDY: 0.5}
+	if value := it.FilteredValue(0.0625, 0.5, across).Red; math.Abs(value-0.5) > 0.05 {
+		t.Errorf("Expected the average (near 0.5) but got %f\n", value)
+	}
+}
+
+func TestImageWrapModes(t *testing.T) {
+	values := []float32{0.0, 0.25, 0.5, 1.0}
+	for _, c := range []struct {
+		wrap     string
+		u        float64
+		expected float64
+	}{
+		{"repeat", 1.125, 0.0},
+		{"repeat", -0.125, 1.0},
+		{"clamp", 1.125, 1.0},
+		{"clamp", -0.125, 0.0},
+		{"mirror", 1.125, 1.0},
+		{"mirror", -0.125, 0.0},
+	} {
+		it := greyImage(t, 4, 1, values, "nearest", c.wrap)
+		if value := it.Value(c.u, 0.5).Red; math.Abs(value-c.expected) > 1e-6 {
+			t.Errorf("Expected %f at u %f with %s wrap but got %f\n", c.expected, c.u, c.wrap, value)
+		}
+	}
+	if _, err := CheckWrap("tile"); err == nil {
+		t.Errorf("Expected an error for an unknown wrap mode\n")
+	}
+}
+
+func TestPlacement(t *testing.T) {
+	p, err := NewPlacement([2]float64{2.0, 4.0}, [2]float64{0.5, 0.0}, 90.0)
+	if err != nil {
+		t.Fatalf("Error creating placement: %s\n", err)
+	}
+	// (1, 0) turns to (0, 1), then scales to (0, 4), then moves to (0.5, 4)
+	u, v := p.Map(1.0, 0.0)
+	if math.Abs(u-0.5) > 1e-9 || math.Abs(v-4.0) > 1e-9 {
+		t.Errorf("Expected (0.5, 4) but got (%f, %f)\n", u, v)
+	}
+	if _, err := NewPlacement([2]float64{1.0, 0.0}, [2]float64{}, 0.0); err == nil {
+		t.Errorf("Expected an error for a zero scale component\n")
+	}
+	if x := WrapCoordinate("mirror", 1.25); math.Abs(x-0.75) > 1e-9 {
+		t.Errorf("Expected 0.75 but got %f\n", x)
+	}
+}
@@ -0,0 +1,39 @@
+package texture
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"math"
+)
+
+// Invert holds information about a texture which is the negative of another texture
+// each channel is subtracted from one, so colors brighter than white become black
+type Invert struct {
+	TextureName string `json:"texture_name"`
+	texture     Texture
+}
+
+// Setup finds the texture being inverted
+func (it *Invert) Setup(textures map[string]Texture) (*Invert, error) {
+	var err error
+	it.texture, err = resolve(textures, it.TextureName, "inverted")
+	if err != nil {
+		return nil, err
+	}
+	return it, nil
+}
+
+// Value returns the negative of the texture at the given texture coordinates
+func (it *Invert) Value(u, v float64) shading.Color {
+	return it.CompositeValue(u, v, surfacePoint(u, v), Footprint{})
+}
+
+// CompositeValue returns the negative of the texture at a point
+func (it *Invert) CompositeValue(u, v float64, p geometry.Point, footprint Footprint) shading.Color {
+	c := Sample(it.texture, u, v, p, footprint)
+	return shading.Color{
+		Red:   math.Max(1.0-c.Red, 0.0),
+		Green: math.Max(1.0-c.Green, 0.0),
+		Blue:  math.Max(1.0-c.Blue, 0.0),
+	}
+}
@@ -0,0 +1,57 @@
+package texture
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"fmt"
+	"math"
+)
+
+// Marble holds information about a solid texture of veins running through stone
+// the veins are bands along the x axis, bent by turbulence
+type Marble struct {
+	VeinColor  shading.Color `json:"vein_color"`
+	StoneColor shading.Color `json:"stone_color"`
+	Scale      float64       `json:"scale"`      // number of veins per unit of distance
+	Distortion float64       `json:"distortion"` // how strongly turbulence bends the veins
+	Fractal
+}
+
+// Setup validates and defaults the fields of a Marble
+func (mt *Marble) Setup() (*Marble, error) {
+	if mt.Scale < 0.0 {
+		return nil, fmt.Errorf("marble scale is negative")
+	}
+	if mt.Scale == 0.0 {
+		mt.Scale = 1.0
+	}
+	if mt.Distortion == 0.0 {
+		mt.Distortion = 5.0
+	}
+	if mt.Octaves == 0 {
+		mt.Octaves = 6
+	}
+	err := mt.setupFractal()
+	if err != nil {
+		return nil, err
+	}
+	return mt, nil
+}
+
+// Value returns the color of the marble on the plane of texture coordinates
+func (mt *Marble) Value(u, v float64) shading.Color {
+	return mt.SolidValue(u, v, geometry.Point{
+		X: u,
+		Y: v,
+		Z: 0.0,
+	})
+}
+
+// SolidValue returns the color of the marble at a point
+func (mt *Marble) SolidValue(u, v float64, p geometry.Point) shading.Color {
+	p = scalePoint(p, mt.Scale)
+	phase := math.Pi * (p.X + mt.Distortion*mt.turbulence(p))
+	// sharpen the bands so the veins are thin and the stone between them is broad
+	vein := math.Pow(math.Abs(math.Sin(phase)), 0.25)
+	return mixColors(mt.VeinColor, mt.StoneColor, vein)
+}
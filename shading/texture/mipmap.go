@@ -0,0 +1,122 @@
+package texture
+
+import (
+	"fluorescence/shading"
+	"image"
+	"math"
+)
+
+// texels holds a decoded image as linear colors, along with its mipmap
+type texels struct {
+	levels []mipLevel // the image itself, followed by successively halved copies of it once a mipmap is built
+}
+
+// mipLevel is a single image in a mipmap, holding linear colors
+type mipLevel struct {
+	width  int
+	height int
+	texels []float32 // red, green, and blue of each texel, row by row from the top
+}
+
+// newTexels converts a decoded image into linear colors by removing its gamma
+func newTexels(img image.Image, gamma float64) *texels {
+	bounds := img.Bounds()
+	level := mipLevel{
+		width:  bounds.Dx(),
+		height: bounds.Dy(),
+		texels: make([]float32, 3*bounds.Dx()*bounds.Dy()),
+	}
+	// every 16-bit channel value maps to one linear value, so the power only needs computing once per value
+	var linear [math.MaxUint16 + 1]float32
+	for i := range linear {
+		linear[i] = float32(math.Pow(float64(i)/math.MaxUint16, gamma))
+	}
+	for y := 0; y < level.height; y++ {
+		for x := 0; x < level.width; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			i := 3 * (y*level.width + x)
+			level.texels[i] = linear[r]
+			level.texels[i+1] = linear[g]
+			level.texels[i+2] = linear[b]
+		}
+	}
+	return &texels{
+		levels: []mipLevel{level},
+	}
+}
+
+// bytes returns the memory used by all levels
+func (t *texels) bytes() int {
+	total := 0
+	for _, level := range t.levels {
+		total += 4 * len(level.texels)
+	}
+	return total
+}
+
+// at returns the color of the texel at (x, y)
+func (ml mipLevel) at(x, y int) shading.Color {
+	i := 3 * (y*ml.width + x)
+	return shading.Color{
+		Red:   float64(ml.texels[i]),
+		Green: float64(ml.texels[i+1]),
+		Blue:  float64(ml.texels[i+2]),
+	}
+}
+
+// buildMipmap fills the mipmap by repeatedly halving the image, until a single texel remains
+// each texel is the average of the (up to) four texels it covers on the level above
+// a mipmap that has already been built is left alone
+func (t *texels) buildMipmap() {
+	if len(t.levels) > 1 {
+		return
+	}
+	for {
+		previous := t.levels[len(t.levels)-1]
+		if previous.width == 1 && previous.height == 1 {
+			return
+		}
+		next := mipLevel{
+			width:  maxInt(previous.width/2, 1),
+			height: maxInt(previous.height/2, 1),
+		}
+		next.texels = make([]float32, 3*next.width*next.height)
+		for y := 0; y < next.height; y++ {
+			// levels of odd size leave their last row or column to the texels before them
+			y0, y1 := 2*y, minInt(2*y+1, previous.height-1)
+			for x := 0; x < next.width; x++ {
+				x0, x1 := 2*x, minInt(2*x+1, previous.width-1)
+				i := 3 * (y*next.width + x)
+				for c := 0; c < 3; c++ {
+					next.texels[i+c] = (previous.texels[3*(y0*previous.width+x0)+c] +
+						previous.texels[3*(y0*previous.width+x1)+c] +
+						previous.texels[3*(y1*previous.width+x0)+c] +
+						previous.texels[3*(y1*previous.width+x1)+c]) / 4.0
+				}
+			}
+		}
+		t.levels = append(t.levels, next)
+	}
+}
+
+// nonNegative replaces negative and NaN values, which floating point images may hold but light can not, with zero
+func nonNegative(x float32) float32 {
+	if x > 0.0 {
+		return x
+	}
+	return 0.0
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,46 @@
+package texture
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+)
+
+// Mix holds information about a texture which blends between two other textures by the brightness of a mask texture
+// where the mask is black the first texture shows, and where it is white the second does
+type Mix struct {
+	TextureAName    string `json:"texture_a_name"`
+	TextureBName    string `json:"texture_b_name"`
+	MaskTextureName string `json:"mask_texture_name"`
+	textureA        Texture
+	textureB        Texture
+	mask            Texture
+}
+
+// Setup finds the textures being mixed and the mask mixing them
+func (mt *Mix) Setup(textures map[string]Texture) (*Mix, error) {
+	var err error
+	mt.textureA, err = resolve(textures, mt.TextureAName, "first mixed")
+	if err != nil {
+		return nil, err
+	}
+	mt.textureB, err = resolve(textures, mt.TextureBName, "second mixed")
+	if err != nil {
+		return nil, err
+	}
+	mt.mask, err = resolve(textures, mt.MaskTextureName, "mask")
+	if err != nil {
+		return nil, err
+	}
+	return mt, nil
+}
+
+// Value returns the blend of the two textures at the given texture coordinates
+func (mt *Mix) Value(u, v float64) shading.Color {
+	return mt.CompositeValue(u, v, surfacePoint(u, v), Footprint{})
+}
+
+// CompositeValue returns the blend of the two textures at a point
+func (mt *Mix) CompositeValue(u, v float64, p geometry.Point, footprint Footprint) shading.Color {
+	t := luminance(Sample(mt.mask, u, v, p, footprint))
+	return mixColors(Sample(mt.textureA, u, v, p, footprint), Sample(mt.textureB, u, v, p, footprint), t)
+}
@@ -0,0 +1,38 @@
+package texture
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+)
+
+// Multiply holds information about a texture which is the product of two other textures
+type Multiply struct {
+	TextureAName string `json:"texture_a_name"`
+	TextureBName string `json:"texture_b_name"`
+	textureA     Texture
+	textureB     Texture
+}
+
+// Setup finds the textures being multiplied
+func (mt *Multiply) Setup(textures map[string]Texture) (*Multiply, error) {
+	var err error
+	mt.textureA, err = resolve(textures, mt.TextureAName, "first multiplied")
+	if err != nil {
+		return nil, err
+	}
+	mt.textureB, err = resolve(textures, mt.TextureBName, "second multiplied")
+	if err != nil {
+		return nil, err
+	}
+	return mt, nil
+}
+
+// Value returns the product of the two textures at the given texture coordinates
+func (mt *Multiply) Value(u, v float64) shading.Color {
+	return mt.CompositeValue(u, v, surfacePoint(u, v), Footprint{})
+}
+
+// CompositeValue returns the product of the two textures at a point
+func (mt *Multiply) CompositeValue(u, v float64, p geometry.Point, footprint Footprint) shading.Color {
+	return Sample(mt.textureA, u, v, p, footprint).MultColor(Sample(mt.textureB, u, v, p, footprint))
+}
@@ -0,0 +1,227 @@
+package texture
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// permutation is a fixed shuffle of [0, 256), repeated twice so lookups never need wrapping
+// it is seeded so that noise, and so every procedural texture, is the same from render to render
+var permutation = func() [512]int {
+	var table [512]int
+	for i, p := range rand.New(rand.NewSource(1)).Perm(256) {
+		table[i] = p
+		table[i+256] = p
+	}
+	return table
+}()
+
+// Fractal describes noise summed over several octaves, each at a higher frequency and lower amplitude than the last
+type Fractal struct {
+	Basis      string  `json:"basis"`      // the noise summed, either "perlin" or "simplex"
+	Octaves    int     `json:"octaves"`    // number of octaves summed
+	Lacunarity float64 `json:"lacunarity"` // how much the frequency grows between octaves
+	Gain       float64 `json:"gain"`       // how much the amplitude shrinks between octaves
+}
+
+// setupFractal validates and defaults a Fractal's fields
+func (f *Fractal) setupFractal() error {
+	switch f.Basis {
+	case "":
+		f.Basis = "perlin"
+	case "perlin", "simplex":
+	default:
+		return fmt.Errorf("noise basis (%s) is not one of perlin or simplex", f.Basis)
+	}
+	if f.Octaves < 0 {
+		return fmt.Errorf("noise octave count is negative")
+	}
+	if f.Octaves == 0 {
+		f.Octaves = 1
+	}
+	if f.Lacunarity == 0.0 {
+		f.Lacunarity = 2.0
+	}
+	if f.Gain == 0.0 {
+		f.Gain = 0.5
+	}
+	return nil
+}
+
+// noise returns a single octave of the basis noise at a point, roughly within [-1, 1]
+func (f Fractal) noise(p geometry.Point) float64 {
+	if f.Basis == "simplex" {
+		return simplex(p)
+	}
+	return perlin(p)
+}
+
+// fbm returns fractional Brownian motion at a point, the signed sum of all octaves, roughly within [-1, 1]
+func (f Fractal) fbm(p geometry.Point) float64 {
+	total := 0.0
+	amplitude := 1.0
+	frequency := 1.0
+	normalization := 0.0
+	for i := 0; i < f.Octaves; i++ {
+		total += amplitude * f.noise(scalePoint(p, frequency))
+		normalization += amplitude
+		amplitude *= f.Gain
+		frequency *= f.Lacunarity
+	}
+	return total / normalization
+}
+
+// turbulence returns the sum of the magnitudes of all octaves at a point, roughly within [0, 1]
+func (f Fractal) turbulence(p geometry.Point) float64 {
+	total := 0.0
+	amplitude := 1.0
+	frequency := 1.0
+	normalization := 0.0
+	for i := 0; i < f.Octaves; i++ {
+		total += amplitude * math.Abs(f.noise(scalePoint(p, frequency)))
+		normalization += amplitude
+		amplitude *= f.Gain
+		frequency *= f.Lacunarity
+	}
+	return total / normalization
+}
+
+// perlin returns improved Perlin gradient noise at a point, within [-1, 1]
+func perlin(p geometry.Point) float64 {
+	fx, fy, fz := math.Floor(p.X), math.Floor(p.Y), math.Floor(p.Z)
+	xi, yi, zi := int(fx)&255, int(fy)&255, int(fz)&255
+	x, y, z := p.X-fx, p.Y-fy, p.Z-fz
+	u, v, w := fade(x), fade(y), fade(z)
+
+	a := permutation[xi] + yi
+	aa := permutation[a] + zi
+	ab := permutation[a+1] + zi
+	b := permutation[xi+1] + yi
+	ba := permutation[b] + zi
+	bb := permutation[b+1] + zi
+
+	return lerp(w,
+		lerp(v,
+			lerp(u, gradient(permutation[aa], x, y, z), gradient(permutation[ba], x-1, y, z)),
+			lerp(u, gradient(permutation[ab], x, y-1, z), gradient(permutation[bb], x-1, y-1, z))),
+		lerp(v,
+			lerp(u, gradient(permutation[aa+1], x, y, z-1), gradient(permutation[ba+1], x-1, y, z-1)),
+			lerp(u, gradient(permutation[ab+1], x, y-1, z-1), gradient(permutation[bb+1], x-1, y-1, z-1))))
+}
+
+// simplexGradients are the gradients at the corners of a simplex, the midpoints of a cube's edges
+var simplexGradients = [12][3]float64{
+	{1, 1, 0}, {-1, 1, 0}, {1, -1, 0}, {-1, -1, 0},
+	{1, 0, 1}, {-1, 0, 1}, {1, 0, -1}, {-1, 0, -1},
+	{0, 1, 1}, {0, -1, 1}, {0, 1, -1}, {0, -1, -1},
+}
+
+// simplex returns simplex noise at a point, within roughly [-1, 1]
+func simplex(p geometry.Point) float64 {
+	const skew = 1.0 / 3.0
+	const unskew = 1.0 / 6.0
+
+	// find the simplex cell containing the point
+	s := (p.X + p.Y + p.Z) * skew
+	i, j, k := math.Floor(p.X+s), math.Floor(p.Y+s), math.Floor(p.Z+s)
+	t := (i + j + k) * unskew
+	x0, y0, z0 := p.X-(i-t), p.Y-(j-t), p.Z-(k-t)
+
+	// find which of the six simplices within the cell holds the point
+	var i1, j1, k1, i2, j2, k2 float64
+	if x0 >= y0 {
+		if y0 >= z0 {
+			i1, j1, k1, i2, j2, k2 = 1, 0, 0, 1, 1, 0
+		} else if x0 >= z0 {
+			i1, j1, k1, i2, j2, k2 = 1, 0, 0, 1, 0, 1
+		} else {
+			i1, j1, k1, i2, j2, k2 = 0, 0, 1, 1, 0, 1
+		}
+	} else {
+		if y0 < z0 {
+			i1, j1, k1, i2, j2, k2 = 0, 0, 1, 0, 1, 1
+		} else if x0 < z0 {
+			i1, j1, k1, i2, j2, k2 = 0, 1, 0, 0, 1, 1
+		} else {
+			i1, j1, k1, i2, j2, k2 = 0, 1, 0, 1, 1, 0
+		}
+	}
+
+	corners := [4][3]float64{
+		{x0, y0, z0},
+		{x0 - i1 + unskew, y0 - j1 + unskew, z0 - k1 + unskew},
+		{x0 - i2 + 2.0*unskew, y0 - j2 + 2.0*unskew, z0 - k2 + 2.0*unskew},
+		{x0 - 1.0 + 3.0*unskew, y0 - 1.0 + 3.0*unskew, z0 - 1.0 + 3.0*unskew},
+	}
+	offsets := [4][3]int{
+		{0, 0, 0},
+		{int(i1), int(j1), int(k1)},
+		{int(i2), int(j2), int(k2)},
+		{1, 1, 1},
+	}
+	ii, jj, kk := int(i)&255, int(j)&255, int(k)&255
+
+	total := 0.0
+	for c := 0; c < 4; c++ {
+		x, y, z := corners[c][0], corners[c][1], corners[c][2]
+		falloff := 0.6 - x*x - y*y - z*z
+		if falloff <= 0.0 {
+			continue
+		}
+		hash := permutation[ii+offsets[c][0]+permutation[jj+offsets[c][1]+permutation[kk+offsets[c][2]]]] % 12
+		g := simplexGradients[hash]
+		falloff *= falloff
+		total += falloff * falloff * (g[0]*x + g[1]*y + g[2]*z)
+	}
+	return 32.0 * total
+}
+
+// gradient returns the dot product of an offset with one of twelve gradient directions chosen by a hash
+func gradient(hash int, x, y, z float64) float64 {
+	h := hash & 15
+	u := y
+	if h < 8 {
+		u = x
+	}
+	v := z
+	if h < 4 {
+		v = y
+	} else if h == 12 || h == 14 {
+		v = x
+	}
+	if h&1 != 0 {
+		u = -u
+	}
+	if h&2 != 0 {
+		v = -v
+	}
+	return u + v
+}
+
+// fade eases a fraction towards 0 and 1 so that noise is smooth across lattice cells
+func fade(t float64) float64 {
+	return t * t * t * (t*(t*6.0-15.0) + 10.0)
+}
+
+// lerp linearly interpolates from a to b by t
+func lerp(t, a, b float64) float64 {
+	return a + t*(b-a)
+}
+
+// scalePoint returns a point with each of its coordinates multiplied by s
+func scalePoint(p geometry.Point, s float64) geometry.Point {
+	return geometry.Point{
+		X: p.X * s,
+		Y: p.Y * s,
+		Z: p.Z * s,
+	}
+}
+
+// mixColors linearly interpolates between two colors by t, clamped to [0, 1]
+func mixColors(a, b shading.Color, t float64) shading.Color {
+	t = math.Min(math.Max(t, 0.0), 1.0)
+	return a.MultScalar(1.0 - t).Add(b.MultScalar(t))
+}
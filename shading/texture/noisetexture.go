@@ -0,0 +1,56 @@
+package texture
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"fmt"
+)
+
+// Noise holds information about a solid texture which blends between two colors by a noise function
+type Noise struct {
+	ColorA  shading.Color `json:"color_a"`
+	ColorB  shading.Color `json:"color_b"`
+	Scale   float64       `json:"scale"`   // frequency of the noise, in features per unit of distance
+	Pattern string        `json:"pattern"` // how octaves are combined, either "fbm" or "turbulence"
+	Fractal
+}
+
+// Setup validates and defaults the fields of a Noise
+func (nt *Noise) Setup() (*Noise, error) {
+	switch nt.Pattern {
+	case "":
+		nt.Pattern = "fbm"
+	case "fbm", "turbulence":
+	default:
+		return nil, fmt.Errorf("noise pattern (%s) is not one of fbm or turbulence", nt.Pattern)
+	}
+	if nt.Scale < 0.0 {
+		return nil, fmt.Errorf("noise scale is negative")
+	}
+	if nt.Scale == 0.0 {
+		nt.Scale = 1.0
+	}
+	err := nt.setupFractal()
+	if err != nil {
+		return nil, err
+	}
+	return nt, nil
+}
+
+// Value returns the color of the noise on the plane of texture coordinates
+func (nt *Noise) Value(u, v float64) shading.Color {
+	return nt.SolidValue(u, v, geometry.Point{
+		X: u,
+		Y: v,
+		Z: 0.0,
+	})
+}
+
+// SolidValue returns the color of the noise at a point
+func (nt *Noise) SolidValue(u, v float64, p geometry.Point) shading.Color {
+	p = scalePoint(p, nt.Scale)
+	if nt.Pattern == "turbulence" {
+		return mixColors(nt.ColorA, nt.ColorB, nt.turbulence(p))
+	}
+	return mixColors(nt.ColorA, nt.ColorB, 0.5*(nt.fbm(p)+1.0))
+}
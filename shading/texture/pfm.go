@@ -0,0 +1,90 @@
+package texture
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// decodePFM decodes a portable float map (.pfm) image into linear colors
+// grayscale maps are expanded to gray colors
+func decodePFM(data []byte) (mipLevel, error) {
+	// the header is three whitespace separated tokens after the magic number, then a single whitespace character
+	fields := make([]string, 0, 4)
+	i := 0
+	for len(fields) < 4 {
+		for i < len(data) && isSpace(data[i]) {
+			i++
+		}
+		start := i
+		for i < len(data) && !isSpace(data[i]) {
+			i++
+		}
+		if start == i {
+			return mipLevel{}, fmt.Errorf("pfm header is truncated")
+		}
+		fields = append(fields, string(data[start:i]))
+	}
+	if i >= len(data) {
+		return mipLevel{}, fmt.Errorf("pfm header is truncated")
+	}
+	i++
+	channels := 3
+	if fields[0] == "Pf" {
+		channels = 1
+	} else if fields[0] != "PF" {
+		return mipLevel{}, fmt.Errorf("pfm magic number (%s) is not one of PF or Pf", fields[0])
+	}
+	width, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return mipLevel{}, err
+	}
+	height, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return mipLevel{}, err
+	}
+	scale, err := strconv.ParseFloat(fields[3], 64)
+	if err != nil {
+		return mipLevel{}, err
+	}
+	if width <= 0 || height <= 0 {
+		return mipLevel{}, fmt.Errorf("pfm image has no pixels")
+	}
+	// a negative scale marks little-endian values
+	var order binary.ByteOrder = binary.BigEndian
+	if scale < 0.0 {
+		order = binary.LittleEndian
+	}
+	// the dimensions are checked against the data one at a time, so their product can not overflow
+	pixels := data[i:]
+	if width > len(pixels)/(4*channels) || height > len(pixels)/(4*channels*width) {
+		return mipLevel{}, fmt.Errorf("pfm pixels are truncated")
+	}
+
+	level := mipLevel{
+		width:  width,
+		height: height,
+		texels: make([]float32, 3*width*height),
+	}
+	for y := 0; y < height; y++ {
+		// rows are stored from the bottom up
+		row := height - 1 - y
+		for x := 0; x < width; x++ {
+			for c := 0; c < 3; c++ {
+				source := c
+				if channels == 1 {
+					source = 0
+				}
+				offset := 4 * (channels*(y*width+x) + source)
+				level.texels[3*(row*width+x)+c] = nonNegative(math.Float32frombits(order.Uint32(pixels[offset : offset+4])))
+			}
+		}
+	}
+	return level, nil
+}
+
+// isSpace returns whether a byte is whitespace in a portable map header
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
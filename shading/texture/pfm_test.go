@@ -0,0 +1,70 @@
+package texture
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// pfmFile returns a portable float map with the given header followed by the values in the given byte order
+func pfmFile(header string, order binary.ByteOrder, values []float32) []byte {
+	data := []byte(header)
+	for _, value := range values {
+		var b [4]byte
+		order.PutUint32(b[:], math.Float32bits(value))
+		data = append(data, b[:]...)
+	}
+	return data
+}
+
+func TestDecodePFMColor(t *testing.T) {
+	// two pixels, red then blue, in little-endian order
+	data := pfmFile("PF\n2 1\n-1.0\n", binary.LittleEndian, []float32{1.0, 0.0, 0.0, 0.0, 0.0, 2.5})
+	level, err := decodePFM(data)
+	if err != nil {
+		t.Fatalf("Error decoding pfm: %s\n", err)
+	}
+	expected := []float32{1.0, 0.0, 0.0, 0.0, 0.0, 2.5}
+	if level.width != 2 || level.height != 1 {
+		t.Fatalf("Expected a 2 by 1 image but got %d by %d\n", level.width, level.height)
+	}
+	for i := range expected {
+		if level.texels[i] != expected[i] {
+			t.Errorf("Expected texel value %d to be %f but got %f\n", i, expected[i], level.texels[i])
+		}
+	}
+}
+
+func TestDecodePFMGray(t *testing.T) {
+	// a column of two big-endian gray pixels, stored from the bottom up
+	data := pfmFile("Pf 1 2 1.0\n", binary.BigEndian, []float32{0.25, 0.75})
+	level, err := decodePFM(data)
+	if err != nil {
+		t.Fatalf("Error decoding pfm: %s\n", err)
+	}
+	expected := []float32{0.75, 0.75, 0.75, 0.25, 0.25, 0.25}
+	for i := range expected {
+		if level.texels[i] != expected[i] {
+			t.Errorf("Expected texel value %d to be %f but got %f\n", i, expected[i], level.texels[i])
+		}
+	}
+}
+
+func TestDecodePFMErrors(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		data []byte
+	}{
+		{"empty", []byte{}},
+		{"header ending at the end of the data", []byte("PF 1 1 -1")},
+		{"magic number", pfmFile("P6\n1 1\n-1\n", binary.LittleEndian, []float32{0.0, 0.0, 0.0})},
+		{"width", pfmFile("PF\nwide 1\n-1\n", binary.LittleEndian, []float32{0.0, 0.0, 0.0})},
+		{"no pixels", []byte("PF\n0 1\n-1\n")},
+		{"truncated pixels", pfmFile("PF\n2 1\n-1\n", binary.LittleEndian, []float32{0.0, 0.0, 0.0})},
+		{"overflowing size", pfmFile("PF\n4611686018427387904 4611686018427387904\n-1\n", binary.LittleEndian, []float32{0.0, 0.0, 0.0})},
+	} {
+		if _, err := decodePFM(c.data); err == nil {
+			t.Errorf("Expected an error for pfm with bad %s\n", c.name)
+		}
+	}
+}
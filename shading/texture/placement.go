@@ -0,0 +1,126 @@
+package texture
+
+import (
+	"fluorescence/geometry"
+	"fmt"
+	"math"
+)
+
+// Placement moves a texture around on a surface
+// texture coordinates are rotated, then scaled, then offset
+type Placement struct {
+	scale    [2]float64
+	offset   [2]float64
+	cosTheta float64
+	sinTheta float64
+}
+
+// NewPlacement returns the Placement which rotates by an angle in degrees, then scales, then offsets texture coordinates
+// a zero scale means the texture covers the surface once
+func NewPlacement(scale, offset [2]float64, rotation float64) (Placement, error) {
+	if scale == [2]float64{} {
+		scale = [2]float64{1.0, 1.0}
+	}
+	if scale[0] == 0.0 || scale[1] == 0.0 {
+		return Placement{}, fmt.Errorf("texture scale has a zero component")
+	}
+	return Placement{
+		scale:    scale,
+		offset:   offset,
+		cosTheta: math.Cos((math.Pi / 180.0) * rotation),
+		sinTheta: math.Sin((math.Pi / 180.0) * rotation),
+	}, nil
+}
+
+// Map returns the placed texture coordinates
+func (p Placement) Map(u, v float64) (float64, float64) {
+	u, v = p.RotateScale(u, v)
+	return u + p.offset[0], v + p.offset[1]
+}
+
+// RotateScale rotates and then scales a change in texture coordinates, which the offset does not move
+func (p Placement) RotateScale(du, dv float64) (float64, float64) {
+	return p.scale[0] * (p.cosTheta*du - p.sinTheta*dv), p.scale[1] * (p.sinTheta*du + p.cosTheta*dv)
+}
+
+// MapFootprint returns a footprint carried into the placed texture coordinates
+func (p Placement) MapFootprint(footprint Footprint) Footprint {
+	dudx, dvdx := p.RotateScale(footprint.DUDX, footprint.DVDX)
+	dudy, dvdy := p.RotateScale(footprint.DUDY, footprint.DVDY)
+	return Footprint{
+		DUDX: dudx,
+		DVDX: dvdx,
+		DUDY: dudy,
+		DVDY: dvdy,
+	}
+}
+
+// MapFrame returns the rates of change of a surface point with the placed texture coordinates,
+// given its rates of change with the original coordinates
+func (p Placement) MapFrame(tangent, bitangent geometry.Vector) (geometry.Vector, geometry.Vector) {
+	placedTangent := tangent.MultScalar(p.cosTheta).Sub(bitangent.MultScalar(p.sinTheta))
+	placedBitangent := tangent.MultScalar(p.sinTheta).Add(bitangent.MultScalar(p.cosTheta))
+	return placedTangent.DivScalar(p.scale[0]), placedBitangent.DivScalar(p.scale[1])
+}
+
+// CheckWrap validates a wrap mode, returning "repeat" if none is given
+// a wrap mode is one of "repeat", "clamp", or "mirror"
+func CheckWrap(wrap string) (string, error) {
+	switch wrap {
+	case "":
+		return "repeat", nil
+	case "repeat", "clamp", "mirror":
+		return wrap, nil
+	default:
+		return "", fmt.Errorf("wrap mode (%s) is not one of repeat, clamp, or mirror", wrap)
+	}
+}
+
+// WrapCoordinate brings a texture coordinate back into [0, 1) according to a wrap mode
+func WrapCoordinate(wrap string, x float64) float64 {
+	switch wrap {
+	case "clamp":
+		return math.Min(math.Max(x, 0.0), math.Nextafter(1.0, 0.0))
+	case "mirror":
+		x = math.Mod(x, 2.0)
+		if x < 0.0 {
+			x += 2.0
+		}
+		if x >= 1.0 {
+			x = 2.0 - x
+		}
+		return math.Min(x, math.Nextafter(1.0, 0.0))
+	default:
+		return x - math.Floor(x)
+	}
+}
+
+// wrapTexel brings a texel coordinate back into [0, size) according to a wrap mode
+func wrapTexel(wrap string, x, size int) int {
+	switch wrap {
+	case "clamp":
+		if x < 0 {
+			return 0
+		}
+		if x >= size {
+			return size - 1
+		}
+		return x
+	case "mirror":
+		period := 2 * size
+		x %= period
+		if x < 0 {
+			x += period
+		}
+		if x >= size {
+			x = period - 1 - x
+		}
+		return x
+	default:
+		x %= size
+		if x < 0 {
+			x += size
+		}
+		return x
+	}
+}
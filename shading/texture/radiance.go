@@ -0,0 +1,191 @@
+package texture
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// decodeRadiance decodes a Radiance RGBE (.hdr) image into linear colors
+// the colors are radiance values, so are neither limited to [0, 1] nor gamma encoded
+func decodeRadiance(data []byte) (mipLevel, error) {
+	r := bufio.NewReader(bytes.NewReader(data))
+	// the header is a list of variables, ended by an empty line
+	headerSize := 0
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return mipLevel{}, fmt.Errorf("radiance header is truncated")
+		}
+		headerSize += len(line)
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, "FORMAT=") && line != "FORMAT=32-bit_rle_rgbe" {
+			return mipLevel{}, fmt.Errorf("radiance format (%s) is not supported", strings.TrimPrefix(line, "FORMAT="))
+		}
+	}
+	resolution, err := r.ReadString('\n')
+	if err != nil {
+		return mipLevel{}, fmt.Errorf("radiance resolution is missing")
+	}
+	headerSize += len(resolution)
+	fields := strings.Fields(resolution)
+	if len(fields) != 4 || (fields[0] != "-Y" && fields[0] != "+Y") || fields[2] != "+X" {
+		return mipLevel{}, fmt.Errorf("radiance resolution (%s) is not supported", strings.TrimSpace(resolution))
+	}
+	height, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return mipLevel{}, err
+	}
+	width, err := strconv.Atoi(fields[3])
+	if err != nil {
+		return mipLevel{}, err
+	}
+	if width <= 0 || height <= 0 {
+		return mipLevel{}, fmt.Errorf("radiance image has no pixels")
+	}
+	// every scanline takes at least a few bytes, so the height is checked against the data,
+	// and the width is divided into the largest image rather than multiplied by the height, so neither can overflow
+	if height > (len(data)-headerSize)/radianceScanlineSize(width) {
+		return mipLevel{}, fmt.Errorf("radiance pixels are truncated")
+	}
+	if width > math.MaxInt32/(3*height) {
+		return mipLevel{}, fmt.Errorf("radiance image (%d by %d) is too large", width, height)
+	}
+
+	level := mipLevel{
+		width:  width,
+		height: height,
+		texels: make([]float32, 3*width*height),
+	}
+	scanline := make([]byte, 4*width)
+	for y := 0; y < height; y++ {
+		err = readRadianceScanline(r, scanline)
+		if err != nil {
+			return mipLevel{}, err
+		}
+		// a +Y image is stored from the bottom up
+		row := y
+		if fields[0] == "+Y" {
+			row = height - 1 - y
+		}
+		for x := 0; x < width; x++ {
+			red, green, blue := rgbeToFloat(scanline[4*x : 4*x+4])
+			i := 3 * (row*width + x)
+			level.texels[i] = red
+			level.texels[i+1] = green
+			level.texels[i+2] = blue
+		}
+	}
+	return level, nil
+}
+
+// radianceScanlineSize returns the fewest bytes a scanline of the given width can be stored in
+// that is a single pixel followed by runs repeating it, with each run counting 256 times as many pixels as the last
+func radianceScanlineSize(width int) int {
+	size := 4
+	covered := 1
+	for shift := uint(0); covered < width && shift < 64; shift += 8 {
+		covered += 255 << shift
+		size += 4
+	}
+	return size
+}
+
+// readRadianceScanline reads one scanline of RGBE pixels, which may be flat or run-length encoded
+func readRadianceScanline(r *bufio.Reader, scanline []byte) error {
+	width := len(scanline) / 4
+	start, err := r.Peek(4)
+	if err != nil {
+		return fmt.Errorf("radiance pixels are truncated")
+	}
+	// newer encoders run-length encode each channel separately, marked by a leading 2, 2
+	if width >= 8 && width < 32768 && start[0] == 2 && start[1] == 2 && start[2]&0x80 == 0 {
+		if int(start[2])<<8|int(start[3]) != width {
+			return fmt.Errorf("radiance scanline width does not match the image")
+		}
+		r.Discard(4)
+		for c := 0; c < 4; c++ {
+			for x := 0; x < width; {
+				count, err := r.ReadByte()
+				if err != nil {
+					return fmt.Errorf("radiance pixels are truncated")
+				}
+				if count > 128 {
+					// a run of a single value
+					count -= 128
+					value, err := r.ReadByte()
+					if err != nil {
+						return fmt.Errorf("radiance pixels are truncated")
+					}
+					if x+int(count) > width {
+						return fmt.Errorf("radiance run overflows its scanline")
+					}
+					for i := 0; i < int(count); i++ {
+						scanline[4*x+c] = value
+						x++
+					}
+				} else {
+					// a run of differing values
+					if count == 0 || x+int(count) > width {
+						return fmt.Errorf("radiance run overflows its scanline")
+					}
+					for i := 0; i < int(count); i++ {
+						value, err := r.ReadByte()
+						if err != nil {
+							return fmt.Errorf("radiance pixels are truncated")
+						}
+						scanline[4*x+c] = value
+						x++
+					}
+				}
+			}
+		}
+		return nil
+	}
+	// older encoders write whole pixels, where a pixel of 1, 1, 1 repeats the one before it
+	shift := uint(0)
+	pixel := make([]byte, 4)
+	for x := 0; x < width; {
+		_, err := io.ReadFull(r, pixel)
+		if err != nil {
+			return fmt.Errorf("radiance pixels are truncated")
+		}
+		if pixel[0] == 1 && pixel[1] == 1 && pixel[2] == 1 {
+			if x == 0 {
+				return fmt.Errorf("radiance run has no pixel to repeat")
+			}
+			count := int(pixel[3]) << shift
+			if x+count > width {
+				return fmt.Errorf("radiance run overflows its scanline")
+			}
+			for i := 0; i < count; i++ {
+				copy(scanline[4*x:4*x+4], scanline[4*x-4:4*x])
+				x++
+			}
+			shift += 8
+			continue
+		}
+		copy(scanline[4*x:4*x+4], pixel)
+		x++
+		shift = 0
+	}
+	return nil
+}
+
+// rgbeToFloat converts a pixel of three mantissas sharing an exponent into linear red, green, and blue
+func rgbeToFloat(rgbe []byte) (float32, float32, float32) {
+	if rgbe[3] == 0 {
+		return 0.0, 0.0, 0.0
+	}
+	f := math.Ldexp(1.0, int(rgbe[3])-(128+8))
+	return float32((float64(rgbe[0]) + 0.5) * f),
+		float32((float64(rgbe[1]) + 0.5) * f),
+		float32((float64(rgbe[2]) + 0.5) * f)
+}
@@ -0,0 +1,84 @@
+package texture
+
+import (
+	"math"
+	"testing"
+)
+
+// radianceHeader is the header of a Radiance image, before its resolution line
+const radianceHeader = "#?RADIANCE\nFORMAT=32-bit_rle_rgbe\n\n"
+
+// rgbe returns the linear value of a mantissa with an exponent, as Radiance stores it
+func rgbe(mantissa, exponent byte) float32 {
+	return float32((float64(mantissa) + 0.5) * math.Ldexp(1.0, int(exponent)-(128+8)))
+}
+
+func TestDecodeRadianceRLE(t *testing.T) {
+	data := []byte(radianceHeader + "-Y 1 +X 8\n")
+	// each channel is run-length encoded separately: red as literals, and the others as runs
+	data = append(data, 2, 2, 0, 8)
+	data = append(data, 8, 0, 16, 32, 48, 64, 80, 96, 112)
+	data = append(data, 128+8, 64)
+	data = append(data, 128+3, 0, 128+5, 200)
+	data = append(data, 128+8, 129)
+	level, err := decodeRadiance(data)
+	if err != nil {
+		t.Fatalf("Error decoding radiance image: %s\n", err)
+	}
+	if level.width != 8 || level.height != 1 {
+		t.Fatalf("Expected an 8 by 1 image but got %d by %d\n", level.width, level.height)
+	}
+	for x := 0; x < 8; x++ {
+		blue := byte(0)
+		if x >= 3 {
+			blue = 200
+		}
+		expected := [3]float32{rgbe(byte(16*x), 129), rgbe(64, 129), rgbe(blue, 129)}
+		for c := 0; c < 3; c++ {
+			if value := level.texels[3*x+c]; value != expected[c] {
+				t.Errorf("Expected channel %d of pixel %d to be %f but got %f\n", c, x, expected[c], value)
+			}
+		}
+	}
+}
+
+func TestDecodeRadianceFlat(t *testing.T) {
+	// a +Y image is stored from the bottom up, and a pixel of 1, 1, 1 repeats the pixel before it
+	data := []byte(radianceHeader + "+Y 2 +X 3\n")
+	data = append(data, 128, 64, 0, 129, 1, 1, 1, 2)
+	data = append(data, 0, 0, 0, 0, 0, 0, 0, 0, 64, 64, 64, 130)
+	level, err := decodeRadiance(data)
+	if err != nil {
+		t.Fatalf("Error decoding radiance image: %s\n", err)
+	}
+	expected := []float32{
+		0.0, 0.0, 0.0, 0.0, 0.0, 0.0, rgbe(64, 130), rgbe(64, 130), rgbe(64, 130),
+		rgbe(128, 129), rgbe(64, 129), rgbe(0, 129), rgbe(128, 129), rgbe(64, 129), rgbe(0, 129), rgbe(128, 129), rgbe(64, 129), rgbe(0, 129),
+	}
+	for i := range expected {
+		if level.texels[i] != expected[i] {
+			t.Errorf("Expected texel value %d to be %f but got %f\n", i, expected[i], level.texels[i])
+		}
+	}
+}
+
+func TestDecodeRadianceErrors(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		data []byte
+	}{
+		{"header", []byte("#?RADIANCE\nFORMAT=32-bit_rle_rgbe\n")},
+		{"format", []byte("#?RADIANCE\nFORMAT=32-bit_rle_xyze\n\n-Y 1 +X 1\n\x00\x00\x00\x00")},
+		{"resolution", []byte(radianceHeader + "+X 1 -Y 1\n\x00\x00\x00\x00")},
+		{"truncated pixels", []byte(radianceHeader + "-Y 2 +X 1\n\x00\x00\x00\x00")},
+		{"huge resolution", []byte(radianceHeader + "-Y 3000000000 +X 3000000000\n\x00\x00\x00\x00")},
+		{"scanline width", []byte(radianceHeader + "-Y 1 +X 8\n\x02\x02\x00\x09")},
+		{"run length", []byte(radianceHeader + "-Y 1 +X 8\n\x02\x02\x00\x08\x89\x00")},
+		{"literal length", []byte(radianceHeader + "-Y 1 +X 8\n\x02\x02\x00\x08\x00")},
+		{"repeat without a pixel", []byte(radianceHeader + "-Y 1 +X 2\n\x01\x01\x01\x02")},
+	} {
+		if _, err := decodeRadiance(c.data); err == nil {
+			t.Errorf("Expected an error for radiance image with bad %s\n", c.name)
+		}
+	}
+}
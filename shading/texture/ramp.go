@@ -0,0 +1,59 @@
+package texture
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"fmt"
+	"sort"
+)
+
+// RampStop is a single color on a ColorRamp
+type RampStop struct {
+	Position float64       `json:"position"`
+	Color    shading.Color `json:"color"`
+}
+
+// ColorRamp holds information about a texture which remaps the brightness of another texture onto a ramp of colors
+// brightnesses between two stops blend their colors, and those beyond the first or last stop take its color
+type ColorRamp struct {
+	TextureName string     `json:"texture_name"`
+	Stops       []RampStop `json:"stops"`
+	texture     Texture
+}
+
+// Setup sorts the stops of the ramp and finds the texture being remapped
+func (cr *ColorRamp) Setup(textures map[string]Texture) (*ColorRamp, error) {
+	if len(cr.Stops) == 0 {
+		return nil, fmt.Errorf("color ramp has no stops")
+	}
+	sort.SliceStable(cr.Stops, func(i, j int) bool {
+		return cr.Stops[i].Position < cr.Stops[j].Position
+	})
+	var err error
+	cr.texture, err = resolve(textures, cr.TextureName, "remapped")
+	if err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// Value returns the ramp's color for the texture at the given texture coordinates
+func (cr *ColorRamp) Value(u, v float64) shading.Color {
+	return cr.CompositeValue(u, v, surfacePoint(u, v), Footprint{})
+}
+
+// CompositeValue returns the ramp's color for the texture at a point
+func (cr *ColorRamp) CompositeValue(u, v float64, p geometry.Point, footprint Footprint) shading.Color {
+	x := luminance(Sample(cr.texture, u, v, p, footprint))
+	if x <= cr.Stops[0].Position {
+		return cr.Stops[0].Color
+	}
+	for i := 1; i < len(cr.Stops); i++ {
+		if x < cr.Stops[i].Position {
+			previous := cr.Stops[i-1]
+			t := (x - previous.Position) / (cr.Stops[i].Position - previous.Position)
+			return mixColors(previous.Color, cr.Stops[i].Color, t)
+		}
+	}
+	return cr.Stops[len(cr.Stops)-1].Color
+}
@@ -0,0 +1,159 @@
+package texture
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"math"
+	"testing"
+)
+
+var (
+	black = shading.Color{}
+	white = shading.Color{Red: 1.0, Green: 1.0, Blue: 1.0}
+)
+
+// colorsClose returns whether two colors are the same to within a small tolerance
+func colorsClose(a, b shading.Color) bool {
+	return math.Abs(a.Red-b.Red) < 1e-9 && math.Abs(a.Green-b.Green) < 1e-9 && math.Abs(a.Blue-b.Blue) < 1e-9
+}
+
+// between returns whether a grey lies within [0, 1]
+func between(c shading.Color) bool {
+	return c.Red >= 0.0 && c.Red <= 1.0 && c.Red == c.Green && c.Green == c.Blue
+}
+
+func TestCheckerSolid(t *testing.T) {
+	ct, err := (&Checker{Even: black, Odd: white, Scale: 10.0, IsSolid: true}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up checker: %s\n", err)
+	}
+	// solid checks ignore the texture coordinates
+	if c := ct.SolidValue(0.95, 0.0, geometry.Point{X: 0.05, Y: 0.05, Z: 0.05}); !colorsClose(c, black) {
+		t.Errorf("Expected even cube %v but got %v\n", black, c)
+	}
+	if c := ct.SolidValue(0.0, 0.0, geometry.Point{X: 0.05, Y: 0.05, Z: 0.15}); !colorsClose(c, white) {
+		t.Errorf("Expected odd cube %v but got %v\n", white, c)
+	}
+	ct.IsSolid = false
+	if c := ct.SolidValue(0.15, 0.0, geometry.Point{}); !colorsClose(c, white) {
+		t.Errorf("Expected odd square %v but got %v\n", white, c)
+	}
+}
+
+func TestGradient(t *testing.T) {
+	linear, err := (&Gradient{StartColor: black, EndColor: white}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up gradient: %s\n", err)
+	}
+	for _, c := range []struct {
+		u, expected float64
+	}{
+		{0.0, 0.0},
+		{0.25, 0.25},
+		{1.0, 1.0},
+	} {
+		if value := linear.Value(c.u, 0.7).Red; math.Abs(value-c.expected) > 1e-9 {
+			t.Errorf("Expected %f at u %f but got %f\n", c.expected, c.u, value)
+		}
+	}
+	radial, err := (&Gradient{StartColor: black, EndColor: white, GradientType: "radial"}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up gradient: %s\n", err)
+	}
+	if value := radial.Value(0.5, 0.5).Red; value != 0.0 {
+		t.Errorf("Expected the start color at the center but got %f\n", value)
+	}
+	if value := radial.Value(0.75, 0.5).Red; math.Abs(value-0.5) > 1e-9 {
+		t.Errorf("Expected halfway to the end color but got %f\n", value)
+	}
+}
+
+func TestNoiseSolid(t *testing.T) {
+	for _, basis := range []string{"perlin", "simplex"} {
+		for _, pattern := range []string{"fbm", "turbulence"} {
+			nt, err := (&Noise{
+				ColorA:  black,
+				ColorB:  white,
+				Pattern: pattern,
+				Fractal: Fractal{Basis: basis, Octaves: 4},
+			}).Setup()
+			if err != nil {
+				t.Fatalf("Error setting up noise: %s\n", err)
+			}
+			varies := false
+			first := nt.SolidValue(0.0, 0.0, geometry.Point{X: 0.3, Y: 0.7, Z: 0.1})
+			for i := 0; i < 100; i++ {
+				p := geometry.Point{X: 0.37 * float64(i), Y: 0.11 * float64(i), Z: -0.23 * float64(i)}
+				// the same coordinates everywhere, so any change comes from the point
+				c := nt.SolidValue(0.5, 0.5, p)
+				if !between(c) {
+					t.Fatalf("Expected a grey within [0, 1] for %s %s noise but got %v\n", basis, pattern, c)
+				}
+				if c != first {
+					varies = true
+				}
+				if again := nt.SolidValue(0.5, 0.5, p); again != c {
+					t.Fatalf("Expected the same value at the same point but got %v and %v\n", c, again)
+				}
+			}
+			if !varies {
+				t.Errorf("Expected %s %s noise to vary through space\n", basis, pattern)
+			}
+		}
+	}
+	// perlin noise is zero on the lattice, which fbm maps halfway between the colors
+	nt, err := (&Noise{ColorA: black, ColorB: white}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up noise: %s\n", err)
+	}
+	if value := nt.SolidValue(0.0, 0.0, geometry.Point{X: 2.0, Y: -3.0, Z: 5.0}).Red; math.Abs(value-0.5) > 1e-9 {
+		t.Errorf("Expected 0.5 on the lattice but got %f\n", value)
+	}
+}
+
+func TestMarbleAndWoodAtOrigin(t *testing.T) {
+	mt, err := (&Marble{VeinColor: black, StoneColor: white}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up marble: %s\n", err)
+	}
+	// with no turbulence at the origin, the origin lies in the middle of a vein
+	if c := mt.SolidValue(0.5, 0.5, geometry.PointZero); !colorsClose(c, black) {
+		t.Errorf("Expected vein color %v but got %v\n", black, c)
+	}
+	wt, err := (&Wood{EarlyColor: white, LateColor: black}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up wood: %s\n", err)
+	}
+	// the rings are centered on the Y axis, starting with early wood
+	if c := wt.SolidValue(0.5, 0.5, geometry.PointZero); !colorsClose(c, white) {
+		t.Errorf("Expected early wood color %v but got %v\n", white, c)
+	}
+	for i := 0; i < 100; i++ {
+		p := geometry.Point{X: 0.13 * float64(i), Y: 0.29 * float64(i), Z: 0.07 * float64(i)}
+		if c := mt.SolidValue(0.0, 0.0, p); !between(c) {
+			t.Fatalf("Expected a grey within [0, 1] for marble but got %v\n", c)
+		}
+		if c := wt.SolidValue(0.0, 0.0, p); !between(c) {
+			t.Fatalf("Expected a grey within [0, 1] for wood but got %v\n", c)
+		}
+	}
+}
+
+func TestVoronoiFeaturePoint(t *testing.T) {
+	vt, err := (&Voronoi{CellColor: black, EdgeColor: white, Pattern: "distance", Jitter: 0.5}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up voronoi: %s\n", err)
+	}
+	// the feature point of the cube at the origin has no distance to the nearest feature point
+	feature := geometry.Point{
+		X: 0.5 + vt.Jitter*(cellHash(0, 0, 0, 0)-0.5),
+		Y: 0.5 + vt.Jitter*(cellHash(0, 0, 0, 1)-0.5),
+		Z: 0.5 + vt.Jitter*(cellHash(0, 0, 0, 2)-0.5),
+	}
+	if c := vt.SolidValue(0.0, 0.0, feature); !colorsClose(c, black) {
+		t.Errorf("Expected cell color %v at a feature point but got %v\n", black, c)
+	}
+	if _, err := (&Voronoi{Jitter: 2.0}).Setup(); err == nil {
+		t.Errorf("Expected an error for a jitter above 1\n")
+	}
+}
@@ -1,8 +1,60 @@
 package texture
 
-import "fluorescence/shading"
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+)
 
 // Texture defines behaviors of a Texture implementation
 type Texture interface {
 	Value(u, v float64) shading.Color
 }
+
+// Solid is implemented by Textures which fill space rather than covering a surface,
+// so need the position of the point being shaded
+type Solid interface {
+	// SolidValue returns the color of the texture at a point, which has texture coordinates (u, v) on its surface
+	SolidValue(u, v float64, p geometry.Point) shading.Color
+}
+
+// Composite is implemented by Textures built from other Textures,
+// which pass the point and footprint being shaded on to the Textures they use
+type Composite interface {
+	// CompositeValue returns the color of the texture at a point, over a footprint centered on its texture coordinates (u, v)
+	CompositeValue(u, v float64, p geometry.Point, footprint Footprint) shading.Color
+}
+
+// Filtered is implemented by Textures which can average their color over an area of texture space
+type Filtered interface {
+	// FilteredValue returns the average color of the texture over the footprint centered on (u, v)
+	FilteredValue(u, v float64, footprint Footprint) shading.Color
+}
+
+// Footprint describes the area of texture space covered by a single pixel
+// it is the parallelogram spanned by two axes, each given as its change in texture coordinates U and V
+type Footprint struct {
+	DUDX float64
+	DVDX float64
+	DUDY float64
+	DVDY float64
+}
+
+// IsZero returns whether the footprint covers no area, meaning the texture should be point sampled
+func (f Footprint) IsZero() bool {
+	return f == Footprint{}
+}
+
+// Sample returns the color of a texture at a point, over a footprint centered on its texture coordinates (u, v)
+// textures which can not filter are point sampled
+func Sample(t Texture, u, v float64, p geometry.Point, footprint Footprint) shading.Color {
+	if composite, ok := t.(Composite); ok {
+		return composite.CompositeValue(u, v, p, footprint)
+	}
+	if solid, ok := t.(Solid); ok {
+		return solid.SolidValue(u, v, p)
+	}
+	if filtered, ok := t.(Filtered); ok && !footprint.IsZero() {
+		return filtered.FilteredValue(u, v, footprint)
+	}
+	return t.Value(u, v)
+}
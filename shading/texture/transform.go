@@ -0,0 +1,43 @@
+package texture
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+)
+
+// Transform holds information about a texture which places another texture differently on the surface
+// texture coordinates are rotated, then scaled, then offset, before the other texture is looked up
+type Transform struct {
+	TextureName string     `json:"texture_name"`
+	Scale       [2]float64 `json:"scale"`    // number of times the texture repeats across the surface in U and V
+	Offset      [2]float64 `json:"offset"`   // shift applied to the texture coordinates after scaling
+	Rotation    float64    `json:"rotation"` // rotation of the texture coordinates about the origin, in degrees
+	texture     Texture
+	placement   Placement
+}
+
+// Setup validates and fills the internal fields of a Transform, and finds the texture being transformed
+func (tt *Transform) Setup(textures map[string]Texture) (*Transform, error) {
+	var err error
+	tt.placement, err = NewPlacement(tt.Scale, tt.Offset, tt.Rotation)
+	if err != nil {
+		return nil, err
+	}
+	tt.texture, err = resolve(textures, tt.TextureName, "transformed")
+	if err != nil {
+		return nil, err
+	}
+	return tt, nil
+}
+
+// Value returns the color of the transformed texture at the given texture coordinates
+func (tt *Transform) Value(u, v float64) shading.Color {
+	return tt.CompositeValue(u, v, surfacePoint(u, v), Footprint{})
+}
+
+// CompositeValue returns the color of the transformed texture at a point
+// the footprint is transformed along with the coordinates, so filtering follows the new placement
+func (tt *Transform) CompositeValue(u, v float64, p geometry.Point, footprint Footprint) shading.Color {
+	mappedU, mappedV := tt.placement.Map(u, v)
+	return Sample(tt.texture, mappedU, mappedV, p, tt.placement.MapFootprint(footprint))
+}
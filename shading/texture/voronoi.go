@@ -0,0 +1,96 @@
+package texture
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"fmt"
+	"math"
+)
+
+// Voronoi holds information about a solid cellular texture
+// space is divided into cells around randomly placed feature points, one per unit cube
+type Voronoi struct {
+	CellColor shading.Color `json:"cell_color"`
+	EdgeColor shading.Color `json:"edge_color"`
+	Scale     float64       `json:"scale"`   // number of cells per unit of distance
+	Jitter    float64       `json:"jitter"`  // how far feature points stray from the center of their cube, from 0 to 1
+	Pattern   string        `json:"pattern"` // either "distance", shading by distance to the nearest feature point, or "edges"
+}
+
+// Setup validates and defaults the fields of a Voronoi
+func (vt *Voronoi) Setup() (*Voronoi, error) {
+	switch vt.Pattern {
+	case "":
+		vt.Pattern = "edges"
+	case "distance", "edges":
+	default:
+		return nil, fmt.Errorf("voronoi pattern (%s) is not one of distance or edges", vt.Pattern)
+	}
+	if vt.Scale < 0.0 {
+		return nil, fmt.Errorf("voronoi scale is negative")
+	}
+	if vt.Scale == 0.0 {
+		vt.Scale = 1.0
+	}
+	if vt.Jitter < 0.0 || vt.Jitter > 1.0 {
+		return nil, fmt.Errorf("voronoi jitter is not within [0, 1]")
+	}
+	if vt.Jitter == 0.0 {
+		vt.Jitter = 1.0
+	}
+	return vt, nil
+}
+
+// Value returns the color of the cells on the plane of texture coordinates
+func (vt *Voronoi) Value(u, v float64) shading.Color {
+	return vt.SolidValue(u, v, geometry.Point{
+		X: u,
+		Y: v,
+		Z: 0.0,
+	})
+}
+
+// SolidValue returns the color of the cells at a point
+func (vt *Voronoi) SolidValue(u, v float64, p geometry.Point) shading.Color {
+	p = scalePoint(p, vt.Scale)
+	nearest, secondNearest := vt.featureDistances(p)
+	if vt.Pattern == "distance" {
+		return mixColors(vt.CellColor, vt.EdgeColor, nearest)
+	}
+	// points equally far from two feature points lie on the edge between their cells
+	return mixColors(vt.EdgeColor, vt.CellColor, (secondNearest-nearest)*4.0)
+}
+
+// featureDistances returns the distances from a point to its nearest and second nearest feature points
+func (vt *Voronoi) featureDistances(p geometry.Point) (float64, float64) {
+	cx, cy, cz := int(math.Floor(p.X)), int(math.Floor(p.Y)), int(math.Floor(p.Z))
+	nearest := math.Inf(1)
+	secondNearest := math.Inf(1)
+	for dz := -1; dz <= 1; dz++ {
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				x, y, z := cx+dx, cy+dy, cz+dz
+				feature := geometry.Point{
+					X: float64(x) + 0.5 + vt.Jitter*(cellHash(x, y, z, 0)-0.5),
+					Y: float64(y) + 0.5 + vt.Jitter*(cellHash(x, y, z, 1)-0.5),
+					Z: float64(z) + 0.5 + vt.Jitter*(cellHash(x, y, z, 2)-0.5),
+				}
+				distance := p.To(feature).Magnitude()
+				if distance < nearest {
+					secondNearest = nearest
+					nearest = distance
+				} else if distance < secondNearest {
+					secondNearest = distance
+				}
+			}
+		}
+	}
+	return nearest, secondNearest
+}
+
+// cellHash returns a repeatable pseudo-random number in [0, 1) for a lattice cell and a channel
+func cellHash(x, y, z, channel int) float64 {
+	h := permutation[(permutation[(permutation[(x&255)]+(y&255))&255]+(z&255))&255]
+	h = permutation[(h+channel*71)&255]
+	return float64(h) / 256.0
+}
@@ -0,0 +1,63 @@
+package texture
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/shading"
+	"fmt"
+	"math"
+)
+
+// Wood holds information about a solid texture of growth rings in wood
+// the rings are centered on the y axis, made irregular by noise
+type Wood struct {
+	EarlyColor shading.Color `json:"early_color"` // color of the light wood grown early in each year
+	LateColor  shading.Color `json:"late_color"`  // color of the dark wood grown late in each year
+	Scale      float64       `json:"scale"`       // number of rings per unit of distance
+	Distortion float64       `json:"distortion"`  // how strongly noise warps the rings
+	Fractal
+}
+
+// Setup validates and defaults the fields of a Wood
+func (wt *Wood) Setup() (*Wood, error) {
+	if wt.Scale < 0.0 {
+		return nil, fmt.Errorf("wood scale is negative")
+	}
+	if wt.Scale == 0.0 {
+		wt.Scale = 10.0
+	}
+	if wt.Distortion == 0.0 {
+		wt.Distortion = 0.5
+	}
+	if wt.Octaves == 0 {
+		wt.Octaves = 3
+	}
+	err := wt.setupFractal()
+	if err != nil {
+		return nil, err
+	}
+	return wt, nil
+}
+
+// Value returns the color of the wood on the plane of texture coordinates
+func (wt *Wood) Value(u, v float64) shading.Color {
+	return wt.SolidValue(u, v, geometry.Point{
+		X: u,
+		Y: v,
+		Z: 0.0,
+	})
+}
+
+// SolidValue returns the color of the wood at a point
+func (wt *Wood) SolidValue(u, v float64, p geometry.Point) shading.Color {
+	p = scalePoint(p, wt.Scale)
+	// the grain is stretched along the trunk, so noise varies slowly along y
+	warp := wt.Distortion * wt.fbm(geometry.Point{
+		X: p.X,
+		Y: p.Y / 8.0,
+		Z: p.Z,
+	})
+	rings := math.Hypot(p.X, p.Z) + warp
+	ring := rings - math.Floor(rings)
+	// each ring fades slowly from early to late wood, then snaps back
+	return mixColors(wt.EarlyColor, wt.LateColor, ring*ring*ring)
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fluorescence/geometry"
 	"fluorescence/shading"
+	"fluorescence/shading/light"
+	"fluorescence/shading/material"
 	"image"
 	"math"
 	"math/rand"
@@ -20,7 +22,8 @@ type Tile struct {
 }
 
 // TraceImage is the powerhouse function, driving the raycasting algorith by casting rays into the scene
-func TraceImage(params *Parameters, img *image.RGBA64, doneChan chan<- int, maxThreads int64) {
+// the light reaching each pixel from each of the scene's light groups is also written to groupImages, which may be empty
+func TraceImage(params *Parameters, img *image.RGBA64, groupImages []*LightGroupImage, doneChan chan<- int, maxThreads int64) {
 
 	tiles := getTiles(params, img)
 
@@ -34,28 +37,32 @@ func TraceImage(params *Parameters, img *image.RGBA64, doneChan chan<- int, maxT
 	for _, tile := range tiles {
 		r := rand.New(rand.NewSource(time.Now().UnixNano()))
 		sem.Acquire(context.Background(), 1)
-		go traceTile(params, r, img, doneChan, sem, tile, params.SampleCount)
+		go traceTile(params, r, img, groupImages, doneChan, sem, tile, params.SampleCount)
 	}
 
 }
 
 // traceTile iterates over the pixels in a tile and writes the received colors to the image
-func traceTile(p *Parameters, rng *rand.Rand, img *image.RGBA64, dc chan<- int, sem *semaphore.Weighted, t Tile, sampleCount int) {
+func traceTile(p *Parameters, rng *rand.Rand, img *image.RGBA64, groupImages []*LightGroupImage, dc chan<- int, sem *semaphore.Weighted, t Tile, sampleCount int) {
 	defer sem.Release(1)
 	for y := t.Origin.Y; y < t.Origin.Y+t.Span.Y; y++ {
 		for x := t.Origin.X; x < t.Origin.X+t.Span.X; x++ {
-			pixelColor := tracePixel(p, int(x), int(y), rng)
+			pixelColor, groupColors := tracePixel(p, int(x), int(y), rng)
 
 			img.SetRGBA64(int(x), p.ImageHeight-int(y)-1, pixelColor.ToRGBA64())
+			for i, gi := range groupImages {
+				gi.Set(int(x), p.ImageHeight-int(y)-1, groupColors[i])
+			}
 			dc <- 1
 		}
 	}
 	// dc <- 1
 }
 
-// tracePixel gets the color for a pixel
-func tracePixel(p *Parameters, x, y int, rng *rand.Rand) shading.Color {
+// tracePixel gets the color for a pixel, along with the linear light reaching it from each light group
+func tracePixel(p *Parameters, x, y int, rng *rand.Rand) (shading.Color, []shading.Color) {
 	pixelColor := shading.Color{}
+	groupColors := make([]shading.Color, len(p.Scene.LightGroups))
 	for s := 0; s < p.SampleCount; s++ {
 		// pick a random spot on the pixel to shoot a ray into
 		// this is purely random, NOT stratified
@@ -64,18 +71,35 @@ func tracePixel(p *Parameters, x, y int, rng *rand.Rand) shading.Color {
 
 		ray := p.Scene.Camera.GetRay(u, v, rng)
 
-		tempColor := traceRay(p, ray, rng, 0)
+		// paths are only followed for their light groups if the scene has any
+		var path *lightPath
+		if len(groupColors) > 0 {
+			path = &lightPath{
+				throughput: shading.ColorWhite,
+				groups:     groupColors,
+			}
+		}
+
+		tempColor := traceRay(p, ray, p.Scene.Camera.GetRayCone(), rng, 0, nil, 0.0, nil, path)
 		pixelColor = pixelColor.Add(tempColor)
 	}
+	for i := range groupColors {
+		groupColors[i] = groupColors[i].DivScalar(float64(p.SampleCount))
+	}
 	if p.UseScalingTruncation {
-		return pixelColor.DivScalar(float64(p.SampleCount)).ScaleDown(1.0).Pow(1.0 / p.GammaCorrection)
+		return pixelColor.DivScalar(float64(p.SampleCount)).ScaleDown(1.0).Pow(1.0 / p.GammaCorrection), groupColors
 	}
-	return pixelColor.DivScalar(float64(p.SampleCount)).Clamp(0, 1).Pow(1.0 / p.GammaCorrection)
+	return pixelColor.DivScalar(float64(p.SampleCount)).Clamp(0, 1).Pow(1.0 / p.GammaCorrection), groupColors
 
 }
 
 // traceRay casts in individual ray into the scene
-func traceRay(parameters *Parameters, r geometry.Ray, rng *rand.Rand, depth int) shading.Color {
+// cone is the spread of rays this ray stands for, and medium is the participating medium the ray is travelling through, if any
+// scatterPDF is the probability density with which the surface the ray left chose its direction,
+// or zero if the environment was not also sampled directly from that surface
+// link is which lights illuminate the surface the ray left, so which of the lights the ray finds it may carry back
+// path collects the light each light group sends along the ray, and is nil if the scene has no light groups
+func traceRay(parameters *Parameters, r geometry.Ray, cone geometry.RayCone, rng *rand.Rand, depth int, medium material.Medium, scatterPDF float64, link *material.LightLink, path *lightPath) shading.Color {
 
 	// if we've gone too deep...
 	if depth > parameters.MaxBounces {
@@ -84,31 +108,250 @@ func traceRay(parameters *Parameters, r geometry.Ray, rng *rand.Rand, depth int)
 	}
 	// check if we've hit something
 	rayHit, hitSomething := parameters.Scene.Objects.Intersection(r, parameters.TMin, parameters.TMax)
+	if hitSomething {
+		rayHit.ConeWidth = cone.WidthAt(rayHit.Time * r.Direction.Magnitude())
+	}
+
+	// inside a medium, the ray may scatter or be absorbed before it reaches the next surface
+	if medium != nil {
+		distance := math.Inf(1)
+		if hitSomething {
+			distance = rayHit.Time * r.Direction.Magnitude()
+		}
+		weight, mediumRay, scatteredInMedium := medium.Transmit(r, distance, rng)
+		if weight == shading.ColorBlack {
+			return shading.ColorBlack
+		}
+		path.attenuate(weight)
+		// scattering inside a medium counts as a bounce, so dense media can't walk on without end
+		if scatteredInMedium {
+			return weight.MultColor(traceRay(parameters, mediumRay, cone, rng, depth+1, medium, 0.0, link, path))
+		}
+		return weight.MultColor(traceSurface(parameters, r, rayHit, hitSomething, cone, rng, depth, scatterPDF, link, path))
+	}
+	return traceSurface(parameters, r, rayHit, hitSomething, cone, rng, depth, scatterPDF, link, path)
+}
+
+// traceSurface finds the color leaving a surface along the ray that hit it
+func traceSurface(parameters *Parameters, r geometry.Ray, rayHit *material.RayHit, hitSomething bool, cone geometry.RayCone, rng *rand.Rand, depth int, scatterPDF float64, link *material.LightLink, path *lightPath) shading.Color {
 	// if we did not hit something...
 	if !hitSomething {
-		// ...return the background color
-		// TODO: add support for HDR skymaps
-		return parameters.BackgroundColor
+		// ...return the light arriving from the environment
+		return environmentRadiance(parameters, r, scatterPDF, link, path)
 	}
 
 	mat := rayHit.Material
 
+	// materials may move their textures around on the surface
+	if mapper, ok := mat.(material.Mapper); ok {
+		mappedHit := mapper.MapUV(*rayHit)
+		rayHit = &mappedHit
+	}
+
+	// light given out by the surface is found before the path carries on past it
+	emitted := emittedLight(rayHit, link, path)
+
 	// if the surface is BLACK, it's not going to let any incoming light contribute to the outgoing color
 	// so we can safely say no light is reflected and simply return the emittance of the material
-	if mat.Reflectance(rayHit.U, rayHit.V) == shading.ColorBlack {
-		return mat.Emittance(rayHit.U, rayHit.V)
+	if mat.Reflectance(*rayHit) == shading.ColorBlack {
+		return emitted
+	}
+
+	// normal and bump maps change the normal used for shading, but not the surface itself
+	shadingHit := *rayHit
+	if perturber, ok := mat.(material.Perturber); ok {
+		shadingHit = perturber.PerturbNormal(*rayHit)
 	}
 
 	// get the reflection incoming ray
-	scatteredRay, wasScattered := rayHit.Material.Scatter(*rayHit, rng)
+	scatteredRay, wasScattered := mat.Scatter(shadingHit, rng)
 	// if no ray could have reflected to us, we just return BLACK
-	if !wasScattered {
+	// nor could one have if it only looked like a reflection to the shading normal, but went through the real surface
+	if !wasScattered || !shadingHit.IsConsistent(scatteredRay.Direction) {
 		return shading.ColorBlack
 	}
+	// rays which pass into a material with a participating medium travel through it
+	// whether the ray went inside depends on the real surface, so the geometric normal is used
+	var nextMedium material.Medium
+	if m, ok := mat.(material.Medium); ok && scatteredRay.Direction.Dot(rayHit.Geometric()) < 0 {
+		nextMedium = m
+	}
 	// get the color that came to this point and gave us the outgoing ray
-	incomingColor := traceRay(parameters, scatteredRay, rng, depth+1)
+	// the scattered ray's cone starts as wide as this one is here, and keeps spreading at the same rate
+	nextCone := geometry.RayCone{
+		Width:  rayHit.ConeWidth,
+		Spread: cone.Spread,
+	}
+	// surfaces whose scattering can be evaluated also look at the environment directly,
+	// and the light found that way is shared with the scattered ray by multiple importance sampling
+	directColor := shading.ColorBlack
+	nextScatterPDF := 0.0
+	if evaluator, ok := directEvaluator(mat); ok {
+		directColor = sampleEnvironment(parameters, &shadingHit, evaluator, rng, path).Add(sampleLights(parameters, &shadingHit, evaluator, path))
+		nextScatterPDF = evaluator.PDF(shadingHit, scatteredRay.Direction)
+	}
+	scatteredAttenuation := attenuation(&shadingHit, scatteredRay)
+	path.attenuate(scatteredAttenuation)
+	incomingColor := traceRay(parameters, scatteredRay, nextCone, rng, depth+1, nextMedium, nextScatterPDF, rayHit.LightLink, path)
 	// return the (very-roughly approximated) value of the rendering equation
-	return mat.Emittance(rayHit.U, rayHit.V).Add(mat.Reflectance(rayHit.U, rayHit.V).MultColor(incomingColor))
+	return emitted.Add(directColor).Add(scatteredAttenuation.MultColor(incomingColor))
+}
+
+// emittedLight returns the light given out by a surface towards the ray that hit it
+// a named surface only gives light to the surface the ray left if that surface is linked to it,
+// and the light of a surface in a light group is also credited to that group along the path
+func emittedLight(rayHit *material.RayHit, link *material.LightLink, path *lightPath) shading.Color {
+	emittance := rayHit.Material.Emittance(*rayHit)
+	if rayHit.Source == nil || emittance == shading.ColorBlack {
+		return emittance
+	}
+	if !link.Illuminates(rayHit.Source.Name) {
+		return shading.ColorBlack
+	}
+	path.add(rayHit.Source.Group, emittance)
+	return emittance
+}
+
+// directEvaluator returns the Evaluator of a material which can have light sampled directly towards it
+// materials which weight their own scattered rays can not be evaluated for other directions
+func directEvaluator(mat material.Material) (material.Evaluator, bool) {
+	if _, ok := mat.(material.Attenuator); ok {
+		return nil, false
+	}
+	evaluator, ok := mat.(material.Evaluator)
+	return evaluator, ok
+}
+
+// environmentRadiance returns the light arriving from the environment along a ray that left the scene
+// if the surface the ray left also sampled the environment directly, the light is weighted to share it with that sample
+// the environment is a light like any other, so is linked to surfaces and credited to its group in the same way
+func environmentRadiance(parameters *Parameters, r geometry.Ray, scatterPDF float64, link *material.LightLink, path *lightPath) shading.Color {
+	source := parameters.Scene.EnvironmentSource
+	if !link.Illuminates(source.Name) {
+		return shading.ColorBlack
+	}
+	radiance := parameters.Scene.Environment.Radiance(r.Direction)
+	if sampler, ok := parameters.Scene.Environment.(light.Sampler); ok && scatterPDF > 0 {
+		radiance = radiance.MultScalar(powerHeuristic(scatterPDF, sampler.PDF(r.Direction)))
+	}
+	path.add(source.Group, radiance)
+	return radiance
+}
+
+// sampleEnvironment returns the light reflected towards the outgoing ray from a direction chosen by the environment
+// the direction is checked for other objects in the way with a shadow ray
+func sampleEnvironment(parameters *Parameters, rayHit *material.RayHit, evaluator material.Evaluator, rng *rand.Rand, path *lightPath) shading.Color {
+	sampler, ok := parameters.Scene.Environment.(light.Sampler)
+	source := parameters.Scene.EnvironmentSource
+	if !ok || !rayHit.LightLink.Illuminates(source.Name) {
+		return shading.ColorBlack
+	}
+	direction, radiance, pdf := sampler.Sample(rng)
+	if pdf <= 0 || radiance == shading.ColorBlack || !rayHit.IsConsistent(direction) {
+		return shading.ColorBlack
+	}
+	brdf := evaluator.BRDF(*rayHit, direction)
+	if brdf == shading.ColorBlack {
+		return shading.ColorBlack
+	}
+	shadowRay := geometry.Ray{
+		Origin:    rayHit.Point,
+		Direction: direction,
+		Time:      rayHit.Ray.Time,
+	}
+	if _, blocked := parameters.Scene.Objects.Intersection(shadowRay, parameters.TMin, parameters.TMax); blocked {
+		return shading.ColorBlack
+	}
+	cosine := math.Abs(direction.Dot(rayHit.NormalAtHit.Unit()))
+	weight := powerHeuristic(pdf, evaluator.PDF(*rayHit, direction))
+	contribution := brdf.MultColor(radiance).MultScalar(cosine * weight / pdf)
+	path.add(source.Group, contribution)
+	return contribution
+}
+
+// sampleLights returns the light reflected towards the outgoing ray from each of the scene's lights linked to the surface
+// lights are points or directions, so can never be found by chance and need no weighting against the scattered ray
+// materials which aren't Evaluators never get here, so are not lit by these lights at all
+// the light from lights in a group is also credited to that group along the path
+func sampleLights(parameters *Parameters, rayHit *material.RayHit, evaluator material.Evaluator, path *lightPath) shading.Color {
+	total := shading.ColorBlack
+	hitPoint := rayHit.Point
+	for _, sl := range parameters.Scene.Lights {
+		if !rayHit.LightLink.Illuminates(sl.Name) {
+			continue
+		}
+		direction, distance, radiance := sl.Light.Illuminate(hitPoint)
+		// light reaching the point through the surface itself can't be reflected, whatever the shading normal says
+		if radiance == shading.ColorBlack || !rayHit.IsConsistent(direction) {
+			continue
+		}
+		brdf := evaluator.BRDF(*rayHit, direction)
+		if brdf == shading.ColorBlack {
+			continue
+		}
+		// anything between the point and the light casts a shadow, though not the light's own position
+		shadowRay := geometry.Ray{
+			Origin:    hitPoint,
+			Direction: direction,
+			Time:      rayHit.Ray.Time,
+		}
+		if _, blocked := parameters.Scene.Objects.Intersection(shadowRay, parameters.TMin, math.Min(distance, parameters.TMax)); blocked {
+			continue
+		}
+		cosine := math.Abs(direction.Dot(rayHit.NormalAtHit.Unit()))
+		contribution := brdf.MultColor(radiance).MultScalar(cosine)
+		path.add(sl.Group, contribution)
+		total = total.Add(contribution)
+	}
+	return total
+}
+
+// lightPath follows a path from the camera, collecting the light each light group sends back along it
+type lightPath struct {
+	throughput shading.Color   // fraction of the light leaving the path's current point which reaches the camera
+	groups     []shading.Color // light reaching the camera from each group, summed over the pixel's samples
+}
+
+// attenuate reduces the light carried back along the path by the weight of its latest step
+func (lp *lightPath) attenuate(weight shading.Color) {
+	if lp == nil {
+		return
+	}
+	lp.throughput = lp.throughput.MultColor(weight)
+}
+
+// add credits light leaving the path's current point towards the camera to a group, if the light is in one
+func (lp *lightPath) add(group int, c shading.Color) {
+	if lp == nil || group < 0 {
+		return
+	}
+	lp.groups[group] = lp.groups[group].Add(lp.throughput.MultColor(c))
+}
+
+// powerHeuristic returns the weight given to a sample taken with probability density a,
+// when the same light could also have been found by a sample with probability density b
+func powerHeuristic(a, b float64) float64 {
+	return (a * a) / (a*a + b*b)
+}
+
+// attenuation returns how much of the light arriving along the scattered ray is reflected towards the outgoing ray
+func attenuation(rayHit *material.RayHit, scatteredRay geometry.Ray) shading.Color {
+	// some materials weight each scattered ray themselves
+	if attenuator, ok := rayHit.Material.(material.Attenuator); ok {
+		return attenuator.Attenuation(*rayHit, scatteredRay)
+	}
+	// materials which can't evaluate their scattering distribution already sample in proportion to it,
+	// so their flat reflectance is the correct weight
+	evaluator, ok := rayHit.Material.(material.Evaluator)
+	if !ok {
+		return rayHit.Material.Reflectance(*rayHit)
+	}
+	pdf := evaluator.PDF(*rayHit, scatteredRay.Direction)
+	if pdf <= 0 {
+		return shading.ColorBlack
+	}
+	cosine := math.Abs(scatteredRay.Direction.Unit().Dot(rayHit.NormalAtHit.Unit()))
+	return evaluator.BRDF(*rayHit, scatteredRay.Direction).MultScalar(cosine / pdf)
 }
 
 // getTiles creates and return a grid of tiles on the image
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fluorescence/geometry"
+	"fluorescence/geometry/primitive/primitivelist"
+	"fluorescence/shading"
+	"fluorescence/shading/light"
+	"fluorescence/shading/material"
+	"fluorescence/shading/texture"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// groupScene returns parameters for a scene with a point light in the "key" group, an environment in the "sky" group,
+// and nothing to cast shadows
+func groupScene(t *testing.T) *Parameters {
+	pl, err := (&light.Point{
+		Position:  geometry.Point{Y: 2.0},
+		Color:     shading.ColorWhite,
+		Intensity: 4.0,
+	}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up point light: %s\n", err)
+	}
+	return &Parameters{
+		TMin: 1e-7,
+		TMax: math.MaxFloat64,
+		Scene: &Scene{
+			Objects:           &primitivelist.PrimitiveList{},
+			Environment:       &light.Uniform{Color: shading.Color{Blue: 0.5}},
+			EnvironmentSource: &material.LightSource{Name: "environment", Group: 1},
+			Lights:            []*SceneLight{{Name: "lamp", Group: 0, Light: pl}},
+			LightGroups:       []string{"key", "sky"},
+		},
+	}
+}
+
+// halfPath returns a path which carries half of the light back to the camera, with the given number of groups
+func halfPath(groups int) *lightPath {
+	return &lightPath{
+		throughput: shading.Color{Red: 0.5, Green: 0.5, Blue: 0.5},
+		groups:     make([]shading.Color, groups),
+	}
+}
+
+// floorHit returns a hit on a white floor facing up at the origin, seen from straight above
+func floorHit(link *material.LightLink) *material.RayHit {
+	return &material.RayHit{
+		Ray: geometry.Ray{
+			Origin:    geometry.Point{Y: 1.0},
+			Direction: geometry.Vector{Y: -1.0},
+		},
+		NormalAtHit: geometry.Vector{Y: 1.0},
+		Material: material.Lambertian{
+			ReflectanceTexture: &texture.Color{Color: shading.ColorWhite},
+			EmittanceTexture:   &texture.Color{Color: shading.ColorBlack},
+		},
+		LightLink: link,
+	}
+}
+
+func TestSampleLightsGroups(t *testing.T) {
+	parameters := groupScene(t)
+	rayHit := floorHit(nil)
+	path := halfPath(2)
+	c := sampleLights(parameters, rayHit, rayHit.Material.(material.Evaluator), path)
+	// a white floor two units below a light of intensity 4 reflects 1 / pi
+	expected := 1.0 / math.Pi
+	if math.Abs(c.Red-expected) > 1e-9 {
+		t.Errorf("Expected %f from the light but got %f\n", expected, c.Red)
+	}
+	if math.Abs(path.groups[0].Red-0.5*expected) > 1e-9 || path.groups[1] != shading.ColorBlack {
+		t.Errorf("Expected half the light in the key group alone but got %v\n", path.groups)
+	}
+
+	rayHit = floorHit(&material.LightLink{Exclude: []string{"lamp"}})
+	path = halfPath(2)
+	if c := sampleLights(parameters, rayHit, rayHit.Material.(material.Evaluator), path); c != shading.ColorBlack {
+		t.Errorf("Expected no light from an excluded light but got %v\n", c)
+	}
+	if path.groups[0] != shading.ColorBlack {
+		t.Errorf("Expected nothing credited to the key group but got %v\n", path.groups[0])
+	}
+}
+
+func TestEmittedLightLinking(t *testing.T) {
+	panel := floorHit(nil)
+	panel.Material = material.Lambertian{
+		ReflectanceTexture: &texture.Color{Color: shading.ColorBlack},
+		EmittanceTexture:   &texture.Color{Color: shading.Color{Red: 2.0}},
+	}
+	panel.Source = &material.LightSource{Name: "panel", Group: 0}
+
+	path := halfPath(1)
+	if c := emittedLight(panel, nil, path); c.Red != 2.0 {
+		t.Errorf("Expected the panel's light but got %v\n", c)
+	}
+	if path.groups[0].Red != 1.0 {
+		t.Errorf("Expected half the panel's light in its group but got %v\n", path.groups[0])
+	}
+	path = halfPath(1)
+	if c := emittedLight(panel, &material.LightLink{Include: []string{"lamp"}}, path); c != shading.ColorBlack {
+		t.Errorf("Expected no light from a panel the last surface isn't linked to but got %v\n", c)
+	}
+	if path.groups[0] != shading.ColorBlack {
+		t.Errorf("Expected nothing credited to the panel's group but got %v\n", path.groups[0])
+	}
+	// surfaces without a name give their light to every surface
+	panel.Source = nil
+	if c := emittedLight(panel, &material.LightLink{Include: []string{"lamp"}}, halfPath(1)); c.Red != 2.0 {
+		t.Errorf("Expected the unnamed panel's light but got %v\n", c)
+	}
+}
+
+func TestEnvironmentLinking(t *testing.T) {
+	parameters := groupScene(t)
+	ray := geometry.Ray{Direction: geometry.Vector{Y: 1.0}}
+	path := halfPath(2)
+	if c := environmentRadiance(parameters, ray, 0.0, nil, path); c.Blue != 0.5 {
+		t.Errorf("Expected the environment's light but got %v\n", c)
+	}
+	if path.groups[1].Blue != 0.25 || path.groups[0] != shading.ColorBlack {
+		t.Errorf("Expected half the environment's light in the sky group alone but got %v\n", path.groups)
+	}
+	path = halfPath(2)
+	if c := environmentRadiance(parameters, ray, 0.0, &material.LightLink{Exclude: []string{"environment"}}, path); c != shading.ColorBlack {
+		t.Errorf("Expected no light from an excluded environment but got %v\n", c)
+	}
+	if path.groups[1] != shading.ColorBlack {
+		t.Errorf("Expected nothing credited to the sky group but got %v\n", path.groups[1])
+	}
+	// environments which can be sampled are linked the same way
+	rayHit := floorHit(&material.LightLink{Include: []string{"lamp"}})
+	sky, err := (&light.Sky{Resolution: 16, SunElevation: 45.0}).Setup()
+	if err != nil {
+		t.Fatalf("Error setting up sky: %s\n", err)
+	}
+	parameters.Scene.Environment = sky
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 16; i++ {
+		if c := sampleEnvironment(parameters, rayHit, rayHit.Material.(material.Evaluator), rng, path); c != shading.ColorBlack {
+			t.Fatalf("Expected no light from a sky the surface isn't linked to but got %v\n", c)
+		}
+	}
+}